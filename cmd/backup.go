@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/tools/backup"
+
+	"github.com/urfave/cli/v2"
+)
+
+func backupCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "backup",
+		Usage:     "dump the schema, pages and page content of a Notion database to JSON files",
+		ArgsUsage: "<databaseID>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "directory to write the backup to (default: ./backup/<databaseID>/<time>)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			databaseID := c.Args().First()
+			if len(databaseID) == 0 || c.NArg() > 1 {
+				return exitf(exitUsage, "backup: expected a single database ID")
+			}
+			if err := requireFlags(c, "notionSecret"); err != nil {
+				return exitf(exitUsage, "%v", err)
+			}
+			dir := c.String("dir")
+			if len(dir) == 0 {
+				dir = filepath.Join("backup", databaseID, time.Now().Format("20060102-150405"))
+			}
+
+			client := notionapi.NewClient(c.String("notionSecret"), notionClientOptions(c)...)
+			pages, err := backup.Backup(c.Context, client, databaseID, dir)
+			if err != nil {
+				return exitf(exitFailure, "backup: %v", err)
+			}
+			fmt.Fprintf(c.App.Writer, "%v pages backed up to %v\n", pages, dir)
+			return nil
+		},
+	}
+}
+
+func restoreCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "restore",
+		Usage:     "recreate a backed up Notion database, with its pages, in a new database",
+		ArgsUsage: "<dir>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "parentPageID",
+				Usage: "page to create the new database in",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			dir := c.Args().First()
+			if len(dir) == 0 || c.NArg() > 1 {
+				return exitf(exitUsage, "restore: expected a single backup directory")
+			}
+			if err := requireFlags(c, "notionSecret", "parentPageID"); err != nil {
+				return exitf(exitUsage, "%v", err)
+			}
+
+			client := notionapi.NewClient(c.String("notionSecret"), notionClientOptions(c)...)
+			result, err := backup.Restore(c.Context, client, dir, c.String("parentPageID"))
+			for _, value := range result.Skipped {
+				fmt.Fprintf(c.App.Writer, "page %v: %v %q not restored: %v\n", value.PageID, value.Property, value.Value, value.Reason)
+			}
+			if err != nil {
+				if len(result.DatabaseID) > 0 {
+					return exitf(exitFailure, "restore: %v (%v pages restored to database %v)", err, result.Pages, result.DatabaseID)
+				}
+				return exitf(exitFailure, "restore: %v", err)
+			}
+			fmt.Fprintf(c.App.Writer, "%v pages restored to database %v\n", result.Pages, result.DatabaseID)
+			return nil
+		},
+	}
+}
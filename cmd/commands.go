@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"notionsync/pkg/metrics"
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/todoapi"
+	"notionsync/pkg/tracing"
+	"notionsync/tools/journal"
+	"notionsync/tools/notion"
+	"notionsync/tools/todo"
+
+	"github.com/urfave/cli/v2"
+)
+
+func newNotion(c *cli.Context, opts ...notion.Option) (notion.API, error) {
+	if err := requireFlags(c, "notionSecret", "notionDatabaseID"); err != nil {
+		return nil, exitf(exitUsage, "%v", err)
+	}
+
+	policy, err := notion.ParseDeletionPolicy(c.String("deletionPolicy"))
+	if err != nil {
+		return nil, exitf(exitUsage, "%v", err)
+	}
+
+	opts = append(opts,
+		notion.WithClientOptions(notionClientOptions(c)...),
+		notion.WithDeletionPolicy(policy, c.Duration("deletionRetention")),
+	)
+	if taskListDatabase := c.String("notionTaskListDatabaseID"); len(taskListDatabase) > 0 {
+		opts = append(opts, notion.WithTaskListDatabase(taskListDatabase))
+	}
+	if statusProperty := c.String("statusProperty"); len(statusProperty) > 0 {
+		mapping, err := notion.ParseStatusMapping(c.StringSlice("statusMap"))
+		if err != nil {
+			return nil, exitf(exitUsage, "%v", err)
+		}
+		opts = append(opts, notion.WithStatusProperty(statusProperty, mapping))
+	}
+	if c.Bool("conflictComments") {
+		opts = append(opts, notion.WithConflictComments())
+	}
+
+	return notion.New(c.String("notionSecret"), c.String("notionDatabaseID"), opts...), nil
+}
+
+func newTodo(c *cli.Context, notionAPI notion.API) (todo.API, error) {
+	if err := requireFlags(c, "todoClientID", "todoClientSecret"); err != nil {
+		return nil, exitf(exitUsage, "%v", err)
+	}
+
+	clientOpts, err := todoClientOptions(c)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []todo.Option{
+		todo.WithListRules(todo.ParseListRules(c.StringSlice("includeList")), todo.ParseListRules(c.StringSlice("excludeList"))),
+		todo.WithDiscoveryInterval(c.Duration("listDiscoveryInterval")),
+		todo.WithClientOptions(clientOpts...),
+	}
+	if c.Bool("mirrorComments") {
+		opts = append(opts, todo.WithCommentMirror())
+	}
+
+	todoAPI, err := todo.New(c.String("todoClientID"), c.String("todoClientSecret"), notionAPI, opts...)
+	if err != nil {
+		return nil, exitf(exitFailure, "create todo client: %v (run the login command first)", err)
+	}
+
+	return todoAPI, nil
+}
+
+// newSync returns the APIs of the commands that write to Notion, journaling
+// their changes when enabled.
+func newSync(c *cli.Context) (todo.API, notion.API, error) {
+	var opts []notion.Option
+	if path := c.String("journal"); len(path) > 0 {
+		j, err := journal.Open(path)
+		if err != nil {
+			return nil, nil, exitf(exitFailure, "%v", err)
+		}
+		opts = append(opts, notion.WithJournal(j))
+	}
+
+	notionAPI, err := newNotion(c, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	todoAPI, err := newTodo(c, notionAPI)
+	if err != nil {
+		return nil, nil, err
+	}
+	return todoAPI, notionAPI, nil
+}
+
+// notionClientOptions returns the options of the Notion API clients the
+// commands create.
+func notionClientOptions(c *cli.Context) []notionapi.ClientOption {
+	opts := []notionapi.ClientOption{
+		notionapi.WithHTTPClient(instrumentedClient("notion")),
+		notionapi.WithUserAgent(userAgent(c)),
+	}
+	if baseURL := c.String("notionBaseURL"); len(baseURL) > 0 {
+		opts = append(opts, notionapi.WithBaseURL(baseURL))
+	}
+	return opts
+}
+
+// todoClientOptions returns the options of the To Do API clients the commands
+// create.
+func todoClientOptions(c *cli.Context) ([]todoapi.ClientOption, error) {
+	cloud, err := todoapi.ParseCloud(c.String("todoCloud"))
+	if err != nil {
+		return nil, exitf(exitUsage, "%v", err)
+	}
+
+	opts := []todoapi.ClientOption{
+		todoapi.WithHTTPClient(instrumentedClient("todo")),
+		todoapi.WithUserAgent(userAgent(c)),
+		todoapi.WithCloud(cloud),
+	}
+	if graphURL := c.String("todoGraphURL"); len(graphURL) > 0 {
+		opts = append(opts, todoapi.WithBaseURL(graphURL))
+	}
+	if loginURL := c.String("todoLoginURL"); len(loginURL) > 0 {
+		opts = append(opts, todoapi.WithLoginURL(loginURL))
+	}
+	return opts, nil
+}
+
+func userAgent(c *cli.Context) string {
+	return c.App.Name + "/" + version
+}
+
+// instrumentedClient returns an http.Client that traces and records metrics
+// for the requests made to api.
+func instrumentedClient(api string) *http.Client {
+	return &http.Client{
+		Transport: tracing.RoundTripper(api, metrics.InstrumentRoundTripper(api, nil)),
+	}
+}
+
+func runAction(c *cli.Context) error {
+	todoAPI, notionAPI, err := newSync(c)
+	if err != nil {
+		return err
+	}
+
+	serve(c.String("listen"), todoAPI, notionAPI, c.Int("readyIntervals"))
+
+	if err := todoAPI.UpdateNotionAllToDo(c.Context); err != nil {
+		return exitf(exitFailure, "run: %v", err)
+	}
+	return nil
+}
+
+func runCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "run",
+		Usage:  "keep syncing To Do changes into Notion",
+		Action: runAction,
+	}
+}
+
+func onceCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "once",
+		Usage: "sync every task of every task list once and exit",
+		Action: func(c *cli.Context) error {
+			todoAPI, _, err := newSync(c)
+			if err != nil {
+				return err
+			}
+
+			if err := todoAPI.SyncOnce(c.Context); err != nil {
+				return exitf(exitFailure, "once: %v", err)
+			}
+			return nil
+		},
+	}
+}
+
+func reconcileCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "reconcile",
+		Usage: "sync every task once and mark Notion rows of tasks no longer in To Do as deleted",
+		Action: func(c *cli.Context) error {
+			todoAPI, _, err := newSync(c)
+			if err != nil {
+				return err
+			}
+
+			if err := todoAPI.Reconcile(c.Context); err != nil {
+				return exitf(exitFailure, "reconcile: %v", err)
+			}
+			return nil
+		},
+	}
+}
+
+func loginCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "login",
+		Usage: "authorize To Do access and save the refresh token",
+		Action: func(c *cli.Context) error {
+			if err := requireFlags(c, "todoClientID", "todoClientSecret"); err != nil {
+				return exitf(exitUsage, "%v", err)
+			}
+
+			clientOpts, err := todoClientOptions(c)
+			if err != nil {
+				return err
+			}
+			if err := todo.GetToken(c.String("todoClientID"), c.String("todoClientSecret"), clientOpts...); err != nil {
+				return exitf(exitFailure, "login: %v", err)
+			}
+			return nil
+		},
+	}
+}
+
+func schemaCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "schema",
+		Usage: "check or apply the properties the sync needs on the Notion database",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "check",
+				Usage: "report missing or mistyped properties",
+				Action: func(c *cli.Context) error {
+					notionAPI, err := newNotion(c)
+					if err != nil {
+						return err
+					}
+
+					problems, err := notionAPI.CheckSchema(c.Context)
+					if err != nil {
+						return exitf(exitFailure, "schema check: %v", err)
+					}
+
+					for _, problem := range problems {
+						fmt.Fprintln(c.App.Writer, problem)
+					}
+					if len(problems) > 0 {
+						return exitf(exitSchemaMismatch, "schema check: %v problems found", len(problems))
+					}
+
+					fmt.Fprintln(c.App.Writer, "schema ok")
+					return nil
+				},
+			},
+			{
+				Name:  "apply",
+				Usage: "add missing properties",
+				Action: func(c *cli.Context) error {
+					notionAPI, err := newNotion(c)
+					if err != nil {
+						return err
+					}
+
+					if err := notionAPI.ApplySchema(c.Context); err != nil {
+						return exitf(exitSchemaMismatch, "schema apply: %v", err)
+					}
+
+					fmt.Fprintln(c.App.Writer, "schema applied")
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func statusCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "status",
+		Usage: "show the state of every list worker of a running sync, queried from its --listen address",
+		Action: func(c *cli.Context) error {
+			addr := c.String("listen")
+			if len(addr) == 0 {
+				return localStatus(c)
+			}
+
+			resp, err := queryStatus(addr)
+			if err != nil {
+				return exitf(exitFailure, "status: %v", err)
+			}
+
+			printStatus(c.App.Writer, resp)
+			if !resp.Ready {
+				return exitf(exitFailure, "status: not ready")
+			}
+			return nil
+		},
+	}
+}
+
+// queryStatus fetches /status from the server of a running sync.
+func queryStatus(addr string) (statusResponse, error) {
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get(strings.TrimSuffix(addr, "/") + "/status")
+	if err != nil {
+		return statusResponse{}, err
+	}
+	defer res.Body.Close()
+
+	var resp statusResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return statusResponse{}, fmt.Errorf("decode %v response: %w", res.Status, err)
+	}
+	return resp, nil
+}
+
+func printStatus(w io.Writer, resp statusResponse) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LIST\tSTATE\tLAST DELTA\tBACKLOG\tLAST ERROR")
+	for _, list := range resp.Lists {
+		lastDelta := "-"
+		if !list.LastDelta.IsZero() {
+			lastDelta = time.Since(list.LastDelta).Truncate(time.Second).String() + " ago"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", list.DisplayName, list.State, lastDelta, list.Backlog, list.LastError)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintln(w)
+	if resp.Ready {
+		fmt.Fprintln(w, "ready")
+		return
+	}
+	fmt.Fprintln(w, "not ready:")
+	for _, problem := range resp.Problems {
+		fmt.Fprintf(w, "  %v\n", problem)
+	}
+}
+
+// localStatus checks the To Do token and the Notion database directly, for
+// when no sync is running with --listen.
+func localStatus(c *cli.Context) error {
+	var failed bool
+
+	if err := requireFlags(c, "todoClientID", "todoClientSecret"); err != nil {
+		return exitf(exitUsage, "%v", err)
+	}
+	clientOpts, err := todoClientOptions(c)
+	if err != nil {
+		return err
+	}
+	client, err := todoapi.NewClient(c.String("todoClientID"), c.String("todoClientSecret"), clientOpts...)
+	if err == nil {
+		var lists []todoapi.TaskList
+		lists, err = client.ListTaskLists(c.Context)
+		if err == nil {
+			fmt.Fprintf(c.App.Writer, "todo: ok, %v task lists\n", len(lists))
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(c.App.Writer, "todo: %v\n", err)
+		failed = true
+	}
+
+	notionAPI, err := newNotion(c)
+	if err != nil {
+		return err
+	}
+	problems, err := notionAPI.CheckSchema(c.Context)
+	switch {
+	case err != nil:
+		fmt.Fprintf(c.App.Writer, "notion: %v\n", err)
+		failed = true
+	case len(problems) > 0:
+		fmt.Fprintf(c.App.Writer, "notion: reachable, %v schema problems\n", len(problems))
+		failed = true
+	default:
+		fmt.Fprintln(c.App.Writer, "notion: ok")
+	}
+
+	if failed {
+		return exitf(exitFailure, "status: not healthy")
+	}
+	return nil
+}
+
+func versionCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "version",
+		Usage: "print the version",
+		Action: func(c *cli.Context) error {
+			fmt.Fprintln(c.App.Writer, version)
+			return nil
+		},
+	}
+}
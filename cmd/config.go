@@ -0,0 +1,319 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/tracing"
+	"notionsync/tools/notion"
+
+	"github.com/BurntSushi/toml"
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+	"gopkg.in/yaml.v2"
+)
+
+// Settings are layered: the config file is read first, environment variables
+// override it and command line flags override both.
+func newFlags() []cli.Flag {
+	logConfig := logger.DefaultConfig()
+
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Usage:   "load settings from a YAML (.yaml, .yml) or TOML (.toml) file",
+			EnvVars: []string{"NOTION_SYNC_CONFIG"},
+		},
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "notionSecret",
+			Aliases: []string{"ns"},
+			Usage:   "notion secret",
+			EnvVars: []string{"NOTION_SECRET"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "notionDatabaseID",
+			Aliases: []string{"nd"},
+			Usage:   "notion databaseID",
+			EnvVars: []string{"NOTION_DATABASE_ID"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "notionTaskListDatabaseID",
+			Aliases: []string{"nl"},
+			Usage:   "notion database keeping one page per task list, linked from tasks by a \"Task List\" relation",
+			EnvVars: []string{"NOTION_TASK_LIST_DATABASE_ID"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "notionBaseURL",
+			Usage:   "base URL of the Notion API, for a proxy or a fake server (default: " + notionapi.DefaultBaseURL + ")",
+			EnvVars: []string{"NOTION_BASE_URL"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "todoClientID",
+			Aliases: []string{"tc"},
+			Usage:   "todo clientID",
+			EnvVars: []string{"TODO_CLIENT_ID"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "todoClientSecret",
+			Aliases: []string{"tcs"},
+			Usage:   "todo client secret",
+			EnvVars: []string{"TODO_CLIENT_SECRET"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "todoCloud",
+			Usage:   "Microsoft cloud of To Do: global, china or usgov",
+			Value:   "global",
+			EnvVars: []string{"TODO_CLOUD"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "todoGraphURL",
+			Usage:   "Microsoft Graph URL overriding the one of todoCloud, for a proxy or a fake server",
+			EnvVars: []string{"TODO_GRAPH_URL"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "todoLoginURL",
+			Usage:   "Microsoft login URL overriding the one of todoCloud",
+			EnvVars: []string{"TODO_LOGIN_URL"},
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:    "includeList",
+			Aliases: []string{"il"},
+			Usage:   "only sync task lists matching name or wellKnownListName, optionally routed as name=databaseID",
+			EnvVars: []string{"TODO_INCLUDE_LISTS"},
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:    "excludeList",
+			Aliases: []string{"el"},
+			Usage:   "skip task lists matching name or wellKnownListName",
+			EnvVars: []string{"TODO_EXCLUDE_LISTS"},
+		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:    "listDiscoveryInterval",
+			Usage:   "how often to look for newly created task lists",
+			Value:   5 * time.Minute,
+			EnvVars: []string{"TODO_LIST_DISCOVERY_INTERVAL"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "listen",
+			Usage:   "address of the HTTP server exposing /metrics, /healthz, /readyz and /status while running, e.g. :9090",
+			EnvVars: []string{"NOTION_SYNC_LISTEN"},
+		}),
+		altsrc.NewIntFlag(&cli.IntFlag{
+			Name:    "readyIntervals",
+			Usage:   "report not ready when a task list has not synced within this many poll intervals",
+			Value:   3,
+			EnvVars: []string{"NOTION_SYNC_READY_INTERVALS"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "deletionPolicy",
+			Usage:   "what to do with the row of a task deleted in To Do: checkbox, archive, or archive-after the retention",
+			Value:   string(notion.DeletionCheckbox),
+			EnvVars: []string{"NOTION_DELETION_POLICY"},
+		}),
+		altsrc.NewDurationFlag(&cli.DurationFlag{
+			Name:    "deletionRetention",
+			Usage:   "how long rows stay marked deleted before being archived under the archive-after policy",
+			Value:   30 * 24 * time.Hour,
+			EnvVars: []string{"NOTION_DELETION_RETENTION"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "statusProperty",
+			Usage:   "also write the To Do status of tasks to this Notion status property, empty to only tick Done",
+			EnvVars: []string{"NOTION_STATUS_PROPERTY"},
+		}),
+		altsrc.NewStringSliceFlag(&cli.StringSliceFlag{
+			Name:    "statusMap",
+			Usage:   "map a To Do status to an option of the status property as todoStatus=option, e.g. waitingOnOthers=Blocked",
+			EnvVars: []string{"NOTION_STATUS_MAP"},
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    "conflictComments",
+			Usage:   "comment on a Notion page when a To Do change overwrites values last edited in Notion",
+			EnvVars: []string{"NOTION_CONFLICT_COMMENTS"},
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    "mirrorComments",
+			Usage:   "copy the comments on the Notion page of a task to the end of its To Do body",
+			EnvVars: []string{"NOTION_MIRROR_COMMENTS"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "journal",
+			Usage:   "append every change made to a Notion page to this JSONL file, empty to disable",
+			Value:   "./log/journal.jsonl",
+			EnvVars: []string{"NOTION_SYNC_JOURNAL"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "traceExporter",
+			Usage:   "export OpenTelemetry spans to: none, stdout or otlp",
+			Value:   tracing.ExporterNone,
+			EnvVars: []string{"NOTION_SYNC_TRACE_EXPORTER"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "traceEndpoint",
+			Usage:   "OTLP/HTTP collector address for the otlp exporter, e.g. http://localhost:4318; defaults to the OTEL_EXPORTER_OTLP_* variables",
+			EnvVars: []string{"NOTION_SYNC_TRACE_ENDPOINT"},
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    "logEnableConsole",
+			Usage:   "write logs to stdout",
+			Value:   logConfig.EnableConsole,
+			EnvVars: []string{"LOG_ENABLE_STDOUT"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "logConsoleLevel",
+			Usage:   "stdout log level: debug, info, warn, error or fatal",
+			Value:   logConfig.ConsoleLevel,
+			EnvVars: []string{"LOG_CONSOLE_LEVEL"},
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    "logConsoleJSON",
+			Usage:   "write stdout logs as JSON",
+			Value:   logConfig.ConsoleJSONFormat,
+			EnvVars: []string{"LOG_CONSOLE_JSON_FORMAT"},
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    "logEnableFile",
+			Usage:   "write logs to a file",
+			Value:   logConfig.EnableFile,
+			EnvVars: []string{"LOG_ENABLE_FILE"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "logFileLevel",
+			Usage:   "file log level: debug, info, warn, error or fatal",
+			Value:   logConfig.FileLevel,
+			EnvVars: []string{"LOG_FILE_LEVEL"},
+		}),
+		altsrc.NewBoolFlag(&cli.BoolFlag{
+			Name:    "logFileJSON",
+			Usage:   "write file logs as JSON",
+			Value:   logConfig.FileJSONFormat,
+			EnvVars: []string{"LOG_FILE_JSON_FORMAT"},
+		}),
+		altsrc.NewStringFlag(&cli.StringFlag{
+			Name:    "logFile",
+			Usage:   "log file path",
+			Value:   logConfig.FileName,
+			EnvVars: []string{"LOG_FILE_NAME"},
+		}),
+	}
+}
+
+// loadConfigFile applies the file named by the config flag to every flag it
+// does not set explicitly, picking the format from the file extension.
+func loadConfigFile(flags []cli.Flag) cli.BeforeFunc {
+	return func(c *cli.Context) error {
+		path := c.String("config")
+		if len(path) == 0 {
+			return nil
+		}
+
+		var (
+			source altsrc.InputSourceContext
+			keys   map[string]interface{}
+			err    error
+		)
+		switch ext := strings.ToLower(filepath.Ext(path)); ext {
+		case ".toml":
+			if source, err = altsrc.NewTomlSourceFromFile(path); err == nil {
+				_, err = toml.DecodeFile(path, &keys)
+			}
+		case ".yaml", ".yml", "":
+			if source, err = altsrc.NewYamlSourceFromFile(path); err == nil {
+				err = readYAML(path, &keys)
+			}
+		default:
+			return fmt.Errorf("unsupported config file extension %q", ext)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := altsrc.ApplyInputSourceValues(c, source, flags); err != nil {
+			return err
+		}
+		return applyConfigBools(c, source, keys, flags)
+	}
+}
+
+func readYAML(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, v)
+}
+
+// applyConfigBools sets the bool flags whose key is in the config file. The
+// altsrc bool flags only apply true, as their source reads a missing key as
+// false, which leaves a default of true unchanged by false in the file.
+func applyConfigBools(c *cli.Context, source altsrc.InputSourceContext, keys map[string]interface{}, flags []cli.Flag) error {
+	for _, flag := range flags {
+		f, ok := flag.(*altsrc.BoolFlag)
+		if !ok {
+			continue
+		}
+		if _, ok := keys[f.Name]; !ok || c.IsSet(f.Name) || envVarSet(f.EnvVars) {
+			continue
+		}
+
+		value, err := source.Bool(f.Name)
+		if err != nil {
+			return err
+		}
+		if err := c.Set(f.Name, strconv.FormatBool(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func envVarSet(names []string) bool {
+	for _, name := range names {
+		if _, ok := os.LookupEnv(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// requireFlags reports the first of names that has no value from any source.
+// Flags cannot be marked Required, as that is checked before the config file
+// is loaded.
+func requireFlags(c *cli.Context, names ...string) error {
+	for _, name := range names {
+		if len(c.String(name)) == 0 {
+			return fmt.Errorf("required flag %q not set", name)
+		}
+	}
+	return nil
+}
+
+// loggerConfig returns the logger config with the log flags applied.
+func loggerConfig(c *cli.Context) *logger.Config {
+	config := logger.DefaultConfig()
+	config.EnableConsole = c.Bool("logEnableConsole")
+	config.ConsoleLevel = c.String("logConsoleLevel")
+	config.ConsoleJSONFormat = c.Bool("logConsoleJSON")
+	config.EnableFile = c.Bool("logEnableFile")
+	config.FileLevel = c.String("logFileLevel")
+	config.FileJSONFormat = c.Bool("logFileJSON")
+	config.FileName = c.String("logFile")
+	return &config
+}
+
+// tracingConfig returns the tracing config with the trace flags applied.
+func tracingConfig(c *cli.Context) tracing.Config {
+	return tracing.Config{
+		Exporter:    c.String("traceExporter"),
+		Endpoint:    c.String("traceEndpoint"),
+		ServiceName: c.App.Name,
+		Version:     version,
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/urfave/cli/v2"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	t.Parallel()
+
+	type settings struct {
+		NotionSecret     string
+		LogEnableConsole bool
+		LogEnableFile    bool
+		ConflictComments bool
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		args     []string
+		expected settings
+	}{
+		{
+			name:     "defaults",
+			expected: settings{LogEnableConsole: true, LogEnableFile: true},
+		},
+		{
+			name: "yaml",
+			file: "notionSync.yaml",
+			content: `notionSecret: secret
+logEnableConsole: false
+logEnableFile: false
+conflictComments: true
+`,
+			expected: settings{NotionSecret: "secret", ConflictComments: true},
+		},
+		{
+			name: "toml",
+			file: "notionSync.toml",
+			content: `notionSecret = "secret"
+logEnableFile = false
+`,
+			expected: settings{NotionSecret: "secret", LogEnableConsole: true},
+		},
+		{
+			name:     "flag overrides file",
+			file:     "notionSync.yml",
+			content:  "logEnableFile: false\n",
+			args:     []string{"--logEnableFile=true"},
+			expected: settings{LogEnableConsole: true, LogEnableFile: true},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			args := []string{"notionSync"}
+			if len(tt.file) > 0 {
+				path := filepath.Join(t.TempDir(), tt.file)
+				if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				args = append(args, "--config", path)
+			}
+			args = append(args, tt.args...)
+
+			flags := newFlags()
+			var got settings
+			app := &cli.App{
+				Flags:  flags,
+				Before: loadConfigFile(flags),
+				Action: func(c *cli.Context) error {
+					got = settings{
+						NotionSecret:     c.String("notionSecret"),
+						LogEnableConsole: c.Bool("logEnableConsole"),
+						LogEnableFile:    c.Bool("logEnableFile"),
+						ConflictComments: c.Bool("conflictComments"),
+					}
+					return nil
+				},
+			}
+			if err := app.Run(args); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Fatalf("settings not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/renderer"
+
+	"github.com/urfave/cli/v2"
+)
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "export",
+		Usage:     "render a Notion page and its blocks as Markdown or HTML",
+		ArgsUsage: "<pageID>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format, markdown or html",
+				Value: "markdown",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "write to a file instead of stdout",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			pageID := c.Args().First()
+			if len(pageID) == 0 || c.NArg() > 1 {
+				return exitf(exitUsage, "export: expected a single page ID")
+			}
+			format := c.String("format")
+			if format != "markdown" && format != "html" {
+				return exitf(exitUsage, "export: unknown format %q, expected markdown or html", format)
+			}
+			if err := requireFlags(c, "notionSecret"); err != nil {
+				return exitf(exitUsage, "%v", err)
+			}
+
+			client := notionapi.NewClient(c.String("notionSecret"), notionClientOptions(c)...)
+			page, err := client.FindPageByID(c.Context, pageID)
+			if err != nil {
+				return exitf(exitFailure, "export: %v", err)
+			}
+			nodes, err := renderer.FetchTree(c.Context, client, pageID)
+			if err != nil {
+				return exitf(exitFailure, "export: %v", err)
+			}
+
+			title := renderer.PageTitle(page)
+			var out string
+			if format == "html" {
+				out = renderer.HTMLDocument(title, nodes)
+			} else {
+				out = "# " + title + "\n\n" + renderer.Markdown(nodes) + "\n"
+			}
+
+			path := c.String("output")
+			if len(path) == 0 {
+				if _, err := io.WriteString(c.App.Writer, out); err != nil {
+					return exitf(exitFailure, "export: %v", err)
+				}
+				return nil
+			}
+
+			f, err := os.Create(path)
+			if err != nil {
+				return exitf(exitFailure, "export: %v", err)
+			}
+			if _, err := io.WriteString(f, out); err != nil {
+				_ = f.Close()
+				return exitf(exitFailure, "export: %v", err)
+			}
+			if err := f.Close(); err != nil {
+				return exitf(exitFailure, "export: %v", err)
+			}
+			return nil
+		},
+	}
+}
+
+func importCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "import",
+		Usage:     "append a Markdown file to a Notion page, uploading its local images, reading stdin without a file",
+		ArgsUsage: "<pageID> [file]",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "allowOutsideDir",
+				Usage: "upload local images outside the directory of the file, by absolute paths or paths with ..",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			pageID := c.Args().First()
+			if len(pageID) == 0 || c.NArg() > 2 {
+				return exitf(exitUsage, "import: expected a page ID and an optional file")
+			}
+			if err := requireFlags(c, "notionSecret"); err != nil {
+				return exitf(exitUsage, "%v", err)
+			}
+
+			// Local images are relative to the file, or to the working
+			// directory on stdin.
+			var (
+				src []byte
+				err error
+				dir = "."
+			)
+			if path := c.Args().Get(1); len(path) > 0 {
+				src, err = os.ReadFile(path)
+				dir = filepath.Dir(path)
+			} else {
+				src, err = io.ReadAll(os.Stdin)
+			}
+			if err != nil {
+				return exitf(exitFailure, "import: %v", err)
+			}
+
+			blocks := renderer.ParseMarkdown(src)
+			client := notionapi.NewClient(c.String("notionSecret"), notionClientOptions(c)...)
+			var opts []renderer.UploadOption
+			if c.Bool("allowOutsideDir") {
+				opts = append(opts, renderer.AllowOutsideDir())
+			}
+			if err := renderer.UploadLocalFiles(c.Context, client, blocks, dir, opts...); err != nil {
+				return exitf(exitFailure, "import: %v", err)
+			}
+			if err := renderer.AppendBlocks(c.Context, client, pageID, blocks); err != nil {
+				return exitf(exitFailure, "import: %v", err)
+			}
+			fmt.Fprintf(c.App.Writer, "%v blocks appended to page %v\n", len(blocks), pageID)
+			return nil
+		},
+	}
+}
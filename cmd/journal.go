@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"notionsync/tools/journal"
+	"notionsync/tools/notion"
+
+	"github.com/urfave/cli/v2"
+)
+
+func historyCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "history",
+		Usage:     "show the changes the sync made to the Notion page of a To Do task, from the journal",
+		ArgsUsage: "<taskID>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print the journal entries as JSON lines",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			taskID := c.Args().First()
+			if len(taskID) == 0 || c.NArg() > 1 {
+				return exitf(exitUsage, "history: expected a single task ID")
+			}
+			path := c.String("journal")
+			if len(path) == 0 {
+				return exitf(exitUsage, "history: journal is disabled")
+			}
+
+			entries, err := journal.History(path, taskID)
+			if err != nil {
+				return exitf(exitFailure, "history: %v", err)
+			}
+			if len(entries) == 0 {
+				return exitf(exitFailure, "history: no journal entries for task %v", taskID)
+			}
+
+			if c.Bool("json") {
+				enc := json.NewEncoder(c.App.Writer)
+				for _, entry := range entries {
+					if err := enc.Encode(entry); err != nil {
+						return exitf(exitFailure, "history: %v", err)
+					}
+				}
+				return nil
+			}
+
+			for _, entry := range entries {
+				printEntry(c.App.Writer, entry)
+			}
+			return nil
+		},
+	}
+}
+
+func printEntry(w io.Writer, entry journal.Entry) {
+	fmt.Fprintf(w, "%v  %v  page %v  %v", entry.Time.Local().Format(time.RFC3339), entry.Operation, entry.PageID, entry.Outcome)
+	if len(entry.Error) > 0 {
+		fmt.Fprintf(w, ": %v", entry.Error)
+	}
+	fmt.Fprintln(w)
+
+	names := make([]string, 0, len(entry.After))
+	for name := range entry.After {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		after := journal.Value(entry.After[name])
+		before, ok := entry.Before[name]
+		if !ok {
+			fmt.Fprintf(w, "    %v: %v\n", name, after)
+			continue
+		}
+		fmt.Fprintf(w, "    %v: %v -> %v\n", name, journal.Value(before), after)
+	}
+}
+
+func rollbackCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "rollback",
+		Usage: "restore the Notion properties the sync changed since a point in time, from the journal",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "undo changes made at or after this time, as RFC 3339, a date (2006-01-02) or a duration ago (2h)",
+			},
+			&cli.BoolFlag{
+				Name:  "dryRun",
+				Usage: "print the values that would be restored without writing them",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			since, err := parseSince(c.String("since"), time.Now())
+			if err != nil {
+				return exitf(exitUsage, "rollback: %v", err)
+			}
+			path := c.String("journal")
+			if len(path) == 0 {
+				return exitf(exitUsage, "rollback: journal is disabled")
+			}
+
+			restores, err := journal.Restores(path, since)
+			if err != nil {
+				return exitf(exitFailure, "rollback: %v", err)
+			}
+
+			var notionAPI notion.API
+			if !c.Bool("dryRun") {
+				j, err := journal.Open(path)
+				if err != nil {
+					return exitf(exitFailure, "rollback: %v", err)
+				}
+				defer j.Close()
+
+				notionAPI, err = newNotion(c, notion.WithJournal(j))
+				if err != nil {
+					return err
+				}
+			}
+
+			var restored, failed int
+			for _, restore := range restores {
+				if restore.Created {
+					fmt.Fprintf(c.App.Writer, "page %v (task %v): created by the sync, skipped\n", restore.PageID, restore.TaskID)
+					continue
+				}
+				if len(restore.Properties) == 0 && !restore.Unarchive {
+					continue
+				}
+
+				printRestore(c.App.Writer, restore)
+				if notionAPI == nil {
+					restored++
+					continue
+				}
+
+				err := notionAPI.WithDatabaseID(restore.DatabaseID).RestorePage(c.Context, restore.TaskID, restore.PageID, restore.Properties, restore.Unarchive)
+				if err != nil {
+					fmt.Fprintf(c.App.Writer, "    failed: %v\n", err)
+					failed++
+					continue
+				}
+				restored++
+			}
+
+			if notionAPI == nil {
+				fmt.Fprintf(c.App.Writer, "%v pages would be restored\n", restored)
+				return nil
+			}
+			fmt.Fprintf(c.App.Writer, "%v pages restored, %v failed\n", restored, failed)
+			if failed > 0 {
+				return exitf(exitFailure, "rollback: %v pages failed to restore", failed)
+			}
+			return nil
+		},
+	}
+}
+
+func printRestore(w io.Writer, restore journal.Restore) {
+	fmt.Fprintf(w, "page %v (task %v):\n", restore.PageID, restore.TaskID)
+	if restore.Unarchive {
+		fmt.Fprintln(w, "    unarchive")
+	}
+
+	names := make([]string, 0, len(restore.Properties))
+	for name := range restore.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := journal.Value(restore.Properties[name])
+		if len(value) == 0 {
+			value = "(empty)"
+		}
+		fmt.Fprintf(w, "    %v: %v\n", name, value)
+	}
+}
+
+// parseSince reads a point in time given as RFC 3339, a date, or a duration
+// before now.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, fmt.Errorf("--since is required")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q, expected RFC 3339, a date or a duration", s)
+}
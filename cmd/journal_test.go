@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		since    string
+		expected time.Time
+		expError string
+	}{
+		{
+			name:     "RFC 3339",
+			since:    "2022-06-30T08:30:00+02:00",
+			expected: time.Date(2022, 6, 30, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "date",
+			since:    "2022-06-30",
+			expected: time.Date(2022, 6, 30, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "duration",
+			since:    "2h30m",
+			expected: now.Add(-150 * time.Minute),
+		},
+		{
+			name:     "missing",
+			expError: "--since is required",
+		},
+		{
+			name:     "invalid",
+			since:    "yesterday",
+			expError: `invalid --since "yesterday"`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseSince(tt.since, now)
+			if len(tt.expError) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.expError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -1,69 +1,102 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"os"
+	"time"
 
-	"notionsync/tools/notion"
-	"notionsync/tools/todo"
+	"notionsync/pkg/logger"
+	"notionsync/pkg/tracing"
 
 	"github.com/urfave/cli/v2"
 )
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// Exit codes returned by the commands.
+const (
+	exitFailure        = 1
+	exitUsage          = 2
+	exitSchemaMismatch = 3
+)
+
 func main() {
-	app := &cli.App{
-		Name:  "sync",
-		Usage: "todo sync notion!",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "notionSecret",
-				Aliases:  []string{"ns"},
-				Usage:    "notion secret",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:     "notionDatabaseID",
-				Aliases:  []string{"nd"},
-				Usage:    "notion databaseID",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:     "todoClientID",
-				Aliases:  []string{"tc"},
-				Usage:    "todo clientID",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:     "todoClientSecret",
-				Aliases:  []string{"tcs"},
-				Usage:    "todo client secret",
-				Required: true,
-			},
-		},
-		Action: func(c *cli.Context) error {
-			var (
-				notionSecret     = c.String("notionSecret")
-				notionDatabaseID = c.String("notionDatabaseID")
-				todoClientID     = c.String("todoClientID")
-				todoClientSecret = c.String("todoClientSecret")
-			)
+	flags := newFlags()
+	loadConfig := loadConfigFile(flags)
 
-			notionAPI := notion.New(notionSecret, notionDatabaseID)
-			todoAPI, err := todo.New(todoClientID, todoClientSecret, notionAPI)
-			if err != nil {
-				panic(err)
+	app := &cli.App{
+		Name:    "notionSync",
+		Usage:   "todo sync notion!",
+		Version: version,
+		Flags:   flags,
+		Before: func(c *cli.Context) error {
+			if err := loadConfig(c); err != nil {
+				return exitf(exitUsage, "load config: %v", err)
 			}
+			logger.Init(loggerConfig(c))
 
-			if err := todoAPI.UpdateNotionAllToDo(); err != nil {
-				panic(err)
+			shutdown, err := tracing.Init(c.Context, tracingConfig(c))
+			if err != nil {
+				return exitf(exitUsage, "init tracing: %v", err)
 			}
-
+			shutdownTracing = shutdown
+			return nil
+		},
+		After: func(c *cli.Context) error {
+			flushTracing()
 			return nil
 		},
+		// Exit codes make the app exit before After runs, so flush here too.
+		ExitErrHandler: func(c *cli.Context, err error) {
+			flushTracing()
+			cli.HandleExitCoder(err)
+		},
+		// Without a command, keep syncing as before subcommands existed.
+		Action: runAction,
+		Commands: []*cli.Command{
+			runCommand(),
+			onceCommand(),
+			loginCommand(),
+			reconcileCommand(),
+			schemaCommand(),
+			statusCommand(),
+			historyCommand(),
+			rollbackCommand(),
+			exportCommand(),
+			importCommand(),
+			backupCommand(),
+			restoreCommand(),
+			tasksCommand(),
+			versionCommand(),
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "notionSync: %v\n", err)
+		os.Exit(exitFailure)
+	}
+}
+
+// shutdownTracing flushes the spans buffered by the exporter set up in Before.
+var shutdownTracing func(context.Context) error
+
+func flushTracing() {
+	if shutdownTracing == nil {
+		return
 	}
 
-	err := app.Run(os.Args)
-	if err != nil {
-		log.Fatal(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Warnf("flush traces failed: %v", err)
 	}
+	shutdownTracing = nil
+}
+
+// exitf returns an error that makes the app exit with code after printing
+// the message.
+func exitf(code int, format string, args ...interface{}) cli.ExitCoder {
+	return cli.Exit(fmt.Sprintf(format, args...), code)
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/metrics"
+	"notionsync/tools/notion"
+	"notionsync/tools/todo"
+)
+
+// notionPingTTL limits how often readiness probes reach out to Notion.
+const notionPingTTL = 30 * time.Second
+
+// statusResponse is served on /status and read by the status command.
+type statusResponse struct {
+	Ready    bool     `json:"ready"`
+	Problems []string `json:"problems,omitempty"`
+	todo.Status
+}
+
+type server struct {
+	todo           todo.API
+	notion         notion.API
+	readyIntervals int
+
+	mu        sync.Mutex
+	pingedAt  time.Time
+	pingError error
+}
+
+// serve starts the HTTP server exposing /metrics, /healthz, /readyz and
+// /status in the background. It does nothing when addr is empty.
+func serve(addr string, todoAPI todo.API, notionAPI notion.API, readyIntervals int) {
+	if len(addr) == 0 {
+		return
+	}
+
+	s := &server{
+		todo:           todoAPI,
+		notion:         notionAPI,
+		readyIntervals: readyIntervals,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+	mux.HandleFunc("/status", s.status)
+
+	go func() {
+		logger.Infof("http server listening on %v", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Errorf("http server stopped: %v", err)
+		}
+	}()
+}
+
+func (s *server) pingNotion() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.pingedAt) > notionPingTTL {
+		s.pingError = s.notion.Ping(context.Background())
+		s.pingedAt = time.Now()
+	}
+	return s.pingError
+}
+
+func (s *server) check() statusResponse {
+	resp := statusResponse{Status: s.todo.Status()}
+	resp.Problems = resp.Status.Problems(s.readyIntervals, time.Now())
+	if err := s.pingNotion(); err != nil {
+		resp.Problems = append(resp.Problems, "notion: "+err.Error())
+	}
+	resp.Ready = len(resp.Problems) == 0
+	return resp
+}
+
+func (s *server) healthz(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+func (s *server) readyz(w http.ResponseWriter, _ *http.Request) {
+	resp := s.check()
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(strings.Join(resp.Problems, "\n") + "\n"))
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+func (s *server) status(w http.ResponseWriter, _ *http.Request) {
+	resp := s.check()
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
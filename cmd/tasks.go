@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/tools/export"
+
+	"github.com/urfave/cli/v2"
+)
+
+func tasksCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "tasks",
+		Usage: "write the rows of the Notion task database as CSV, JSON lines or XLSX",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output format, csv, jsonl or xlsx",
+				Value: string(export.FormatCSV),
+			},
+			&cli.StringSliceFlag{
+				Name:  "column",
+				Usage: "property to write as a column, in order; all properties when not set",
+			},
+			&cli.StringFlag{
+				Name:  "filter",
+				Usage: `database query filter as JSON, e.g. {"property":"Deleted","checkbox":{"equals":false}}`,
+			},
+			&cli.StringSliceFlag{
+				Name:  "sort",
+				Usage: "property[:asc|desc] to sort by, or created_time / last_edited_time",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "write to a file instead of stdout",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if err := requireFlags(c, "notionSecret", "notionDatabaseID"); err != nil {
+				return exitf(exitUsage, "%v", err)
+			}
+			format, err := export.ParseFormat(c.String("format"))
+			if err != nil {
+				return exitf(exitUsage, "tasks: %v", err)
+			}
+			opts := export.Options{
+				Format:   format,
+				Columns:  c.StringSlice("column"),
+				Location: time.Local,
+			}
+			if filter := c.String("filter"); len(filter) > 0 {
+				opts.Filter = &notionapi.DatabaseQueryFilter{}
+				if err := json.Unmarshal([]byte(filter), opts.Filter); err != nil {
+					return exitf(exitUsage, "tasks: invalid filter: %v", err)
+				}
+			}
+			for _, s := range c.StringSlice("sort") {
+				sort, err := export.ParseSort(s)
+				if err != nil {
+					return exitf(exitUsage, "tasks: %v", err)
+				}
+				opts.Sorts = append(opts.Sorts, sort)
+			}
+
+			client := notionapi.NewClient(c.String("notionSecret"), notionClientOptions(c)...)
+			path := c.String("output")
+			if len(path) == 0 {
+				if _, err := export.Export(c.Context, client, c.String("notionDatabaseID"), c.App.Writer, opts); err != nil {
+					return exitf(exitFailure, "tasks: %v", err)
+				}
+				return nil
+			}
+
+			f, err := os.Create(path)
+			if err != nil {
+				return exitf(exitFailure, "tasks: %v", err)
+			}
+			rows, err := export.Export(c.Context, client, c.String("notionDatabaseID"), f, opts)
+			if err != nil {
+				_ = f.Close()
+				return exitf(exitFailure, "tasks: %v", err)
+			}
+			if err := f.Close(); err != nil {
+				return exitf(exitFailure, "tasks: %v", err)
+			}
+			fmt.Fprintf(c.App.Writer, "%v rows written to %v\n", rows, path)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,160 @@
+package cassette_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"notionsync/pkg/cassette"
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/notionapi/notionapitest"
+	"notionsync/pkg/todoapi"
+	"notionsync/pkg/todoapi/todoapitest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordReplay(t *testing.T) {
+	t.Parallel()
+
+	srv := notionapitest.NewServer()
+	db := srv.AddDatabase(notionapi.Database{
+		Parent: notionapi.Parent{Type: notionapi.ParentTypePage, PageID: "b0668f48-8d66-4733-9bdb-2f82215707f7"},
+		Properties: notionapi.DatabaseProperties{
+			"Name": {Type: notionapi.DBPropTypeTitle},
+			"Done": {Type: notionapi.DBPropTypeCheckbox},
+		},
+	})
+	done, notDone := true, false
+	for name, checked := range map[string]*bool{"Buy milk": &done, "Plan trip": &notDone} {
+		srv.AddPage(db.ID, notionapi.DatabasePageProperties{
+			"Name": {Title: []notionapi.RichText{{Text: &notionapi.Text{Content: name}}}},
+			"Done": {Checkbox: checked},
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "testdata", "query.json")
+	query := func(client *notionapi.Client, checked bool) []string {
+		t.Helper()
+
+		resp, err := client.QueryDatabase(context.Background(), db.ID, &notionapi.DatabaseQuery{
+			Filter: notionapi.Prop("Done").Checkbox().Equals(checked).Query(),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var titles []string
+		for _, page := range resp.Results {
+			titles = append(titles, page.Properties.(notionapi.DatabasePageProperties)["Name"].Title[0].PlainText)
+		}
+		return titles
+	}
+
+	rec, err := cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := notionapi.NewClient("secret-api-key",
+		notionapi.WithBaseURL(srv.BaseURL()),
+		notionapi.WithHTTPClient(srv.Server.Client()),
+		notionapi.WithMiddleware(rec.Middleware),
+	)
+	exp := [][]string{query(client, true), query(client, false)}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(string(b), "secret-api-key") {
+		t.Fatalf("expected API key to be redacted, got:\n%s", b)
+	}
+
+	// Replay in the opposite order: requests are told apart by their body.
+	rec, err = cassette.New(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client = notionapi.NewClient("another-api-key",
+		notionapi.WithBaseURL(srv.BaseURL()),
+		notionapi.WithMiddleware(rec.Middleware),
+	)
+	got := [][]string{nil, query(client, false)}
+	got[0] = query(client, true)
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("query results not equal (-exp, +got):\n%v", diff)
+	}
+	if unused := rec.Unused(); len(unused) > 0 {
+		t.Fatalf("expected every interaction to be replayed, got %+v", unused)
+	}
+
+	if _, err := client.FindDatabaseByID(context.Background(), db.ID); err == nil {
+		t.Fatal("expected error for a request that was not recorded")
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	t.Parallel()
+
+	srv := todoapitest.NewServer()
+	defer srv.Close()
+	list := srv.AddList(todoapi.TaskList{DisplayName: "Private project"})
+
+	path := filepath.Join(t.TempDir(), "lists.json")
+	rec, err := cassette.New(path, cassette.ModeRecord, cassette.WithSecrets("Private project"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newClient := func() *todoapi.Client {
+		t.Helper()
+
+		opts := append(srv.ClientOptions(),
+			todoapi.WithRefreshToken("my-refresh-token"),
+			todoapi.WithMiddleware(rec.Middleware),
+		)
+		client, err := todoapi.NewClient("client-id", "my-client-secret", opts...)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return client
+	}
+
+	lists, err := newClient().ListTaskLists(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, secret := range []string{"my-refresh-token", "my-client-secret", "access-token-", "refresh-token-", "Private project"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("expected %q to be redacted, got:\n%s", secret, b)
+		}
+	}
+
+	// The token request matches although its credentials differ.
+	if rec, err = cassette.New(path, cassette.ModeReplay); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	replayed, err := newClient().ListTaskLists(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lists[0].DisplayName = cassette.Redacted
+	if diff := cmp.Diff(lists, replayed); diff != "" {
+		t.Fatalf("task lists not equal (-exp, +got):\n%v", diff)
+	}
+	if replayed[0].Id != list.Id {
+		t.Fatalf("expected task list %v, got %v", list.Id, replayed[0].Id)
+	}
+}
@@ -0,0 +1,254 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// Mode is whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay answers requests with the interactions of the cassette file,
+	// and fails those it has none for.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests on and records them, to replace the cassette
+	// file on Save.
+	ModeRecord
+)
+
+// Matcher reports whether a recorded request matches a request being sent.
+// Both are redacted.
+type Matcher func(r, recorded Request) bool
+
+// DefaultMatcher matches requests by method, URL and body, comparing JSON
+// bodies by value.
+func DefaultMatcher(r, recorded Request) bool {
+	if r.Method != recorded.Method || r.URL != recorded.URL {
+		return false
+	}
+	if len(r.JSON) > 0 && len(recorded.JSON) > 0 {
+		var got, exp interface{}
+		if json.Unmarshal(r.JSON, &got) != nil || json.Unmarshal(recorded.JSON, &exp) != nil {
+			return false
+		}
+		return reflect.DeepEqual(got, exp)
+	}
+	return bytes.Equal(joinBody(r.JSON, r.Body), joinBody(recorded.JSON, recorded.Body))
+}
+
+// Recorder records or replays the requests sent through its middleware.
+type Recorder struct {
+	path     string
+	mode     Mode
+	redactor redactor
+	match    Matcher
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// Option is used to override default recorder behavior.
+type Option func(*Recorder)
+
+// WithRedactedHeaders redacts more headers than Authorization,
+// Proxy-Authorization, Cookie and Set-Cookie.
+func WithRedactedHeaders(names ...string) Option {
+	return func(r *Recorder) {
+		r.redactor.addHeaders(names...)
+	}
+}
+
+// WithRedactedFields redacts more fields of JSON objects, at any depth, and of
+// form bodies and queries, than the OAuth2 tokens and client secrets.
+func WithRedactedFields(names ...string) Option {
+	return func(r *Recorder) {
+		r.redactor.addFields(names...)
+	}
+}
+
+// WithSecrets redacts values wherever they appear, e.g. an API key or a
+// private page ID.
+func WithSecrets(secrets ...string) Option {
+	return func(r *Recorder) {
+		for _, secret := range secrets {
+			if len(secret) > 0 {
+				r.redactor.secrets = append(r.redactor.secrets, secret)
+			}
+		}
+	}
+}
+
+// WithMatcher overrides how requests are matched with recorded ones on
+// replay, DefaultMatcher by default.
+func WithMatcher(match Matcher) Option {
+	return func(r *Recorder) {
+		r.match = match
+	}
+}
+
+// New returns a Recorder of the cassette file at path. In replay mode the
+// file is read right away.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:     path,
+		mode:     mode,
+		redactor: newRedactor(),
+		match:    DefaultMatcher,
+		cassette: &Cassette{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if mode == ModeReplay {
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = c
+		r.used = make([]bool, len(c.Interactions))
+	}
+
+	return r, nil
+}
+
+// Middleware returns the transport of the recorder on top of next, which
+// only record mode sends requests with. It can be passed as is to the
+// WithMiddleware options of the notionapi and todoapi clients.
+func (r *Recorder) Middleware(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{recorder: r, next: next}
+}
+
+// Save writes the interactions recorded so far to the cassette file. It does
+// nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette.Save(r.path)
+}
+
+// Unused returns the recorded interactions that were not replayed, e.g. to
+// check that a test sent every request it was recorded with.
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Interaction
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// request returns the redacted form of a request, with its body read.
+func (r *Recorder) request(req *http.Request, body []byte) Request {
+	recorded := Request{
+		Method: req.Method,
+		URL:    r.redactor.url(req.URL),
+		Header: r.redactor.header(req.Header),
+	}
+	recorded.JSON, recorded.Body = splitBody(r.redactor.body(body, req.Header.Get("Content-Type")))
+	return recorded
+}
+
+func (r *Recorder) response(resp *http.Response, body []byte) Response {
+	recorded := Response{
+		Status: resp.StatusCode,
+		Header: r.redactor.header(resp.Header),
+	}
+	recorded.JSON, recorded.Body = splitBody(r.redactor.body(body, resp.Header.Get("Content-Type")))
+	return recorded
+}
+
+func (r *Recorder) record(i Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+}
+
+// replay answers a request with the first unused interaction matching it,
+// so that repeated requests get their responses in recorded order.
+func (r *Recorder) replay(req *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.match(recorded, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+
+		body := joinBody(interaction.Response.JSON, interaction.Response.Body)
+		header := interaction.Response.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.Status, http.StatusText(interaction.Response.Status)),
+			StatusCode:    interaction.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("cassette: no interaction recorded in %v for %v %v", r.path, recorded.Method, recorded.URL)
+}
+
+type transport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: failed to read request body: %w", err)
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	recorded := t.recorder.request(req, body)
+
+	if t.recorder.mode == ModeReplay {
+		return t.recorder.replay(req, recorded)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	t.recorder.record(Interaction{Request: recorded, Response: t.recorder.response(resp, respBody)})
+	return resp, nil
+}
@@ -0,0 +1,159 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values of redacted headers and fields, and secrets.
+const Redacted = "REDACTED"
+
+var (
+	// defaultHeaders carry the Notion API key, Graph access tokens and
+	// cookies.
+	defaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+	// defaultFields are the OAuth2 credentials of token requests and
+	// responses.
+	defaultFields = []string{"access_token", "refresh_token", "id_token", "client_secret", "client_assertion", "code_verifier"}
+	// defaultFormFields are only redacted in form bodies and queries: code is
+	// also the key of Notion code blocks and annotations.
+	defaultFormFields = []string{"code"}
+)
+
+// redactor scrubs credentials from requests and responses before they are
+// recorded, or matched against recorded ones.
+type redactor struct {
+	headers    map[string]bool
+	fields     map[string]bool
+	formFields map[string]bool
+	secrets    []string
+}
+
+func newRedactor() redactor {
+	r := redactor{
+		headers:    make(map[string]bool),
+		fields:     make(map[string]bool),
+		formFields: make(map[string]bool),
+	}
+	r.addHeaders(defaultHeaders...)
+	r.addFields(defaultFields...)
+	for _, name := range defaultFormFields {
+		r.formFields[name] = true
+	}
+	return r
+}
+
+func (r redactor) addHeaders(names ...string) {
+	for _, name := range names {
+		r.headers[http.CanonicalHeaderKey(name)] = true
+	}
+}
+
+func (r redactor) addFields(names ...string) {
+	for _, name := range names {
+		r.fields[name] = true
+		r.formFields[name] = true
+	}
+}
+
+// secret replaces the secrets in s.
+func (r redactor) secret(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+func (r redactor) header(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	redacted := make(http.Header, len(h))
+	for name, values := range h {
+		if r.headers[http.CanonicalHeaderKey(name)] {
+			redacted[name] = []string{Redacted}
+			continue
+		}
+		for _, v := range values {
+			redacted[name] = append(redacted[name], r.secret(v))
+		}
+	}
+	return redacted
+}
+
+func (r redactor) url(u *url.URL) string {
+	redacted := *u
+	if q := u.Query(); len(q) > 0 && r.form(q) {
+		redacted.RawQuery = q.Encode()
+	}
+	return r.secret(redacted.String())
+}
+
+// form redacts the fields of a query or form body, reporting whether any
+// was.
+func (r redactor) form(values url.Values) bool {
+	var changed bool
+	for name := range values {
+		if r.formFields[name] {
+			values[name] = []string{Redacted}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// body redacts a request or response body: the fields of JSON and form
+// bodies, then any secret.
+func (r redactor) body(b []byte, contentType string) []byte {
+	if len(b) == 0 {
+		return b
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case json.Valid(b):
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err == nil && r.json(v) {
+			if redacted, err := json.Marshal(v); err == nil {
+				b = redacted
+			}
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		if values, err := url.ParseQuery(string(b)); err == nil && r.form(values) {
+			b = []byte(values.Encode())
+		}
+	}
+
+	for _, secret := range r.secrets {
+		b = bytes.ReplaceAll(b, []byte(secret), []byte(Redacted))
+	}
+	return b
+}
+
+// json redacts the fields of a decoded JSON value at any depth, reporting
+// whether any was.
+func (r redactor) json(v interface{}) bool {
+	var changed bool
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if r.fields[key] {
+				v[key] = Redacted
+				changed = true
+				continue
+			}
+			changed = r.json(value) || changed
+		}
+	case []interface{}:
+		for _, value := range v {
+			changed = r.json(value) || changed
+		}
+	}
+	return changed
+}
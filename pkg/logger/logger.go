@@ -63,6 +63,11 @@ var (
 
 var _log *Logger
 
+// DefaultConfig returns the config the package logger is initialized with.
+func DefaultConfig() Config {
+	return _defaultConfig
+}
+
 func Debugf(format string, args ...interface{}) {
 	_log.sugared.Debugf(format, args...)
 }
@@ -3,6 +3,7 @@ package logger
 import (
 	"context"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -31,19 +32,39 @@ func SetTraceFromOldContext(ctx context.Context, oldCtx context.Context) context
 	return context.WithValue(ctx, _loggerKey, T(oldCtx))
 }
 
+// T returns the logger stored in ctx, or the package logger. When ctx carries
+// an OpenTelemetry span, its trace and span IDs are added to every line.
 func T(ctx context.Context) *Logger {
 	if ctx == nil {
 		goto end
 	}
 
 	if ctxLogger, ok := ctx.Value(_loggerKey).(*Logger); ok {
-		return ctxLogger
+		return ctxLogger.withSpan(ctx)
 	}
 
+	return _log.withSpan(ctx)
+
 end:
 	return _log
 }
 
+func (l *Logger) withSpan(ctx context.Context) *Logger {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() {
+		return l
+	}
+
+	rv := new(Logger)
+	*rv = *l
+	rv.sugared = l.sugared.With(
+		zap.String("trace.id", spanCtx.TraceID().String()),
+		zap.String("span.id", spanCtx.SpanID().String()),
+	)
+
+	return rv
+}
+
 func (l *Logger) with(keyValues ...zapcore.Field) *Logger {
 	var args = make([]interface{}, 0, len(keyValues))
 	for _, keyValue := range keyValues {
@@ -0,0 +1,109 @@
+// Package metrics collects Prometheus metrics about the sync and the API
+// calls it makes.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "notionsync"
+
+// Task operations.
+const (
+	OperationCreated = "created"
+	OperationUpdated = "updated"
+	OperationDeleted = "deleted"
+)
+
+var (
+	_registry = prometheus.NewRegistry()
+
+	_polls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "polls_total",
+		Help:      "Delta polls of a task list.",
+	}, []string{"list"})
+
+	_tasks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tasks_total",
+		Help:      "Tasks written to Notion by list and operation.",
+	}, []string{"list", "operation"})
+
+	_lastSync = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_sync_timestamp_seconds",
+		Help:      "Unix time of the last successful sync of a task list.",
+	}, []string{"list"})
+
+	_apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "HTTP requests made to an API by endpoint and status.",
+	}, []string{"api", "endpoint", "status"})
+
+	_apiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_errors_total",
+		Help:      "HTTP requests to an API that failed or returned a non 2xx status.",
+	}, []string{"api", "endpoint", "status"})
+
+	_apiLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of HTTP requests to an API by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"api", "endpoint"})
+
+	_rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "HTTP requests to an API rejected with 429 Too Many Requests.",
+	}, []string{"api"})
+)
+
+func init() {
+	_registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		_polls,
+		_tasks,
+		_lastSync,
+		_apiRequests,
+		_apiErrors,
+		_apiLatency,
+		_rateLimited,
+	)
+}
+
+// Handler serves the collected metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(_registry, promhttp.HandlerOpts{})
+}
+
+// ObservePoll counts a delta poll of a task list.
+func ObservePoll(list string) {
+	_polls.WithLabelValues(list).Inc()
+}
+
+// ObserveTask counts a task written to Notion with one of the Operation
+// constants.
+func ObserveTask(list, operation string) {
+	_tasks.WithLabelValues(list, operation).Inc()
+}
+
+// SetLastSync records the time a task list was last synced successfully.
+func SetLastSync(list string, t time.Time) {
+	_lastSync.WithLabelValues(list).Set(float64(t.Unix()))
+}
+
+// DeleteLastSync drops the last sync time of a task list that was renamed or
+// is no longer synced, so that its series does not stay frozen.
+func DeleteLastSync(list string) {
+	_lastSync.DeleteLabelValues(list)
+}
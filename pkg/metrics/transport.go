@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type roundTripper struct {
+	api  string
+	next http.RoundTripper
+}
+
+// InstrumentRoundTripper records the requests made through next under the
+// given api label. A nil next uses http.DefaultTransport.
+func InstrumentRoundTripper(api string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{api: api, next: next}
+}
+
+// InstrumentClient returns an http.Client whose transport is instrumented
+// with InstrumentRoundTripper.
+func InstrumentClient(api string) *http.Client {
+	return &http.Client{Transport: InstrumentRoundTripper(api, nil)}
+}
+
+func (rt *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := Endpoint(req.Method, req.URL.Path)
+
+	start := time.Now()
+	res, err := rt.next.RoundTrip(req)
+	_apiLatency.WithLabelValues(rt.api, endpoint).Observe(time.Since(start).Seconds())
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(res.StatusCode)
+	}
+
+	_apiRequests.WithLabelValues(rt.api, endpoint, status).Inc()
+	if err != nil || res.StatusCode >= http.StatusBadRequest {
+		_apiErrors.WithLabelValues(rt.api, endpoint, status).Inc()
+	}
+	if err == nil && res.StatusCode == http.StatusTooManyRequests {
+		_rateLimited.WithLabelValues(rt.api).Inc()
+	}
+
+	return res, err
+}
+
+// Endpoint returns a low cardinality label for a request, replacing the IDs
+// in its path with "{id}", e.g. "POST /v1/databases/{id}/query".
+func Endpoint(method, path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if isID(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return method + " " + strings.Join(segments, "/")
+}
+
+// isID reports whether a path segment looks like a Notion UUID or a Graph
+// object ID rather than a fixed part of the route.
+func isID(segment string) bool {
+	if len(segment) < 16 {
+		return false
+	}
+	for _, r := range segment {
+		if unicode.IsDigit(r) || r == '=' || r == '-' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"notionsync/pkg/metrics"
+)
+
+func TestEndpoint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		exp    string
+	}{
+		{
+			name:   "notion database query",
+			method: http.MethodPost,
+			path:   "/v1/databases/668d797c-76fa-4934-9b05-ad288df2d136/query",
+			exp:    "POST /v1/databases/{id}/query",
+		},
+		{
+			name:   "notion search",
+			method: http.MethodPost,
+			path:   "/v1/search",
+			exp:    "POST /v1/search",
+		},
+		{
+			name:   "graph task delta",
+			method: http.MethodGet,
+			path:   "/beta/me/tasks/lists/AAMkADIyAAAhrbPWAAA=/tasks/delta",
+			exp:    "GET /beta/me/tasks/lists/{id}/tasks/delta",
+		},
+		{
+			name:   "oauth token",
+			method: http.MethodPost,
+			path:   "/common/oauth2/v2.0/token",
+			exp:    "POST /common/oauth2/v2.0/token",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := metrics.Endpoint(tt.method, tt.path); got != tt.exp {
+				t.Errorf("endpoint not equal (expected: %v, got: %v)", tt.exp, got)
+			}
+		})
+	}
+}
+
+func TestInstrumentRoundTripper(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: metrics.InstrumentRoundTripper("test", nil)}
+	res, err := client.Get(server.URL + "/v1/users/me")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, exp := range []string{
+		`notionsync_api_requests_total{api="test",endpoint="GET /v1/users/me",status="429"} 1`,
+		`notionsync_api_errors_total{api="test",endpoint="GET /v1/users/me",status="429"} 1`,
+		`notionsync_rate_limited_total{api="test"} 1`,
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("metrics missing %q", exp)
+		}
+	}
+}
+
+func TestDeleteLastSync(t *testing.T) {
+	t.Parallel()
+
+	metrics.SetLastSync("Renamed list", time.Unix(1656676800, 0))
+	scrape := func() string {
+		rec := httptest.NewRecorder()
+		metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec.Body.String()
+	}
+
+	exp := `notionsync_last_sync_timestamp_seconds{list="Renamed list"} 1.6566768e+09`
+	if body := scrape(); !strings.Contains(body, exp) {
+		t.Fatalf("metrics missing %q", exp)
+	}
+	metrics.DeleteLastSync("Renamed list")
+	if body := scrape(); strings.Contains(body, `list="Renamed list"`) {
+		t.Fatalf("expected the series to be deleted, got %v", body)
+	}
+}
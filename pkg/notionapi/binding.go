@@ -0,0 +1,748 @@
+package notionapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct fields are bound to database properties with a `notion` tag naming
+// the property and its type, e.g.
+//
+//	type Task struct {
+//		Title  string     `notion:"Task,title"`
+//		Due    *time.Time `notion:"Due,date"`
+//		Points float64    `notion:"Points,number,omitempty"`
+//	}
+//
+// Depending on the property type, a field holds the plain value (string,
+// numbers, bool, time.Time, []string of names, IDs or URLs) or the API type
+// of the value (e.g. []RichText, SelectOptions, Date, []User), or a pointer
+// to either. A field of type DatabasePageProperty holds the whole property.
+// Fields tagged "-" or without a tag are skipped, and untagged embedded
+// structs are bound as if their fields were in the outer struct.
+
+// MarshalProperties returns the properties of a database page bound to the
+// fields of v, a struct or a pointer to one, for CreatePageParams and
+// UpdatePageParams. Computed properties (formula, rollup, created and last
+// edited time and user, unique ID and verification) are read-only and left
+// out. Empty values (empty strings and slices, nil pointers, zero dates and
+// options) are written as typed empty properties, which clear the property,
+// and zero numbers and false checkboxes as such. Fields tagged omitempty are
+// left out instead when they are empty, zero or false.
+func MarshalProperties(v interface{}) (DatabasePageProperties, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("notion: cannot marshal %T into properties, expected a struct", v)
+	}
+	fields, err := propertyFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	props := make(DatabasePageProperties, len(fields))
+	for _, field := range fields {
+		fv, ok := fieldByIndex(rv, field.index)
+		if !ok {
+			continue
+		}
+		prop, ok, err := marshalProperty(field, fv)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			props[field.name] = prop
+		}
+	}
+	return props, nil
+}
+
+// UnmarshalProperties sets the fields of v, a pointer to a struct, from the
+// properties of a database page. Fields of properties missing from props are
+// left as they are; fields of properties without a value are set to their
+// zero value.
+func UnmarshalProperties(props DatabasePageProperties, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("notion: cannot unmarshal properties into %T, expected a pointer to a struct", v)
+	}
+	rv = rv.Elem()
+	fields, err := propertyFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		prop, ok := props[field.name]
+		if !ok {
+			continue
+		}
+		if len(prop.Type) > 0 && prop.Type != field.typ {
+			return fmt.Errorf("notion: cannot unmarshal %v property %q into field %v tagged %v", prop.Type, field.name, field.goName, field.typ)
+		}
+		if err := unmarshalProperty(field, prop, allocFieldByIndex(rv, field.index)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalPage sets the fields of v from the properties of page, which must
+// be in a database.
+func UnmarshalPage(page Page, v interface{}) error {
+	props, ok := page.Properties.(DatabasePageProperties)
+	if !ok {
+		return fmt.Errorf("notion: page %v is not in a database", page.ID)
+	}
+	return UnmarshalProperties(props, v)
+}
+
+type propertyField struct {
+	index     []int
+	goName    string
+	name      string
+	typ       DatabasePropertyType
+	omitEmpty bool
+}
+
+func propertyFields(t reflect.Type) ([]propertyField, error) {
+	var fields []propertyField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("notion")
+		if !ok || tag == "-" {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if !ok && sf.Anonymous && ft.Kind() == reflect.Struct {
+				embedded, err := propertyFields(ft)
+				if err != nil {
+					return nil, err
+				}
+				for _, field := range embedded {
+					field.index = append([]int{i}, field.index...)
+					fields = append(fields, field)
+				}
+			}
+			continue
+		}
+		if len(sf.PkgPath) > 0 {
+			return nil, fmt.Errorf("notion: field %v is tagged but not exported", sf.Name)
+		}
+
+		parts := strings.Split(tag, ",")
+		field := propertyField{index: []int{i}, goName: sf.Name, name: parts[0]}
+		if len(field.name) == 0 {
+			field.name = sf.Name
+		}
+		if len(parts) < 2 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("notion: field %v: missing property type in tag %q", sf.Name, tag)
+		}
+		field.typ = DatabasePropertyType(parts[1])
+		for _, option := range parts[2:] {
+			switch option {
+			case "omitempty":
+				field.omitEmpty = true
+			default:
+				return nil, fmt.Errorf("notion: field %v: unknown option %q in tag %q", sf.Name, option, tag)
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// fieldByIndex returns the field at index, reporting false when it is in a
+// nil embedded struct.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// allocFieldByIndex returns the field at index, allocating nil embedded
+// structs on the way.
+func allocFieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+var (
+	errUnsupportedField = errors.New("unsupported field type")
+
+	propertyType      = reflect.TypeOf(DatabasePageProperty{})
+	timeType          = reflect.TypeOf(time.Time{})
+	dateTimeType      = reflect.TypeOf(DateTime{})
+	dateType          = reflect.TypeOf(Date{})
+	richTextsType     = reflect.TypeOf([]RichText{})
+	selectOptionType  = reflect.TypeOf(SelectOptions{})
+	selectOptionsType = reflect.TypeOf([]SelectOptions{})
+	userType          = reflect.TypeOf(User{})
+	usersType         = reflect.TypeOf([]User{})
+	filesType         = reflect.TypeOf([]File{})
+	relationsType     = reflect.TypeOf([]Relation{})
+	stringsType       = reflect.TypeOf([]string{})
+	formulaType       = reflect.TypeOf(FormulaResult{})
+	rollupType        = reflect.TypeOf(RollupResult{})
+	propertiesType    = reflect.TypeOf([]DatabasePageProperty{})
+	uniqueIDType      = reflect.TypeOf(UniqueID{})
+	verificationType  = reflect.TypeOf(Verification{})
+)
+
+func marshalProperty(field propertyField, v reflect.Value) (DatabasePageProperty, bool, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return emptyProperty(field)
+		}
+		v = v.Elem()
+	}
+	if v.Type() == propertyType {
+		return v.Interface().(DatabasePageProperty), true, nil
+	}
+
+	var prop DatabasePageProperty
+	switch field.typ {
+	case DBPropTypeTitle, DBPropTypeRichText:
+		var texts []RichText
+		switch {
+		case v.Kind() == reflect.String:
+			if v.Len() > 0 {
+				texts = []RichText{{Text: &Text{Content: v.String()}}}
+			}
+		case v.Type() == richTextsType:
+			texts = v.Interface().([]RichText)
+		default:
+			return prop, false, marshalError(field, v)
+		}
+		if len(texts) == 0 {
+			return emptyProperty(field)
+		}
+		if field.typ == DBPropTypeTitle {
+			prop.Title = texts
+		} else {
+			prop.RichText = texts
+		}
+	case DBPropTypeURL, DBPropTypeEmail, DBPropTypePhoneNumber:
+		if v.Kind() != reflect.String {
+			return prop, false, marshalError(field, v)
+		}
+		if v.Len() == 0 {
+			return emptyProperty(field)
+		}
+		s := v.String()
+		switch field.typ {
+		case DBPropTypeURL:
+			prop.URL = &s
+		case DBPropTypeEmail:
+			prop.Email = &s
+		default:
+			prop.PhoneNumber = &s
+		}
+	case DBPropTypeNumber:
+		n, ok := numberValue(v)
+		if !ok {
+			return prop, false, marshalError(field, v)
+		}
+		if field.omitEmpty && n == 0 {
+			return emptyProperty(field)
+		}
+		prop.Number = &n
+	case DBPropTypeCheckbox:
+		if v.Kind() != reflect.Bool {
+			return prop, false, marshalError(field, v)
+		}
+		if field.omitEmpty && !v.Bool() {
+			return emptyProperty(field)
+		}
+		b := v.Bool()
+		prop.Checkbox = &b
+	case DBPropTypeSelect, DBPropTypeStatus:
+		var option SelectOptions
+		switch {
+		case v.Kind() == reflect.String:
+			option.Name = v.String()
+		case v.Type() == selectOptionType:
+			option = v.Interface().(SelectOptions)
+		default:
+			return prop, false, marshalError(field, v)
+		}
+		if len(option.ID) == 0 && len(option.Name) == 0 {
+			return emptyProperty(field)
+		}
+		if field.typ == DBPropTypeSelect {
+			prop.Select = &option
+		} else {
+			prop.Status = &option
+		}
+	case DBPropTypeMultiSelect:
+		switch v.Type() {
+		case stringsType:
+			for _, name := range v.Interface().([]string) {
+				prop.MultiSelect = append(prop.MultiSelect, SelectOptions{Name: name})
+			}
+		case selectOptionsType:
+			prop.MultiSelect = v.Interface().([]SelectOptions)
+		default:
+			return prop, false, marshalError(field, v)
+		}
+		if len(prop.MultiSelect) == 0 {
+			return emptyProperty(field)
+		}
+	case DBPropTypeDate:
+		var date Date
+		switch v.Type() {
+		case timeType:
+			date.Start = NewDateTime(v.Interface().(time.Time), true)
+		case dateTimeType:
+			date.Start = v.Interface().(DateTime)
+		case dateType:
+			date = v.Interface().(Date)
+		default:
+			return prop, false, marshalError(field, v)
+		}
+		if date.Start.IsZero() {
+			return emptyProperty(field)
+		}
+		prop.Date = &date
+	case DBPropTypePeople:
+		switch v.Type() {
+		case stringsType:
+			for _, id := range v.Interface().([]string) {
+				prop.People = append(prop.People, User{ID: id})
+			}
+		case usersType:
+			prop.People = v.Interface().([]User)
+		default:
+			return prop, false, marshalError(field, v)
+		}
+		if len(prop.People) == 0 {
+			return emptyProperty(field)
+		}
+	case DBPropTypeFiles:
+		switch v.Type() {
+		case stringsType:
+			for _, url := range v.Interface().([]string) {
+				prop.Files = append(prop.Files, File{Name: url, Type: FileTypeExternal, External: &FileExternal{URL: url}})
+			}
+		case filesType:
+			prop.Files = v.Interface().([]File)
+		default:
+			return prop, false, marshalError(field, v)
+		}
+		if len(prop.Files) == 0 {
+			return emptyProperty(field)
+		}
+	case DBPropTypeRelation:
+		switch v.Type() {
+		case stringsType:
+			for _, id := range v.Interface().([]string) {
+				prop.Relation = append(prop.Relation, Relation{ID: id})
+			}
+		case relationsType:
+			prop.Relation = v.Interface().([]Relation)
+		default:
+			return prop, false, marshalError(field, v)
+		}
+		if len(prop.Relation) == 0 {
+			return emptyProperty(field)
+		}
+	case DBPropTypeFormula, DBPropTypeRollup,
+		DBPropTypeCreatedTime, DBPropTypeCreatedBy,
+		DBPropTypeLastEditedTime, DBPropTypeLastEditedBy,
+		DBPropTypeUniqueID, DBPropTypeVerification:
+		return prop, false, nil
+	default:
+		return prop, false, fmt.Errorf("notion: field %v: unknown property type %v", field.goName, field.typ)
+	}
+	return prop, true, nil
+}
+
+// emptyProperty returns the property clearing the value of field, or no
+// property when the field is tagged omitempty.
+func emptyProperty(field propertyField) (DatabasePageProperty, bool, error) {
+	return DatabasePageProperty{Type: field.typ}, !field.omitEmpty, nil
+}
+
+func marshalError(field propertyField, v reflect.Value) error {
+	return fmt.Errorf("notion: cannot marshal field %v of type %v into %v property %q", field.goName, v.Type(), field.typ, field.name)
+}
+
+func unmarshalProperty(field propertyField, prop DatabasePageProperty, v reflect.Value) error {
+	target := v
+	if v.Kind() == reflect.Ptr {
+		target = reflect.New(v.Type().Elem()).Elem()
+	}
+	ok, err := unmarshalValue(field.typ, prop, target)
+	if err == errUnsupportedField {
+		return unmarshalError(field, v)
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case !ok:
+		v.Set(reflect.Zero(v.Type()))
+	case v.Kind() == reflect.Ptr:
+		v.Set(target.Addr())
+	}
+	return nil
+}
+
+func unmarshalError(field propertyField, v reflect.Value) error {
+	return fmt.Errorf("notion: cannot unmarshal %v property %q into field %v of type %v", field.typ, field.name, field.goName, v.Type())
+}
+
+// unmarshalValue sets v to the value of prop, reporting false when the
+// property has no value.
+func unmarshalValue(typ DatabasePropertyType, prop DatabasePageProperty, v reflect.Value) (bool, error) {
+	if v.Type() == propertyType {
+		v.Set(reflect.ValueOf(prop))
+		return true, nil
+	}
+
+	switch typ {
+	case DBPropTypeTitle, DBPropTypeRichText:
+		texts := prop.RichText
+		if typ == DBPropTypeTitle {
+			texts = prop.Title
+		}
+		if v.Type() == richTextsType {
+			v.Set(reflect.ValueOf(texts))
+			return texts != nil, nil
+		}
+		return texts != nil, setString(v, PlainText(texts))
+	case DBPropTypeURL, DBPropTypeEmail, DBPropTypePhoneNumber:
+		s := prop.URL
+		switch typ {
+		case DBPropTypeEmail:
+			s = prop.Email
+		case DBPropTypePhoneNumber:
+			s = prop.PhoneNumber
+		}
+		if s == nil {
+			return false, setString(v, "")
+		}
+		return true, setString(v, *s)
+	case DBPropTypeNumber:
+		return setNumber(v, prop.Number)
+	case DBPropTypeCheckbox:
+		if v.Kind() != reflect.Bool {
+			return false, errUnsupportedField
+		}
+		if prop.Checkbox == nil {
+			return false, nil
+		}
+		v.SetBool(*prop.Checkbox)
+		return true, nil
+	case DBPropTypeSelect, DBPropTypeStatus:
+		option := prop.Select
+		if typ == DBPropTypeStatus {
+			option = prop.Status
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			if option == nil {
+				return false, nil
+			}
+			v.SetString(option.Name)
+		case v.Type() == selectOptionType:
+			if option == nil {
+				return false, nil
+			}
+			v.Set(reflect.ValueOf(*option))
+		default:
+			return false, errUnsupportedField
+		}
+		return true, nil
+	case DBPropTypeMultiSelect:
+		switch v.Type() {
+		case stringsType:
+			names := make([]string, len(prop.MultiSelect))
+			for i, option := range prop.MultiSelect {
+				names[i] = option.Name
+			}
+			v.Set(reflect.ValueOf(names))
+		case selectOptionsType:
+			v.Set(reflect.ValueOf(prop.MultiSelect))
+		default:
+			return false, errUnsupportedField
+		}
+		return prop.MultiSelect != nil, nil
+	case DBPropTypeDate:
+		return setDate(v, prop.Date)
+	case DBPropTypePeople:
+		return setUsers(v, prop.People)
+	case DBPropTypeCreatedBy, DBPropTypeLastEditedBy:
+		user := prop.CreatedBy
+		if typ == DBPropTypeLastEditedBy {
+			user = prop.LastEditedBy
+		}
+		return setUser(v, user)
+	case DBPropTypeFiles:
+		switch v.Type() {
+		case stringsType:
+			urls := make([]string, 0, len(prop.Files))
+			for _, file := range prop.Files {
+				switch {
+				case file.File != nil:
+					urls = append(urls, file.File.URL)
+				case file.External != nil:
+					urls = append(urls, file.External.URL)
+				}
+			}
+			v.Set(reflect.ValueOf(urls))
+		case filesType:
+			v.Set(reflect.ValueOf(prop.Files))
+		default:
+			return false, errUnsupportedField
+		}
+		return prop.Files != nil, nil
+	case DBPropTypeRelation:
+		switch v.Type() {
+		case stringsType:
+			ids := make([]string, len(prop.Relation))
+			for i, relation := range prop.Relation {
+				ids[i] = relation.ID
+			}
+			v.Set(reflect.ValueOf(ids))
+		case relationsType:
+			v.Set(reflect.ValueOf(prop.Relation))
+		default:
+			return false, errUnsupportedField
+		}
+		return prop.Relation != nil, nil
+	case DBPropTypeCreatedTime, DBPropTypeLastEditedTime:
+		t := prop.CreatedTime
+		if typ == DBPropTypeLastEditedTime {
+			t = prop.LastEditedTime
+		}
+		return setTime(v, t)
+	case DBPropTypeFormula:
+		return setFormula(v, prop.Formula)
+	case DBPropTypeRollup:
+		return setRollup(v, prop.Rollup)
+	case DBPropTypeUniqueID:
+		id := prop.UniqueID
+		if id == nil {
+			return false, nil
+		}
+		switch {
+		case v.Type() == uniqueIDType:
+			v.Set(reflect.ValueOf(*id))
+			return true, nil
+		case v.Kind() == reflect.String:
+			s := strconv.Itoa(id.Number)
+			if id.Prefix != nil {
+				s = *id.Prefix + "-" + s
+			}
+			v.SetString(s)
+			return true, nil
+		}
+		n := float64(id.Number)
+		return setNumber(v, &n)
+	case DBPropTypeVerification:
+		verification := prop.Verification
+		if verification == nil {
+			return false, nil
+		}
+		switch {
+		case v.Type() == verificationType:
+			v.Set(reflect.ValueOf(*verification))
+		case v.Kind() == reflect.String:
+			v.SetString(verification.State)
+		default:
+			return false, errUnsupportedField
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("notion: unknown property type %v", typ)
+}
+
+func setFormula(v reflect.Value, formula *FormulaResult) (bool, error) {
+	if v.Type() == formulaType {
+		if formula == nil {
+			return false, nil
+		}
+		v.Set(reflect.ValueOf(*formula))
+		return true, nil
+	}
+	if formula == nil {
+		return false, nil
+	}
+	switch formula.Type {
+	case FormulaResultTypeString:
+		if formula.String == nil {
+			return false, setString(v, "")
+		}
+		return true, setString(v, *formula.String)
+	case FormulaResultTypeNumber:
+		return setNumber(v, formula.Number)
+	case FormulaResultTypeBoolean:
+		if v.Kind() != reflect.Bool {
+			return false, errUnsupportedField
+		}
+		if formula.Boolean == nil {
+			return false, nil
+		}
+		v.SetBool(*formula.Boolean)
+		return true, nil
+	case FormulaResultTypeDate:
+		return setDate(v, formula.Date)
+	}
+	return false, fmt.Errorf("notion: unknown formula result type %v", formula.Type)
+}
+
+func setRollup(v reflect.Value, rollup *RollupResult) (bool, error) {
+	if v.Type() == rollupType {
+		if rollup == nil {
+			return false, nil
+		}
+		v.Set(reflect.ValueOf(*rollup))
+		return true, nil
+	}
+	if rollup == nil {
+		return false, nil
+	}
+	switch rollup.Type {
+	case RollupResultTypeNumber:
+		return setNumber(v, rollup.Number)
+	case RollupResultTypeDate:
+		return setDate(v, rollup.Date)
+	case RollupResultTypeArray:
+		if v.Type() != propertiesType {
+			return false, errUnsupportedField
+		}
+		v.Set(reflect.ValueOf(rollup.Array))
+		return rollup.Array != nil, nil
+	}
+	return false, fmt.Errorf("notion: unknown rollup result type %v", rollup.Type)
+}
+
+func setString(v reflect.Value, s string) error {
+	if v.Kind() != reflect.String {
+		return errUnsupportedField
+	}
+	v.SetString(s)
+	return nil
+}
+
+func setNumber(v reflect.Value, n *float64) (bool, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return false, errUnsupportedField
+	}
+	if n == nil {
+		return false, nil
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(*n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(*n))
+	default:
+		v.SetInt(int64(*n))
+	}
+	return true, nil
+}
+
+func numberValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func setDate(v reflect.Value, date *Date) (bool, error) {
+	switch v.Type() {
+	case dateType, dateTimeType, timeType:
+	default:
+		return false, errUnsupportedField
+	}
+	if date == nil {
+		return false, nil
+	}
+	switch v.Type() {
+	case dateType:
+		v.Set(reflect.ValueOf(*date))
+	case dateTimeType:
+		v.Set(reflect.ValueOf(date.Start))
+	default:
+		v.Set(reflect.ValueOf(date.Start.Time))
+	}
+	return true, nil
+}
+
+func setTime(v reflect.Value, t *time.Time) (bool, error) {
+	if v.Type() != timeType {
+		return false, errUnsupportedField
+	}
+	if t == nil {
+		return false, nil
+	}
+	v.Set(reflect.ValueOf(*t))
+	return true, nil
+}
+
+func setUsers(v reflect.Value, users []User) (bool, error) {
+	switch v.Type() {
+	case stringsType:
+		ids := make([]string, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		v.Set(reflect.ValueOf(ids))
+	case usersType:
+		v.Set(reflect.ValueOf(users))
+	default:
+		return false, errUnsupportedField
+	}
+	return users != nil, nil
+}
+
+func setUser(v reflect.Value, user *User) (bool, error) {
+	switch {
+	case v.Type() == userType:
+	case v.Kind() == reflect.String:
+	default:
+		return false, errUnsupportedField
+	}
+	if user == nil {
+		return false, nil
+	}
+	if v.Kind() == reflect.String {
+		v.SetString(user.ID)
+	} else {
+		v.Set(reflect.ValueOf(*user))
+	}
+	return true, nil
+}
@@ -0,0 +1,206 @@
+package notionapi_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	notion "notionsync/pkg/notionapi"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+type taskProperties struct {
+	Task     string                `notion:"Task,title"`
+	Notes    []notion.RichText     `notion:"Notes,rich_text"`
+	Link     string                `notion:"Link,url"`
+	Points   float64               `notion:"Points,number"`
+	Estimate *int                  `notion:"Estimate,number,omitempty"`
+	Done     bool                  `notion:"Done,checkbox"`
+	Priority string                `notion:"Priority,select"`
+	Status   *notion.SelectOptions `notion:"Status,status"`
+	Tags     []string              `notion:"Tags,multi_select"`
+	Due      notion.DateTime       `notion:"Due,date"`
+	Owners   []string              `notion:"Owners,people"`
+	Blocks   []string              `notion:"Blocks,relation"`
+	Overdue  bool                  `notion:"Overdue,formula"`
+	Total    float64               `notion:"Total,rollup"`
+	Key      string                `notion:"Key,unique_id"`
+	Created  time.Time             `notion:"Created,created_time"`
+	Author   string                `notion:"Author,created_by"`
+	Ignored  string                `notion:"-"`
+}
+
+func TestMarshalProperties(t *testing.T) {
+	t.Parallel()
+
+	due := notion.NewDateTime(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), false)
+	task := taskProperties{
+		Task:     "Write tests",
+		Priority: "P0",
+		Status:   &notion.SelectOptions{Name: "In progress"},
+		Tags:     []string{"go"},
+		Due:      due,
+		Owners:   []string{"be32af33-a4a7-4a9f-ba0c-4cd30cd6ac9e"},
+		Overdue:  true,
+		Total:    3,
+		Ignored:  "ignored",
+	}
+
+	props, err := notion.MarshalProperties(&task)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expProps := notion.DatabasePageProperties{
+		"Task":     {Title: []notion.RichText{{Text: &notion.Text{Content: "Write tests"}}}},
+		"Points":   {Number: notion.Float64Ptr(0)},
+		"Done":     {Checkbox: notion.BoolPtr(false)},
+		"Priority": {Select: &notion.SelectOptions{Name: "P0"}},
+		"Status":   {Status: &notion.SelectOptions{Name: "In progress"}},
+		"Tags":     {MultiSelect: []notion.SelectOptions{{Name: "go"}}},
+		"Due":      {Date: &notion.Date{Start: due}},
+		"Owners":   {People: []notion.User{{ID: "be32af33-a4a7-4a9f-ba0c-4cd30cd6ac9e"}}},
+		"Notes":    {Type: notion.DBPropTypeRichText},
+		"Link":     {Type: notion.DBPropTypeURL},
+		"Blocks":   {Type: notion.DBPropTypeRelation},
+	}
+	if diff := cmp.Diff(expProps, props, cmp.AllowUnexported(notion.DateTime{})); diff != "" {
+		t.Fatalf("properties not equal (-exp, +got):\n%v", diff)
+	}
+
+	// Empty values clear the property.
+	for name, exp := range map[string]string{
+		"Notes":  `{"rich_text":[],"type":"rich_text"}`,
+		"Link":   `{"type":"url","url":null}`,
+		"Blocks": `{"relation":[],"type":"relation"}`,
+	} {
+		b, err := json.Marshal(props[name])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(exp, string(b)); diff != "" {
+			t.Fatalf("%v not equal (-exp, +got):\n%v", name, diff)
+		}
+	}
+}
+
+func TestUnmarshalProperties(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2022, 5, 29, 10, 0, 0, 0, time.UTC)
+	due := notion.NewDateTime(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), false)
+	prefix := "TASK"
+	estimate := 5
+
+	tests := []struct {
+		name    string
+		props   notion.DatabasePageProperties
+		init    taskProperties
+		expTask taskProperties
+		expErr  string
+	}{
+		{
+			name: "all types",
+			props: notion.DatabasePageProperties{
+				"Task": {
+					Type:  notion.DBPropTypeTitle,
+					Title: []notion.RichText{{PlainText: "Write "}, {PlainText: "tests"}},
+				},
+				"Notes":    {Type: notion.DBPropTypeRichText, RichText: []notion.RichText{}},
+				"Link":     {Type: notion.DBPropTypeURL, URL: notion.StringPtr("https://example.com")},
+				"Points":   {Type: notion.DBPropTypeNumber, Number: notion.Float64Ptr(2)},
+				"Estimate": {Type: notion.DBPropTypeNumber, Number: notion.Float64Ptr(5)},
+				"Done":     {Type: notion.DBPropTypeCheckbox, Checkbox: notion.BoolPtr(true)},
+				"Priority": {Type: notion.DBPropTypeSelect, Select: &notion.SelectOptions{Name: "P0"}},
+				"Status":   {Type: notion.DBPropTypeStatus, Status: &notion.SelectOptions{ID: "1", Name: "Done"}},
+				"Tags":     {Type: notion.DBPropTypeMultiSelect, MultiSelect: []notion.SelectOptions{{Name: "go"}, {Name: "notion"}}},
+				"Due":      {Type: notion.DBPropTypeDate, Date: &notion.Date{Start: due}},
+				"Owners":   {Type: notion.DBPropTypePeople, People: []notion.User{{ID: "user-1"}}},
+				"Blocks":   {Type: notion.DBPropTypeRelation, Relation: []notion.Relation{{ID: "page-1"}}},
+				"Overdue": {
+					Type:    notion.DBPropTypeFormula,
+					Formula: &notion.FormulaResult{Type: notion.FormulaResultTypeBoolean, Boolean: notion.BoolPtr(true)},
+				},
+				"Total": {
+					Type:   notion.DBPropTypeRollup,
+					Rollup: &notion.RollupResult{Type: notion.RollupResultTypeNumber, Number: notion.Float64Ptr(7)},
+				},
+				"Key":     {Type: notion.DBPropTypeUniqueID, UniqueID: &notion.UniqueID{Prefix: &prefix, Number: 42}},
+				"Created": {Type: notion.DBPropTypeCreatedTime, CreatedTime: &created},
+				"Author":  {Type: notion.DBPropTypeCreatedBy, CreatedBy: &notion.User{ID: "user-2"}},
+			},
+			expTask: taskProperties{
+				Task:     "Write tests",
+				Notes:    []notion.RichText{},
+				Link:     "https://example.com",
+				Points:   2,
+				Estimate: &estimate,
+				Done:     true,
+				Priority: "P0",
+				Status:   &notion.SelectOptions{ID: "1", Name: "Done"},
+				Tags:     []string{"go", "notion"},
+				Due:      due,
+				Owners:   []string{"user-1"},
+				Blocks:   []string{"page-1"},
+				Overdue:  true,
+				Total:    7,
+				Key:      "TASK-42",
+				Created:  created,
+				Author:   "user-2",
+			},
+		},
+		{
+			name: "empty values and missing properties",
+			props: notion.DatabasePageProperties{
+				"Estimate": {Type: notion.DBPropTypeNumber},
+				"Status":   {Type: notion.DBPropTypeStatus},
+			},
+			init: taskProperties{
+				Task:     "Keep",
+				Estimate: &estimate,
+				Status:   &notion.SelectOptions{Name: "Done"},
+			},
+			expTask: taskProperties{Task: "Keep"},
+		},
+		{
+			name:   "type mismatch",
+			props:  notion.DatabasePageProperties{"Done": {Type: notion.DBPropTypeFormula}},
+			expErr: `notion: cannot unmarshal formula property "Done" into field Done tagged checkbox`,
+		},
+		{
+			name: "unsupported field type",
+			props: notion.DatabasePageProperties{
+				"Total": {
+					Type:   notion.DBPropTypeRollup,
+					Rollup: &notion.RollupResult{Type: notion.RollupResultTypeArray},
+				},
+			},
+			expErr: `notion: cannot unmarshal rollup property "Total" into field Total of type float64`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			task := tt.init
+			err := notion.UnmarshalProperties(tt.props, &task)
+			if tt.expErr != "" {
+				if err == nil || err.Error() != tt.expErr {
+					t.Fatalf("error not equal (expected: %v, got: %v)", tt.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expTask, task, cmp.AllowUnexported(notion.DateTime{}), cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("task not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
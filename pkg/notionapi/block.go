@@ -11,8 +11,11 @@ type Block struct {
 	Object         string     `json:"object"`
 	ID             string     `json:"id,omitempty"`
 	Type           BlockType  `json:"type,omitempty"`
+	Parent         *Parent    `json:"parent,omitempty"`
 	CreatedTime    *time.Time `json:"created_time,omitempty"`
+	CreatedBy      *User      `json:"created_by,omitempty"`
 	LastEditedTime *time.Time `json:"last_edited_time,omitempty"`
+	LastEditedBy   *User      `json:"last_edited_by,omitempty"`
 	HasChildren    bool       `json:"has_children,omitempty"`
 	Archived       *bool      `json:"archived,omitempty"`
 
@@ -32,6 +35,7 @@ type Block struct {
 	Embed            *Embed           `json:"embed,omitempty"`
 	Image            *FileBlock       `json:"image,omitempty"`
 	Video            *FileBlock       `json:"video,omitempty"`
+	Audio            *FileBlock       `json:"audio,omitempty"`
 	File             *FileBlock       `json:"file,omitempty"`
 	PDF              *FileBlock       `json:"pdf,omitempty"`
 	Bookmark         *Bookmark        `json:"bookmark,omitempty"`
@@ -45,15 +49,31 @@ type Block struct {
 	LinkToPage       *LinkToPage      `json:"link_to_page,omitempty"`
 	SyncedBlock      *SyncedBlock     `json:"synced_block,omitempty"`
 	Template         *RichTextBlock   `json:"template,omitempty"`
+	Table            *Table           `json:"table,omitempty"`
+	TableRow         *TableRow        `json:"table_row,omitempty"`
 }
 
 type RichTextBlock struct {
-	Text     []RichText `json:"text"`
+	RichText []RichText `json:"rich_text"`
+	Color    Color      `json:"color,omitempty"`
 	Children []Block    `json:"children,omitempty"`
+
+	// Deprecated: Text is the key of the rich text of blocks before API
+	// version 2022-02-22. Blocks read with such a version have it moved to
+	// RichText, and requests use the key of the version of the client. When
+	// set, the rich text is encoded under the text key.
+	Text []RichText `json:"text,omitempty"`
 }
 
 type Heading struct {
-	Text []RichText `json:"text"`
+	RichText     []RichText `json:"rich_text"`
+	Color        Color      `json:"color,omitempty"`
+	IsToggleable bool       `json:"is_toggleable,omitempty"`
+	Children     []Block    `json:"children,omitempty"`
+
+	// Deprecated: Text is the key of the rich text of blocks before API
+	// version 2022-02-22, see RichTextBlock.
+	Text []RichText `json:"text,omitempty"`
 }
 
 type ToDo struct {
@@ -76,7 +96,8 @@ type Callout struct {
 
 type Code struct {
 	RichTextBlock
-	Language *string `json:"language,omitempty"`
+	Caption  []RichText `json:"caption,omitempty"`
+	Language *string    `json:"language,omitempty"`
 }
 
 type Embed struct {
@@ -86,9 +107,10 @@ type Embed struct {
 type FileBlock struct {
 	Type FileType `json:"type"`
 
-	File     *FileFile     `json:"file,omitempty"`
-	External *FileExternal `json:"external,omitempty"`
-	Caption  []RichText    `json:"caption,omitempty"`
+	File       *FileFile            `json:"file,omitempty"`
+	External   *FileExternal        `json:"external,omitempty"`
+	FileUpload *FileUploadReference `json:"file_upload,omitempty"`
+	Caption    []RichText           `json:"caption,omitempty"`
 }
 
 type Bookmark struct {
@@ -132,6 +154,20 @@ type SyncedFromType string
 
 const SyncedFromTypeBlockID SyncedFromType = "block_id"
 
+// Table is a table block, whose children are its rows. Rows must be given
+// along with the table when it is created.
+type Table struct {
+	TableWidth      int     `json:"table_width"`
+	HasColumnHeader bool    `json:"has_column_header"`
+	HasRowHeader    bool    `json:"has_row_header"`
+	Children        []Block `json:"children,omitempty"`
+}
+
+// TableRow is a row of a table, with the rich text of each cell.
+type TableRow struct {
+	Cells [][]RichText `json:"cells"`
+}
+
 type (
 	Divider         struct{}
 	TableOfContents struct{}
@@ -157,6 +193,7 @@ const (
 	BlockTypeEmbed            BlockType = "embed"
 	BlockTypeImage            BlockType = "image"
 	BlockTypeVideo            BlockType = "video"
+	BlockTypeAudio            BlockType = "audio"
 	BlockTypeFile             BlockType = "file"
 	BlockTypePDF              BlockType = "pdf"
 	BlockTypeBookmark         BlockType = "bookmark"
@@ -170,6 +207,8 @@ const (
 	BlockTypeLinkToPage       BlockType = "link_to_page"
 	BlockTypeSyncedBlock      BlockType = "synced_block"
 	BlockTypeTemplate         BlockType = "template"
+	BlockTypeTable            BlockType = "table"
+	BlockTypeTableRow         BlockType = "table_row"
 	BlockTypeUnsupported      BlockType = "unsupported"
 )
 
@@ -194,3 +233,119 @@ func (b Block) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(alias)
 }
+
+// UnmarshalJSON implements json.Unmarshaler. Rich text read from API versions
+// before 2022-02-22 is moved from the legacy text key to RichText.
+func (b *Block) UnmarshalJSON(data []byte) error {
+	type blockAlias Block
+
+	var alias blockAlias
+	if err := json.Unmarshal(data, &alias); err != nil {
+		return err
+	}
+	*b = Block(alias)
+
+	switch {
+	case b.Heading1 != nil && b.Heading1.RichText == nil:
+		b.Heading1.RichText, b.Heading1.Text = b.Heading1.Text, nil
+	case b.Heading2 != nil && b.Heading2.RichText == nil:
+		b.Heading2.RichText, b.Heading2.Text = b.Heading2.Text, nil
+	case b.Heading3 != nil && b.Heading3.RichText == nil:
+		b.Heading3.RichText, b.Heading3.Text = b.Heading3.Text, nil
+	}
+	if body := b.richTextBlock(); body != nil && body.RichText == nil {
+		body.RichText, body.Text = body.Text, nil
+	}
+
+	return nil
+}
+
+// richTextJSON is the encoding of the rich text of a block: under the
+// legacy text key when Text is set, or else under rich_text, which is
+// required even without text.
+type richTextJSON struct {
+	RichText *[]RichText `json:"rich_text,omitempty"`
+	Text     *[]RichText `json:"text,omitempty"`
+	Color    Color       `json:"color,omitempty"`
+	Children []Block     `json:"children,omitempty"`
+}
+
+func newRichTextJSON(richText, text []RichText, color Color, children []Block) richTextJSON {
+	j := richTextJSON{Color: color, Children: children}
+	switch {
+	case text != nil:
+		j.Text = &text
+	case richText != nil:
+		j.RichText = &richText
+	default:
+		j.RichText = &[]RichText{}
+	}
+	return j
+}
+
+// MarshalJSON implements json.Marshaler.
+func (b RichTextBlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newRichTextJSON(b.RichText, b.Text, b.Color, b.Children))
+}
+
+// MarshalJSON implements json.Marshaler.
+func (h Heading) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		richTextJSON
+		IsToggleable bool `json:"is_toggleable,omitempty"`
+	}{newRichTextJSON(h.RichText, h.Text, h.Color, h.Children), h.IsToggleable})
+}
+
+// MarshalJSON implements json.Marshaler, which ToDo would otherwise take
+// from RichTextBlock without its own fields.
+func (t ToDo) MarshalJSON() ([]byte, error) {
+	b := t.RichTextBlock
+	return json.Marshal(struct {
+		richTextJSON
+		Checked *bool `json:"checked,omitempty"`
+	}{newRichTextJSON(b.RichText, b.Text, b.Color, b.Children), t.Checked})
+}
+
+// MarshalJSON implements json.Marshaler, see ToDo.MarshalJSON.
+func (c Callout) MarshalJSON() ([]byte, error) {
+	b := c.RichTextBlock
+	return json.Marshal(struct {
+		richTextJSON
+		Icon *Icon `json:"icon,omitempty"`
+	}{newRichTextJSON(b.RichText, b.Text, b.Color, b.Children), c.Icon})
+}
+
+// MarshalJSON implements json.Marshaler, see ToDo.MarshalJSON.
+func (c Code) MarshalJSON() ([]byte, error) {
+	b := c.RichTextBlock
+	return json.Marshal(struct {
+		richTextJSON
+		Caption  []RichText `json:"caption,omitempty"`
+		Language *string    `json:"language,omitempty"`
+	}{newRichTextJSON(b.RichText, b.Text, b.Color, b.Children), c.Caption, c.Language})
+}
+
+// richTextBlock returns the rich text body of the block types that have one.
+func (b *Block) richTextBlock() *RichTextBlock {
+	switch {
+	case b.Paragraph != nil:
+		return b.Paragraph
+	case b.BulletedListItem != nil:
+		return b.BulletedListItem
+	case b.NumberedListItem != nil:
+		return b.NumberedListItem
+	case b.ToDo != nil:
+		return &b.ToDo.RichTextBlock
+	case b.Toggle != nil:
+		return b.Toggle
+	case b.Callout != nil:
+		return &b.Callout.RichTextBlock
+	case b.Quote != nil:
+		return b.Quote
+	case b.Code != nil:
+		return &b.Code.RichTextBlock
+	case b.Template != nil:
+		return b.Template
+	}
+	return nil
+}
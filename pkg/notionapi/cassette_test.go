@@ -0,0 +1,67 @@
+package notionapi_test
+
+import (
+	"context"
+	"testing"
+
+	"notionsync/pkg/cassette"
+	notion "notionsync/pkg/notionapi"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestFindPageByIDCassette decodes recorded pages that the hand-written
+// responses of TestFindPageByID do not cover.
+func TestFindPageByIDCassette(t *testing.T) {
+	t.Parallel()
+
+	rec, err := cassette.New("testdata/cassettes/find_page.json", cassette.ModeReplay)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := notion.NewClient("secret-api-key", notion.WithMiddleware(rec.Middleware))
+	ctx := context.Background()
+
+	t.Run("page in a block", func(t *testing.T) {
+		page, err := client.FindPageByID(ctx, "3c9d2f6e-0d5a-4b8e-9f41-7a2b6c1e8d40")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		exp := notion.Parent{Type: notion.ParentTypeBlock, BlockID: "d1a5c0b2-6e4f-4f7a-9c3d-2b8e1f0a7c55"}
+		if diff := cmp.Diff(exp, page.Parent); diff != "" {
+			t.Fatalf("parent not equal (-exp, +got):\n%v", diff)
+		}
+		props, ok := page.Properties.(notion.PageProperties)
+		if !ok || props.Title.Title[0].PlainText != "Meeting notes" {
+			t.Fatalf("unexpected properties: %#v", page.Properties)
+		}
+	})
+
+	t.Run("database page with computed and unknown properties", func(t *testing.T) {
+		page, err := client.FindPageByID(ctx, "9b1f4c2a-5e7d-4a36-8c0b-1d2e3f4a5b6c")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		props := page.Properties.(notion.DatabasePageProperties)
+
+		due := props["Due"].Formula
+		exp, _ := notion.ParseDateTime("2022-07-01")
+		if due == nil || due.Date == nil || !due.Date.Start.Equal(exp) || due.Date.End != nil {
+			t.Errorf("unexpected formula: %#v", due)
+		}
+		if estimates := props["Estimates"].Rollup; estimates == nil || len(estimates.Array) != 2 ||
+			*estimates.Array[0].Number != 3 || estimates.Array[1].Number != nil {
+			t.Errorf("unexpected rollup: %#v", estimates)
+		}
+		if id := props["ID"].UniqueID; id == nil || *id.Prefix != "TASK" || id.Number != 42 {
+			t.Errorf("unexpected unique ID: %#v", id)
+		}
+		if start := props["Start"]; start.Type != "button" {
+			t.Errorf("unexpected property: %#v", start)
+		}
+	})
+
+	if unused := rec.Unused(); len(unused) > 0 {
+		t.Fatalf("expected every interaction to be replayed, got %v", len(unused))
+	}
+}
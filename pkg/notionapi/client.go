@@ -6,30 +6,54 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"path"
 	"strconv"
+	"strings"
 )
 
 const (
-	baseURL       = "https://api.notion.com/v1"
-	apiVersion    = "2021-08-16"
-	clientVersion = "0.0.0"
+	// DefaultBaseURL is the URL the paths of API requests are relative to.
+	DefaultBaseURL = "https://api.notion.com/v1"
+	clientVersion  = "0.0.0"
 )
 
 // Client is used for HTTP requests to the Notion API.
 type Client struct {
-	apiKey     string
-	httpClient *http.Client
+	apiKey      string
+	apiVersion  string
+	baseURL     string
+	userAgent   string
+	httpClient  *http.Client
+	middlewares []Middleware
 }
 
 // ClientOption is used to override default client behavior.
 type ClientOption func(*Client)
 
+// Middleware wraps the transport requests are sent with, to observe or change
+// requests and responses. Middleware that changes a request should clone it
+// first, as http.RoundTripper requires.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper, for writing
+// middleware.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(r).
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
 // NewClient returns a new Client.
 func NewClient(apiKey string, opts ...ClientOption) *Client {
 	c := &Client{
 		apiKey:     apiKey,
+		apiVersion: DefaultAPIVersion,
+		baseURL:    DefaultBaseURL,
+		userAgent:  "go-notion/" + clientVersion,
 		httpClient: http.DefaultClient,
 	}
 
@@ -37,6 +61,19 @@ func NewClient(apiKey string, opts ...ClientOption) *Client {
 		opt(c)
 	}
 
+	if len(c.middlewares) > 0 {
+		transport := c.httpClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		for i := len(c.middlewares) - 1; i >= 0; i-- {
+			transport = c.middlewares[i](transport)
+		}
+		httpClient := *c.httpClient
+		httpClient.Transport = transport
+		c.httpClient = &httpClient
+	}
+
 	return c
 }
 
@@ -47,15 +84,38 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 	}
 }
 
+// WithBaseURL overrides DefaultBaseURL, to send requests through a proxy or
+// to a fake server.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithUserAgent overrides the User-Agent header of requests.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithMiddleware wraps the transport of the http.Client with middlewares,
+// the first one seeing requests first. The http.Client itself is not changed.
+func WithMiddleware(middlewares ...Middleware) ClientOption {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
 func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
-	req, err := http.NewRequestWithContext(ctx, method, baseURL+url, body)
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+url, body)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", c.apiKey))
-	req.Header.Set("Notion-Version", apiVersion)
-	req.Header.Set("User-Agent", "go-notion/"+clientVersion)
+	req.Header.Set("Notion-Version", c.apiVersion)
+	req.Header.Set("User-Agent", c.userAgent)
 
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
@@ -96,7 +156,9 @@ func (c *Client) QueryDatabase(ctx context.Context, id string, query *DatabaseQu
 	body := &bytes.Buffer{}
 
 	if query != nil {
-		err = json.NewEncoder(body).Encode(query)
+		versioned := *query
+		versioned.Filter = versionedFilter(query.Filter, c.legacyText())
+		err = json.NewEncoder(body).Encode(versioned)
 		if err != nil {
 			return DatabaseQueryResponse{}, fmt.Errorf("notion: failed to encode filter to JSON: %w", err)
 		}
@@ -234,6 +296,7 @@ func (c *Client) CreatePage(ctx context.Context, params CreatePageParams) (page
 
 	body := &bytes.Buffer{}
 
+	params.Children = versionedBlocks(params.Children, c.legacyText())
 	err = json.NewEncoder(body).Encode(params)
 	if err != nil {
 		return Page{}, fmt.Errorf("notion: failed to encode body params to JSON: %w", err)
@@ -380,7 +443,7 @@ func (c *Client) AppendBlockChildren(ctx context.Context, blockID string, childr
 		Children []Block `json:"children"`
 	}
 
-	dto := PostBody{children}
+	dto := PostBody{versionedBlocks(children, c.legacyText())}
 	body := &bytes.Buffer{}
 
 	err = json.NewEncoder(body).Encode(dto)
@@ -442,7 +505,7 @@ func (c *Client) FindBlockByID(ctx context.Context, blockID string) (block Block
 func (c *Client) UpdateBlock(ctx context.Context, blockID string, block Block) (updatedBlock Block, err error) {
 	body := &bytes.Buffer{}
 
-	err = json.NewEncoder(body).Encode(block)
+	err = json.NewEncoder(body).Encode(versionedBlock(block, c.legacyText()))
 	if err != nil {
 		return Block{}, fmt.Errorf("notion: failed to encode body params to JSON: %w", err)
 	}
@@ -620,3 +683,258 @@ func (c *Client) Search(ctx context.Context, opts *SearchOpts) (result SearchRes
 
 	return result, nil
 }
+
+// CreateComment creates a comment on a page or in an existing discussion
+// thread.
+// See: https://developers.notion.com/reference/create-a-comment
+func (c *Client) CreateComment(ctx context.Context, params CreateCommentParams) (comment Comment, err error) {
+	if err := params.Validate(); err != nil {
+		return Comment{}, fmt.Errorf("notion: invalid comment params: %w", err)
+	}
+
+	body := &bytes.Buffer{}
+
+	err = json.NewEncoder(body).Encode(params)
+	if err != nil {
+		return Comment{}, fmt.Errorf("notion: failed to encode body params to JSON: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/comments", body)
+	if err != nil {
+		return Comment{}, fmt.Errorf("notion: invalid request: %w", err)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return Comment{}, fmt.Errorf("notion: failed to make HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Comment{}, fmt.Errorf("notion: failed to create comment: %w", parseErrorResponse(res))
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&comment)
+	if err != nil {
+		return Comment{}, fmt.Errorf("notion: failed to parse HTTP response: %w", err)
+	}
+
+	return comment, nil
+}
+
+// ListComments returns a list of the unresolved comments on a page or block,
+// and pagination metadata.
+// See: https://developers.notion.com/reference/retrieve-a-comment
+func (c *Client) ListComments(ctx context.Context, blockID string, query *PaginationQuery) (result ListCommentsResponse, err error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/comments", nil)
+	if err != nil {
+		return ListCommentsResponse{}, fmt.Errorf("notion: invalid request: %w", err)
+	}
+
+	q := url.Values{}
+	q.Set("block_id", blockID)
+	if query != nil {
+		if query.StartCursor != "" {
+			q.Set("start_cursor", query.StartCursor)
+		}
+		if query.PageSize != 0 {
+			q.Set("page_size", strconv.Itoa(query.PageSize))
+		}
+	}
+	req.URL.RawQuery = q.Encode()
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return ListCommentsResponse{}, fmt.Errorf("notion: failed to make HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return ListCommentsResponse{}, fmt.Errorf("notion: failed to list comments: %w", parseErrorResponse(res))
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&result)
+	if err != nil {
+		return ListCommentsResponse{}, fmt.Errorf("notion: failed to parse HTTP response: %w", err)
+	}
+
+	return result, nil
+}
+
+// CreateFileUpload starts a file upload, whose contents are then sent with
+// SendFileUpload.
+// See: https://developers.notion.com/reference/create-a-file-upload
+func (c *Client) CreateFileUpload(ctx context.Context, params CreateFileUploadParams) (upload FileUpload, err error) {
+	if err := params.Validate(); err != nil {
+		return FileUpload{}, fmt.Errorf("notion: invalid file upload params: %w", err)
+	}
+
+	body := &bytes.Buffer{}
+
+	err = json.NewEncoder(body).Encode(params)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to encode body params to JSON: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/file_uploads", body)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: invalid request: %w", err)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to make HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return FileUpload{}, fmt.Errorf("notion: failed to create file upload: %w", parseErrorResponse(res))
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&upload)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to parse HTTP response: %w", err)
+	}
+
+	return upload, nil
+}
+
+// SendFileUpload sends the contents of a single-part upload, or one part of
+// a multi-part upload, as multipart/form-data.
+// See: https://developers.notion.com/reference/send-a-file-upload
+func (c *Client) SendFileUpload(ctx context.Context, id string, params SendFileUploadParams) (upload FileUpload, err error) {
+	if err := params.Validate(); err != nil {
+		return FileUpload{}, fmt.Errorf("notion: invalid file upload params: %w", err)
+	}
+
+	body, contentType, err := params.encode()
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to encode file upload: %w", err)
+	}
+
+	// The body is not JSON, so it is set after newRequest to keep it from
+	// being versioned.
+	req, err := c.newRequest(ctx, http.MethodPost, "/file_uploads/"+id+"/send", nil)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: invalid request: %w", err)
+	}
+	req.Body = io.NopCloser(body)
+	req.ContentLength = int64(body.Len())
+	req.Header.Set("Content-Type", contentType)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to make HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return FileUpload{}, fmt.Errorf("notion: failed to send file upload: %w", parseErrorResponse(res))
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&upload)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to parse HTTP response: %w", err)
+	}
+
+	return upload, nil
+}
+
+// CompleteFileUpload completes a multi-part upload once all of its parts
+// have been sent.
+// See: https://developers.notion.com/reference/complete-a-file-upload
+func (c *Client) CompleteFileUpload(ctx context.Context, id string) (upload FileUpload, err error) {
+	req, err := c.newRequest(ctx, http.MethodPost, "/file_uploads/"+id+"/complete", nil)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: invalid request: %w", err)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to make HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return FileUpload{}, fmt.Errorf("notion: failed to complete file upload: %w", parseErrorResponse(res))
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&upload)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to parse HTTP response: %w", err)
+	}
+
+	return upload, nil
+}
+
+// FindFileUploadByID fetches a file upload by ID.
+// See: https://developers.notion.com/reference/retrieve-a-file-upload
+func (c *Client) FindFileUploadByID(ctx context.Context, id string) (upload FileUpload, err error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/file_uploads/"+id, nil)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: invalid request: %w", err)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to make HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return FileUpload{}, fmt.Errorf("notion: failed to find file upload: %w", parseErrorResponse(res))
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&upload)
+	if err != nil {
+		return FileUpload{}, fmt.Errorf("notion: failed to parse HTTP response: %w", err)
+	}
+
+	return upload, nil
+}
+
+// UploadFile uploads size bytes read from r as a file named filename, in a
+// single part up to MaxSinglePartSize and in parts of FileUploadPartSize
+// otherwise. An empty contentType is guessed from the extension of filename.
+// The returned upload can be attached through a FileUploadReference.
+func (c *Client) UploadFile(ctx context.Context, filename, contentType string, r io.Reader, size int64) (FileUpload, error) {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(filename))
+	}
+
+	if size <= MaxSinglePartSize {
+		upload, err := c.CreateFileUpload(ctx, CreateFileUploadParams{Filename: filename, ContentType: contentType})
+		if err != nil {
+			return FileUpload{}, err
+		}
+		return c.SendFileUpload(ctx, upload.ID, SendFileUploadParams{
+			Filename:    filename,
+			ContentType: contentType,
+			Data:        r,
+		})
+	}
+
+	parts := int((size + FileUploadPartSize - 1) / FileUploadPartSize)
+	upload, err := c.CreateFileUpload(ctx, CreateFileUploadParams{
+		Mode:          FileUploadModeMultiPart,
+		Filename:      filename,
+		ContentType:   contentType,
+		NumberOfParts: parts,
+	})
+	if err != nil {
+		return FileUpload{}, err
+	}
+	for part := 1; part <= parts; part++ {
+		_, err := c.SendFileUpload(ctx, upload.ID, SendFileUploadParams{
+			Filename:    filename,
+			ContentType: contentType,
+			Data:        io.LimitReader(r, FileUploadPartSize),
+			PartNumber:  part,
+		})
+		if err != nil {
+			return FileUpload{}, err
+		}
+	}
+
+	return c.CompleteFileUpload(ctx, upload.ID)
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -433,7 +437,7 @@ func TestQueryDatabase(t *testing.T) {
 			query: &notion.DatabaseQuery{
 				Filter: &notion.DatabaseQueryFilter{
 					Property: "Name",
-					Text: &notion.TextDatabaseQueryFilter{
+					RichText: &notion.TextDatabaseQueryFilter{
 						Contains: "foobar",
 					},
 				},
@@ -641,7 +645,7 @@ func TestQueryDatabase(t *testing.T) {
 			expPostBody: map[string]interface{}{
 				"filter": map[string]interface{}{
 					"property": "Name",
-					"text": map[string]interface{}{
+					"rich_text": map[string]interface{}{
 						"contains": "foobar",
 					},
 				},
@@ -1590,6 +1594,103 @@ func TestFindPageByID(t *testing.T) {
 			},
 			expError: nil,
 		},
+		{
+			name: "database parent with status, unique ID and verification",
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "page",
+						"id": "606ed832-7d79-46de-bbed-5b4896e7bc02",
+						"created_time": "2022-09-01T10:00:00.000Z",
+						"last_edited_time": "2022-09-01T10:00:00.000Z",
+						"created_by": {
+							"object": "user",
+							"id": "be32e790-8292-46df-a248-b784fdf483cf"
+						},
+						"parent": {
+							"type": "database_id",
+							"database_id": "39ddfc9d-33c9-404c-89cf-79f01c42dd0c"
+						},
+						"archived": false,
+						"url": "https://www.notion.so/Avocado-606ed8327d7946debbed5b4896e7bc02",
+						"public_url": null,
+						"properties": {
+							"Status": {
+								"id": "XDuQ",
+								"type": "status",
+								"status": {
+									"id": "cf4952eb-1265-46ec-86ab-4bded4fa2e3b",
+									"name": "In progress",
+									"color": "blue"
+								}
+							},
+							"ID": {
+								"id": "nWaZ",
+								"type": "unique_id",
+								"unique_id": {
+									"prefix": "TASK",
+									"number": 42
+								}
+							},
+							"Verification": {
+								"id": "Pn%3Ax",
+								"type": "verification",
+								"verification": {
+									"state": "verified",
+									"verified_by": null,
+									"date": {
+										"start": "2022-09-01T10:00:00.000Z",
+										"end": null
+									}
+								}
+							}
+						}
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expPage: notion.Page{
+				ID:             "606ed832-7d79-46de-bbed-5b4896e7bc02",
+				CreatedTime:    mustParseTime(time.RFC3339Nano, "2022-09-01T10:00:00.000Z"),
+				LastEditedTime: mustParseTime(time.RFC3339Nano, "2022-09-01T10:00:00.000Z"),
+				CreatedBy:      &notion.User{ID: "be32e790-8292-46df-a248-b784fdf483cf"},
+				URL:            "https://www.notion.so/Avocado-606ed8327d7946debbed5b4896e7bc02",
+				Parent: notion.Parent{
+					Type:       notion.ParentTypeDatabase,
+					DatabaseID: "39ddfc9d-33c9-404c-89cf-79f01c42dd0c",
+				},
+				Properties: notion.DatabasePageProperties{
+					"Status": notion.DatabasePageProperty{
+						ID:   "XDuQ",
+						Type: notion.DBPropTypeStatus,
+						Status: &notion.SelectOptions{
+							ID:    "cf4952eb-1265-46ec-86ab-4bded4fa2e3b",
+							Name:  "In progress",
+							Color: notion.ColorBlue,
+						},
+					},
+					"ID": notion.DatabasePageProperty{
+						ID:   "nWaZ",
+						Type: notion.DBPropTypeUniqueID,
+						UniqueID: &notion.UniqueID{
+							Prefix: notion.StringPtr("TASK"),
+							Number: 42,
+						},
+					},
+					"Verification": notion.DatabasePageProperty{
+						ID:   "Pn%3Ax",
+						Type: notion.DBPropTypeVerification,
+						Verification: &notion.Verification{
+							State: "verified",
+							Date: &notion.Date{
+								Start: mustParseDateTime("2022-09-01T10:00:00.000Z"),
+							},
+						},
+					},
+				},
+			},
+			expError: nil,
+		},
 		{
 			name: "error response",
 			respBody: func(_ *http.Request) io.Reader {
@@ -1671,7 +1772,7 @@ func TestCreatePage(t *testing.T) {
 						Object: "block",
 						Type:   notion.BlockTypeParagraph,
 						Paragraph: &notion.RichTextBlock{
-							Text: []notion.RichText{
+							RichText: []notion.RichText{
 								{
 									Text: &notion.Text{
 										Content: "Lorem ipsum dolor sit amet.",
@@ -1766,7 +1867,7 @@ func TestCreatePage(t *testing.T) {
 						"object": "block",
 						"type":   "paragraph",
 						"paragraph": map[string]interface{}{
-							"text": []interface{}{
+							"rich_text": []interface{}{
 								map[string]interface{}{
 									"text": map[string]interface{}{
 										"content": "Lorem ipsum dolor sit amet.",
@@ -1850,7 +1951,7 @@ func TestCreatePage(t *testing.T) {
 						Object: "block",
 						Type:   notion.BlockTypeParagraph,
 						Paragraph: &notion.RichTextBlock{
-							Text: []notion.RichText{
+							RichText: []notion.RichText{
 								{
 									Text: &notion.Text{
 										Content: "Lorem ipsum dolor sit amet.",
@@ -1919,7 +2020,7 @@ func TestCreatePage(t *testing.T) {
 						"object": "block",
 						"type":   "paragraph",
 						"paragraph": map[string]interface{}{
-							"text": []interface{}{
+							"rich_text": []interface{}{
 								map[string]interface{}{
 									"text": map[string]interface{}{
 										"content": "Lorem ipsum dolor sit amet.",
@@ -2504,6 +2605,144 @@ func TestUpdatePage(t *testing.T) {
 			},
 			expError: nil,
 		},
+		{
+			name: "database page props without value, clears them",
+			params: notion.UpdatePageParams{
+				DatabasePageProperties: &notion.DatabasePageProperties{
+					"Notes": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeRichText,
+					},
+					"Due": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeDate,
+					},
+					"Priority": notion.DatabasePageProperty{
+						Type:   notion.DBPropTypeSelect,
+						Select: &notion.SelectOptions{Name: "P2"},
+					},
+				},
+			},
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "page",
+						"id": "e4f419a7-f01f-4d5b-af58-ff4786a429fe",
+						"created_time": "2021-05-17T17:56:00.000Z",
+						"last_edited_time": "2021-05-22T16:24:23.007Z",
+						"parent": {
+							"type": "database_id",
+							"database_id": "4cb17949-f08d-4d5c-ab50-fe6ba689d2c8"
+						},
+						"archived": false,
+						"properties": {}
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expPostBody: map[string]interface{}{
+				"properties": map[string]interface{}{
+					"Notes": map[string]interface{}{
+						"type":      "rich_text",
+						"rich_text": []interface{}{},
+					},
+					"Due": map[string]interface{}{
+						"type": "date",
+						"date": nil,
+					},
+					"Priority": map[string]interface{}{
+						"type": "select",
+						"select": map[string]interface{}{
+							"name": "P2",
+						},
+					},
+				},
+			},
+			expResponse: notion.Page{
+				ID:             "e4f419a7-f01f-4d5b-af58-ff4786a429fe",
+				CreatedTime:    mustParseTime(time.RFC3339Nano, "2021-05-17T17:56:00.000Z"),
+				LastEditedTime: mustParseTime(time.RFC3339Nano, "2021-05-22T16:24:23.007Z"),
+				Parent: notion.Parent{
+					Type:       notion.ParentTypeDatabase,
+					DatabaseID: "4cb17949-f08d-4d5c-ab50-fe6ba689d2c8",
+				},
+				Properties: notion.DatabasePageProperties{},
+			},
+			expError: nil,
+		},
+		{
+			name: "archived, successful response",
+			params: notion.UpdatePageParams{
+				Archived: notion.BoolPtr(true),
+			},
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "page",
+						"id": "e4f419a7-f01f-4d5b-af58-ff4786a429fe",
+						"created_time": "2021-05-17T17:56:00.000Z",
+						"last_edited_time": "2021-05-22T16:24:23.007Z",
+						"parent": {
+							"type": "database_id",
+							"database_id": "4cb17949-f08d-4d5c-ab50-fe6ba689d2c8"
+						},
+						"archived": true,
+						"properties": {}
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expPostBody: map[string]interface{}{
+				"archived": true,
+			},
+			expResponse: notion.Page{
+				ID:             "e4f419a7-f01f-4d5b-af58-ff4786a429fe",
+				CreatedTime:    mustParseTime(time.RFC3339Nano, "2021-05-17T17:56:00.000Z"),
+				LastEditedTime: mustParseTime(time.RFC3339Nano, "2021-05-22T16:24:23.007Z"),
+				Parent: notion.Parent{
+					Type:       notion.ParentTypeDatabase,
+					DatabaseID: "4cb17949-f08d-4d5c-ab50-fe6ba689d2c8",
+				},
+				Archived:   true,
+				Properties: notion.DatabasePageProperties{},
+			},
+			expError: nil,
+		},
+		{
+			name: "restore archived, successful response",
+			params: notion.UpdatePageParams{
+				Archived: notion.BoolPtr(false),
+			},
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "page",
+						"id": "e4f419a7-f01f-4d5b-af58-ff4786a429fe",
+						"created_time": "2021-05-17T17:56:00.000Z",
+						"last_edited_time": "2021-05-22T16:24:23.007Z",
+						"parent": {
+							"type": "database_id",
+							"database_id": "4cb17949-f08d-4d5c-ab50-fe6ba689d2c8"
+						},
+						"archived": false,
+						"properties": {}
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expPostBody: map[string]interface{}{
+				"archived": false,
+			},
+			expResponse: notion.Page{
+				ID:             "e4f419a7-f01f-4d5b-af58-ff4786a429fe",
+				CreatedTime:    mustParseTime(time.RFC3339Nano, "2021-05-17T17:56:00.000Z"),
+				LastEditedTime: mustParseTime(time.RFC3339Nano, "2021-05-22T16:24:23.007Z"),
+				Parent: notion.Parent{
+					Type:       notion.ParentTypeDatabase,
+					DatabaseID: "4cb17949-f08d-4d5c-ab50-fe6ba689d2c8",
+				},
+				Properties: notion.DatabasePageProperties{},
+			},
+			expError: nil,
+		},
 		{
 			name: "error response",
 			params: notion.UpdatePageParams{
@@ -2544,7 +2783,7 @@ func TestUpdatePage(t *testing.T) {
 			name:        "missing any params",
 			params:      notion.UpdatePageParams{},
 			expResponse: notion.Page{},
-			expError:    errors.New("notion: invalid page params: at least one of database page properties, title, icon or cover is required"),
+			expError:    errors.New("notion: invalid page params: at least one of database page properties, title, icon, cover or archived is required"),
 		},
 	}
 
@@ -2900,7 +3139,7 @@ func TestFindBlockChildrenById(t *testing.T) {
 								"has_children": false,
 								"type": "paragraph",
 								"paragraph": {
-									"text": [
+									"rich_text": [
 										{
 											"type": "text",
 											"text": {
@@ -2941,7 +3180,7 @@ func TestFindBlockChildrenById(t *testing.T) {
 						LastEditedTime: notion.TimePtr(mustParseTime(time.RFC3339Nano, "2021-05-14T09:15:00.000Z")),
 						Type:           notion.BlockTypeParagraph,
 						Paragraph: &notion.RichTextBlock{
-							Text: []notion.RichText{
+							RichText: []notion.RichText{
 								{
 									Type: notion.RichTextTypeText,
 									Text: &notion.Text{
@@ -2961,6 +3200,115 @@ func TestFindBlockChildrenById(t *testing.T) {
 			},
 			expError: nil,
 		},
+		{
+			name: "table blocks, successful response",
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "list",
+						"results": [
+							{
+								"object": "block",
+								"id": "ae9c9a31-1c1e-4ae2-a5ee-c539a2d43113",
+								"parent": {
+									"type": "page_id",
+									"page_id": "b0668f48-8d66-4733-9bdb-2f82215707f7"
+								},
+								"created_time": "2022-09-01T10:00:00.000Z",
+								"last_edited_time": "2022-09-01T10:00:00.000Z",
+								"has_children": true,
+								"type": "table",
+								"table": {
+									"table_width": 2,
+									"has_column_header": true,
+									"has_row_header": false
+								}
+							},
+							{
+								"object": "block",
+								"id": "53d6ec36-3a04-4a83-ae1c-4b8dc6a6a0c1",
+								"parent": {
+									"type": "block_id",
+									"block_id": "ae9c9a31-1c1e-4ae2-a5ee-c539a2d43113"
+								},
+								"created_time": "2022-09-01T10:00:00.000Z",
+								"last_edited_time": "2022-09-01T10:00:00.000Z",
+								"has_children": false,
+								"type": "table_row",
+								"table_row": {
+									"cells": [
+										[
+											{
+												"type": "text",
+												"text": {
+													"content": "Task",
+													"link": null
+												},
+												"plain_text": "Task",
+												"href": null
+											}
+										],
+										[]
+									]
+								}
+							}
+						],
+						"next_cursor": null,
+						"has_more": false
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expQueryParams: nil,
+			expResponse: notion.BlockChildrenResponse{
+				Results: []notion.Block{
+					{
+						Object: "block",
+						ID:     "ae9c9a31-1c1e-4ae2-a5ee-c539a2d43113",
+						Parent: &notion.Parent{
+							Type:   notion.ParentTypePage,
+							PageID: "b0668f48-8d66-4733-9bdb-2f82215707f7",
+						},
+						CreatedTime:    notion.TimePtr(mustParseTime(time.RFC3339Nano, "2022-09-01T10:00:00.000Z")),
+						LastEditedTime: notion.TimePtr(mustParseTime(time.RFC3339Nano, "2022-09-01T10:00:00.000Z")),
+						HasChildren:    true,
+						Type:           notion.BlockTypeTable,
+						Table: &notion.Table{
+							TableWidth:      2,
+							HasColumnHeader: true,
+						},
+					},
+					{
+						Object: "block",
+						ID:     "53d6ec36-3a04-4a83-ae1c-4b8dc6a6a0c1",
+						Parent: &notion.Parent{
+							Type:    notion.ParentTypeBlock,
+							BlockID: "ae9c9a31-1c1e-4ae2-a5ee-c539a2d43113",
+						},
+						CreatedTime:    notion.TimePtr(mustParseTime(time.RFC3339Nano, "2022-09-01T10:00:00.000Z")),
+						LastEditedTime: notion.TimePtr(mustParseTime(time.RFC3339Nano, "2022-09-01T10:00:00.000Z")),
+						Type:           notion.BlockTypeTableRow,
+						TableRow: &notion.TableRow{
+							Cells: [][]notion.RichText{
+								{
+									{
+										Type: notion.RichTextTypeText,
+										Text: &notion.Text{
+											Content: "Task",
+										},
+										PlainText: "Task",
+									},
+								},
+								{},
+							},
+						},
+					},
+				},
+				HasMore:    false,
+				NextCursor: nil,
+			},
+			expError: nil,
+		},
 		{
 			name:  "without query, successful response",
 			query: nil,
@@ -3069,7 +3417,7 @@ func TestAppendBlockChildren(t *testing.T) {
 				{
 					Type: notion.BlockTypeParagraph,
 					Paragraph: &notion.RichTextBlock{
-						Text: []notion.RichText{
+						RichText: []notion.RichText{
 							{
 								Text: &notion.Text{
 									Content: "Lorem ipsum dolor sit amet.",
@@ -3092,7 +3440,7 @@ func TestAppendBlockChildren(t *testing.T) {
 								"has_children": false,
 								"type": "paragraph",
 								"paragraph": {
-									"text": [
+									"rich_text": [
 										{
 											"type": "text",
 											"text": {
@@ -3126,7 +3474,7 @@ func TestAppendBlockChildren(t *testing.T) {
 						"object": "block",
 						"type":   "paragraph",
 						"paragraph": map[string]interface{}{
-							"text": []interface{}{
+							"rich_text": []interface{}{
 								map[string]interface{}{
 									"text": map[string]interface{}{
 										"content": "Lorem ipsum dolor sit amet.",
@@ -3146,7 +3494,7 @@ func TestAppendBlockChildren(t *testing.T) {
 						LastEditedTime: notion.TimePtr(mustParseTime(time.RFC3339Nano, "2021-05-14T09:15:00.000Z")),
 						Type:           notion.BlockTypeParagraph,
 						Paragraph: &notion.RichTextBlock{
-							Text: []notion.RichText{
+							RichText: []notion.RichText{
 								{
 									Type: notion.RichTextTypeText,
 									Text: &notion.Text{
@@ -3172,7 +3520,7 @@ func TestAppendBlockChildren(t *testing.T) {
 				{
 					Type: notion.BlockTypeParagraph,
 					Paragraph: &notion.RichTextBlock{
-						Text: []notion.RichText{
+						RichText: []notion.RichText{
 							{
 								Text: &notion.Text{
 									Content: "Lorem ipsum dolor sit amet.",
@@ -3199,7 +3547,7 @@ func TestAppendBlockChildren(t *testing.T) {
 						"object": "block",
 						"type":   "paragraph",
 						"paragraph": map[string]interface{}{
-							"text": []interface{}{
+							"rich_text": []interface{}{
 								map[string]interface{}{
 									"text": map[string]interface{}{
 										"content": "Lorem ipsum dolor sit amet.",
@@ -4041,7 +4389,7 @@ func TestUpdateBlock(t *testing.T) {
 			name: "successful response",
 			block: notion.Block{
 				Paragraph: &notion.RichTextBlock{
-					Text: []notion.RichText{
+					RichText: []notion.RichText{
 						{
 							Text: &notion.Text{
 								Content: "Foobar",
@@ -4061,7 +4409,7 @@ func TestUpdateBlock(t *testing.T) {
 						"archived": false,
 						"type": "paragraph",
 						"paragraph": {
-							"text": [
+							"rich_text": [
 								{
 									"type": "text",
 									"text": {
@@ -4088,7 +4436,7 @@ func TestUpdateBlock(t *testing.T) {
 			expPostBody: map[string]interface{}{
 				"object": "block",
 				"paragraph": map[string]interface{}{
-					"text": []interface{}{
+					"rich_text": []interface{}{
 						map[string]interface{}{
 							"text": map[string]interface{}{
 								"content": "Foobar",
@@ -4105,7 +4453,7 @@ func TestUpdateBlock(t *testing.T) {
 				LastEditedTime: mustParseTimePointer(time.RFC3339, "2021-10-02T06:31:00Z"),
 				HasChildren:    true,
 				Paragraph: &notion.RichTextBlock{
-					Text: []notion.RichText{
+					RichText: []notion.RichText{
 						{
 							Type: notion.RichTextTypeText,
 							Text: &notion.Text{
@@ -4126,7 +4474,7 @@ func TestUpdateBlock(t *testing.T) {
 			name: "error response",
 			block: notion.Block{
 				Paragraph: &notion.RichTextBlock{
-					Text: []notion.RichText{
+					RichText: []notion.RichText{
 						{
 							Text: &notion.Text{
 								Content: "Foobar",
@@ -4149,7 +4497,7 @@ func TestUpdateBlock(t *testing.T) {
 			expPostBody: map[string]interface{}{
 				"object": "block",
 				"paragraph": map[string]interface{}{
-					"text": []interface{}{
+					"rich_text": []interface{}{
 						map[string]interface{}{
 							"text": map[string]interface{}{
 								"content": "Foobar",
@@ -4241,7 +4589,7 @@ func TestDeleteBlock(t *testing.T) {
 						"archived": true,
 						"type": "paragraph",
 						"paragraph": {
-							"text": [
+							"rich_text": [
 								{
 									"type": "text",
 									"text": {
@@ -4273,7 +4621,7 @@ func TestDeleteBlock(t *testing.T) {
 				LastEditedTime: mustParseTimePointer(time.RFC3339, "2021-10-02T06:31:00Z"),
 				HasChildren:    true,
 				Paragraph: &notion.RichTextBlock{
-					Text: []notion.RichText{
+					RichText: []notion.RichText{
 						{
 							Type: notion.RichTextTypeText,
 							Text: &notion.Text{
@@ -4341,3 +4689,1072 @@ func TestDeleteBlock(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateComment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		params         notion.CreateCommentParams
+		respBody       func(r *http.Request) io.Reader
+		respStatusCode int
+		expPostBody    map[string]interface{}
+		expResponse    notion.Comment
+		expError       error
+	}{
+		{
+			name: "on page, successful response",
+			params: notion.CreateCommentParams{
+				ParentPageID: "5c6a2821-6bb1-4a7e-b6e1-c50111515c3d",
+				RichText: []notion.RichText{
+					{
+						Text: &notion.Text{
+							Content: "Hello world",
+						},
+					},
+				},
+			},
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "comment",
+						"id": "b52b8ed6-e029-4707-a671-832549c09de3",
+						"parent": {
+							"type": "page_id",
+							"page_id": "5c6a2821-6bb1-4a7e-b6e1-c50111515c3d"
+						},
+						"discussion_id": "f1407351-36f5-4c49-a13c-49f8ba11776d",
+						"created_time": "2022-07-15T20:53:00.000Z",
+						"last_edited_time": "2022-07-15T20:53:00.000Z",
+						"created_by": {
+							"object": "user",
+							"id": "067dee40-6ebd-496f-b446-093c715fb5ec"
+						},
+						"rich_text": [
+							{
+								"type": "text",
+								"text": {
+									"content": "Hello world",
+									"link": null
+								},
+								"annotations": {
+									"bold": false,
+									"italic": false,
+									"strikethrough": false,
+									"underline": false,
+									"code": false,
+									"color": "default"
+								},
+								"plain_text": "Hello world",
+								"href": null
+							}
+						]
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expPostBody: map[string]interface{}{
+				"parent": map[string]interface{}{
+					"page_id": "5c6a2821-6bb1-4a7e-b6e1-c50111515c3d",
+				},
+				"rich_text": []interface{}{
+					map[string]interface{}{
+						"text": map[string]interface{}{
+							"content": "Hello world",
+						},
+					},
+				},
+			},
+			expResponse: notion.Comment{
+				ID: "b52b8ed6-e029-4707-a671-832549c09de3",
+				Parent: notion.Parent{
+					Type:   notion.ParentTypePage,
+					PageID: "5c6a2821-6bb1-4a7e-b6e1-c50111515c3d",
+				},
+				DiscussionID:   "f1407351-36f5-4c49-a13c-49f8ba11776d",
+				CreatedTime:    mustParseTime(time.RFC3339Nano, "2022-07-15T20:53:00.000Z"),
+				LastEditedTime: mustParseTime(time.RFC3339Nano, "2022-07-15T20:53:00.000Z"),
+				CreatedBy: &notion.User{
+					ID: "067dee40-6ebd-496f-b446-093c715fb5ec",
+				},
+				RichText: []notion.RichText{
+					{
+						Type: notion.RichTextTypeText,
+						Text: &notion.Text{
+							Content: "Hello world",
+						},
+						Annotations: &notion.Annotations{
+							Color: notion.ColorDefault,
+						},
+						PlainText: "Hello world",
+					},
+				},
+			},
+			expError: nil,
+		},
+		{
+			name: "in discussion, error response",
+			params: notion.CreateCommentParams{
+				DiscussionID: "f1407351-36f5-4c49-a13c-49f8ba11776d",
+				RichText: []notion.RichText{
+					{
+						Text: &notion.Text{
+							Content: "Hello world",
+						},
+					},
+				},
+			},
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "error",
+						"status": 400,
+						"code": "validation_error",
+						"message": "foobar"
+					}`,
+				)
+			},
+			respStatusCode: http.StatusBadRequest,
+			expPostBody: map[string]interface{}{
+				"discussion_id": "f1407351-36f5-4c49-a13c-49f8ba11776d",
+				"rich_text": []interface{}{
+					map[string]interface{}{
+						"text": map[string]interface{}{
+							"content": "Hello world",
+						},
+					},
+				},
+			},
+			expResponse: notion.Comment{},
+			expError:    errors.New("notion: failed to create comment: foobar (code: validation_error, status: 400)"),
+		},
+		{
+			name: "parent page and discussion",
+			params: notion.CreateCommentParams{
+				ParentPageID: "5c6a2821-6bb1-4a7e-b6e1-c50111515c3d",
+				DiscussionID: "f1407351-36f5-4c49-a13c-49f8ba11776d",
+				RichText:     []notion.RichText{{Text: &notion.Text{Content: "Hello world"}}},
+			},
+			expResponse: notion.Comment{},
+			expError:    errors.New("notion: invalid comment params: parent page ID and discussion ID cannot both be set"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			httpClient := &http.Client{
+				Transport: &mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+					postBody := make(map[string]interface{})
+
+					err := json.NewDecoder(r.Body).Decode(&postBody)
+					if err != nil && err != io.EOF {
+						t.Fatal(err)
+					}
+
+					if len(tt.expPostBody) == 0 && len(postBody) != 0 {
+						t.Errorf("unexpected post body: %#v", postBody)
+					}
+
+					if len(tt.expPostBody) != 0 && len(postBody) == 0 {
+						t.Errorf("post body not equal (expected %+v, got: nil)", tt.expPostBody)
+					}
+
+					if len(tt.expPostBody) != 0 && len(postBody) != 0 {
+						if diff := cmp.Diff(tt.expPostBody, postBody); diff != "" {
+							t.Errorf("post body not equal (-exp, +got):\n%v", diff)
+						}
+					}
+
+					return &http.Response{
+						StatusCode: tt.respStatusCode,
+						Status:     http.StatusText(tt.respStatusCode),
+						Body:       ioutil.NopCloser(tt.respBody(r)),
+					}, nil
+				}},
+			}
+			client := notion.NewClient("secret-api-key", notion.WithHTTPClient(httpClient))
+			comment, err := client.CreateComment(context.Background(), tt.params)
+
+			if tt.expError == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expError != nil && err == nil {
+				t.Fatalf("error not equal (expected: %v, got: nil)", tt.expError)
+			}
+			if tt.expError != nil && err != nil && tt.expError.Error() != err.Error() {
+				t.Fatalf("error not equal (expected: %v, got: %v)", tt.expError, err)
+			}
+
+			if diff := cmp.Diff(tt.expResponse, comment); diff != "" {
+				t.Fatalf("response not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestListComments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		query          *notion.PaginationQuery
+		respBody       func(r *http.Request) io.Reader
+		respStatusCode int
+		expQueryParams url.Values
+		expResponse    notion.ListCommentsResponse
+		expError       error
+	}{
+		{
+			name: "with query, successful response",
+			query: &notion.PaginationQuery{
+				StartCursor: "7c6b1c95-de50-45ca-94e6-af1d9fd295ab",
+				PageSize:    42,
+			},
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "list",
+						"results": [
+							{
+								"object": "comment",
+								"id": "94cc56ab-9f02-409d-9f99-1037e9fe502f",
+								"parent": {
+									"type": "page_id",
+									"page_id": "5c6a2821-6bb1-4a7e-b6e1-c50111515c3d"
+								},
+								"discussion_id": "f1407351-36f5-4c49-a13c-49f8ba11776d",
+								"created_time": "2022-07-15T16:52:00.000Z",
+								"last_edited_time": "2022-07-15T19:16:00.000Z",
+								"created_by": {
+									"object": "user",
+									"id": "9b15170a-9941-4297-8ee6-83fa7649a87a"
+								},
+								"rich_text": [
+									{
+										"type": "text",
+										"text": {
+											"content": "Single comment",
+											"link": null
+										},
+										"plain_text": "Single comment",
+										"href": null
+									}
+								]
+							}
+						],
+						"next_cursor": "A^hd",
+						"has_more": true
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expQueryParams: url.Values{
+				"block_id":     []string{"5c6a2821-6bb1-4a7e-b6e1-c50111515c3d"},
+				"start_cursor": []string{"7c6b1c95-de50-45ca-94e6-af1d9fd295ab"},
+				"page_size":    []string{"42"},
+			},
+			expResponse: notion.ListCommentsResponse{
+				Results: []notion.Comment{
+					{
+						ID: "94cc56ab-9f02-409d-9f99-1037e9fe502f",
+						Parent: notion.Parent{
+							Type:   notion.ParentTypePage,
+							PageID: "5c6a2821-6bb1-4a7e-b6e1-c50111515c3d",
+						},
+						DiscussionID:   "f1407351-36f5-4c49-a13c-49f8ba11776d",
+						CreatedTime:    mustParseTime(time.RFC3339Nano, "2022-07-15T16:52:00.000Z"),
+						LastEditedTime: mustParseTime(time.RFC3339Nano, "2022-07-15T19:16:00.000Z"),
+						CreatedBy: &notion.User{
+							ID: "9b15170a-9941-4297-8ee6-83fa7649a87a",
+						},
+						RichText: []notion.RichText{
+							{
+								Type: notion.RichTextTypeText,
+								Text: &notion.Text{
+									Content: "Single comment",
+								},
+								PlainText: "Single comment",
+							},
+						},
+					},
+				},
+				HasMore:    true,
+				NextCursor: notion.StringPtr("A^hd"),
+			},
+			expError: nil,
+		},
+		{
+			name:  "without query, successful response",
+			query: nil,
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "list",
+						"results": [],
+						"next_cursor": null,
+						"has_more": false
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expQueryParams: url.Values{
+				"block_id": []string{"5c6a2821-6bb1-4a7e-b6e1-c50111515c3d"},
+			},
+			expResponse: notion.ListCommentsResponse{
+				Results:    []notion.Comment{},
+				HasMore:    false,
+				NextCursor: nil,
+			},
+			expError: nil,
+		},
+		{
+			name: "error response",
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "error",
+						"status": 403,
+						"code": "restricted_resource",
+						"message": "foobar"
+					}`,
+				)
+			},
+			respStatusCode: http.StatusForbidden,
+			expQueryParams: url.Values{
+				"block_id": []string{"5c6a2821-6bb1-4a7e-b6e1-c50111515c3d"},
+			},
+			expResponse: notion.ListCommentsResponse{},
+			expError:    errors.New("notion: failed to list comments: foobar (code: restricted_resource, status: 403)"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			httpClient := &http.Client{
+				Transport: &mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+					if diff := cmp.Diff(tt.expQueryParams, r.URL.Query()); diff != "" {
+						t.Errorf("query params not equal (-exp, +got):\n%v", diff)
+					}
+
+					return &http.Response{
+						StatusCode: tt.respStatusCode,
+						Status:     http.StatusText(tt.respStatusCode),
+						Body:       ioutil.NopCloser(tt.respBody(r)),
+					}, nil
+				}},
+			}
+			client := notion.NewClient("secret-api-key", notion.WithHTTPClient(httpClient))
+			resp, err := client.ListComments(context.Background(), "5c6a2821-6bb1-4a7e-b6e1-c50111515c3d", tt.query)
+
+			if tt.expError == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expError != nil && err == nil {
+				t.Fatalf("error not equal (expected: %v, got: nil)", tt.expError)
+			}
+			if tt.expError != nil && err != nil && tt.expError.Error() != err.Error() {
+				t.Fatalf("error not equal (expected: %v, got: %v)", tt.expError, err)
+			}
+
+			if diff := cmp.Diff(tt.expResponse, resp); diff != "" {
+				t.Fatalf("response not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestSendFileUpload(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		params         notion.SendFileUploadParams
+		respBody       func(r *http.Request) io.Reader
+		respStatusCode int
+		expFields      map[string]string
+		expResponse    notion.FileUpload
+		expError       error
+	}{
+		{
+			name: "part of multi-part upload, successful response",
+			params: notion.SendFileUploadParams{
+				Filename:    "notes.pdf",
+				ContentType: "application/pdf",
+				Data:        strings.NewReader("foobar"),
+				PartNumber:  2,
+			},
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "file_upload",
+						"id": "b52b8ed6-e029-4707-a671-832549c09de3",
+						"created_time": "2025-03-15T20:53:00.000Z",
+						"last_edited_time": "2025-03-15T20:57:00.000Z",
+						"expiry_time": "2025-03-15T21:53:00.000Z",
+						"upload_url": "https://api.notion.com/v1/file_uploads/b52b8ed6-e029-4707-a671-832549c09de3/send",
+						"archived": false,
+						"status": "pending",
+						"filename": "notes.pdf",
+						"content_type": "application/pdf",
+						"content_length": null,
+						"number_of_parts": {
+							"total": 3,
+							"sent": 2
+						}
+					}`,
+				)
+			},
+			respStatusCode: http.StatusOK,
+			expFields: map[string]string{
+				"part_number": "2",
+				"file":        "notes.pdf application/pdf foobar",
+			},
+			expResponse: notion.FileUpload{
+				ID:             "b52b8ed6-e029-4707-a671-832549c09de3",
+				CreatedTime:    mustParseTime(time.RFC3339Nano, "2025-03-15T20:53:00.000Z"),
+				LastEditedTime: mustParseTime(time.RFC3339Nano, "2025-03-15T20:57:00.000Z"),
+				ExpiryTime:     mustParseTimePointer(time.RFC3339Nano, "2025-03-15T21:53:00.000Z"),
+				UploadURL:      "https://api.notion.com/v1/file_uploads/b52b8ed6-e029-4707-a671-832549c09de3/send",
+				Status:         notion.FileUploadStatusPending,
+				Filename:       "notes.pdf",
+				ContentType:    "application/pdf",
+				NumberOfParts:  &notion.FileUploadParts{Total: 3, Sent: 2},
+			},
+			expError: nil,
+		},
+		{
+			name: "single-part upload, error response",
+			params: notion.SendFileUploadParams{
+				Data: strings.NewReader("foobar"),
+			},
+			respBody: func(_ *http.Request) io.Reader {
+				return strings.NewReader(
+					`{
+						"object": "error",
+						"status": 400,
+						"code": "validation_error",
+						"message": "foobar"
+					}`,
+				)
+			},
+			respStatusCode: http.StatusBadRequest,
+			expFields: map[string]string{
+				"file": "file application/octet-stream foobar",
+			},
+			expResponse: notion.FileUpload{},
+			expError:    errors.New("notion: failed to send file upload: foobar (code: validation_error, status: 400)"),
+		},
+		{
+			name:        "missing data",
+			params:      notion.SendFileUploadParams{Filename: "notes.pdf"},
+			expResponse: notion.FileUpload{},
+			expError:    errors.New("notion: invalid file upload params: data is required"),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			httpClient := &http.Client{
+				Transport: &mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+					if exp := "/v1/file_uploads/b52b8ed6-e029-4707-a671-832549c09de3/send"; r.URL.Path != exp {
+						t.Errorf("path not equal (expected: %v, got: %v)", exp, r.URL.Path)
+					}
+
+					mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+					if err != nil || mediaType != "multipart/form-data" {
+						t.Fatalf("unexpected content type: %v", r.Header.Get("Content-Type"))
+					}
+					fields := make(map[string]string)
+					mr := multipart.NewReader(r.Body, params["boundary"])
+					for {
+						part, err := mr.NextPart()
+						if err == io.EOF {
+							break
+						}
+						if err != nil {
+							t.Fatal(err)
+						}
+						data, err := ioutil.ReadAll(part)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if part.FileName() != "" {
+							fields[part.FormName()] = fmt.Sprintf("%v %v %s", part.FileName(), part.Header.Get("Content-Type"), data)
+						} else {
+							fields[part.FormName()] = string(data)
+						}
+					}
+					if diff := cmp.Diff(tt.expFields, fields); diff != "" {
+						t.Errorf("form fields not equal (-exp, +got):\n%v", diff)
+					}
+
+					return &http.Response{
+						StatusCode: tt.respStatusCode,
+						Status:     http.StatusText(tt.respStatusCode),
+						Body:       ioutil.NopCloser(tt.respBody(r)),
+					}, nil
+				}},
+			}
+			client := notion.NewClient("secret-api-key", notion.WithHTTPClient(httpClient))
+			upload, err := client.SendFileUpload(context.Background(), "b52b8ed6-e029-4707-a671-832549c09de3", tt.params)
+
+			if tt.expError == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expError != nil && err == nil {
+				t.Fatalf("error not equal (expected: %v, got: nil)", tt.expError)
+			}
+			if tt.expError != nil && err != nil && tt.expError.Error() != err.Error() {
+				t.Fatalf("error not equal (expected: %v, got: %v)", tt.expError, err)
+			}
+
+			if diff := cmp.Diff(tt.expResponse, upload); diff != "" {
+				t.Fatalf("response not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		size        int
+		expRequests []string
+	}{
+		{
+			name: "single part",
+			size: 1024,
+			expRequests: []string{
+				`POST /v1/file_uploads {"filename":"image.png","content_type":"image/png"}`,
+				"POST /v1/file_uploads/upload-id/send 1024",
+			},
+		},
+		{
+			name: "multiple parts",
+			size: 2*notion.FileUploadPartSize + 1,
+			expRequests: []string{
+				`POST /v1/file_uploads {"mode":"multi_part","filename":"image.png","content_type":"image/png","number_of_parts":3}`,
+				"POST /v1/file_uploads/upload-id/send 1:10485760",
+				"POST /v1/file_uploads/upload-id/send 2:10485760",
+				"POST /v1/file_uploads/upload-id/send 3:1",
+				"POST /v1/file_uploads/upload-id/complete",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var requests []string
+			httpClient := &http.Client{
+				Transport: &mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+					request := r.Method + " " + r.URL.Path
+					switch {
+					case strings.HasSuffix(r.URL.Path, "/send"):
+						if err := r.ParseMultipartForm(1 << 20); err != nil {
+							t.Fatal(err)
+						}
+						file, header, err := r.FormFile("file")
+						if err != nil {
+							t.Fatal(err)
+						}
+						file.Close()
+						size := strconv.FormatInt(header.Size, 10)
+						if part := r.FormValue("part_number"); part != "" {
+							size = part + ":" + size
+						}
+						request += " " + size
+					case r.Body != nil:
+						body, err := ioutil.ReadAll(r.Body)
+						if err != nil {
+							t.Fatal(err)
+						}
+						request += " " + strings.TrimSpace(string(body))
+					}
+					requests = append(requests, request)
+
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Status:     http.StatusText(http.StatusOK),
+						Body:       ioutil.NopCloser(strings.NewReader(`{"object": "file_upload", "id": "upload-id", "status": "uploaded"}`)),
+					}, nil
+				}},
+			}
+			client := notion.NewClient("secret-api-key", notion.WithHTTPClient(httpClient))
+			upload, err := client.UploadFile(context.Background(), "image.png", "", strings.NewReader(strings.Repeat("a", tt.size)), int64(tt.size))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if upload.ID != "upload-id" || upload.Status != notion.FileUploadStatusUploaded {
+				t.Fatalf("unexpected upload: %+v", upload)
+			}
+
+			if diff := cmp.Diff(tt.expRequests, requests); diff != "" {
+				t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	httpClient := &http.Client{
+		Transport: &mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+			got = append(got, "transport "+r.URL.String()+" "+r.Header.Get("User-Agent")+" "+r.Header.Get("X-Trace"))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Status:     http.StatusText(http.StatusOK),
+				Body:       ioutil.NopCloser(strings.NewReader(`{"object": "user", "id": "be32e790-8292-46df-a248-b784fdf483cf"}`)),
+			}, nil
+		}},
+	}
+	middleware := func(name string) notion.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return notion.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				got = append(got, name+" request")
+				r = r.Clone(r.Context())
+				r.Header.Set("X-Trace", r.Header.Get("X-Trace")+name)
+				res, err := next.RoundTrip(r)
+				got = append(got, fmt.Sprintf("%v response %v", name, res.StatusCode))
+				return res, err
+			})
+		}
+	}
+
+	client := notion.NewClient("secret-api-key",
+		notion.WithHTTPClient(httpClient),
+		notion.WithBaseURL("http://localhost:8080/notion/v1/"),
+		notion.WithUserAgent("notionSync/1.0"),
+		notion.WithMiddleware(middleware("a"), middleware("b")),
+	)
+	if _, err := client.FindUserByID(context.Background(), "be32e790-8292-46df-a248-b784fdf483cf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []string{
+		"a request",
+		"b request",
+		"transport http://localhost:8080/notion/v1/users/be32e790-8292-46df-a248-b784fdf483cf notionSync/1.0 ab",
+		"b response 200",
+		"a response 200",
+	}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("calls not equal (-exp, +got):\n%v", diff)
+	}
+	if _, ok := httpClient.Transport.(*mockRoundtripper); !ok {
+		t.Fatalf("http client was changed: %T", httpClient.Transport)
+	}
+}
+
+func TestAPIVersion(t *testing.T) {
+	t.Parallel()
+
+	children := []notion.Block{
+		{
+			Type: notion.BlockTypeParagraph,
+			Paragraph: &notion.RichTextBlock{
+				RichText: []notion.RichText{
+					{
+						Text: &notion.Text{
+							Content: "Lorem ipsum dolor sit amet.",
+						},
+					},
+				},
+			},
+		},
+		{
+			Type: notion.BlockTypeToDo,
+			ToDo: &notion.ToDo{
+				Checked: notion.BoolPtr(false),
+			},
+		},
+	}
+	filter := &notion.DatabaseQueryFilter{
+		Or: []notion.DatabaseQueryFilter{
+			{
+				Property: "Name",
+				Title: &notion.TextDatabaseQueryFilter{
+					Contains: "foobar",
+				},
+			},
+			{
+				Property: "Notes",
+				RichText: &notion.TextDatabaseQueryFilter{
+					Contains: "foobar",
+				},
+			},
+		},
+	}
+	legacyParagraph := `{
+		"object": "list",
+		"results": [
+			{
+				"object": "block",
+				"id": "ae9c9a31-1c1e-4ae2-a5ee-c539a2d43113",
+				"has_children": false,
+				"type": "paragraph",
+				"paragraph": {
+					"text": [
+						{
+							"type": "text",
+							"text": {
+								"content": "Lorem ipsum dolor sit amet.",
+								"link": null
+							},
+							"plain_text": "Lorem ipsum dolor sit amet.",
+							"href": null
+						}
+					]
+				}
+			}
+		],
+		"next_cursor": null,
+		"has_more": false
+	}`
+
+	tests := []struct {
+		name        string
+		opts        []notion.ClientOption
+		call        func(*notion.Client) (interface{}, error)
+		respBody    string
+		expVersion  string
+		expPostBody map[string]interface{}
+		expResponse interface{}
+	}{
+		{
+			name: "default version, rich_text blocks",
+			call: func(c *notion.Client) (interface{}, error) {
+				return c.AppendBlockChildren(context.Background(), "00000000-0000-0000-0000-000000000000", children)
+			},
+			respBody:   `{"object": "list", "results": [], "next_cursor": null, "has_more": false}`,
+			expVersion: notion.DefaultAPIVersion,
+			expPostBody: map[string]interface{}{
+				"children": []interface{}{
+					map[string]interface{}{
+						"object": "block",
+						"type":   "paragraph",
+						"paragraph": map[string]interface{}{
+							"rich_text": []interface{}{
+								map[string]interface{}{
+									"text": map[string]interface{}{
+										"content": "Lorem ipsum dolor sit amet.",
+									},
+								},
+							},
+						},
+					},
+					map[string]interface{}{
+						"object": "block",
+						"type":   "to_do",
+						"to_do": map[string]interface{}{
+							"rich_text": []interface{}{},
+							"checked":   false,
+						},
+					},
+				},
+			},
+			expResponse: notion.BlockChildrenResponse{Results: []notion.Block{}},
+		},
+		{
+			name: "default version, rich_text block body left intact",
+			call: func(c *notion.Client) (interface{}, error) {
+				return c.AppendBlockChildren(context.Background(), "00000000-0000-0000-0000-000000000000", []notion.Block{
+					{
+						Type: notion.BlockTypeCallout,
+						Callout: &notion.Callout{
+							RichTextBlock: notion.RichTextBlock{
+								RichText: []notion.RichText{
+									{
+										Type:        notion.RichTextTypeText,
+										Text:        &notion.Text{Content: "text"},
+										Annotations: &notion.Annotations{Bold: true},
+									},
+								},
+								Children: []notion.Block{
+									{
+										Type: notion.BlockTypeCode,
+										Code: &notion.Code{
+											RichTextBlock: notion.RichTextBlock{
+												RichText: []notion.RichText{{Text: &notion.Text{Content: "property"}}},
+											},
+											Caption:  []notion.RichText{{Text: &notion.Text{Content: "text"}}},
+											Language: notion.StringPtr("go"),
+										},
+									},
+								},
+							},
+							Icon: &notion.Icon{Type: notion.IconTypeEmoji, Emoji: notion.StringPtr("💡")},
+						},
+					},
+				})
+			},
+			respBody:   `{"object": "list", "results": [], "next_cursor": null, "has_more": false}`,
+			expVersion: notion.DefaultAPIVersion,
+			expPostBody: map[string]interface{}{
+				"children": []interface{}{
+					map[string]interface{}{
+						"object": "block",
+						"type":   "callout",
+						"callout": map[string]interface{}{
+							"rich_text": []interface{}{
+								map[string]interface{}{
+									"type":        "text",
+									"text":        map[string]interface{}{"content": "text"},
+									"annotations": map[string]interface{}{"bold": true},
+								},
+							},
+							"children": []interface{}{
+								map[string]interface{}{
+									"object": "block",
+									"type":   "code",
+									"code": map[string]interface{}{
+										"rich_text": []interface{}{
+											map[string]interface{}{"text": map[string]interface{}{"content": "property"}},
+										},
+										"caption": []interface{}{
+											map[string]interface{}{"text": map[string]interface{}{"content": "text"}},
+										},
+										"language": "go",
+									},
+								},
+							},
+							"icon": map[string]interface{}{"type": "emoji", "emoji": "💡"},
+						},
+					},
+				},
+			},
+			expResponse: notion.BlockChildrenResponse{Results: []notion.Block{}},
+		},
+		{
+			name: "legacy version, page properties left intact",
+			opts: []notion.ClientOption{notion.WithAPIVersion(notion.APIVersion20210816)},
+			call: func(c *notion.Client) (interface{}, error) {
+				return c.CreatePage(context.Background(), notion.CreatePageParams{
+					ParentType: notion.ParentTypeDatabase,
+					ParentID:   "00000000-0000-0000-0000-000000000000",
+					DatabasePageProperties: &notion.DatabasePageProperties{
+						"property":  {Title: []notion.RichText{{Text: &notion.Text{Content: "Title"}}}},
+						"rich_text": {RichText: []notion.RichText{{Text: &notion.Text{Content: "Notes"}}}},
+					},
+					Children: children[:1],
+				})
+			},
+			respBody: `{
+				"object": "page",
+				"id": "9b1f4c2a-5e7d-4a36-8c0b-1d2e3f4a5b6c",
+				"parent": {"type": "database_id", "database_id": "00000000-0000-0000-0000-000000000000"},
+				"properties": {}
+			}`,
+			expVersion: notion.APIVersion20210816,
+			expPostBody: map[string]interface{}{
+				"parent": map[string]interface{}{"database_id": "00000000-0000-0000-0000-000000000000"},
+				"properties": map[string]interface{}{
+					"property": map[string]interface{}{
+						"title": []interface{}{
+							map[string]interface{}{"text": map[string]interface{}{"content": "Title"}},
+						},
+					},
+					"rich_text": map[string]interface{}{
+						"rich_text": []interface{}{
+							map[string]interface{}{"text": map[string]interface{}{"content": "Notes"}},
+						},
+					},
+				},
+				"children": []interface{}{
+					map[string]interface{}{
+						"object": "block",
+						"type":   "paragraph",
+						"paragraph": map[string]interface{}{
+							"text": []interface{}{
+								map[string]interface{}{
+									"text": map[string]interface{}{
+										"content": "Lorem ipsum dolor sit amet.",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expResponse: notion.Page{
+				ID:         "9b1f4c2a-5e7d-4a36-8c0b-1d2e3f4a5b6c",
+				Parent:     notion.Parent{Type: notion.ParentTypeDatabase, DatabaseID: "00000000-0000-0000-0000-000000000000"},
+				Properties: notion.DatabasePageProperties{},
+			},
+		},
+		{
+			name: "legacy version, text blocks",
+			opts: []notion.ClientOption{notion.WithAPIVersion(notion.APIVersion20210816)},
+			call: func(c *notion.Client) (interface{}, error) {
+				return c.AppendBlockChildren(context.Background(), "00000000-0000-0000-0000-000000000000", children)
+			},
+			respBody:   legacyParagraph,
+			expVersion: notion.APIVersion20210816,
+			expPostBody: map[string]interface{}{
+				"children": []interface{}{
+					map[string]interface{}{
+						"object": "block",
+						"type":   "paragraph",
+						"paragraph": map[string]interface{}{
+							"text": []interface{}{
+								map[string]interface{}{
+									"text": map[string]interface{}{
+										"content": "Lorem ipsum dolor sit amet.",
+									},
+								},
+							},
+						},
+					},
+					map[string]interface{}{
+						"object": "block",
+						"type":   "to_do",
+						"to_do": map[string]interface{}{
+							"text":    []interface{}{},
+							"checked": false,
+						},
+					},
+				},
+			},
+			expResponse: notion.BlockChildrenResponse{
+				Results: []notion.Block{
+					{
+						Object: "block",
+						ID:     "ae9c9a31-1c1e-4ae2-a5ee-c539a2d43113",
+						Type:   notion.BlockTypeParagraph,
+						Paragraph: &notion.RichTextBlock{
+							RichText: []notion.RichText{
+								{
+									Type: notion.RichTextTypeText,
+									Text: &notion.Text{
+										Content: "Lorem ipsum dolor sit amet.",
+									},
+									PlainText: "Lorem ipsum dolor sit amet.",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "default version, rich_text filter",
+			call: func(c *notion.Client) (interface{}, error) {
+				return c.QueryDatabase(context.Background(), "00000000-0000-0000-0000-000000000000", &notion.DatabaseQuery{Filter: filter})
+			},
+			respBody:   `{"object": "list", "results": [], "next_cursor": null, "has_more": false}`,
+			expVersion: notion.DefaultAPIVersion,
+			expPostBody: map[string]interface{}{
+				"filter": map[string]interface{}{
+					"or": []interface{}{
+						map[string]interface{}{
+							"property": "Name",
+							"title":    map[string]interface{}{"contains": "foobar"},
+						},
+						map[string]interface{}{
+							"property":  "Notes",
+							"rich_text": map[string]interface{}{"contains": "foobar"},
+						},
+					},
+				},
+			},
+			expResponse: notion.DatabaseQueryResponse{Results: []notion.Page{}},
+		},
+		{
+			name: "legacy version, text filter",
+			opts: []notion.ClientOption{notion.WithAPIVersion(notion.APIVersion20210816)},
+			call: func(c *notion.Client) (interface{}, error) {
+				return c.QueryDatabase(context.Background(), "00000000-0000-0000-0000-000000000000", &notion.DatabaseQuery{Filter: filter})
+			},
+			respBody:   `{"object": "list", "results": [], "next_cursor": null, "has_more": false}`,
+			expVersion: notion.APIVersion20210816,
+			expPostBody: map[string]interface{}{
+				"filter": map[string]interface{}{
+					"or": []interface{}{
+						map[string]interface{}{
+							"property": "Name",
+							"title":    map[string]interface{}{"contains": "foobar"},
+						},
+						map[string]interface{}{
+							"property": "Notes",
+							"text":     map[string]interface{}{"contains": "foobar"},
+						},
+					},
+				},
+			},
+			expResponse: notion.DatabaseQueryResponse{Results: []notion.Page{}},
+		},
+		{
+			name: "legacy version, text formula filter",
+			opts: []notion.ClientOption{notion.WithAPIVersion(notion.APIVersion20210816)},
+			call: func(c *notion.Client) (interface{}, error) {
+				return c.QueryDatabase(context.Background(), "00000000-0000-0000-0000-000000000000", &notion.DatabaseQuery{
+					Filter: notion.Prop("Summary").Formula().String().Contains("foobar").Query(),
+				})
+			},
+			respBody:   `{"object": "list", "results": [], "next_cursor": null, "has_more": false}`,
+			expVersion: notion.APIVersion20210816,
+			expPostBody: map[string]interface{}{
+				"filter": map[string]interface{}{
+					"property": "Summary",
+					"formula": map[string]interface{}{
+						"text": map[string]interface{}{"contains": "foobar"},
+					},
+				},
+			},
+			expResponse: notion.DatabaseQueryResponse{Results: []notion.Page{}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			httpClient := &http.Client{
+				Transport: &mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+					if got := r.Header.Get("Notion-Version"); got != tt.expVersion {
+						t.Errorf("version not equal (expected: %v, got: %v)", tt.expVersion, got)
+					}
+
+					postBody := make(map[string]interface{})
+					if err := json.NewDecoder(r.Body).Decode(&postBody); err != nil {
+						t.Fatal(err)
+					}
+					if diff := cmp.Diff(tt.expPostBody, postBody); diff != "" {
+						t.Errorf("post body not equal (-exp, +got):\n%v", diff)
+					}
+
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Status:     http.StatusText(http.StatusOK),
+						Body:       ioutil.NopCloser(strings.NewReader(tt.respBody)),
+					}, nil
+				}},
+			}
+			client := notion.NewClient("secret-api-key", append(tt.opts, notion.WithHTTPClient(httpClient))...)
+			resp, err := tt.call(client)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expResponse, resp); diff != "" {
+				t.Fatalf("response not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Comment is a comment on a page or a block, in a discussion thread.
+// See: https://developers.notion.com/reference/comment-object
+type Comment struct {
+	ID             string     `json:"id"`
+	Parent         Parent     `json:"parent"`
+	DiscussionID   string     `json:"discussion_id"`
+	RichText       []RichText `json:"rich_text"`
+	CreatedTime    time.Time  `json:"created_time"`
+	LastEditedTime time.Time  `json:"last_edited_time"`
+	CreatedBy      *User      `json:"created_by,omitempty"`
+}
+
+// CreateCommentParams are the params used for creating a comment, either on a
+// page, which starts a new discussion, or in an existing discussion.
+type CreateCommentParams struct {
+	// Either ParentPageID or DiscussionID must be set.
+	ParentPageID string
+	DiscussionID string
+
+	RichText []RichText
+}
+
+// Validate validates params for creating a comment.
+func (p CreateCommentParams) Validate() error {
+	if p.ParentPageID == "" && p.DiscussionID == "" {
+		return errors.New("either parent page ID or discussion ID is required")
+	}
+	if p.ParentPageID != "" && p.DiscussionID != "" {
+		return errors.New("parent page ID and discussion ID cannot both be set")
+	}
+	if len(p.RichText) == 0 {
+		return errors.New("rich text is required")
+	}
+
+	return nil
+}
+
+func (p CreateCommentParams) MarshalJSON() ([]byte, error) {
+	type CreateCommentParamsDTO struct {
+		Parent       *Parent    `json:"parent,omitempty"`
+		DiscussionID string     `json:"discussion_id,omitempty"`
+		RichText     []RichText `json:"rich_text"`
+	}
+
+	dto := CreateCommentParamsDTO{
+		DiscussionID: p.DiscussionID,
+		RichText:     p.RichText,
+	}
+	if p.ParentPageID != "" {
+		dto.Parent = &Parent{PageID: p.ParentPageID}
+	}
+
+	return json.Marshal(dto)
+}
+
+// ListCommentsResponse contains results (comments) and pagination data
+// returned from a list request.
+type ListCommentsResponse struct {
+	Results    []Comment `json:"results"`
+	HasMore    bool      `json:"has_more"`
+	NextCursor *string   `json:"next_cursor"`
+}
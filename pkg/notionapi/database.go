@@ -14,10 +14,16 @@ type Database struct {
 	LastEditedTime time.Time          `json:"last_edited_time"`
 	URL            string             `json:"url"`
 	Title          []RichText         `json:"title"`
+	Description    []RichText         `json:"description,omitempty"`
 	Properties     DatabaseProperties `json:"properties"`
 	Parent         Parent             `json:"parent"`
 	Icon           *Icon              `json:"icon,omitempty"`
 	Cover          *Cover             `json:"cover,omitempty"`
+	CreatedBy      *User              `json:"created_by,omitempty"`
+	LastEditedBy   *User              `json:"last_edited_by,omitempty"`
+	IsInline       bool               `json:"is_inline,omitempty"`
+	Archived       bool               `json:"archived,omitempty"`
+	PublicURL      *string            `json:"public_url,omitempty"`
 }
 
 // DatabaseProperties is a mapping of properties defined on a database.
@@ -32,6 +38,13 @@ type (
 	SelectMetadata struct {
 		Options []SelectOptions `json:"options"`
 	}
+	StatusMetadata struct {
+		Options []SelectOptions `json:"options"`
+		Groups  []StatusGroup   `json:"groups"`
+	}
+	UniqueIDMetadata struct {
+		Prefix *string `json:"prefix"`
+	}
 	FormulaMetadata struct {
 		Expression string `json:"expression"`
 	}
@@ -74,6 +87,15 @@ type SelectOptions struct {
 	Color Color  `json:"color,omitempty"`
 }
 
+// StatusGroup sorts the options of a status property into To-do, In
+// progress and Complete.
+type StatusGroup struct {
+	ID        string   `json:"id,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Color     Color    `json:"color,omitempty"`
+	OptionIDs []string `json:"option_ids,omitempty"`
+}
+
 type FormulaResult struct {
 	Type FormulaResultType `json:"type"`
 
@@ -103,8 +125,9 @@ type File struct {
 	Name string   `json:"name"`
 	Type FileType `json:"type"`
 
-	File     *FileFile     `json:"file,omitempty"`
-	External *FileExternal `json:"external,omitempty"`
+	File       *FileFile            `json:"file,omitempty"`
+	External   *FileExternal        `json:"external,omitempty"`
+	FileUpload *FileUploadReference `json:"file_upload,omitempty"`
 }
 
 type DatabaseProperty struct {
@@ -132,6 +155,10 @@ type DatabaseProperty struct {
 	Formula     *FormulaMetadata  `json:"formula,omitempty"`
 	Relation    *RelationMetadata `json:"relation,omitempty"`
 	Rollup      *RollupMetadata   `json:"rollup,omitempty"`
+
+	Status       *StatusMetadata   `json:"status,omitempty"`
+	UniqueID     *UniqueIDMetadata `json:"unique_id,omitempty"`
+	Verification *EmptyMetadata    `json:"verification,omitempty"`
 }
 
 // DatabaseQuery is used for quering a database.
@@ -149,20 +176,41 @@ type DatabaseQueryResponse struct {
 	NextCursor *string `json:"next_cursor"`
 }
 
-// DatabaseQueryFilter is used to filter database contents.
-// See: https://developers.notion.com/reference/post-database-query#post-database-query-filter
+// DatabaseQueryFilter is a property filter, a timestamp filter or a compound
+// filter. A property filter names the property and sets the condition for
+// its type, a timestamp filter sets Timestamp and the condition of the same
+// name, and a compound filter sets either Or or And. Filters are most easily
+// built with Prop and Timestamp, and checked with Validate.
+// See: https://developers.notion.com/reference/post-database-query-filter
 type DatabaseQueryFilter struct {
-	Property string `json:"property,omitempty"`
-
-	Text        *TextDatabaseQueryFilter        `json:"text,omitempty"`
-	Number      *NumberDatabaseQueryFilter      `json:"number,omitempty"`
-	Checkbox    *CheckboxDatabaseQueryFilter    `json:"checkbox,omitempty"`
-	Select      *SelectDatabaseQueryFilter      `json:"select,omitempty"`
-	MultiSelect *MultiSelectDatabaseQueryFilter `json:"multi_select,omitempty"`
-	Date        *DateDatabaseQueryFilter        `json:"date,omitempty"`
-	People      *PeopleDatabaseQueryFilter      `json:"people,omitempty"`
-	Files       *FilesDatabaseQueryFilter       `json:"files,omitempty"`
-	Relation    *RelationDatabaseQueryFilter    `json:"relation,omitempty"`
+	Property  string        `json:"property,omitempty"`
+	Timestamp TimestampType `json:"timestamp,omitempty"`
+
+	Title          *TextDatabaseQueryFilter        `json:"title,omitempty"`
+	RichText       *TextDatabaseQueryFilter        `json:"rich_text,omitempty"`
+	URL            *TextDatabaseQueryFilter        `json:"url,omitempty"`
+	Email          *TextDatabaseQueryFilter        `json:"email,omitempty"`
+	PhoneNumber    *TextDatabaseQueryFilter        `json:"phone_number,omitempty"`
+	Number         *NumberDatabaseQueryFilter      `json:"number,omitempty"`
+	Checkbox       *CheckboxDatabaseQueryFilter    `json:"checkbox,omitempty"`
+	Select         *SelectDatabaseQueryFilter      `json:"select,omitempty"`
+	MultiSelect    *MultiSelectDatabaseQueryFilter `json:"multi_select,omitempty"`
+	Status         *StatusDatabaseQueryFilter      `json:"status,omitempty"`
+	Date           *DateDatabaseQueryFilter        `json:"date,omitempty"`
+	CreatedTime    *DateDatabaseQueryFilter        `json:"created_time,omitempty"`
+	LastEditedTime *DateDatabaseQueryFilter        `json:"last_edited_time,omitempty"`
+	People         *PeopleDatabaseQueryFilter      `json:"people,omitempty"`
+	CreatedBy      *PeopleDatabaseQueryFilter      `json:"created_by,omitempty"`
+	LastEditedBy   *PeopleDatabaseQueryFilter      `json:"last_edited_by,omitempty"`
+	Files          *FilesDatabaseQueryFilter       `json:"files,omitempty"`
+	Relation       *RelationDatabaseQueryFilter    `json:"relation,omitempty"`
+	Formula        *FormulaDatabaseQueryFilter     `json:"formula,omitempty"`
+	Rollup         *RollupDatabaseQueryFilter      `json:"rollup,omitempty"`
+	UniqueID       *NumberDatabaseQueryFilter      `json:"unique_id,omitempty"`
+
+	// Deprecated: Text is the key of RichText before API version 2022-02-22.
+	// Requests use the key of the version of the client for either field.
+	Text *TextDatabaseQueryFilter `json:"text,omitempty"`
 
 	Or  []DatabaseQueryFilter `json:"or,omitempty"`
 	And []DatabaseQueryFilter `json:"and,omitempty"`
@@ -180,14 +228,14 @@ type TextDatabaseQueryFilter struct {
 }
 
 type NumberDatabaseQueryFilter struct {
-	Equals               *int `json:"equals,omitempty"`
-	DoesNotEqual         *int `json:"does_not_equal,omitempty"`
-	GreaterThan          *int `json:"greater_than,omitempty"`
-	LessThan             *int `json:"less_than,omitempty"`
-	GreaterThanOrEqualTo *int `json:"greater_than_or_equal_to,omitempty"`
-	LessThanOrEqualTo    *int `json:"less_than_or_equal_to,omitempty"`
-	IsEmpty              bool `json:"is_empty,omitempty"`
-	IsNotEmpty           bool `json:"is_not_empty,omitempty"`
+	Equals               *float64 `json:"equals,omitempty"`
+	DoesNotEqual         *float64 `json:"does_not_equal,omitempty"`
+	GreaterThan          *float64 `json:"greater_than,omitempty"`
+	LessThan             *float64 `json:"less_than,omitempty"`
+	GreaterThanOrEqualTo *float64 `json:"greater_than_or_equal_to,omitempty"`
+	LessThanOrEqualTo    *float64 `json:"less_than_or_equal_to,omitempty"`
+	IsEmpty              bool     `json:"is_empty,omitempty"`
+	IsNotEmpty           bool     `json:"is_not_empty,omitempty"`
 }
 
 type CheckboxDatabaseQueryFilter struct {
@@ -202,6 +250,13 @@ type SelectDatabaseQueryFilter struct {
 	IsNotEmpty   bool   `json:"is_not_empty,omitempty"`
 }
 
+type StatusDatabaseQueryFilter struct {
+	Equals       string `json:"equals,omitempty"`
+	DoesNotEqual string `json:"does_not_equal,omitempty"`
+	IsEmpty      bool   `json:"is_empty,omitempty"`
+	IsNotEmpty   bool   `json:"is_not_empty,omitempty"`
+}
+
 type MultiSelectDatabaseQueryFilter struct {
 	Contains       string `json:"contains,omitempty"`
 	DoesNotContain string `json:"does_not_contain,omitempty"`
@@ -223,6 +278,7 @@ type DateDatabaseQueryFilter struct {
 	NextWeek   *struct{}  `json:"next_week,omitempty"`
 	NextMonth  *struct{}  `json:"next_month,omitempty"`
 	NextYear   *struct{}  `json:"next_year,omitempty"`
+	ThisWeek   *struct{}  `json:"this_week,omitempty"`
 }
 
 type PeopleDatabaseQueryFilter struct {
@@ -244,11 +300,28 @@ type RelationDatabaseQueryFilter struct {
 	IsNotEmpty     bool   `json:"is_not_empty,omitempty"`
 }
 
+// FormulaDatabaseQueryFilter filters on the result of a formula, with the
+// condition for the type of the result.
 type FormulaDatabaseQueryFilter struct {
-	Text     TextDatabaseQueryFilter     `json:"text,omitempty"`
-	Checkbox CheckboxDatabaseQueryFilter `json:"checkbox,omitempty"`
-	Number   NumberDatabaseQueryFilter   `json:"number,omitempty"`
-	Date     DateDatabaseQueryFilter     `json:"date,omitempty"`
+	String   *TextDatabaseQueryFilter     `json:"string,omitempty"`
+	Checkbox *CheckboxDatabaseQueryFilter `json:"checkbox,omitempty"`
+	Number   *NumberDatabaseQueryFilter   `json:"number,omitempty"`
+	Date     *DateDatabaseQueryFilter     `json:"date,omitempty"`
+
+	// Deprecated: Text is the key of String before API version 2022-02-22.
+	// Requests use the key of the version of the client for either field.
+	Text *TextDatabaseQueryFilter `json:"text,omitempty"`
+}
+
+// RollupDatabaseQueryFilter filters on the result of a rollup. Any, Every
+// and None apply a filter without property to the items of an array rollup,
+// Number and Date to the result of a rollup with a function.
+type RollupDatabaseQueryFilter struct {
+	Any    *DatabaseQueryFilter       `json:"any,omitempty"`
+	Every  *DatabaseQueryFilter       `json:"every,omitempty"`
+	None   *DatabaseQueryFilter       `json:"none,omitempty"`
+	Number *NumberDatabaseQueryFilter `json:"number,omitempty"`
+	Date   *DateDatabaseQueryFilter   `json:"date,omitempty"`
 }
 
 type DatabaseQuerySort struct {
@@ -273,6 +346,7 @@ type (
 	RollupResultType     string
 	SortTimestamp        string
 	SortDirection        string
+	TimestampType        string
 )
 
 const (
@@ -296,6 +370,9 @@ const (
 	DBPropTypeCreatedBy      DatabasePropertyType = "created_by"
 	DBPropTypeLastEditedTime DatabasePropertyType = "last_edited_time"
 	DBPropTypeLastEditedBy   DatabasePropertyType = "last_edited_by"
+	DBPropTypeStatus         DatabasePropertyType = "status"
+	DBPropTypeUniqueID       DatabasePropertyType = "unique_id"
+	DBPropTypeVerification   DatabasePropertyType = "verification"
 
 	// Number format enums.
 	NumberFormatNumber           NumberFormat = "number"
@@ -348,6 +425,10 @@ const (
 	// Sort direction enums.
 	SortDirAsc  SortDirection = "ascending"
 	SortDirDesc SortDirection = "descending"
+
+	// Timestamp filter enums.
+	TimestampCreatedTime    TimestampType = "created_time"
+	TimestampLastEditedTime TimestampType = "last_edited_time"
 )
 
 // Metadata returns the underlying property metadata, based on its `type` field.
@@ -14,4 +14,7 @@ type FileType string
 const (
 	FileTypeFile     FileType = "file"
 	FileTypeExternal FileType = "external"
+	// FileTypeFileUpload refers to a file uploaded with the file upload API.
+	// It is only used when writing; Notion returns such files as FileTypeFile.
+	FileTypeFileUpload FileType = "file_upload"
 )
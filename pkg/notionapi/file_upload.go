@@ -0,0 +1,161 @@
+package notionapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of file uploads. Files up to MaxSinglePartSize are sent in a single
+// part; larger files in parts of FileUploadPartSize, the last one smaller.
+const (
+	MaxSinglePartSize  = 20 << 20
+	FileUploadPartSize = 10 << 20
+	maxFileUploadParts = 1000
+)
+
+type FileUploadMode string
+
+const (
+	FileUploadModeSinglePart FileUploadMode = "single_part"
+	FileUploadModeMultiPart  FileUploadMode = "multi_part"
+)
+
+type FileUploadStatus string
+
+const (
+	FileUploadStatusPending  FileUploadStatus = "pending"
+	FileUploadStatusUploaded FileUploadStatus = "uploaded"
+	FileUploadStatusExpired  FileUploadStatus = "expired"
+	FileUploadStatusFailed   FileUploadStatus = "failed"
+)
+
+// FileUpload is a file being uploaded to Notion. Once uploaded, it can be
+// attached to a file block or a files property by its ID, through
+// FileUploadReference, until it expires.
+// See: https://developers.notion.com/reference/file-upload
+type FileUpload struct {
+	ID             string           `json:"id"`
+	CreatedTime    time.Time        `json:"created_time"`
+	LastEditedTime time.Time        `json:"last_edited_time"`
+	ExpiryTime     *time.Time       `json:"expiry_time"`
+	UploadURL      string           `json:"upload_url,omitempty"`
+	Archived       bool             `json:"archived"`
+	Status         FileUploadStatus `json:"status"`
+	Filename       string           `json:"filename"`
+	ContentType    string           `json:"content_type"`
+	ContentLength  *int64           `json:"content_length"`
+	NumberOfParts  *FileUploadParts `json:"number_of_parts,omitempty"`
+}
+
+// FileUploadParts counts the parts of a multi-part upload.
+type FileUploadParts struct {
+	Total int `json:"total"`
+	Sent  int `json:"sent"`
+}
+
+// FileUploadReference refers to an uploaded file from a block or a files
+// property, with type FileTypeFileUpload.
+type FileUploadReference struct {
+	ID string `json:"id"`
+}
+
+// CreateFileUploadParams are the params used for starting a file upload.
+type CreateFileUploadParams struct {
+	// Mode defaults to single part.
+	Mode FileUploadMode `json:"mode,omitempty"`
+	// Filename is required for multi-part uploads.
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	// NumberOfParts is required for multi-part uploads.
+	NumberOfParts int `json:"number_of_parts,omitempty"`
+}
+
+// Validate validates params for starting a file upload.
+func (p CreateFileUploadParams) Validate() error {
+	switch p.Mode {
+	case "", FileUploadModeSinglePart:
+		if p.NumberOfParts != 0 {
+			return errors.New("number of parts is only allowed for multi-part uploads")
+		}
+	case FileUploadModeMultiPart:
+		if p.Filename == "" {
+			return errors.New("filename is required for multi-part uploads")
+		}
+		if p.NumberOfParts < 1 || p.NumberOfParts > maxFileUploadParts {
+			return fmt.Errorf("number of parts must be between 1 and %v", maxFileUploadParts)
+		}
+	default:
+		return fmt.Errorf("unsupported mode %q", p.Mode)
+	}
+
+	return nil
+}
+
+// SendFileUploadParams are the contents of a file, or of a part of it, sent
+// to a file upload.
+type SendFileUploadParams struct {
+	Filename    string
+	ContentType string
+	Data        io.Reader
+	// PartNumber is the 1-based number of the part of a multi-part upload,
+	// and zero for single-part uploads.
+	PartNumber int
+}
+
+// Validate validates params for sending a file upload.
+func (p SendFileUploadParams) Validate() error {
+	if p.Data == nil {
+		return errors.New("data is required")
+	}
+	if p.PartNumber < 0 || p.PartNumber > maxFileUploadParts {
+		return fmt.Errorf("part number must be between 0 and %v", maxFileUploadParts)
+	}
+
+	return nil
+}
+
+// encode returns the multipart/form-data body of params, with its content
+// type.
+func (p SendFileUploadParams) encode() (*bytes.Buffer, string, error) {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	if p.PartNumber > 0 {
+		if err := w.WriteField("part_number", strconv.Itoa(p.PartNumber)); err != nil {
+			return nil, "", err
+		}
+	}
+
+	filename := p.Filename
+	if filename == "" {
+		filename = "file"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%v"`, quoteEscaper.Replace(filename)))
+	if p.ContentType != "" {
+		header.Set("Content-Type", p.ContentType)
+	} else {
+		header.Set("Content-Type", "application/octet-stream")
+	}
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := io.Copy(part, p.Data); err != nil {
+		return nil, "", err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return body, w.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
@@ -0,0 +1,656 @@
+package notionapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Prop starts a filter on the database property name, e.g.
+//
+//	Prop("Done").Checkbox().Equals(false).And(Prop("Task").Title().Equals(title))
+//
+// The condition method must match the type of the property, which Validate
+// checks against the schema of the database.
+func Prop(name string) PropertyFilter {
+	return PropertyFilter{name: name}
+}
+
+// RollupItem starts a condition on the items of an array rollup, for
+// RollupCondition.Any, Every and None.
+func RollupItem() PropertyFilter {
+	return PropertyFilter{}
+}
+
+// Timestamp starts a filter on the created_time or last_edited_time of pages,
+// which needs no property of that type.
+func Timestamp(timestamp TimestampType) DateCondition {
+	return DateCondition{filter: func(c DateDatabaseQueryFilter) DatabaseQueryFilter {
+		f := DatabaseQueryFilter{Timestamp: timestamp}
+		if timestamp == TimestampLastEditedTime {
+			f.LastEditedTime = &c
+		} else {
+			f.CreatedTime = &c
+		}
+		return f
+	}}
+}
+
+// PropertyFilter selects the condition of a property filter by property type.
+type PropertyFilter struct {
+	name string
+}
+
+func (p PropertyFilter) Title() TextCondition {
+	return TextCondition{filter: func(c TextDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, Title: &c}
+	}}
+}
+
+func (p PropertyFilter) RichText() TextCondition {
+	return TextCondition{filter: func(c TextDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, RichText: &c}
+	}}
+}
+
+func (p PropertyFilter) URL() TextCondition {
+	return TextCondition{filter: func(c TextDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, URL: &c}
+	}}
+}
+
+func (p PropertyFilter) Email() TextCondition {
+	return TextCondition{filter: func(c TextDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, Email: &c}
+	}}
+}
+
+func (p PropertyFilter) PhoneNumber() TextCondition {
+	return TextCondition{filter: func(c TextDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, PhoneNumber: &c}
+	}}
+}
+
+func (p PropertyFilter) Number() NumberCondition {
+	return NumberCondition{filter: func(c NumberDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, Number: &c}
+	}}
+}
+
+// UniqueID filters on the number of a unique ID, without its prefix.
+func (p PropertyFilter) UniqueID() NumberCondition {
+	return NumberCondition{filter: func(c NumberDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, UniqueID: &c}
+	}}
+}
+
+func (p PropertyFilter) Checkbox() CheckboxCondition {
+	return CheckboxCondition{filter: func(c CheckboxDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, Checkbox: &c}
+	}}
+}
+
+func (p PropertyFilter) Select() OptionCondition {
+	return OptionCondition{filter: func(c SelectDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, Select: &c}
+	}}
+}
+
+func (p PropertyFilter) Status() OptionCondition {
+	return OptionCondition{filter: func(c SelectDatabaseQueryFilter) DatabaseQueryFilter {
+		status := StatusDatabaseQueryFilter(c)
+		return DatabaseQueryFilter{Property: p.name, Status: &status}
+	}}
+}
+
+func (p PropertyFilter) MultiSelect() ContainsCondition {
+	return ContainsCondition{filter: func(c containsFilter) DatabaseQueryFilter {
+		multiSelect := MultiSelectDatabaseQueryFilter(c)
+		return DatabaseQueryFilter{Property: p.name, MultiSelect: &multiSelect}
+	}}
+}
+
+// People filters on the IDs of the users of a people property.
+func (p PropertyFilter) People() ContainsCondition {
+	return ContainsCondition{filter: func(c containsFilter) DatabaseQueryFilter {
+		people := PeopleDatabaseQueryFilter(c)
+		return DatabaseQueryFilter{Property: p.name, People: &people}
+	}}
+}
+
+func (p PropertyFilter) CreatedBy() ContainsCondition {
+	return ContainsCondition{filter: func(c containsFilter) DatabaseQueryFilter {
+		people := PeopleDatabaseQueryFilter(c)
+		return DatabaseQueryFilter{Property: p.name, CreatedBy: &people}
+	}}
+}
+
+func (p PropertyFilter) LastEditedBy() ContainsCondition {
+	return ContainsCondition{filter: func(c containsFilter) DatabaseQueryFilter {
+		people := PeopleDatabaseQueryFilter(c)
+		return DatabaseQueryFilter{Property: p.name, LastEditedBy: &people}
+	}}
+}
+
+// Relation filters on the IDs of the related pages.
+func (p PropertyFilter) Relation() ContainsCondition {
+	return ContainsCondition{filter: func(c containsFilter) DatabaseQueryFilter {
+		relation := RelationDatabaseQueryFilter(c)
+		return DatabaseQueryFilter{Property: p.name, Relation: &relation}
+	}}
+}
+
+func (p PropertyFilter) Date() DateCondition {
+	return DateCondition{filter: func(c DateDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, Date: &c}
+	}}
+}
+
+func (p PropertyFilter) CreatedTime() DateCondition {
+	return DateCondition{filter: func(c DateDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, CreatedTime: &c}
+	}}
+}
+
+func (p PropertyFilter) LastEditedTime() DateCondition {
+	return DateCondition{filter: func(c DateDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, LastEditedTime: &c}
+	}}
+}
+
+func (p PropertyFilter) Files() EmptyCondition {
+	return EmptyCondition{filter: func(isEmpty bool) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: p.name, Files: &FilesDatabaseQueryFilter{IsEmpty: isEmpty, IsNotEmpty: !isEmpty}}
+	}}
+}
+
+// Formula selects the condition on the result of a formula by result type.
+func (p PropertyFilter) Formula() FormulaCondition {
+	return FormulaCondition{name: p.name}
+}
+
+// Rollup selects the condition on the result of a rollup.
+func (p PropertyFilter) Rollup() RollupCondition {
+	return RollupCondition{name: p.name}
+}
+
+// FormulaCondition selects the condition of a formula filter by the type of
+// the result of the formula.
+type FormulaCondition struct {
+	name string
+}
+
+func (f FormulaCondition) String() TextCondition {
+	return TextCondition{filter: func(c TextDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: f.name, Formula: &FormulaDatabaseQueryFilter{String: &c}}
+	}}
+}
+
+func (f FormulaCondition) Checkbox() CheckboxCondition {
+	return CheckboxCondition{filter: func(c CheckboxDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: f.name, Formula: &FormulaDatabaseQueryFilter{Checkbox: &c}}
+	}}
+}
+
+func (f FormulaCondition) Number() NumberCondition {
+	return NumberCondition{filter: func(c NumberDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: f.name, Formula: &FormulaDatabaseQueryFilter{Number: &c}}
+	}}
+}
+
+func (f FormulaCondition) Date() DateCondition {
+	return DateCondition{filter: func(c DateDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: f.name, Formula: &FormulaDatabaseQueryFilter{Date: &c}}
+	}}
+}
+
+// RollupCondition sets the condition of a rollup filter.
+type RollupCondition struct {
+	name string
+}
+
+// Any matches when an item of the rollup matches item, built with RollupItem.
+func (r RollupCondition) Any(item Filter) Filter {
+	return Filter{DatabaseQueryFilter{Property: r.name, Rollup: &RollupDatabaseQueryFilter{Any: &item.DatabaseQueryFilter}}}
+}
+
+// Every matches when all items of the rollup match item.
+func (r RollupCondition) Every(item Filter) Filter {
+	return Filter{DatabaseQueryFilter{Property: r.name, Rollup: &RollupDatabaseQueryFilter{Every: &item.DatabaseQueryFilter}}}
+}
+
+// None matches when no item of the rollup matches item.
+func (r RollupCondition) None(item Filter) Filter {
+	return Filter{DatabaseQueryFilter{Property: r.name, Rollup: &RollupDatabaseQueryFilter{None: &item.DatabaseQueryFilter}}}
+}
+
+func (r RollupCondition) Number() NumberCondition {
+	return NumberCondition{filter: func(c NumberDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: r.name, Rollup: &RollupDatabaseQueryFilter{Number: &c}}
+	}}
+}
+
+func (r RollupCondition) Date() DateCondition {
+	return DateCondition{filter: func(c DateDatabaseQueryFilter) DatabaseQueryFilter {
+		return DatabaseQueryFilter{Property: r.name, Rollup: &RollupDatabaseQueryFilter{Date: &c}}
+	}}
+}
+
+// TextCondition builds a condition on text.
+type TextCondition struct {
+	filter func(TextDatabaseQueryFilter) DatabaseQueryFilter
+}
+
+func (c TextCondition) Equals(s string) Filter {
+	return Filter{c.filter(TextDatabaseQueryFilter{Equals: s})}
+}
+
+func (c TextCondition) DoesNotEqual(s string) Filter {
+	return Filter{c.filter(TextDatabaseQueryFilter{DoesNotEqual: s})}
+}
+
+func (c TextCondition) Contains(s string) Filter {
+	return Filter{c.filter(TextDatabaseQueryFilter{Contains: s})}
+}
+
+func (c TextCondition) DoesNotContain(s string) Filter {
+	return Filter{c.filter(TextDatabaseQueryFilter{DoesNotContain: s})}
+}
+
+func (c TextCondition) StartsWith(s string) Filter {
+	return Filter{c.filter(TextDatabaseQueryFilter{StartsWith: s})}
+}
+
+func (c TextCondition) EndsWith(s string) Filter {
+	return Filter{c.filter(TextDatabaseQueryFilter{EndsWith: s})}
+}
+
+func (c TextCondition) IsEmpty() Filter {
+	return Filter{c.filter(TextDatabaseQueryFilter{IsEmpty: true})}
+}
+
+func (c TextCondition) IsNotEmpty() Filter {
+	return Filter{c.filter(TextDatabaseQueryFilter{IsNotEmpty: true})}
+}
+
+// NumberCondition builds a condition on a number.
+type NumberCondition struct {
+	filter func(NumberDatabaseQueryFilter) DatabaseQueryFilter
+}
+
+func (c NumberCondition) Equals(n float64) Filter {
+	return Filter{c.filter(NumberDatabaseQueryFilter{Equals: &n})}
+}
+
+func (c NumberCondition) DoesNotEqual(n float64) Filter {
+	return Filter{c.filter(NumberDatabaseQueryFilter{DoesNotEqual: &n})}
+}
+
+func (c NumberCondition) GreaterThan(n float64) Filter {
+	return Filter{c.filter(NumberDatabaseQueryFilter{GreaterThan: &n})}
+}
+
+func (c NumberCondition) LessThan(n float64) Filter {
+	return Filter{c.filter(NumberDatabaseQueryFilter{LessThan: &n})}
+}
+
+func (c NumberCondition) GreaterThanOrEqualTo(n float64) Filter {
+	return Filter{c.filter(NumberDatabaseQueryFilter{GreaterThanOrEqualTo: &n})}
+}
+
+func (c NumberCondition) LessThanOrEqualTo(n float64) Filter {
+	return Filter{c.filter(NumberDatabaseQueryFilter{LessThanOrEqualTo: &n})}
+}
+
+func (c NumberCondition) IsEmpty() Filter {
+	return Filter{c.filter(NumberDatabaseQueryFilter{IsEmpty: true})}
+}
+
+func (c NumberCondition) IsNotEmpty() Filter {
+	return Filter{c.filter(NumberDatabaseQueryFilter{IsNotEmpty: true})}
+}
+
+// CheckboxCondition builds a condition on a checkbox.
+type CheckboxCondition struct {
+	filter func(CheckboxDatabaseQueryFilter) DatabaseQueryFilter
+}
+
+func (c CheckboxCondition) Equals(b bool) Filter {
+	return Filter{c.filter(CheckboxDatabaseQueryFilter{Equals: &b})}
+}
+
+func (c CheckboxCondition) DoesNotEqual(b bool) Filter {
+	return Filter{c.filter(CheckboxDatabaseQueryFilter{DoesNotEqual: &b})}
+}
+
+// OptionCondition builds a condition on the option of a select or status.
+type OptionCondition struct {
+	filter func(SelectDatabaseQueryFilter) DatabaseQueryFilter
+}
+
+func (c OptionCondition) Equals(name string) Filter {
+	return Filter{c.filter(SelectDatabaseQueryFilter{Equals: name})}
+}
+
+func (c OptionCondition) DoesNotEqual(name string) Filter {
+	return Filter{c.filter(SelectDatabaseQueryFilter{DoesNotEqual: name})}
+}
+
+func (c OptionCondition) IsEmpty() Filter {
+	return Filter{c.filter(SelectDatabaseQueryFilter{IsEmpty: true})}
+}
+
+func (c OptionCondition) IsNotEmpty() Filter {
+	return Filter{c.filter(SelectDatabaseQueryFilter{IsNotEmpty: true})}
+}
+
+// containsFilter has the fields shared by the filters on lists.
+type containsFilter struct {
+	Contains       string `json:"contains,omitempty"`
+	DoesNotContain string `json:"does_not_contain,omitempty"`
+	IsEmpty        bool   `json:"is_empty,omitempty"`
+	IsNotEmpty     bool   `json:"is_not_empty,omitempty"`
+}
+
+// ContainsCondition builds a condition on a list of options, users or pages.
+type ContainsCondition struct {
+	filter func(containsFilter) DatabaseQueryFilter
+}
+
+func (c ContainsCondition) Contains(s string) Filter {
+	return Filter{c.filter(containsFilter{Contains: s})}
+}
+
+func (c ContainsCondition) DoesNotContain(s string) Filter {
+	return Filter{c.filter(containsFilter{DoesNotContain: s})}
+}
+
+func (c ContainsCondition) IsEmpty() Filter {
+	return Filter{c.filter(containsFilter{IsEmpty: true})}
+}
+
+func (c ContainsCondition) IsNotEmpty() Filter {
+	return Filter{c.filter(containsFilter{IsNotEmpty: true})}
+}
+
+// DateCondition builds a condition on a date. The relative conditions are
+// evaluated in the time zone of the integration.
+type DateCondition struct {
+	filter func(DateDatabaseQueryFilter) DatabaseQueryFilter
+}
+
+func (c DateCondition) Equals(t time.Time) Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{Equals: &t})}
+}
+
+func (c DateCondition) Before(t time.Time) Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{Before: &t})}
+}
+
+func (c DateCondition) After(t time.Time) Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{After: &t})}
+}
+
+func (c DateCondition) OnOrBefore(t time.Time) Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{OnOrBefore: &t})}
+}
+
+func (c DateCondition) OnOrAfter(t time.Time) Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{OnOrAfter: &t})}
+}
+
+func (c DateCondition) IsEmpty() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{IsEmpty: true})}
+}
+
+func (c DateCondition) IsNotEmpty() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{IsNotEmpty: true})}
+}
+
+func (c DateCondition) PastWeek() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{PastWeek: &struct{}{}})}
+}
+
+func (c DateCondition) PastMonth() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{PastMonth: &struct{}{}})}
+}
+
+func (c DateCondition) PastYear() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{PastYear: &struct{}{}})}
+}
+
+func (c DateCondition) ThisWeek() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{ThisWeek: &struct{}{}})}
+}
+
+func (c DateCondition) NextWeek() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{NextWeek: &struct{}{}})}
+}
+
+func (c DateCondition) NextMonth() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{NextMonth: &struct{}{}})}
+}
+
+func (c DateCondition) NextYear() Filter {
+	return Filter{c.filter(DateDatabaseQueryFilter{NextYear: &struct{}{}})}
+}
+
+// EmptyCondition builds a condition on whether a property has a value.
+type EmptyCondition struct {
+	filter func(isEmpty bool) DatabaseQueryFilter
+}
+
+func (c EmptyCondition) IsEmpty() Filter {
+	return Filter{c.filter(true)}
+}
+
+func (c EmptyCondition) IsNotEmpty() Filter {
+	return Filter{c.filter(false)}
+}
+
+// Filter is a database query filter made with Prop or Timestamp, which can be
+// combined with And and Or.
+type Filter struct {
+	DatabaseQueryFilter
+}
+
+// And returns a filter matching f and every one of filters. The filters of f
+// are extended when it already is an "and" filter, keeping the nesting
+// within the two levels the API allows.
+func (f Filter) And(filters ...Filter) Filter {
+	and := []DatabaseQueryFilter{f.DatabaseQueryFilter}
+	if len(f.DatabaseQueryFilter.And) > 0 {
+		and = append([]DatabaseQueryFilter(nil), f.DatabaseQueryFilter.And...)
+	}
+	for _, filter := range filters {
+		and = append(and, filter.DatabaseQueryFilter)
+	}
+	return Filter{DatabaseQueryFilter{And: and}}
+}
+
+// Or returns a filter matching f or any one of filters, extending the
+// filters of f when it already is an "or" filter.
+func (f Filter) Or(filters ...Filter) Filter {
+	or := []DatabaseQueryFilter{f.DatabaseQueryFilter}
+	if len(f.DatabaseQueryFilter.Or) > 0 {
+		or = append([]DatabaseQueryFilter(nil), f.DatabaseQueryFilter.Or...)
+	}
+	for _, filter := range filters {
+		or = append(or, filter.DatabaseQueryFilter)
+	}
+	return Filter{DatabaseQueryFilter{Or: or}}
+}
+
+// Query returns the filter to set on DatabaseQuery.Filter.
+func (f Filter) Query() *DatabaseQueryFilter {
+	filter := f.DatabaseQueryFilter
+	return &filter
+}
+
+func (f DatabaseQueryFilter) isCompound() bool {
+	return len(f.And) > 0 || len(f.Or) > 0
+}
+
+// textConditionTypes are the property types the deprecated text condition
+// applied to.
+var textConditionTypes = map[DatabasePropertyType]bool{
+	DBPropTypeTitle: true, DBPropTypeRichText: true, DBPropTypeURL: true, DBPropTypeEmail: true, DBPropTypePhoneNumber: true,
+}
+
+// Validate checks a filter against the properties of a database: every
+// property filter must name a property of the database and set exactly one,
+// non-empty condition matching its type, and compound filters must not set
+// anything else.
+func (f DatabaseQueryFilter) Validate(props DatabaseProperties) error {
+	if f.isCompound() {
+		if len(f.And) > 0 && len(f.Or) > 0 {
+			return errors.New("filter sets both and and or")
+		}
+		if len(f.Property) > 0 || len(f.Timestamp) > 0 || len(f.conditions()) > 0 {
+			return errors.New("compound filter also sets a condition")
+		}
+		for _, filter := range append(f.And, f.Or...) {
+			if err := filter.Validate(props); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	conditions := f.conditions()
+	if len(f.Timestamp) > 0 {
+		if len(f.Property) > 0 {
+			return fmt.Errorf("timestamp filter %v also sets property %q", f.Timestamp, f.Property)
+		}
+		if len(conditions) != 1 || conditions[0].key != string(f.Timestamp) {
+			return fmt.Errorf("timestamp filter %v must set the %v condition only", f.Timestamp, f.Timestamp)
+		}
+		return validateCondition(string(f.Timestamp), conditions[0])
+	}
+
+	if len(f.Property) == 0 {
+		return errors.New("filter sets no property, timestamp, and or or")
+	}
+	prop, ok := props[f.Property]
+	if !ok {
+		return fmt.Errorf("filter on property %q: no such property in the database", f.Property)
+	}
+	if len(conditions) != 1 {
+		return fmt.Errorf("filter on property %q: expected a single condition, got %v", f.Property, len(conditions))
+	}
+	condition := conditions[0]
+	if condition.key != string(prop.Type) && !(condition.key == "text" && textConditionTypes[prop.Type]) {
+		return fmt.Errorf("filter on property %q: %v condition on a property of type %v", f.Property, condition.key, prop.Type)
+	}
+	return validateCondition(fmt.Sprintf("filter on property %q", f.Property), condition)
+}
+
+// validateItem checks the filter on the items of a rollup, which has a
+// condition but no property.
+func (f DatabaseQueryFilter) validateItem() error {
+	if len(f.Property) > 0 || len(f.Timestamp) > 0 || f.isCompound() {
+		return errors.New("rollup item filter must only set a condition")
+	}
+	conditions := f.conditions()
+	if len(conditions) != 1 {
+		return fmt.Errorf("rollup item filter: expected a single condition, got %v", len(conditions))
+	}
+	return validateCondition("rollup item filter", conditions[0])
+}
+
+type condition struct {
+	key   string
+	value interface{}
+}
+
+// conditions returns the conditions set on f, keyed like the API.
+func (f DatabaseQueryFilter) conditions() []condition {
+	var conditions []condition
+	add := func(key string, set bool, value interface{}) {
+		if set {
+			conditions = append(conditions, condition{key, value})
+		}
+	}
+	add("title", f.Title != nil, f.Title)
+	add("rich_text", f.RichText != nil, f.RichText)
+	add("url", f.URL != nil, f.URL)
+	add("email", f.Email != nil, f.Email)
+	add("phone_number", f.PhoneNumber != nil, f.PhoneNumber)
+	add("number", f.Number != nil, f.Number)
+	add("checkbox", f.Checkbox != nil, f.Checkbox)
+	add("select", f.Select != nil, f.Select)
+	add("multi_select", f.MultiSelect != nil, f.MultiSelect)
+	add("status", f.Status != nil, f.Status)
+	add("date", f.Date != nil, f.Date)
+	add("created_time", f.CreatedTime != nil, f.CreatedTime)
+	add("last_edited_time", f.LastEditedTime != nil, f.LastEditedTime)
+	add("people", f.People != nil, f.People)
+	add("created_by", f.CreatedBy != nil, f.CreatedBy)
+	add("last_edited_by", f.LastEditedBy != nil, f.LastEditedBy)
+	add("files", f.Files != nil, f.Files)
+	add("relation", f.Relation != nil, f.Relation)
+	add("formula", f.Formula != nil, f.Formula)
+	add("rollup", f.Rollup != nil, f.Rollup)
+	add("unique_id", f.UniqueID != nil, f.UniqueID)
+	add("text", f.Text != nil, f.Text)
+	return conditions
+}
+
+func validateCondition(context string, c condition) error {
+	switch v := c.value.(type) {
+	case *FormulaDatabaseQueryFilter:
+		return validateOneOf(context+": formula", map[string]interface{}{
+			"string": v.String, "checkbox": v.Checkbox, "number": v.Number, "date": v.Date, "text": v.Text,
+		})
+	case *RollupDatabaseQueryFilter:
+		if err := validateOneOf(context+": rollup", map[string]interface{}{
+			"any": v.Any, "every": v.Every, "none": v.None, "number": v.Number, "date": v.Date,
+		}); err != nil {
+			return err
+		}
+		for _, item := range []*DatabaseQueryFilter{v.Any, v.Every, v.None} {
+			if item != nil {
+				if err := item.validateItem(); err != nil {
+					return fmt.Errorf("%v: %w", context, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	if isEmptyCondition(c.value) {
+		return fmt.Errorf("%v: empty %v condition", context, c.key)
+	}
+	return nil
+}
+
+// validateOneOf checks that exactly one of the conditions is set, and that
+// it is not empty.
+func validateOneOf(context string, conditions map[string]interface{}) error {
+	var set []string
+	for key, value := range conditions {
+		if !isNilPointer(value) {
+			set = append(set, key)
+		}
+	}
+	if len(set) != 1 {
+		return fmt.Errorf("%v: expected a single condition, got %v", context, len(set))
+	}
+	if isEmptyCondition(conditions[set[0]]) {
+		return fmt.Errorf("%v: empty %v condition", context, set[0])
+	}
+	return nil
+}
+
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return v == nil || rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+func isEmptyCondition(v interface{}) bool {
+	b, err := json.Marshal(v)
+	return err != nil || strings.TrimSpace(string(b)) == "{}"
+}
@@ -0,0 +1,195 @@
+package notionapi_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	notion "notionsync/pkg/notionapi"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		filter  notion.Filter
+		expJSON string
+	}{
+		{
+			name:    "checkbox false",
+			filter:  notion.Prop("Done").Checkbox().Equals(false),
+			expJSON: `{"property":"Done","checkbox":{"equals":false}}`,
+		},
+		{
+			name:    "number zero",
+			filter:  notion.Prop("Points").Number().GreaterThan(0),
+			expJSON: `{"property":"Points","number":{"greater_than":0}}`,
+		},
+		{
+			name:    "status",
+			filter:  notion.Prop("Status").Status().DoesNotEqual("Done"),
+			expJSON: `{"property":"Status","status":{"does_not_equal":"Done"}}`,
+		},
+		{
+			name:    "timestamp",
+			filter:  notion.Timestamp(notion.TimestampLastEditedTime).PastWeek(),
+			expJSON: `{"timestamp":"last_edited_time","last_edited_time":{"past_week":{}}}`,
+		},
+		{
+			name:    "formula",
+			filter:  notion.Prop("Overdue").Formula().Checkbox().Equals(true),
+			expJSON: `{"property":"Overdue","formula":{"checkbox":{"equals":true}}}`,
+		},
+		{
+			name:    "rollup item",
+			filter:  notion.Prop("Subtasks").Rollup().Any(notion.RollupItem().RichText().Contains("foo")),
+			expJSON: `{"property":"Subtasks","rollup":{"any":{"rich_text":{"contains":"foo"}}}}`,
+		},
+		{
+			name:    "date",
+			filter:  notion.Prop("Due").Date().OnOrAfter(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)),
+			expJSON: `{"property":"Due","date":{"on_or_after":"2022-06-01T00:00:00Z"}}`,
+		},
+		{
+			name: "and flattened",
+			filter: notion.Prop("Done").Checkbox().Equals(false).
+				And(notion.Prop("Name").Title().Equals("foo")).
+				And(notion.Prop("Tags").MultiSelect().Contains("bar")),
+			expJSON: `{"and":[` +
+				`{"property":"Done","checkbox":{"equals":false}},` +
+				`{"property":"Name","title":{"equals":"foo"}},` +
+				`{"property":"Tags","multi_select":{"contains":"bar"}}]}`,
+		},
+		{
+			name: "or nested in and",
+			filter: notion.Prop("Done").Checkbox().Equals(false).And(
+				notion.Prop("Files").Files().IsEmpty().Or(notion.Prop("Owner").People().IsEmpty()),
+			),
+			expJSON: `{"and":[` +
+				`{"property":"Done","checkbox":{"equals":false}},` +
+				`{"or":[{"property":"Files","files":{"is_empty":true}},{"property":"Owner","people":{"is_empty":true}}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := json.Marshal(tt.filter.Query())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expJSON, string(got)); diff != "" {
+				t.Errorf("json not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	t.Parallel()
+
+	props := notion.DatabaseProperties{
+		"Name":     {Type: notion.DBPropTypeTitle},
+		"Notes":    {Type: notion.DBPropTypeRichText},
+		"Done":     {Type: notion.DBPropTypeCheckbox},
+		"Points":   {Type: notion.DBPropTypeNumber},
+		"Overdue":  {Type: notion.DBPropTypeFormula},
+		"Subtasks": {Type: notion.DBPropTypeRollup},
+	}
+
+	tests := []struct {
+		name   string
+		filter notion.DatabaseQueryFilter
+		expErr string
+	}{
+		{
+			name: "valid compound",
+			filter: notion.Prop("Done").Checkbox().Equals(false).
+				And(notion.Prop("Name").Title().Contains("foo"), notion.Timestamp(notion.TimestampCreatedTime).PastMonth()).
+				DatabaseQueryFilter,
+		},
+		{
+			name:   "valid rollup",
+			filter: notion.Prop("Subtasks").Rollup().Every(notion.RollupItem().Checkbox().Equals(true)).DatabaseQueryFilter,
+		},
+		{
+			name:   "deprecated text",
+			filter: notion.DatabaseQueryFilter{Property: "Notes", Text: &notion.TextDatabaseQueryFilter{Equals: "foo"}},
+		},
+		{
+			name:   "unknown property",
+			filter: notion.Prop("Missing").Checkbox().Equals(true).DatabaseQueryFilter,
+			expErr: `filter on property "Missing": no such property in the database`,
+		},
+		{
+			name:   "type mismatch",
+			filter: notion.Prop("Done").Checkbox().Equals(true).Or(notion.Prop("Points").RichText().Equals("1")).DatabaseQueryFilter,
+			expErr: `filter on property "Points": rich_text condition on a property of type number`,
+		},
+		{
+			name:   "empty condition",
+			filter: notion.DatabaseQueryFilter{Property: "Name", Title: &notion.TextDatabaseQueryFilter{}},
+			expErr: `filter on property "Name": empty title condition`,
+		},
+		{
+			name: "two conditions",
+			filter: notion.DatabaseQueryFilter{
+				Property: "Points",
+				Number:   &notion.NumberDatabaseQueryFilter{IsEmpty: true},
+				Checkbox: &notion.CheckboxDatabaseQueryFilter{Equals: notion.BoolPtr(true)},
+			},
+			expErr: `filter on property "Points": expected a single condition, got 2`,
+		},
+		{
+			name:   "empty formula",
+			filter: notion.DatabaseQueryFilter{Property: "Overdue", Formula: &notion.FormulaDatabaseQueryFilter{}},
+			expErr: `filter on property "Overdue": formula: expected a single condition, got 0`,
+		},
+		{
+			name: "rollup item with property",
+			filter: notion.Prop("Subtasks").Rollup().None(notion.Prop("Done").Checkbox().Equals(true)).
+				DatabaseQueryFilter,
+			expErr: `filter on property "Subtasks": rollup item filter must only set a condition`,
+		},
+		{
+			name: "timestamp mismatch",
+			filter: notion.DatabaseQueryFilter{
+				Timestamp:   notion.TimestampCreatedTime,
+				CreatedTime: &notion.DateDatabaseQueryFilter{IsEmpty: true},
+				Title:       &notion.TextDatabaseQueryFilter{IsEmpty: true},
+			},
+			expErr: `timestamp filter created_time must set the created_time condition only`,
+		},
+		{
+			name: "and with or",
+			filter: notion.DatabaseQueryFilter{
+				And: []notion.DatabaseQueryFilter{notion.Prop("Done").Checkbox().Equals(true).DatabaseQueryFilter},
+				Or:  []notion.DatabaseQueryFilter{notion.Prop("Done").Checkbox().Equals(false).DatabaseQueryFilter},
+			},
+			expErr: `filter sets both and and or`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.filter.Validate(props)
+			if tt.expErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expErr {
+				t.Fatalf("error not equal (expected: %v, got: %v)", tt.expErr, err)
+			}
+		})
+	}
+}
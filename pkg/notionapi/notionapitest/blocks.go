@@ -0,0 +1,488 @@
+package notionapitest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"notionsync/pkg/notionapi"
+)
+
+// maxAppendedBlocks is the most blocks a request may append to a parent.
+const maxAppendedBlocks = 100
+
+// blockFields are the keys of a block that are not its type payload.
+var blockFields = map[string]bool{
+	"object": true, "id": true, "type": true, "parent": true, "created_time": true, "created_by": true,
+	"last_edited_time": true, "last_edited_by": true, "has_children": true, "archived": true,
+}
+
+// AddBlocks appends blocks, with their nested children, to a page or block
+// and returns them as the API would. It panics when the parent does not
+// exist or a block is invalid.
+func (s *Server) AddBlocks(parentID string, blocks ...notionapi.Block) []notionapi.Block {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw := make([]json.RawMessage, len(blocks))
+	for i, block := range blocks {
+		b, err := json.Marshal(block)
+		if err != nil {
+			panic("notionapitest: add blocks: " + err.Error())
+		}
+		raw[i] = b
+	}
+	added, err := s.appendBlocks(parentID, raw)
+	if err != nil {
+		panic("notionapitest: add blocks: " + err.Error())
+	}
+	return added
+}
+
+// Blocks returns the children of a page or block that are not archived, in
+// order. Their own children are not included.
+func (s *Server) Blocks(parentID string) []notionapi.Block {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var blocks []notionapi.Block
+	for _, id := range s.childIDs(parentID) {
+		blocks = append(blocks, s.blockOut(s.blocks[id]))
+	}
+	return blocks
+}
+
+// AddComment adds a comment to a page, starting a discussion unless it has a
+// discussion ID, and returns it as the API would. Unlike through the API, its
+// author can be set, e.g. to a user added with AddUser; it is the bot
+// otherwise.
+func (s *Server) AddComment(comment notionapi.Comment) notionapi.Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if comment.CreatedBy == nil {
+		comment.CreatedBy = &s.bot
+	}
+	added, err := s.addComment(comment)
+	if err != nil {
+		panic("notionapitest: add comment: " + err.Error())
+	}
+	return added
+}
+
+// Comments returns the comments on a page or block, oldest first.
+func (s *Server) Comments(blockID string) []notionapi.Comment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var comments []notionapi.Comment
+	for _, comment := range s.comments {
+		if comment.Parent.PageID == blockID || comment.Parent.BlockID == blockID {
+			var out notionapi.Comment
+			copyJSON(comment, &out)
+			comments = append(comments, out)
+		}
+	}
+	return comments
+}
+
+// childIDs returns the IDs of the children of a page or block that are not
+// archived.
+func (s *Server) childIDs(parentID string) []string {
+	var ids []string
+	for _, id := range s.children[parentID] {
+		if block := s.blocks[id]; block.Archived == nil || !*block.Archived {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (s *Server) blockOut(block *notionapi.Block) notionapi.Block {
+	var out notionapi.Block
+	copyJSON(block, &out)
+	return out
+}
+
+func (s *Server) findBlock(id string) (interface{}, *apiError) {
+	block, ok := s.blocks[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return s.blockOut(block), nil
+}
+
+func (s *Server) listChildren(r *http.Request, id string) (interface{}, *apiError) {
+	if _, ok := s.pages[id]; !ok {
+		if _, ok := s.blocks[id]; !ok {
+			return nil, notFound(id)
+		}
+	}
+
+	ids := s.childIDs(id)
+	start, end, next, err := paginate(r, ids, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := notionapi.BlockChildrenResponse{
+		Results:    []notionapi.Block{},
+		HasMore:    next != nil,
+		NextCursor: next,
+	}
+	for _, id := range ids[start:end] {
+		result.Results = append(result.Results, s.blockOut(s.blocks[id]))
+	}
+	return result, nil
+}
+
+func (s *Server) appendChildren(r *http.Request, id string) (interface{}, *apiError) {
+	var params struct {
+		Children []json.RawMessage `json:"children"`
+	}
+	if err := decode(r, &params); err != nil {
+		return nil, err
+	}
+
+	blocks, err := s.appendBlocks(id, params.Children)
+	if err != nil {
+		return nil, err
+	}
+	return notionapi.BlockChildrenResponse{Results: blocks}, nil
+}
+
+// appendBlocks appends blocks, as sent by a client, to a page or block. The
+// children nested in the payload of a block are appended to it in turn.
+func (s *Server) appendBlocks(parentID string, raw []json.RawMessage) ([]notionapi.Block, *apiError) {
+	var parent notionapi.Parent
+	if p, ok := s.pages[parentID]; ok {
+		if p.Archived {
+			return nil, validationErrorf("Can't edit block that is archived. You must unarchive the block before editing.")
+		}
+		parent = notionapi.Parent{Type: notionapi.ParentTypePage, PageID: parentID}
+	} else if block, ok := s.blocks[parentID]; ok {
+		if block.Archived != nil && *block.Archived {
+			return nil, validationErrorf("Can't edit block that is archived. You must unarchive the block before editing.")
+		}
+		parent = notionapi.Parent{Type: notionapi.ParentTypeBlock, BlockID: parentID}
+		block.HasChildren = true
+	} else {
+		return nil, notFound(parentID)
+	}
+	if len(raw) > maxAppendedBlocks {
+		return nil, validationErrorf("body failed validation: body.children.length should be ≤ `%v`, instead was `%v`.", maxAppendedBlocks, len(raw))
+	}
+
+	blocks := []notionapi.Block{}
+	for _, b := range raw {
+		block, children, err := parseBlock(b)
+		if err != nil {
+			return nil, err
+		}
+
+		now := s.now()
+		archived := false
+		block.ID = s.newID()
+		block.Parent = &parent
+		block.CreatedTime, block.LastEditedTime = &now, &now
+		block.CreatedBy, block.LastEditedBy = &s.bot, &s.bot
+		block.Archived = &archived
+		s.blocks[block.ID] = &block
+		s.children[parentID] = append(s.children[parentID], block.ID)
+
+		if len(children) > 0 {
+			if _, err := s.appendBlocks(block.ID, children); err != nil {
+				return nil, err
+			}
+		}
+		blocks = append(blocks, s.blockOut(&block))
+	}
+	return blocks, nil
+}
+
+// parseBlock parses a block sent by a client, returning the children nested
+// in its payload separately.
+func parseBlock(raw json.RawMessage) (notionapi.Block, []json.RawMessage, *apiError) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return notionapi.Block{}, nil, validationErrorf("body failed validation: block should be an object: %v", err)
+	}
+
+	var typ string
+	if t, ok := fields["type"]; ok {
+		_ = json.Unmarshal(t, &typ)
+	}
+	if typ == "" {
+		for key := range fields {
+			if !blockFields[key] {
+				typ = key
+			}
+		}
+	}
+	if _, ok := fields[typ]; !ok {
+		return notionapi.Block{}, nil, validationErrorf("body failed validation: block type %q should be defined.", typ)
+	}
+
+	var children []json.RawMessage
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(fields[typ], &payload); err == nil {
+		if c, ok := payload["children"]; ok {
+			if err := json.Unmarshal(c, &children); err != nil {
+				return notionapi.Block{}, nil, validationErrorf("body failed validation: children should be an array: %v", err)
+			}
+			delete(payload, "children")
+			fields[typ], _ = json.Marshal(payload)
+		}
+	}
+
+	b, _ := json.Marshal(fields)
+	var block notionapi.Block
+	if err := json.Unmarshal(b, &block); err != nil {
+		return notionapi.Block{}, nil, validationErrorf("body failed validation: %v", err)
+	}
+	block.Type = notionapi.BlockType(typ)
+
+	// The payload of a block type the client does not know is lost in
+	// parsing, and so is the block.
+	b, _ = json.Marshal(block)
+	var parsed map[string]json.RawMessage
+	if err := json.Unmarshal(b, &parsed); err != nil || parsed[typ] == nil {
+		return notionapi.Block{}, nil, validationErrorf("body failed validation: block type %q is not supported.", typ)
+	}
+
+	normalizeBlock(&block)
+	return block, children, nil
+}
+
+// normalizeBlock sets the type and plain text of the rich text of a block.
+func normalizeBlock(block *notionapi.Block) {
+	for _, body := range []*notionapi.RichTextBlock{
+		block.Paragraph, block.BulletedListItem, block.NumberedListItem, block.Toggle, block.Quote, block.Template,
+	} {
+		if body != nil {
+			normalizeRichText(body.RichText)
+		}
+	}
+	for _, heading := range []*notionapi.Heading{block.Heading1, block.Heading2, block.Heading3} {
+		if heading != nil {
+			normalizeRichText(heading.RichText)
+		}
+	}
+	switch {
+	case block.ToDo != nil:
+		normalizeRichText(block.ToDo.RichText)
+	case block.Callout != nil:
+		normalizeRichText(block.Callout.RichText)
+	case block.Code != nil:
+		normalizeRichText(block.Code.RichText)
+		normalizeRichText(block.Code.Caption)
+	}
+}
+
+// updateBlock replaces the payload of a block with the one sent, which must
+// be of the same type, and archives or restores it.
+func (s *Server) updateBlock(r *http.Request, id string) (interface{}, *apiError) {
+	block, ok := s.blocks[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := decode(r, &fields); err != nil {
+		return nil, err
+	}
+	var archived *bool
+	if a, ok := fields["archived"]; ok {
+		if err := json.Unmarshal(a, &archived); err != nil {
+			return nil, validationErrorf("body failed validation: archived should be a boolean.")
+		}
+	}
+	if block.Archived != nil && *block.Archived && (archived == nil || *archived) {
+		return nil, validationErrorf("Can't edit block that is archived. You must unarchive the block before editing.")
+	}
+
+	updated := *block
+	for key, payload := range fields {
+		if blockFields[key] {
+			continue
+		}
+		if key != string(block.Type) {
+			return nil, validationErrorf("body failed validation: block type %v cannot be updated to %v.", block.Type, key)
+		}
+		parsed, _, err := parseBlock(json.RawMessage(`{"` + key + `":` + string(payload) + `}`))
+		if err != nil {
+			return nil, err
+		}
+		parsed.ID, parsed.Parent, parsed.CreatedTime, parsed.CreatedBy = block.ID, block.Parent, block.CreatedTime, block.CreatedBy
+		parsed.HasChildren, parsed.Archived = block.HasChildren, block.Archived
+		updated = parsed
+	}
+	if archived != nil {
+		updated.Archived = archived
+	}
+	now := s.now()
+	updated.LastEditedTime, updated.LastEditedBy = &now, &s.bot
+	*block = updated
+	return s.blockOut(block), nil
+}
+
+// deleteBlock archives a block, or a page, which is returned as a child_page
+// block.
+func (s *Server) deleteBlock(id string) (interface{}, *apiError) {
+	if p, ok := s.pages[id]; ok {
+		now := s.now()
+		p.Archived = true
+		p.LastEditedTime, p.LastEditedBy = now, &s.bot
+		archived := true
+		return notionapi.Block{
+			ID:             p.ID,
+			Type:           notionapi.BlockTypeChildPage,
+			CreatedTime:    &p.CreatedTime,
+			LastEditedTime: &now,
+			Archived:       &archived,
+			ChildPage:      &notionapi.ChildPage{Title: plainText(s.pageTitle(p))},
+		}, nil
+	}
+
+	block, ok := s.blocks[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	now := s.now()
+	archived := true
+	block.Archived = &archived
+	block.LastEditedTime, block.LastEditedBy = &now, &s.bot
+	return s.blockOut(block), nil
+}
+
+// pageTitle returns the title of a page, whatever its parent.
+func (s *Server) pageTitle(p *page) []notionapi.RichText {
+	if p.Parent.Type != notionapi.ParentTypeDatabase {
+		return p.title
+	}
+	for _, value := range p.values {
+		if value.Type == notionapi.DBPropTypeTitle {
+			return value.Title
+		}
+	}
+	return nil
+}
+
+func (s *Server) listUsers(r *http.Request) (interface{}, *apiError) {
+	ids := make([]string, len(s.users))
+	for i, user := range s.users {
+		ids[i] = user.ID
+	}
+	start, end, next, err := paginate(r, ids, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return notionapi.ListUsersResponse{
+		Results:    append([]notionapi.User{}, s.users[start:end]...),
+		HasMore:    next != nil,
+		NextCursor: next,
+	}, nil
+}
+
+func (s *Server) findUser(id string) (interface{}, *apiError) {
+	if id == "me" {
+		return s.bot, nil
+	}
+	for _, user := range s.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, notFound(id)
+}
+
+func (s *Server) createComment(r *http.Request) (interface{}, *apiError) {
+	var params struct {
+		Parent       *notionapi.Parent    `json:"parent"`
+		DiscussionID string               `json:"discussion_id"`
+		RichText     []notionapi.RichText `json:"rich_text"`
+	}
+	if err := decode(r, &params); err != nil {
+		return nil, err
+	}
+
+	comment := notionapi.Comment{
+		DiscussionID: params.DiscussionID,
+		RichText:     params.RichText,
+		CreatedBy:    &s.bot,
+	}
+	switch {
+	case params.Parent != nil && params.DiscussionID != "":
+		return nil, validationErrorf("body failed validation: body.parent and body.discussion_id should not both be defined.")
+	case params.Parent != nil:
+		comment.Parent = *params.Parent
+	case params.DiscussionID == "":
+		return nil, validationErrorf("body failed validation: body.parent or body.discussion_id should be defined.")
+	}
+	return s.addComment(comment)
+}
+
+func (s *Server) addComment(comment notionapi.Comment) (notionapi.Comment, *apiError) {
+	if len(comment.RichText) == 0 {
+		return notionapi.Comment{}, validationErrorf("body failed validation: body.rich_text should be defined.")
+	}
+
+	if comment.DiscussionID != "" {
+		var found bool
+		for _, c := range s.comments {
+			if c.DiscussionID == comment.DiscussionID {
+				comment.Parent, found = c.Parent, true
+				break
+			}
+		}
+		if !found {
+			return notionapi.Comment{}, notFound(comment.DiscussionID)
+		}
+	} else {
+		if _, ok := s.pages[comment.Parent.PageID]; !ok {
+			return notionapi.Comment{}, notFound(comment.Parent.PageID)
+		}
+		comment.DiscussionID = s.newID()
+	}
+	comment.Parent.Type = notionapi.ParentTypePage
+	if comment.Parent.BlockID != "" {
+		comment.Parent.Type = notionapi.ParentTypeBlock
+	}
+
+	now := s.now()
+	comment.ID = s.newID()
+	comment.CreatedTime, comment.LastEditedTime = now, now
+	var richText []notionapi.RichText
+	copyJSON(comment.RichText, &richText)
+	normalizeRichText(richText)
+	comment.RichText = richText
+	s.comments = append(s.comments, comment)
+
+	var out notionapi.Comment
+	copyJSON(comment, &out)
+	return out, nil
+}
+
+func (s *Server) listComments(r *http.Request) (interface{}, *apiError) {
+	blockID := r.URL.Query().Get("block_id")
+	if blockID == "" {
+		return nil, validationErrorf("body failed validation: query.block_id should be defined.")
+	}
+
+	var comments []notionapi.Comment
+	var ids []string
+	for _, comment := range s.comments {
+		if comment.Parent.PageID == blockID || comment.Parent.BlockID == blockID {
+			comments = append(comments, comment)
+			ids = append(ids, comment.ID)
+		}
+	}
+	start, end, next, err := paginate(r, ids, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return notionapi.ListCommentsResponse{
+		Results:    append([]notionapi.Comment{}, comments[start:end]...),
+		HasMore:    next != nil,
+		NextCursor: next,
+	}, nil
+}
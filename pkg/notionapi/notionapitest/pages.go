@@ -0,0 +1,835 @@
+package notionapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"notionsync/pkg/notionapi"
+)
+
+// page is a stored page. Property values are kept by property ID, so that
+// renaming a property keeps them, and the properties of a page are built from
+// the schema of its database when it is handed out.
+type page struct {
+	notionapi.Page
+	values map[string]notionapi.DatabasePageProperty
+	title  []notionapi.RichText
+}
+
+// AddDatabase adds a database, with a new ID when it has none, and returns it
+// as the API would. Properties get IDs and names. It panics when the
+// database is invalid, e.g. without a title property.
+func (s *Server) AddDatabase(db notionapi.Database) notionapi.Database {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The options of the schema get IDs, which must not change those of the
+	// caller.
+	var copied notionapi.Database
+	copyJSON(db, &copied)
+	created, err := s.addDatabase(copied)
+	if err != nil {
+		panic("notionapitest: add database: " + err.Error())
+	}
+	return created
+}
+
+// Database returns the database with an ID.
+func (s *Server) Database(id string) (notionapi.Database, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db, ok := s.databases[id]
+	if !ok {
+		return notionapi.Database{}, false
+	}
+	var out notionapi.Database
+	copyJSON(db, &out)
+	return out, true
+}
+
+// AddPage adds a page with properties to a database and returns it as the API
+// would. Unlike through the API, computed properties such as formulas and
+// rollups can be set. It panics when the database does not exist or the
+// properties do not match its schema.
+func (s *Server) AddPage(databaseID string, props notionapi.DatabasePageProperties) notionapi.Page {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, err := s.addDatabasePage(databaseID, props, true)
+	if err != nil {
+		panic("notionapitest: add page: " + err.Error())
+	}
+	return s.pageOut(p)
+}
+
+// Page returns the page with an ID, archived or not.
+func (s *Server) Page(id string) (notionapi.Page, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pages[id]
+	if !ok {
+		return notionapi.Page{}, false
+	}
+	return s.pageOut(p), true
+}
+
+// Pages returns the pages of a database that are not archived, oldest first.
+func (s *Server) Pages(databaseID string) []notionapi.Page {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pages []notionapi.Page
+	for _, p := range s.databasePages(databaseID) {
+		pages = append(pages, s.pageOut(p))
+	}
+	return pages
+}
+
+func (s *Server) databasePages(databaseID string) []*page {
+	var pages []*page
+	for _, id := range s.pageOrder {
+		if p := s.pages[id]; p.Parent.DatabaseID == databaseID && !p.Archived {
+			pages = append(pages, p)
+		}
+	}
+	return pages
+}
+
+func (s *Server) addDatabase(db notionapi.Database) (notionapi.Database, *apiError) {
+	if db.ID == "" {
+		db.ID = s.newID()
+	}
+	if db.Parent.Type == "" {
+		db.Parent.Type = notionapi.ParentTypePage
+	}
+	if db.Title == nil {
+		db.Title = []notionapi.RichText{}
+	}
+	normalizeRichText(db.Title)
+
+	var titles int
+	props := make(notionapi.DatabaseProperties, len(db.Properties))
+	for name, prop := range db.Properties {
+		if prop.Type == notionapi.DBPropTypeTitle {
+			titles++
+		}
+		prop, err := s.newProperty(name, prop)
+		if err != nil {
+			return notionapi.Database{}, err
+		}
+		props[name] = prop
+	}
+	if titles != 1 {
+		return notionapi.Database{}, validationErrorf("Database must have exactly one title property, found %v.", titles)
+	}
+	db.Properties = props
+
+	now := s.now()
+	db.CreatedTime, db.LastEditedTime = now, now
+	db.CreatedBy, db.LastEditedBy = &s.bot, &s.bot
+	db.URL = objectURL(db.ID)
+
+	stored := db
+	s.databases[db.ID] = &stored
+
+	var out notionapi.Database
+	copyJSON(db, &out)
+	return out, nil
+}
+
+// newProperty returns a property added to a schema, with an ID and the
+// metadata its type needs.
+func (s *Server) newProperty(name string, prop notionapi.DatabaseProperty) (notionapi.DatabaseProperty, *apiError) {
+	prop.Name = name
+	if prop.ID == "" {
+		s.ids++
+		prop.ID = fmt.Sprintf("p%03d", s.ids)
+	}
+
+	switch prop.Type {
+	case notionapi.DBPropTypeTitle:
+		prop.ID = "title"
+		prop.Title = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeRichText:
+		prop.RichText = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeNumber:
+		if prop.Number == nil {
+			prop.Number = &notionapi.NumberMetadata{Format: notionapi.NumberFormatNumber}
+		}
+	case notionapi.DBPropTypeSelect:
+		if prop.Select == nil {
+			prop.Select = &notionapi.SelectMetadata{}
+		}
+		s.newOptions(prop.Select.Options)
+	case notionapi.DBPropTypeMultiSelect:
+		if prop.MultiSelect == nil {
+			prop.MultiSelect = &notionapi.SelectMetadata{}
+		}
+		s.newOptions(prop.MultiSelect.Options)
+	case notionapi.DBPropTypeStatus:
+		if prop.Status == nil || len(prop.Status.Options) == 0 {
+			prop.Status = s.defaultStatus()
+		}
+		s.newOptions(prop.Status.Options)
+	case notionapi.DBPropTypeDate:
+		prop.Date = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypePeople:
+		prop.People = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeFiles:
+		prop.Files = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeCheckbox:
+		prop.Checkbox = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeURL:
+		prop.URL = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeEmail:
+		prop.Email = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypePhoneNumber:
+		prop.PhoneNumber = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeCreatedTime:
+		prop.CreatedTime = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeCreatedBy:
+		prop.CreatedBy = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeLastEditedTime:
+		prop.LastEditedTime = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeLastEditedBy:
+		prop.LastEditedBy = &notionapi.EmptyMetadata{}
+	case notionapi.DBPropTypeRelation:
+		if prop.Relation == nil {
+			return prop, validationErrorf("%v: relation database_id should be defined.", name)
+		}
+	case notionapi.DBPropTypeFormula:
+		if prop.Formula == nil {
+			prop.Formula = &notionapi.FormulaMetadata{}
+		}
+	case notionapi.DBPropTypeRollup:
+		if prop.Rollup == nil {
+			prop.Rollup = &notionapi.RollupMetadata{}
+		}
+	case notionapi.DBPropTypeUniqueID:
+		if prop.UniqueID == nil {
+			prop.UniqueID = &notionapi.UniqueIDMetadata{}
+		}
+	case notionapi.DBPropTypeVerification:
+		prop.Verification = &notionapi.EmptyMetadata{}
+	default:
+		return prop, validationErrorf("%v: unsupported property type %q.", name, prop.Type)
+	}
+	return prop, nil
+}
+
+func (s *Server) newOptions(options []notionapi.SelectOptions) {
+	for i := range options {
+		if options[i].ID == "" {
+			options[i].ID = s.newID()
+		}
+		if options[i].Color == "" {
+			options[i].Color = notionapi.ColorDefault
+		}
+	}
+}
+
+// defaultStatus returns the options a status property is created with.
+func (s *Server) defaultStatus() *notionapi.StatusMetadata {
+	notStarted := notionapi.SelectOptions{ID: s.newID(), Name: "Not started", Color: notionapi.ColorDefault}
+	inProgress := notionapi.SelectOptions{ID: s.newID(), Name: "In progress", Color: notionapi.ColorBlue}
+	done := notionapi.SelectOptions{ID: s.newID(), Name: "Done", Color: notionapi.ColorGreen}
+	return &notionapi.StatusMetadata{
+		Options: []notionapi.SelectOptions{notStarted, inProgress, done},
+		Groups: []notionapi.StatusGroup{
+			{ID: s.newID(), Name: "To-do", Color: notionapi.ColorGray, OptionIDs: []string{notStarted.ID}},
+			{ID: s.newID(), Name: "In progress", Color: notionapi.ColorBlue, OptionIDs: []string{inProgress.ID}},
+			{ID: s.newID(), Name: "Complete", Color: notionapi.ColorGreen, OptionIDs: []string{done.ID}},
+		},
+	}
+}
+
+func (s *Server) createDatabase(r *http.Request) (interface{}, *apiError) {
+	var params struct {
+		Parent     notionapi.Parent             `json:"parent"`
+		Title      []notionapi.RichText         `json:"title"`
+		Properties notionapi.DatabaseProperties `json:"properties"`
+		Icon       *notionapi.Icon              `json:"icon"`
+		Cover      *notionapi.Cover             `json:"cover"`
+	}
+	if err := decode(r, &params); err != nil {
+		return nil, err
+	}
+	if params.Parent.PageID == "" {
+		return nil, validationErrorf("body failed validation: body.parent.page_id should be defined.")
+	}
+
+	return s.addDatabase(notionapi.Database{
+		Parent:     notionapi.Parent{Type: notionapi.ParentTypePage, PageID: params.Parent.PageID},
+		Title:      params.Title,
+		Properties: params.Properties,
+		Icon:       params.Icon,
+		Cover:      params.Cover,
+	})
+}
+
+func (s *Server) findDatabase(id string) (interface{}, *apiError) {
+	db, ok := s.databases[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return db, nil
+}
+
+// updateDatabase adds, renames, changes and removes properties, which are
+// keyed by name or ID, a nil property removing it.
+func (s *Server) updateDatabase(r *http.Request, id string) (interface{}, *apiError) {
+	db, ok := s.databases[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+
+	var params notionapi.UpdateDatabaseParams
+	if err := decode(r, &params); err != nil {
+		return nil, err
+	}
+
+	props := make(notionapi.DatabaseProperties, len(db.Properties))
+	for name, prop := range db.Properties {
+		props[name] = prop
+	}
+	for key, update := range params.Properties {
+		name, prop, exists := findProperty(props, key)
+		switch {
+		case update == nil && !exists:
+			return nil, validationErrorf("%v is not a property that exists.", key)
+		case update == nil && prop.Type == notionapi.DBPropTypeTitle:
+			return nil, validationErrorf("Cannot delete the title property.")
+		case update == nil:
+			delete(props, name)
+			continue
+		case !exists && update.Type == "":
+			return nil, validationErrorf("%v is not a property that exists.", key)
+		}
+
+		newName := key
+		if exists {
+			newName = name
+		}
+		if update.Name != "" {
+			newName = update.Name
+		}
+		if exists && (update.Type == "" || update.Type == prop.Type) {
+			// Keep the metadata unless new metadata is sent.
+			merged := prop
+			if update.Type != "" {
+				merged = *update
+				merged.ID = prop.ID
+			}
+			update = &merged
+		} else if exists {
+			update.ID = prop.ID
+		}
+		newProp, err := s.newProperty(newName, *update)
+		if err != nil {
+			return nil, err
+		}
+		delete(props, name)
+		if _, taken := props[newName]; taken {
+			return nil, validationErrorf("Property %v already exists.", newName)
+		}
+		props[newName] = newProp
+	}
+
+	if len(params.Title) > 0 {
+		normalizeRichText(params.Title)
+		db.Title = params.Title
+	}
+	if params.Icon != nil {
+		db.Icon = params.Icon
+	}
+	if params.Cover != nil {
+		db.Cover = params.Cover
+	}
+	db.Properties = props
+	db.LastEditedTime = s.now()
+	db.LastEditedBy = &s.bot
+	return db, nil
+}
+
+// findProperty finds a property of a schema by name or ID.
+func findProperty(props notionapi.DatabaseProperties, key string) (string, notionapi.DatabaseProperty, bool) {
+	if prop, ok := props[key]; ok {
+		return key, prop, true
+	}
+	for name, prop := range props {
+		if prop.ID == key {
+			return name, prop, true
+		}
+	}
+	return "", notionapi.DatabaseProperty{}, false
+}
+
+func (s *Server) createPage(r *http.Request) (interface{}, *apiError) {
+	var params struct {
+		Parent     notionapi.Parent  `json:"parent"`
+		Properties json.RawMessage   `json:"properties"`
+		Children   []json.RawMessage `json:"children"`
+		Icon       *notionapi.Icon   `json:"icon"`
+		Cover      *notionapi.Cover  `json:"cover"`
+	}
+	if err := decode(r, &params); err != nil {
+		return nil, err
+	}
+
+	var p *page
+	switch {
+	case params.Parent.DatabaseID != "":
+		var props notionapi.DatabasePageProperties
+		if err := json.Unmarshal(params.Properties, &props); err != nil {
+			return nil, validationErrorf("body failed validation: %v", err)
+		}
+		var err *apiError
+		if p, err = s.addDatabasePage(params.Parent.DatabaseID, props, false); err != nil {
+			return nil, err
+		}
+	case params.Parent.PageID != "":
+		var title notionapi.PageTitle
+		if err := json.Unmarshal(params.Properties, &title); err != nil {
+			return nil, validationErrorf("body failed validation: %v", err)
+		}
+		normalizeRichText(title.Title)
+		p = s.newPage(notionapi.Parent{Type: notionapi.ParentTypePage, PageID: params.Parent.PageID})
+		p.title = title.Title
+	default:
+		return nil, validationErrorf("body failed validation: body.parent.database_id or body.parent.page_id should be defined.")
+	}
+	p.Icon, p.Cover = params.Icon, params.Cover
+
+	if len(params.Children) > 0 {
+		if _, err := s.appendBlocks(p.ID, params.Children); err != nil {
+			return nil, err
+		}
+	}
+	return s.pageOut(p), nil
+}
+
+func (s *Server) newPage(parent notionapi.Parent) *page {
+	now := s.now()
+	p := &page{
+		Page: notionapi.Page{
+			ID:             s.newID(),
+			CreatedTime:    now,
+			LastEditedTime: now,
+			Parent:         parent,
+			CreatedBy:      &s.bot,
+			LastEditedBy:   &s.bot,
+		},
+		values: make(map[string]notionapi.DatabasePageProperty),
+	}
+	p.URL = objectURL(p.ID)
+	s.pages[p.ID] = p
+	s.pageOrder = append(s.pageOrder, p.ID)
+	return p
+}
+
+func (s *Server) addDatabasePage(databaseID string, props notionapi.DatabasePageProperties, computed bool) (*page, *apiError) {
+	db, ok := s.databases[databaseID]
+	if !ok {
+		return nil, notFound(databaseID)
+	}
+
+	values := make(map[string]notionapi.DatabasePageProperty)
+	if err := s.writeValues(db, values, props, computed); err != nil {
+		return nil, err
+	}
+
+	number := len(s.databasePages(databaseID)) + 1
+	p := s.newPage(notionapi.Parent{Type: notionapi.ParentTypeDatabase, DatabaseID: databaseID})
+	for _, prop := range db.Properties {
+		if _, set := values[prop.ID]; prop.Type == notionapi.DBPropTypeUniqueID && !set {
+			values[prop.ID] = notionapi.DatabasePageProperty{UniqueID: &notionapi.UniqueID{Prefix: prop.UniqueID.Prefix, Number: number}}
+		}
+	}
+	p.values = values
+	return p, nil
+}
+
+func (s *Server) findPage(r *http.Request, id string) (interface{}, *apiError) {
+	p, ok := s.pages[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+	return capReferences(r, s.pageOut(p)), nil
+}
+
+// referenceLimit is the number of items the API returns in title, rich text,
+// relation and people values of pages since referenceLimitVersion. All the
+// items are read with the page property endpoint.
+const (
+	referenceLimit        = 25
+	referenceLimitVersion = "2022-06-28"
+)
+
+// capReferences cuts the values of a page handed out to r down to
+// referenceLimit items, as the API does.
+func capReferences(r *http.Request, page notionapi.Page) notionapi.Page {
+	props, ok := page.Properties.(notionapi.DatabasePageProperties)
+	if !ok || r.Header.Get("Notion-Version") < referenceLimitVersion {
+		return page
+	}
+	for name, prop := range props {
+		if len(prop.Title) > referenceLimit {
+			prop.Title = prop.Title[:referenceLimit]
+		}
+		if len(prop.RichText) > referenceLimit {
+			prop.RichText = prop.RichText[:referenceLimit]
+		}
+		if len(prop.Relation) > referenceLimit {
+			prop.Relation = prop.Relation[:referenceLimit]
+		}
+		if len(prop.People) > referenceLimit {
+			prop.People = prop.People[:referenceLimit]
+		}
+		props[name] = prop
+	}
+	return page
+}
+
+// findPageProperty returns a property of a database page by ID: the items of
+// title, rich text, relation and people values as a paginated list, and
+// other values as a single property item.
+func (s *Server) findPageProperty(r *http.Request, pageID, propID string) (interface{}, *apiError) {
+	p, ok := s.pages[pageID]
+	if !ok {
+		return nil, notFound(pageID)
+	}
+	db, ok := s.databases[p.Parent.DatabaseID]
+	if !ok {
+		return nil, notFound(propID)
+	}
+	var (
+		schema notionapi.DatabaseProperty
+		found  bool
+	)
+	for _, prop := range db.Properties {
+		if prop.ID == propID {
+			schema, found = prop, true
+		}
+	}
+	if !found {
+		return nil, notFound(propID)
+	}
+
+	value := s.propertyValue(p, schema)
+	item := func(v interface{}) map[string]interface{} {
+		return map[string]interface{}{"object": "property_item", "id": schema.ID, "type": schema.Type, string(schema.Type): v}
+	}
+	var items []map[string]interface{}
+	switch schema.Type {
+	case notionapi.DBPropTypeTitle:
+		for _, text := range value.Title {
+			items = append(items, item(text))
+		}
+	case notionapi.DBPropTypeRichText:
+		for _, text := range value.RichText {
+			items = append(items, item(text))
+		}
+	case notionapi.DBPropTypeRelation:
+		for _, relation := range value.Relation {
+			items = append(items, item(relation))
+		}
+	case notionapi.DBPropTypePeople:
+		for _, person := range value.People {
+			items = append(items, item(person))
+		}
+	default:
+		var out map[string]interface{}
+		copyJSON(value, &out)
+		out["object"] = "property_item"
+		return out, nil
+	}
+
+	cursors := make([]string, len(items))
+	for i := range items {
+		cursors[i] = strconv.Itoa(i)
+	}
+	start, end, next, err := paginate(r, cursors, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"object":        "list",
+		"results":       append([]map[string]interface{}{}, items[start:end]...),
+		"has_more":      next != nil,
+		"next_cursor":   next,
+		"type":          "property_item",
+		"property_item": item(struct{}{}),
+	}, nil
+}
+
+func (s *Server) updatePage(r *http.Request, id string) (interface{}, *apiError) {
+	p, ok := s.pages[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+
+	var params struct {
+		Properties json.RawMessage  `json:"properties"`
+		Archived   *bool            `json:"archived"`
+		Icon       *notionapi.Icon  `json:"icon"`
+		Cover      *notionapi.Cover `json:"cover"`
+	}
+	if err := decode(r, &params); err != nil {
+		return nil, err
+	}
+	if p.Archived && (params.Archived == nil || *params.Archived) {
+		return nil, validationErrorf("Can't edit block that is archived. You must unarchive the block before editing.")
+	}
+
+	if len(params.Properties) > 0 {
+		switch p.Parent.Type {
+		case notionapi.ParentTypeDatabase:
+			var props notionapi.DatabasePageProperties
+			if err := json.Unmarshal(params.Properties, &props); err != nil {
+				return nil, validationErrorf("body failed validation: %v", err)
+			}
+			db, ok := s.databases[p.Parent.DatabaseID]
+			if !ok {
+				return nil, notFound(p.Parent.DatabaseID)
+			}
+			// Values are written to a copy so that a failed update changes
+			// nothing.
+			values := make(map[string]notionapi.DatabasePageProperty, len(p.values))
+			for id, value := range p.values {
+				values[id] = value
+			}
+			if err := s.writeValues(db, values, props, false); err != nil {
+				return nil, err
+			}
+			p.values = values
+		default:
+			var title notionapi.PageTitle
+			if err := json.Unmarshal(params.Properties, &title); err != nil {
+				return nil, validationErrorf("body failed validation: %v", err)
+			}
+			normalizeRichText(title.Title)
+			p.title = title.Title
+		}
+	}
+	if params.Archived != nil {
+		p.Archived = *params.Archived
+	}
+	if params.Icon != nil {
+		p.Icon = params.Icon
+	}
+	if params.Cover != nil {
+		p.Cover = params.Cover
+	}
+	p.LastEditedTime = s.now()
+	p.LastEditedBy = &s.bot
+	return s.pageOut(p), nil
+}
+
+// computedTypes are the property types the API computes, which cannot be
+// written.
+var computedTypes = map[notionapi.DatabasePropertyType]bool{
+	notionapi.DBPropTypeFormula:        true,
+	notionapi.DBPropTypeRollup:         true,
+	notionapi.DBPropTypeCreatedTime:    true,
+	notionapi.DBPropTypeCreatedBy:      true,
+	notionapi.DBPropTypeLastEditedTime: true,
+	notionapi.DBPropTypeLastEditedBy:   true,
+	notionapi.DBPropTypeUniqueID:       true,
+}
+
+// writeValues checks property values written to a page of db against its
+// schema and stores them in values, by property ID. New select options are
+// added to the schema, as the API does.
+func (s *Server) writeValues(db *notionapi.Database, values map[string]notionapi.DatabasePageProperty, props notionapi.DatabasePageProperties, computed bool) *apiError {
+	for key, value := range props {
+		name, schema, ok := findProperty(db.Properties, key)
+		if !ok {
+			return validationErrorf("%v is not a property that exists.", key)
+		}
+		typ, set := valueType(value)
+		if set && typ != schema.Type {
+			return validationErrorf("%v is expected to be %v.", name, schema.Type)
+		}
+		if computedTypes[schema.Type] && !computed {
+			return validationErrorf("%v is a %v property, which cannot be written.", name, schema.Type)
+		}
+
+		switch schema.Type {
+		case notionapi.DBPropTypeTitle:
+			normalizeRichText(value.Title)
+		case notionapi.DBPropTypeRichText:
+			normalizeRichText(value.RichText)
+		case notionapi.DBPropTypeSelect:
+			if value.Select != nil {
+				option := s.selectOption(&schema.Select.Options, *value.Select)
+				value.Select = &option
+			}
+		case notionapi.DBPropTypeMultiSelect:
+			for i, selected := range value.MultiSelect {
+				value.MultiSelect[i] = s.selectOption(&schema.MultiSelect.Options, selected)
+			}
+		case notionapi.DBPropTypeStatus:
+			if value.Status != nil {
+				option, ok := findOption(schema.Status.Options, *value.Status)
+				if !ok {
+					return validationErrorf("Invalid status option. Status option %q does not exist for %v.", value.Status.Name, name)
+				}
+				value.Status = &option
+			}
+		case notionapi.DBPropTypePeople:
+			for i, person := range value.People {
+				for _, user := range s.users {
+					if user.ID == person.ID {
+						value.People[i] = user
+					}
+				}
+			}
+		}
+		db.Properties[name] = schema
+
+		value.ID, value.Type, value.Name = schema.ID, schema.Type, ""
+		values[schema.ID] = value
+	}
+	return nil
+}
+
+// valueType returns the type of the value a property is written with, if
+// any.
+func valueType(prop notionapi.DatabasePageProperty) (notionapi.DatabasePropertyType, bool) {
+	values := []struct {
+		typ notionapi.DatabasePropertyType
+		set bool
+	}{
+		{notionapi.DBPropTypeTitle, prop.Title != nil},
+		{notionapi.DBPropTypeRichText, prop.RichText != nil},
+		{notionapi.DBPropTypeNumber, prop.Number != nil},
+		{notionapi.DBPropTypeSelect, prop.Select != nil},
+		{notionapi.DBPropTypeMultiSelect, prop.MultiSelect != nil},
+		{notionapi.DBPropTypeDate, prop.Date != nil},
+		{notionapi.DBPropTypeFormula, prop.Formula != nil},
+		{notionapi.DBPropTypeRelation, prop.Relation != nil},
+		{notionapi.DBPropTypeRollup, prop.Rollup != nil},
+		{notionapi.DBPropTypePeople, prop.People != nil},
+		{notionapi.DBPropTypeFiles, prop.Files != nil},
+		{notionapi.DBPropTypeCheckbox, prop.Checkbox != nil},
+		{notionapi.DBPropTypeURL, prop.URL != nil},
+		{notionapi.DBPropTypeEmail, prop.Email != nil},
+		{notionapi.DBPropTypePhoneNumber, prop.PhoneNumber != nil},
+		{notionapi.DBPropTypeStatus, prop.Status != nil},
+		{notionapi.DBPropTypeUniqueID, prop.UniqueID != nil},
+		{notionapi.DBPropTypeVerification, prop.Verification != nil},
+	}
+	for _, value := range values {
+		if value.set {
+			return value.typ, true
+		}
+	}
+	return prop.Type, prop.Type != ""
+}
+
+// selectOption returns the option of a select matching selected, adding it to
+// options when there is none.
+func (s *Server) selectOption(options *[]notionapi.SelectOptions, selected notionapi.SelectOptions) notionapi.SelectOptions {
+	if option, ok := findOption(*options, selected); ok {
+		return option
+	}
+	option := notionapi.SelectOptions{ID: s.newID(), Name: selected.Name, Color: selected.Color}
+	if option.Color == "" {
+		option.Color = notionapi.ColorDefault
+	}
+	*options = append(*options, option)
+	return option
+}
+
+func findOption(options []notionapi.SelectOptions, selected notionapi.SelectOptions) (notionapi.SelectOptions, bool) {
+	for _, option := range options {
+		if (selected.ID != "" && option.ID == selected.ID) || (selected.ID == "" && option.Name == selected.Name) {
+			return option, true
+		}
+	}
+	return notionapi.SelectOptions{}, false
+}
+
+// pageOut returns a copy of a page with its properties, as the API returns
+// it.
+func (s *Server) pageOut(p *page) notionapi.Page {
+	out := p.Page
+	if p.Parent.Type != notionapi.ParentTypeDatabase {
+		out.Properties = notionapi.PageProperties{Title: notionapi.PageTitle{Title: p.title}}
+	} else {
+		props := make(notionapi.DatabasePageProperties)
+		if db, ok := s.databases[p.Parent.DatabaseID]; ok {
+			for name, schema := range db.Properties {
+				props[name] = s.propertyValue(p, schema)
+			}
+		}
+		out.Properties = props
+	}
+
+	var copied notionapi.Page
+	copyJSON(out, &copied)
+	return copied
+}
+
+// propertyValue returns the value of a property of a page, computing those
+// the API computes.
+func (s *Server) propertyValue(p *page, schema notionapi.DatabaseProperty) notionapi.DatabasePageProperty {
+	value := p.values[schema.ID]
+	value.ID, value.Type = schema.ID, schema.Type
+	switch schema.Type {
+	case notionapi.DBPropTypeCreatedTime:
+		created := p.CreatedTime
+		value.CreatedTime = &created
+	case notionapi.DBPropTypeCreatedBy:
+		value.CreatedBy = p.CreatedBy
+	case notionapi.DBPropTypeLastEditedTime:
+		edited := p.LastEditedTime
+		value.LastEditedTime = &edited
+	case notionapi.DBPropTypeLastEditedBy:
+		value.LastEditedBy = p.LastEditedBy
+	}
+	return value
+}
+
+// normalizeRichText sets the type and plain text of rich text written by a
+// client, as the API returns them.
+func normalizeRichText(texts []notionapi.RichText) {
+	for i := range texts {
+		text := &texts[i]
+		switch {
+		case text.Text != nil:
+			text.Type = notionapi.RichTextTypeText
+			text.PlainText = text.Text.Content
+			if text.Text.Link != nil {
+				href := text.Text.Link.URL
+				text.HRef = &href
+			}
+		case text.Equation != nil:
+			text.Type = notionapi.RichTextTypeEquation
+			text.PlainText = text.Equation.Expression
+		case text.Mention != nil:
+			text.Type = notionapi.RichTextTypeMention
+		}
+		if text.Annotations == nil {
+			text.Annotations = &notionapi.Annotations{Color: notionapi.ColorDefault}
+		}
+	}
+}
+
+// plainText returns the plain text of rich text.
+func plainText(texts []notionapi.RichText) string {
+	var sb strings.Builder
+	for _, text := range texts {
+		sb.WriteString(text.PlainText)
+	}
+	return sb.String()
+}
+
+func objectURL(id string) string {
+	return "https://www.notion.so/" + strings.ReplaceAll(id, "-", "")
+}
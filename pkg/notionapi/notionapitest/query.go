@@ -0,0 +1,573 @@
+package notionapitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"notionsync/pkg/notionapi"
+)
+
+func (s *Server) queryDatabase(r *http.Request, id string) (interface{}, *apiError) {
+	db, ok := s.databases[id]
+	if !ok {
+		return nil, notFound(id)
+	}
+
+	var query notionapi.DatabaseQuery
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "Error reading body: %v", err)
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &query); err != nil {
+			return nil, &apiError{status: http.StatusBadRequest, code: "invalid_json", message: "Error parsing JSON body: " + err.Error()}
+		}
+	}
+	if query.Filter != nil {
+		if err := query.Filter.Validate(db.Properties); err != nil {
+			return nil, validationErrorf("body failed validation: %v", err)
+		}
+	}
+	for _, sort := range query.Sorts {
+		if err := validateSort(db, sort); err != nil {
+			return nil, err
+		}
+	}
+
+	var pages []*page
+	for _, p := range s.databasePages(id) {
+		if query.Filter == nil || s.matches(db, p, *query.Filter) {
+			pages = append(pages, p)
+		}
+	}
+	s.sortPages(db, pages, query.Sorts)
+
+	ids := make([]string, len(pages))
+	for i, p := range pages {
+		ids[i] = p.ID
+	}
+	start, end, next, apiErr := paginate(nil, ids, query.StartCursor, query.PageSize)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	result := notionapi.DatabaseQueryResponse{
+		Results:    []notionapi.Page{},
+		HasMore:    next != nil,
+		NextCursor: next,
+	}
+	for _, p := range pages[start:end] {
+		result.Results = append(result.Results, capReferences(r, s.pageOut(p)))
+	}
+	return result, nil
+}
+
+func validateSort(db *notionapi.Database, sort notionapi.DatabaseQuerySort) *apiError {
+	switch sort.Direction {
+	case "", notionapi.SortDirAsc, notionapi.SortDirDesc:
+	default:
+		return validationErrorf("body failed validation: sort direction should be ascending or descending, instead was %q.", sort.Direction)
+	}
+	switch {
+	case sort.Property != "" && sort.Timestamp != "":
+		return validationErrorf("body failed validation: sort sets both property and timestamp.")
+	case sort.Property != "":
+		if _, ok := db.Properties[sort.Property]; !ok {
+			return validationErrorf("Could not find sort property with name or id: %v", sort.Property)
+		}
+	case sort.Timestamp == notionapi.SortTimeStampCreatedTime, sort.Timestamp == notionapi.SortTimeStampLastEditedTime:
+	default:
+		return validationErrorf("body failed validation: sort should set property or timestamp.")
+	}
+	return nil
+}
+
+// matches reports whether a page of db matches a filter, which has been
+// validated against the schema of db.
+func (s *Server) matches(db *notionapi.Database, p *page, f notionapi.DatabaseQueryFilter) bool {
+	switch {
+	case len(f.And) > 0:
+		for _, filter := range f.And {
+			if !s.matches(db, p, filter) {
+				return false
+			}
+		}
+		return true
+	case len(f.Or) > 0:
+		for _, filter := range f.Or {
+			if s.matches(db, p, filter) {
+				return true
+			}
+		}
+		return false
+	case f.Timestamp == notionapi.TimestampCreatedTime:
+		return s.matchDate(&p.CreatedTime, true, *f.CreatedTime)
+	case f.Timestamp == notionapi.TimestampLastEditedTime:
+		return s.matchDate(&p.LastEditedTime, true, *f.LastEditedTime)
+	}
+	return s.matchValue(s.propertyValue(p, db.Properties[f.Property]), f)
+}
+
+// matchValue reports whether a property value matches the condition of a
+// filter, whose property is ignored so that the items of rollups can be
+// matched too.
+func (s *Server) matchValue(value notionapi.DatabasePageProperty, f notionapi.DatabaseQueryFilter) bool {
+	switch {
+	case f.Title != nil:
+		return matchText(plainText(value.Title), *f.Title)
+	case f.RichText != nil:
+		return matchText(plainText(value.RichText), *f.RichText)
+	case f.Text != nil:
+		return matchText(plainText(value.Title)+plainText(value.RichText)+stringValue(value.URL)+stringValue(value.Email)+stringValue(value.PhoneNumber), *f.Text)
+	case f.URL != nil:
+		return matchText(stringValue(value.URL), *f.URL)
+	case f.Email != nil:
+		return matchText(stringValue(value.Email), *f.Email)
+	case f.PhoneNumber != nil:
+		return matchText(stringValue(value.PhoneNumber), *f.PhoneNumber)
+	case f.Number != nil:
+		return matchNumber(value.Number, *f.Number)
+	case f.UniqueID != nil:
+		var number *float64
+		if value.UniqueID != nil {
+			n := float64(value.UniqueID.Number)
+			number = &n
+		}
+		return matchNumber(number, *f.UniqueID)
+	case f.Checkbox != nil:
+		return matchCheckbox(value.Checkbox != nil && *value.Checkbox, *f.Checkbox)
+	case f.Select != nil:
+		return matchOption(value.Select, *f.Select)
+	case f.Status != nil:
+		return matchOption(value.Status, notionapi.SelectDatabaseQueryFilter(*f.Status))
+	case f.MultiSelect != nil:
+		var names []string
+		for _, option := range value.MultiSelect {
+			names = append(names, option.Name)
+		}
+		return matchContains(names, notionapi.RelationDatabaseQueryFilter(*f.MultiSelect))
+	case f.Date != nil:
+		if value.Date == nil {
+			return s.matchDate(nil, false, *f.Date)
+		}
+		return s.matchDate(&value.Date.Start.Time, value.Date.Start.HasTime(), *f.Date)
+	case f.CreatedTime != nil:
+		return s.matchDate(value.CreatedTime, true, *f.CreatedTime)
+	case f.LastEditedTime != nil:
+		return s.matchDate(value.LastEditedTime, true, *f.LastEditedTime)
+	case f.People != nil:
+		return matchContains(userIDs(value.People...), notionapi.RelationDatabaseQueryFilter(*f.People))
+	case f.CreatedBy != nil:
+		return matchContains(userIDs(userValue(value.CreatedBy)...), notionapi.RelationDatabaseQueryFilter(*f.CreatedBy))
+	case f.LastEditedBy != nil:
+		return matchContains(userIDs(userValue(value.LastEditedBy)...), notionapi.RelationDatabaseQueryFilter(*f.LastEditedBy))
+	case f.Files != nil:
+		return f.Files.IsEmpty == (len(value.Files) == 0)
+	case f.Relation != nil:
+		var ids []string
+		for _, relation := range value.Relation {
+			ids = append(ids, relation.ID)
+		}
+		return matchContains(ids, *f.Relation)
+	case f.Formula != nil:
+		return s.matchFormula(value.Formula, *f.Formula)
+	case f.Rollup != nil:
+		return s.matchRollup(value.Rollup, *f.Rollup)
+	}
+	return false
+}
+
+func (s *Server) matchFormula(result *notionapi.FormulaResult, f notionapi.FormulaDatabaseQueryFilter) bool {
+	if result == nil {
+		result = &notionapi.FormulaResult{}
+	}
+	switch {
+	case f.String != nil:
+		return matchText(stringValue(result.String), *f.String)
+	case f.Checkbox != nil:
+		return matchCheckbox(result.Boolean != nil && *result.Boolean, *f.Checkbox)
+	case f.Number != nil:
+		return matchNumber(result.Number, *f.Number)
+	case f.Date != nil:
+		if result.Date == nil {
+			return s.matchDate(nil, false, *f.Date)
+		}
+		return s.matchDate(&result.Date.Start.Time, result.Date.Start.HasTime(), *f.Date)
+	}
+	return false
+}
+
+func (s *Server) matchRollup(result *notionapi.RollupResult, f notionapi.RollupDatabaseQueryFilter) bool {
+	if result == nil {
+		result = &notionapi.RollupResult{}
+	}
+	switch {
+	case f.Any != nil:
+		for _, item := range result.Array {
+			if s.matchValue(item, *f.Any) {
+				return true
+			}
+		}
+		return false
+	case f.Every != nil:
+		for _, item := range result.Array {
+			if !s.matchValue(item, *f.Every) {
+				return false
+			}
+		}
+		return true
+	case f.None != nil:
+		for _, item := range result.Array {
+			if s.matchValue(item, *f.None) {
+				return false
+			}
+		}
+		return true
+	case f.Number != nil:
+		return matchNumber(result.Number, *f.Number)
+	case f.Date != nil:
+		if result.Date == nil {
+			return s.matchDate(nil, false, *f.Date)
+		}
+		return s.matchDate(&result.Date.Start.Time, result.Date.Start.HasTime(), *f.Date)
+	}
+	return false
+}
+
+// matchText matches text like the API: equality is exact, the other
+// conditions ignore case.
+func matchText(text string, c notionapi.TextDatabaseQueryFilter) bool {
+	lower := strings.ToLower(text)
+	switch {
+	case c.Equals != "":
+		return text == c.Equals
+	case c.DoesNotEqual != "":
+		return text != c.DoesNotEqual
+	case c.Contains != "":
+		return strings.Contains(lower, strings.ToLower(c.Contains))
+	case c.DoesNotContain != "":
+		return !strings.Contains(lower, strings.ToLower(c.DoesNotContain))
+	case c.StartsWith != "":
+		return strings.HasPrefix(lower, strings.ToLower(c.StartsWith))
+	case c.EndsWith != "":
+		return strings.HasSuffix(lower, strings.ToLower(c.EndsWith))
+	case c.IsEmpty:
+		return text == ""
+	case c.IsNotEmpty:
+		return text != ""
+	}
+	return false
+}
+
+func matchNumber(n *float64, c notionapi.NumberDatabaseQueryFilter) bool {
+	switch {
+	case c.IsEmpty:
+		return n == nil
+	case c.IsNotEmpty:
+		return n != nil
+	case n == nil:
+		return c.DoesNotEqual != nil
+	case c.Equals != nil:
+		return *n == *c.Equals
+	case c.DoesNotEqual != nil:
+		return *n != *c.DoesNotEqual
+	case c.GreaterThan != nil:
+		return *n > *c.GreaterThan
+	case c.LessThan != nil:
+		return *n < *c.LessThan
+	case c.GreaterThanOrEqualTo != nil:
+		return *n >= *c.GreaterThanOrEqualTo
+	case c.LessThanOrEqualTo != nil:
+		return *n <= *c.LessThanOrEqualTo
+	}
+	return false
+}
+
+func matchCheckbox(checked bool, c notionapi.CheckboxDatabaseQueryFilter) bool {
+	switch {
+	case c.Equals != nil:
+		return checked == *c.Equals
+	case c.DoesNotEqual != nil:
+		return checked != *c.DoesNotEqual
+	}
+	return false
+}
+
+func matchOption(option *notionapi.SelectOptions, c notionapi.SelectDatabaseQueryFilter) bool {
+	var name string
+	if option != nil {
+		name = option.Name
+	}
+	switch {
+	case c.Equals != "":
+		return name == c.Equals
+	case c.DoesNotEqual != "":
+		return name != c.DoesNotEqual
+	case c.IsEmpty:
+		return name == ""
+	case c.IsNotEmpty:
+		return name != ""
+	}
+	return false
+}
+
+// matchContains matches a list of option names, user IDs or page IDs. The
+// filters on lists share their fields, so they convert to the relation one.
+func matchContains(items []string, c notionapi.RelationDatabaseQueryFilter) bool {
+	contains := func(s string) bool {
+		for _, item := range items {
+			if item == s {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case c.Contains != "":
+		return contains(c.Contains)
+	case c.DoesNotContain != "":
+		return !contains(c.DoesNotContain)
+	case c.IsEmpty:
+		return len(items) == 0
+	case c.IsNotEmpty:
+		return len(items) > 0
+	}
+	return false
+}
+
+// matchDate matches a date, compared by day when it has no time. Relative
+// conditions are evaluated at the clock of the server, in UTC.
+func (s *Server) matchDate(t *time.Time, hasTime bool, c notionapi.DateDatabaseQueryFilter) bool {
+	switch {
+	case c.IsEmpty:
+		return t == nil
+	case c.IsNotEmpty:
+		return t != nil
+	case t == nil:
+		return false
+	}
+
+	value := *t
+	at := func(ref time.Time) time.Time {
+		if hasTime {
+			return ref
+		}
+		return day(ref)
+	}
+	if !hasTime {
+		value = day(value)
+	}
+
+	now := s.now()
+	between := func(from, to time.Time) bool {
+		return !value.Before(at(from)) && !value.After(at(to))
+	}
+	switch {
+	case c.Equals != nil:
+		return value.Equal(at(*c.Equals))
+	case c.Before != nil:
+		return value.Before(at(*c.Before))
+	case c.After != nil:
+		return value.After(at(*c.After))
+	case c.OnOrBefore != nil:
+		return !value.After(at(*c.OnOrBefore))
+	case c.OnOrAfter != nil:
+		return !value.Before(at(*c.OnOrAfter))
+	case c.PastWeek != nil:
+		return between(now.AddDate(0, 0, -7), now)
+	case c.PastMonth != nil:
+		return between(now.AddDate(0, -1, 0), now)
+	case c.PastYear != nil:
+		return between(now.AddDate(-1, 0, 0), now)
+	case c.NextWeek != nil:
+		return between(now, now.AddDate(0, 0, 7))
+	case c.NextMonth != nil:
+		return between(now, now.AddDate(0, 1, 0))
+	case c.NextYear != nil:
+		return between(now, now.AddDate(1, 0, 0))
+	case c.ThisWeek != nil:
+		start := day(now).AddDate(0, 0, -int(now.Weekday()))
+		return between(start, start.AddDate(0, 0, 7).Add(-time.Nanosecond))
+	}
+	return false
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func userValue(user *notionapi.User) []notionapi.User {
+	if user == nil {
+		return nil
+	}
+	return []notionapi.User{*user}
+}
+
+func userIDs(users ...notionapi.User) []string {
+	var ids []string
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+// sortPages sorts pages by the sorts of a query, the first sort taking
+// precedence, with empty values last in either direction. Pages are in
+// creation order otherwise.
+func (s *Server) sortPages(db *notionapi.Database, pages []*page, sorts []notionapi.DatabaseQuerySort) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		for _, by := range sorts {
+			a, b := s.sortKey(db, pages[i], by), s.sortKey(db, pages[j], by)
+			switch {
+			case a == nil && b == nil:
+				continue
+			case a == nil:
+				return false
+			case b == nil:
+				return true
+			}
+			cmp := compareKeys(a, b)
+			if cmp == 0 {
+				continue
+			}
+			if by.Direction == notionapi.SortDirDesc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// sortKey returns the value a page is sorted by, a string, a number or a
+// time, or nil when it is empty. Options sort in the order of the schema.
+func (s *Server) sortKey(db *notionapi.Database, p *page, by notionapi.DatabaseQuerySort) interface{} {
+	switch by.Timestamp {
+	case notionapi.SortTimeStampCreatedTime:
+		return p.CreatedTime
+	case notionapi.SortTimeStampLastEditedTime:
+		return p.LastEditedTime
+	}
+
+	schema := db.Properties[by.Property]
+	value := s.propertyValue(p, schema)
+	optionIndex := func(option *notionapi.SelectOptions, options []notionapi.SelectOptions) interface{} {
+		if option == nil {
+			return nil
+		}
+		for i, o := range options {
+			if o.ID == option.ID {
+				return float64(i)
+			}
+		}
+		return nil
+	}
+	nonEmpty := func(s string) interface{} {
+		if s == "" {
+			return nil
+		}
+		return strings.ToLower(s)
+	}
+
+	switch schema.Type {
+	case notionapi.DBPropTypeTitle:
+		return nonEmpty(plainText(value.Title))
+	case notionapi.DBPropTypeRichText:
+		return nonEmpty(plainText(value.RichText))
+	case notionapi.DBPropTypeURL:
+		return nonEmpty(stringValue(value.URL))
+	case notionapi.DBPropTypeEmail:
+		return nonEmpty(stringValue(value.Email))
+	case notionapi.DBPropTypePhoneNumber:
+		return nonEmpty(stringValue(value.PhoneNumber))
+	case notionapi.DBPropTypeNumber:
+		if value.Number != nil {
+			return *value.Number
+		}
+	case notionapi.DBPropTypeUniqueID:
+		if value.UniqueID != nil {
+			return float64(value.UniqueID.Number)
+		}
+	case notionapi.DBPropTypeCheckbox:
+		if value.Checkbox != nil && *value.Checkbox {
+			return float64(1)
+		}
+		return float64(0)
+	case notionapi.DBPropTypeSelect:
+		return optionIndex(value.Select, schema.Select.Options)
+	case notionapi.DBPropTypeStatus:
+		return optionIndex(value.Status, schema.Status.Options)
+	case notionapi.DBPropTypeDate:
+		if value.Date != nil {
+			return value.Date.Start.Time
+		}
+	case notionapi.DBPropTypeCreatedTime:
+		return p.CreatedTime
+	case notionapi.DBPropTypeLastEditedTime:
+		return p.LastEditedTime
+	case notionapi.DBPropTypeFormula:
+		if value.Formula != nil {
+			switch {
+			case value.Formula.String != nil:
+				return nonEmpty(*value.Formula.String)
+			case value.Formula.Number != nil:
+				return *value.Formula.Number
+			case value.Formula.Date != nil:
+				return value.Formula.Date.Start.Time
+			}
+		}
+	case notionapi.DBPropTypeRollup:
+		if value.Rollup != nil {
+			switch {
+			case value.Rollup.Number != nil:
+				return *value.Rollup.Number
+			case value.Rollup.Date != nil:
+				return value.Rollup.Date.Start.Time
+			}
+		}
+	}
+	return nil
+}
+
+// compareKeys compares sort keys, keys of different types being equal, as
+// formulas may differ in type between pages.
+func compareKeys(a, b interface{}) int {
+	switch a := a.(type) {
+	case string:
+		if b, ok := b.(string); ok {
+			return strings.Compare(a, b)
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			switch {
+			case a < b:
+				return -1
+			case a > b:
+				return 1
+			}
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			switch {
+			case a.Before(b):
+				return -1
+			case a.After(b):
+				return 1
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,383 @@
+// Package notionapitest provides an in-memory fake of the Notion API for
+// tests, in the manner of net/http/httptest.
+//
+// The fake serves databases, pages with their properties, database queries
+// with filters, sorts and pagination, page property items, block children,
+// users and comments.
+// Requests are validated like the API does where it matters to clients:
+// unknown properties, values of the wrong type, invalid filters and missing
+// objects are answered with the same error codes. Formulas and rollups are
+// not evaluated; pages are stored with the values they are added with.
+package notionapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"notionsync/pkg/notionapi"
+)
+
+// Server is a fake Notion API listening on a local address. Its state can be
+// seeded and inspected with its methods while a client sends requests.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	clock     func() time.Time
+	ids       int
+	bot       notionapi.User
+	users     []notionapi.User
+	databases map[string]*notionapi.Database
+	pages     map[string]*page
+	pageOrder []string
+	blocks    map[string]*notionapi.Block
+	children  map[string][]string
+	comments  []notionapi.Comment
+	failures  []*Failure
+	requests  []string
+}
+
+// Option is used to override default server behavior.
+type Option func(*Server)
+
+// WithClock overrides the clock that created and last edited times are read
+// from, and that relative date filters such as past_week are evaluated at.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.clock = now
+	}
+}
+
+// NewServer starts and returns a new Server, to be closed when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		clock:     time.Now,
+		databases: make(map[string]*notionapi.Database),
+		pages:     make(map[string]*page),
+		blocks:    make(map[string]*notionapi.Block),
+		children:  make(map[string][]string),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.bot = notionapi.User{
+		ID:   s.newID(),
+		Type: notionapi.UserTypeBot,
+		Name: "notionapitest",
+		Bot:  &notionapi.Bot{Owner: notionapi.BotOwner{Type: notionapi.BotOwnerTypeWorkspace, Workspace: true}},
+	}
+	s.users = append(s.users, s.bot)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseURL returns the URL to pass to notionapi.WithBaseURL.
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// Client returns a client sending requests to the server, with opts applied
+// after the base URL and the http.Client of the server.
+func (s *Server) Client(opts ...notionapi.ClientOption) *notionapi.Client {
+	opts = append([]notionapi.ClientOption{
+		notionapi.WithBaseURL(s.BaseURL()),
+		notionapi.WithHTTPClient(s.Server.Client()),
+	}, opts...)
+	return notionapi.NewClient("secret-api-key", opts...)
+}
+
+// Failure makes the requests it matches fail with an error response instead
+// of being served.
+type Failure struct {
+	// Method and Path select the failing requests, any when empty. Path is
+	// matched as a prefix of the path below /v1, e.g. "/databases/".
+	Method string
+	Path   string
+
+	Status int
+	// Code defaults to the error code the API uses for Status.
+	Code    string
+	Message string
+	// RetryAfter sets the Retry-After header, in whole seconds.
+	RetryAfter time.Duration
+	// Times is the number of requests that fail: one when zero, all when
+	// negative.
+	Times int
+}
+
+// Fail makes requests fail as f describes. Failures are matched in the order
+// they were added, and dropped once used up.
+func (s *Server) Fail(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.Times == 0 {
+		f.Times = 1
+	}
+	if f.Code == "" {
+		f.Code = errorCode(f.Status)
+	}
+	if f.Message == "" {
+		f.Message = http.StatusText(f.Status)
+	}
+	s.failures = append(s.failures, &f)
+}
+
+// Requests returns the requests served so far, failed ones included, as the
+// method and the path below /v1, e.g. "POST /databases/<id>/query".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// Bot returns the user of the integration, the author of every change made
+// through the API.
+func (s *Server) Bot() notionapi.User {
+	return s.bot
+}
+
+// AddUser adds a person to the workspace, with a new ID when it has none.
+func (s *Server) AddUser(user notionapi.User) notionapi.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.ID == "" {
+		user.ID = s.newID()
+	}
+	if user.Type == "" {
+		user.Type = notionapi.UserTypePerson
+	}
+	s.users = append(s.users, user)
+	return user
+}
+
+// newID returns a new UUID, sequential to keep tests deterministic.
+func (s *Server) newID() string {
+	s.ids++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", s.ids)
+}
+
+func (s *Server) now() time.Time {
+	return s.clock().UTC().Truncate(time.Millisecond)
+}
+
+// apiError is an error answered with the status and code of the API.
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func errorf(status int, format string, args ...interface{}) *apiError {
+	return &apiError{status: status, code: errorCode(status), message: fmt.Sprintf(format, args...)}
+}
+
+func validationErrorf(format string, args ...interface{}) *apiError {
+	return errorf(http.StatusBadRequest, format, args...)
+}
+
+func notFound(id string) *apiError {
+	return errorf(http.StatusNotFound, "Could not find object with ID: %v.", id)
+}
+
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "validation_error"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "restricted_resource"
+	case http.StatusNotFound:
+		return "object_not_found"
+	case http.StatusConflict:
+		return "conflict_error"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusServiceUnavailable:
+		return "service_unavailable"
+	case http.StatusGatewayTimeout:
+		return "gateway_timeout"
+	}
+	return "internal_server_error"
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	writeJSON(w, err.status, notionapi.APIError{
+		Object:  "error",
+		Status:  err.status,
+		Code:    err.code,
+		Message: err.message,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+path)
+	if f := s.failure(r.Method, path); f != nil {
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Seconds())))
+		}
+		writeError(w, &apiError{status: f.Status, code: f.Code, message: f.Message})
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") || len(r.Header.Get("Authorization")) == len("Bearer ") {
+		writeError(w, errorf(http.StatusUnauthorized, "API token is invalid."))
+		return
+	}
+	if r.Header.Get("Notion-Version") == "" {
+		writeError(w, &apiError{status: http.StatusBadRequest, code: "missing_version", message: "Notion-Version header failed validation."})
+		return
+	}
+
+	result, err := s.route(r, strings.Split(strings.Trim(path, "/"), "/"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// failure returns the failure matching a request, using it up.
+func (s *Server) failure(method, path string) *Failure {
+	for i, f := range s.failures {
+		if (f.Method != "" && f.Method != method) || !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (s *Server) route(r *http.Request, segments []string) (interface{}, *apiError) {
+	route := r.Method + " " + segments[0]
+	switch {
+	case len(segments) == 1 && route == "POST databases":
+		return s.createDatabase(r)
+	case len(segments) == 2 && route == "GET databases":
+		return s.findDatabase(segments[1])
+	case len(segments) == 2 && route == "PATCH databases":
+		return s.updateDatabase(r, segments[1])
+	case len(segments) == 3 && route == "POST databases" && segments[2] == "query":
+		return s.queryDatabase(r, segments[1])
+	case len(segments) == 1 && route == "POST pages":
+		return s.createPage(r)
+	case len(segments) == 2 && route == "GET pages":
+		return s.findPage(r, segments[1])
+	case len(segments) == 4 && route == "GET pages" && segments[2] == "properties":
+		return s.findPageProperty(r, segments[1], segments[3])
+	case len(segments) == 2 && route == "PATCH pages":
+		return s.updatePage(r, segments[1])
+	case len(segments) == 2 && route == "GET blocks":
+		return s.findBlock(segments[1])
+	case len(segments) == 2 && route == "PATCH blocks":
+		return s.updateBlock(r, segments[1])
+	case len(segments) == 2 && route == "DELETE blocks":
+		return s.deleteBlock(segments[1])
+	case len(segments) == 3 && route == "GET blocks" && segments[2] == "children":
+		return s.listChildren(r, segments[1])
+	case len(segments) == 3 && route == "PATCH blocks" && segments[2] == "children":
+		return s.appendChildren(r, segments[1])
+	case len(segments) == 1 && route == "GET users":
+		return s.listUsers(r)
+	case len(segments) == 2 && route == "GET users":
+		return s.findUser(segments[1])
+	case len(segments) == 1 && route == "GET comments":
+		return s.listComments(r)
+	case len(segments) == 1 && route == "POST comments":
+		return s.createComment(r)
+	}
+	return nil, &apiError{status: http.StatusBadRequest, code: "invalid_request_url", message: "Invalid request URL."}
+}
+
+// decode decodes the JSON body of r into v.
+func decode(r *http.Request, v interface{}) *apiError {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &apiError{status: http.StatusBadRequest, code: "invalid_json", message: "Error parsing JSON body: " + err.Error()}
+	}
+	return nil
+}
+
+// copyJSON returns a deep copy of src in dst, so that the values the server
+// keeps and those it hands out never share memory.
+func copyJSON(src, dst interface{}) {
+	b, err := json.Marshal(src)
+	if err != nil {
+		panic(fmt.Sprintf("notionapitest: copy %T: %v", src, err))
+	}
+	if err := json.Unmarshal(b, dst); err != nil {
+		panic(fmt.Sprintf("notionapitest: copy %T: %v", src, err))
+	}
+}
+
+// paginate returns the items of a list starting at the cursor, which is the
+// ID of the first item, and the cursor of the next page.
+func paginate(r *http.Request, ids []string, startCursor string, pageSize int) (start, end int, next *string, err *apiError) {
+	if r != nil {
+		q := r.URL.Query()
+		startCursor = q.Get("start_cursor")
+		if size := q.Get("page_size"); size != "" {
+			n, convErr := strconv.Atoi(size)
+			if convErr != nil {
+				return 0, 0, nil, validationErrorf("body failed validation: page_size should be a number, instead was %q.", size)
+			}
+			pageSize = n
+		}
+	}
+	if pageSize == 0 {
+		pageSize = 100
+	}
+	if pageSize < 1 || pageSize > 100 {
+		return 0, 0, nil, validationErrorf("body failed validation: page_size should be between 1 and 100, instead was %v.", pageSize)
+	}
+
+	if startCursor != "" {
+		start = -1
+		for i, id := range ids {
+			if id == startCursor {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			return 0, 0, nil, validationErrorf("The start_cursor provided is invalid: %v", startCursor)
+		}
+	}
+
+	end = start + pageSize
+	if end >= len(ids) {
+		return start, len(ids), nil, nil
+	}
+	cursor := ids[end]
+	return start, end, &cursor, nil
+}
@@ -0,0 +1,580 @@
+package notionapitest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	notion "notionsync/pkg/notionapi"
+	"notionsync/pkg/notionapi/notionapitest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+var now = time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func richText(s string) []notion.RichText {
+	return []notion.RichText{{Text: &notion.Text{Content: s}}}
+}
+
+func date(s string) *notion.Date {
+	dt, err := notion.ParseDateTime(s)
+	if err != nil {
+		panic(err)
+	}
+	return &notion.Date{Start: dt}
+}
+
+func titles(t *testing.T, pages []notion.Page) []string {
+	t.Helper()
+
+	var got []string
+	for _, page := range pages {
+		props := page.Properties.(notion.DatabasePageProperties)
+		var title string
+		for _, text := range props["Name"].Title {
+			title += text.PlainText
+		}
+		got = append(got, title)
+	}
+	return got
+}
+
+// newTasks returns a server with a database of tasks.
+func newTasks(t *testing.T) (*notionapitest.Server, notion.Database) {
+	t.Helper()
+
+	srv := notionapitest.NewServer(notionapitest.WithClock(func() time.Time { return now }))
+	t.Cleanup(srv.Close)
+
+	db := srv.AddDatabase(notion.Database{
+		Parent: notion.Parent{Type: notion.ParentTypePage, PageID: "b0668f48-8d66-4733-9bdb-2f82215707f7"},
+		Title:  richText("Tasks"),
+		Properties: notion.DatabaseProperties{
+			"Name":   {Type: notion.DBPropTypeTitle},
+			"Done":   {Type: notion.DBPropTypeCheckbox},
+			"Points": {Type: notion.DBPropTypeNumber},
+			"Tags":   {Type: notion.DBPropTypeMultiSelect},
+			"Status": {Type: notion.DBPropTypeStatus},
+			"Due":    {Type: notion.DBPropTypeDate},
+			"Notes":  {Type: notion.DBPropTypeRichText},
+		},
+	})
+	return srv, db
+}
+
+func TestQueryDatabase(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	seed := []notion.DatabasePageProperties{
+		{
+			"Name":   {Title: richText("Write report")},
+			"Points": {Number: notion.Float64Ptr(3)},
+			"Tags":   {MultiSelect: []notion.SelectOptions{{Name: "work"}}},
+			"Status": {Status: &notion.SelectOptions{Name: "In progress"}},
+			"Due":    {Date: date("2022-06-14")},
+		},
+		{
+			"Name":   {Title: richText("Buy milk")},
+			"Done":   {Checkbox: notion.BoolPtr(true)},
+			"Points": {Number: notion.Float64Ptr(1)},
+			"Tags":   {MultiSelect: []notion.SelectOptions{{Name: "home"}}},
+			"Due":    {Date: date("2022-06-20")},
+		},
+		{
+			"Name":   {Title: richText("Plan trip")},
+			"Tags":   {MultiSelect: []notion.SelectOptions{{Name: "home"}, {Name: "travel"}}},
+			"Status": {Status: &notion.SelectOptions{Name: "Done"}},
+			"Notes":  {RichText: richText("Book the HOTEL")},
+		},
+	}
+	for _, props := range seed {
+		srv.AddPage(db.ID, props)
+	}
+	archived := srv.AddPage(db.ID, notion.DatabasePageProperties{"Name": {Title: richText("Archived")}})
+	_, err := srv.Client().UpdatePage(context.Background(), archived.ID, notion.UpdatePageParams{Archived: notion.BoolPtr(true)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query *notion.DatabaseQuery
+		exp   []string
+	}{
+		{
+			name: "no query",
+			exp:  []string{"Write report", "Buy milk", "Plan trip"},
+		},
+		{
+			name:  "checkbox",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Done").Checkbox().Equals(false).Query()},
+			exp:   []string{"Write report", "Plan trip"},
+		},
+		{
+			name:  "number",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Points").Number().GreaterThan(2).Query()},
+			exp:   []string{"Write report"},
+		},
+		{
+			name:  "empty number",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Points").Number().IsEmpty().Query()},
+			exp:   []string{"Plan trip"},
+		},
+		{
+			name:  "multi select",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Tags").MultiSelect().Contains("home").Query()},
+			exp:   []string{"Buy milk", "Plan trip"},
+		},
+		{
+			name:  "status",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Status").Status().DoesNotEqual("Done").Query()},
+			exp:   []string{"Write report", "Buy milk"},
+		},
+		{
+			name:  "text ignores case",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Notes").RichText().Contains("hotel").Query()},
+			exp:   []string{"Plan trip"},
+		},
+		{
+			name:  "date",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Due").Date().Before(now).Query()},
+			exp:   []string{"Write report"},
+		},
+		{
+			name:  "relative date",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Due").Date().NextWeek().Query()},
+			exp:   []string{"Buy milk"},
+		},
+		{
+			name: "compound",
+			query: &notion.DatabaseQuery{Filter: notion.Prop("Done").Checkbox().Equals(true).
+				Or(notion.Prop("Name").Title().StartsWith("plan")).Query()},
+			exp: []string{"Buy milk", "Plan trip"},
+		},
+		{
+			name: "sort by number, empty last",
+			query: &notion.DatabaseQuery{Sorts: []notion.DatabaseQuerySort{
+				{Property: "Points", Direction: notion.SortDirDesc},
+			}},
+			exp: []string{"Write report", "Buy milk", "Plan trip"},
+		},
+		{
+			name: "sort by title",
+			query: &notion.DatabaseQuery{Sorts: []notion.DatabaseQuerySort{
+				{Property: "Name", Direction: notion.SortDirAsc},
+			}},
+			exp: []string{"Buy milk", "Plan trip", "Write report"},
+		},
+	}
+
+	client := srv.Client()
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := client.QueryDatabase(context.Background(), db.ID, tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.exp, titles(t, resp.Results)); diff != "" {
+				t.Fatalf("results not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestQueryDatabasePagination(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	var exp []string
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		srv.AddPage(db.ID, notion.DatabasePageProperties{"Name": {Title: richText(name)}})
+		exp = append(exp, name)
+	}
+
+	client := srv.Client()
+	query := &notion.DatabaseQuery{PageSize: 2}
+	var got []string
+	var requests int
+	for {
+		resp, err := client.QueryDatabase(context.Background(), db.ID, query)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		requests++
+		got = append(got, titles(t, resp.Results)...)
+		if !resp.HasMore {
+			break
+		}
+		query.StartCursor = *resp.NextCursor
+	}
+
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("results not equal (-exp, +got):\n%v", diff)
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 requests, got %v", requests)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	client := srv.Client()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		fn   func() error
+		exp  error
+	}{
+		{
+			name: "filter on unknown property",
+			fn: func() error {
+				_, err := client.QueryDatabase(ctx, db.ID, &notion.DatabaseQuery{Filter: notion.Prop("Owner").People().IsEmpty().Query()})
+				return err
+			},
+			exp: notion.ErrValidation,
+		},
+		{
+			name: "filter of the wrong type",
+			fn: func() error {
+				_, err := client.QueryDatabase(ctx, db.ID, &notion.DatabaseQuery{Filter: notion.Prop("Done").Number().Equals(1).Query()})
+				return err
+			},
+			exp: notion.ErrValidation,
+		},
+		{
+			name: "unknown property",
+			fn: func() error {
+				_, err := client.CreatePage(ctx, notion.CreatePageParams{
+					ParentType:             notion.ParentTypeDatabase,
+					ParentID:               db.ID,
+					DatabasePageProperties: &notion.DatabasePageProperties{"Owner": {Title: richText("foo")}},
+				})
+				return err
+			},
+			exp: notion.ErrValidation,
+		},
+		{
+			name: "value of the wrong type",
+			fn: func() error {
+				_, err := client.CreatePage(ctx, notion.CreatePageParams{
+					ParentType:             notion.ParentTypeDatabase,
+					ParentID:               db.ID,
+					DatabasePageProperties: &notion.DatabasePageProperties{"Done": {Number: notion.Float64Ptr(1)}},
+				})
+				return err
+			},
+			exp: notion.ErrValidation,
+		},
+		{
+			name: "unknown status",
+			fn: func() error {
+				_, err := client.CreatePage(ctx, notion.CreatePageParams{
+					ParentType:             notion.ParentTypeDatabase,
+					ParentID:               db.ID,
+					DatabasePageProperties: &notion.DatabasePageProperties{"Status": {Status: &notion.SelectOptions{Name: "Blocked"}}},
+				})
+				return err
+			},
+			exp: notion.ErrValidation,
+		},
+		{
+			name: "unknown page",
+			fn: func() error {
+				_, err := client.FindPageByID(ctx, "b0668f48-8d66-4733-9bdb-2f82215707f7")
+				return err
+			},
+			exp: notion.ErrObjectNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if err := tt.fn(); !errors.Is(err, tt.exp) {
+				t.Fatalf("expected error %v, got %v", tt.exp, err)
+			}
+		})
+	}
+}
+
+func TestFindPageProperty(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	var notes []notion.RichText
+	for i := 0; i < 30; i++ {
+		notes = append(notes, richText(fmt.Sprint(i))...)
+	}
+	page := srv.AddPage(db.ID, notion.DatabasePageProperties{
+		"Name":   {Title: richText("Write report")},
+		"Notes":  {RichText: notes},
+		"Points": {Number: notion.Float64Ptr(3)},
+	})
+	client := srv.Client()
+	ctx := context.Background()
+
+	found, err := client.FindPageByID(ctx, page.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := len(found.Properties.(notion.DatabasePageProperties)["Notes"].RichText); got != 25 {
+		t.Fatalf("expected the page to hold 25 items, got %v", got)
+	}
+
+	notesID := db.Properties["Notes"].ID
+	query := &notion.PaginationQuery{PageSize: 20}
+	var got string
+	var requests int
+	for {
+		resp, err := client.FindPagePropertyByID(ctx, page.ID, notesID, query)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		requests++
+		for _, item := range resp.Results {
+			got += item.RichText.PlainText
+		}
+		if !resp.HasMore {
+			break
+		}
+		query.StartCursor = resp.NextCursor
+	}
+	if exp := notion.PlainText(notes); got != exp || requests != 2 {
+		t.Fatalf("expected %q in 2 requests, got %q in %v", exp, got, requests)
+	}
+
+	points, err := client.FindPagePropertyByID(ctx, page.ID, db.Properties["Points"].ID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if points.Type != notion.DBPropTypeNumber || points.Number != 3 {
+		t.Fatalf("expected number 3, got %+v", points.PagePropItem)
+	}
+}
+
+func TestPages(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	client := srv.Client()
+	ctx := context.Background()
+
+	page, err := client.CreatePage(ctx, notion.CreatePageParams{
+		ParentType: notion.ParentTypeDatabase,
+		ParentID:   db.ID,
+		DatabasePageProperties: &notion.DatabasePageProperties{
+			"Name": {Title: richText("Write report")},
+			"Tags": {MultiSelect: []notion.SelectOptions{{Name: "work"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.CreatedBy.ID != srv.Bot().ID || !page.CreatedTime.Equal(now) {
+		t.Fatalf("unexpected author or time: %v, %v", page.CreatedBy.ID, page.CreatedTime)
+	}
+
+	page, err = client.UpdatePage(ctx, page.ID, notion.UpdatePageParams{
+		DatabasePageProperties: &notion.DatabasePageProperties{"Done": {Checkbox: notion.BoolPtr(true)}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	props := page.Properties.(notion.DatabasePageProperties)
+	if got := props["Done"].Checkbox; got == nil || !*got {
+		t.Fatalf("expected Done to be checked, got %v", got)
+	}
+	if got := props["Name"].Title[0].PlainText; got != "Write report" {
+		t.Fatalf("expected title to be kept, got %q", got)
+	}
+
+	schema, err := client.FindDatabaseByID(ctx, db.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if options := schema.Properties["Tags"].MultiSelect.Options; len(options) != 1 || options[0].Name != "work" {
+		t.Fatalf("expected option to be added, got %+v", options)
+	}
+
+	if _, err := client.UpdatePage(ctx, page.ID, notion.UpdatePageParams{Archived: notion.BoolPtr(true)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.UpdatePage(ctx, page.ID, notion.UpdatePageParams{Title: richText("foo")}); !errors.Is(err, notion.ErrValidation) {
+		t.Fatalf("expected validation error editing an archived page, got %v", err)
+	}
+	if pages := srv.Pages(db.ID); len(pages) != 0 {
+		t.Fatalf("expected no pages, got %v", len(pages))
+	}
+}
+
+func TestUpdateDatabase(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	page := srv.AddPage(db.ID, notion.DatabasePageProperties{
+		"Name":  {Title: richText("Plan trip")},
+		"Notes": {RichText: richText("Book the hotel")},
+	})
+
+	updated, err := srv.Client().UpdateDatabase(context.Background(), db.ID, notion.UpdateDatabaseParams{
+		Properties: map[string]*notion.DatabaseProperty{
+			"Notes":  {Name: "Details"},
+			"Points": nil,
+			"Owner":  {Type: notion.DBPropTypePeople, People: &notion.EmptyMetadata{}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for name := range updated.Properties {
+		got = append(got, name)
+	}
+	exp := []string{"Details", "Done", "Due", "Name", "Owner", "Status", "Tags"}
+	if diff := cmp.Diff(exp, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Fatalf("properties not equal (-exp, +got):\n%v", diff)
+	}
+
+	page, _ = srv.Page(page.ID)
+	props := page.Properties.(notion.DatabasePageProperties)
+	if got := props["Details"].RichText; len(got) != 1 || got[0].PlainText != "Book the hotel" {
+		t.Fatalf("expected value to be kept by the renamed property, got %+v", got)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	page := srv.AddPage(db.ID, notion.DatabasePageProperties{"Name": {Title: richText("Plan trip")}})
+	client := srv.Client()
+	ctx := context.Background()
+
+	resp, err := client.AppendBlockChildren(ctx, page.ID, []notion.Block{
+		{
+			Type:      notion.BlockTypeParagraph,
+			Paragraph: &notion.RichTextBlock{RichText: richText("Flights")},
+		},
+		{
+			Type: notion.BlockTypeBulletedListItem,
+			BulletedListItem: &notion.RichTextBlock{
+				RichText: richText("Hotels"),
+				Children: []notion.Block{
+					{Type: notion.BlockTypeToDo, ToDo: &notion.ToDo{RichTextBlock: notion.RichTextBlock{RichText: richText("Book")}}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Results) != 2 || !resp.Results[1].HasChildren {
+		t.Fatalf("unexpected appended blocks: %+v", resp.Results)
+	}
+
+	children, err := client.FindBlockChildrenByID(ctx, resp.Results[1].ID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(children.Results) != 1 || children.Results[0].ToDo.RichText[0].PlainText != "Book" {
+		t.Fatalf("unexpected children: %+v", children.Results)
+	}
+
+	if _, err := client.DeleteBlock(ctx, resp.Results[0].ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	children, err = client.FindBlockChildrenByID(ctx, page.ID, &notion.PaginationQuery{PageSize: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(children.Results) != 1 || children.Results[0].ID != resp.Results[1].ID || children.HasMore {
+		t.Fatalf("unexpected children after delete: %+v", children)
+	}
+}
+
+func TestComments(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	page := srv.AddPage(db.ID, notion.DatabasePageProperties{"Name": {Title: richText("Plan trip")}})
+	user := srv.AddUser(notion.User{Name: "Jane"})
+	first := srv.AddComment(notion.Comment{
+		Parent:    notion.Parent{PageID: page.ID},
+		RichText:  richText("Which hotel?"),
+		CreatedBy: &user,
+	})
+
+	client := srv.Client()
+	ctx := context.Background()
+	if _, err := client.CreateComment(ctx, notion.CreateCommentParams{
+		DiscussionID: first.DiscussionID,
+		RichText:     richText("The one by the beach."),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := client.ListComments(ctx, page.ID, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, comment := range resp.Results {
+		got = append(got, comment.CreatedBy.Name+": "+comment.RichText[0].PlainText)
+	}
+	exp := []string{"Jane: Which hotel?", "notionapitest: The one by the beach."}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("comments not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestFail(t *testing.T) {
+	t.Parallel()
+
+	srv, db := newTasks(t)
+	srv.Fail(notionapitest.Failure{
+		Method:     http.MethodPost,
+		Path:       "/databases/",
+		Status:     http.StatusTooManyRequests,
+		RetryAfter: 2 * time.Second,
+	})
+	srv.Fail(notionapitest.Failure{Path: "/users/", Status: http.StatusServiceUnavailable, Times: -1})
+
+	client := srv.Client()
+	ctx := context.Background()
+	if _, err := client.QueryDatabase(ctx, db.ID, nil); !errors.Is(err, notion.ErrRateLimited) {
+		t.Fatalf("expected rate limited error, got %v", err)
+	}
+	if _, err := client.QueryDatabase(ctx, db.ID, nil); err != nil {
+		t.Fatalf("expected failure to be used up, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := client.FindCurrentUser(ctx); !errors.Is(err, notion.ErrServiceUnavailable) {
+			t.Fatalf("expected service unavailable error, got %v", err)
+		}
+	}
+
+	exp := []string{
+		"POST /databases/" + db.ID + "/query",
+		"POST /databases/" + db.ID + "/query",
+		"GET /users/me",
+		"GET /users/me",
+	}
+	if diff := cmp.Diff(exp, srv.Requests()); diff != "" {
+		t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"time"
 )
 
@@ -17,8 +18,11 @@ type Page struct {
 	Parent         Parent    `json:"parent"`
 	Archived       bool      `json:"archived"`
 	URL            string    `json:"url"`
+	PublicURL      *string   `json:"public_url,omitempty"`
 	Icon           *Icon     `json:"icon,omitempty"`
 	Cover          *Cover    `json:"cover,omitempty"`
+	CreatedBy      *User     `json:"created_by,omitempty"`
+	LastEditedBy   *User     `json:"last_edited_by,omitempty"`
 
 	// Properties differ between parent type.
 	// See the `UnmarshalJSON` method.
@@ -61,6 +65,22 @@ type DatabasePageProperty struct {
 	CreatedBy      *User           `json:"created_by,omitempty"`
 	LastEditedTime *time.Time      `json:"last_edited_time,omitempty"`
 	LastEditedBy   *User           `json:"last_edited_by,omitempty"`
+	Status         *SelectOptions  `json:"status,omitempty"`
+	UniqueID       *UniqueID       `json:"unique_id,omitempty"`
+	Verification   *Verification   `json:"verification,omitempty"`
+}
+
+// UniqueID is the value of a unique ID property, e.g. TASK-42.
+type UniqueID struct {
+	Prefix *string `json:"prefix"`
+	Number int     `json:"number"`
+}
+
+// Verification is the value of the verification property of a wiki page.
+type Verification struct {
+	State      string `json:"state"`
+	VerifiedBy *User  `json:"verified_by,omitempty"`
+	Date       *Date  `json:"date,omitempty"`
 }
 
 // CreatePageParams are the params used for creating a page.
@@ -86,6 +106,9 @@ type UpdatePageParams struct {
 	Title                  []RichText
 	Icon                   *Icon
 	Cover                  *Cover
+	// Archived moves the page to the trash when true, and restores it when
+	// false.
+	Archived *bool
 }
 
 // PagePropItem is used for a *single* property object value, e.g. for a `rich_text`
@@ -169,11 +192,57 @@ func (prop DatabasePageProperty) Value() interface{} {
 		return prop.LastEditedTime
 	case DBPropTypeLastEditedBy:
 		return prop.LastEditedBy
+	case DBPropTypeStatus:
+		return prop.Status
+	case DBPropTypeUniqueID:
+		return prop.UniqueID
+	case DBPropTypeVerification:
+		return prop.Verification
 	default:
 		return nil
 	}
 }
 
+// MarshalJSON encodes an empty value of a property with a type as null, or as
+// an empty array for types holding a list, so that writing a property read
+// from a page without a value clears it.
+func (prop DatabasePageProperty) MarshalJSON() ([]byte, error) {
+	type DatabasePagePropertyDTO DatabasePageProperty
+
+	b, err := json.Marshal(DatabasePagePropertyDTO(prop))
+	if err != nil || prop.Type == "" || !isEmptyValue(prop.Value()) {
+		return b, err
+	}
+
+	var dto map[string]json.RawMessage
+	if err := json.Unmarshal(b, &dto); err != nil {
+		return nil, err
+	}
+
+	switch prop.Type {
+	case DBPropTypeTitle, DBPropTypeRichText, DBPropTypeMultiSelect, DBPropTypePeople, DBPropTypeFiles, DBPropTypeRelation:
+		dto[string(prop.Type)] = json.RawMessage("[]")
+	default:
+		dto[string(prop.Type)] = json.RawMessage("null")
+	}
+
+	return json.Marshal(dto)
+}
+
+func isEmptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		return rv.IsNil()
+	case reflect.Slice:
+		return rv.Len() == 0
+	}
+	return false
+}
+
 func (p CreatePageParams) Validate() error {
 	if p.ParentType == "" {
 		return errors.New("parent type is required")
@@ -260,7 +329,7 @@ func (p *Page) UnmarshalJSON(b []byte) error {
 	page := dto.PageAlias
 
 	switch dto.Parent.Type {
-	case "workspace":
+	case "workspace", "block_id":
 		fallthrough
 	case "page_id":
 		var props PageProperties
@@ -287,8 +356,8 @@ func (p *Page) UnmarshalJSON(b []byte) error {
 
 func (p UpdatePageParams) Validate() error {
 	// At least one of the params must be set.
-	if p.DatabasePageProperties == nil && p.Title == nil && p.Icon == nil && p.Cover == nil {
-		return errors.New("at least one of database page properties, title, icon or cover is required")
+	if p.DatabasePageProperties == nil && p.Title == nil && p.Icon == nil && p.Cover == nil && p.Archived == nil {
+		return errors.New("at least one of database page properties, title, icon, cover or archived is required")
 	}
 	if p.Icon != nil {
 		if err := p.Icon.Validate(); err != nil {
@@ -303,11 +372,13 @@ func (p UpdatePageParams) MarshalJSON() ([]byte, error) {
 		Properties interface{} `json:"properties,omitempty"`
 		Icon       *Icon       `json:"icon,omitempty"`
 		Cover      *Cover      `json:"cover,omitempty"`
+		Archived   *bool       `json:"archived,omitempty"`
 	}
 
 	dto := UpdatePageParamsDTO{
-		Icon:  p.Icon,
-		Cover: p.Cover,
+		Icon:     p.Icon,
+		Cover:    p.Cover,
+		Archived: p.Archived,
 	}
 
 	if p.DatabasePageProperties != nil {
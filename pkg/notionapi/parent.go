@@ -5,6 +5,7 @@ type Parent struct {
 
 	PageID     string `json:"page_id,omitempty"`
 	DatabaseID string `json:"database_id,omitempty"`
+	BlockID    string `json:"block_id,omitempty"`
 	Workspace  bool   `json:"workspace,omitempty"`
 }
 
@@ -14,4 +15,5 @@ const (
 	ParentTypeDatabase  ParentType = "database_id"
 	ParentTypePage      ParentType = "page_id"
 	ParentTypeWorkspace ParentType = "workspace"
+	ParentTypeBlock     ParentType = "block_id"
 )
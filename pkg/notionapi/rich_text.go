@@ -1,5 +1,7 @@
 package notionapi
 
+import "strings"
+
 type RichText struct {
 	Type        RichTextType `json:"type,omitempty"`
 	Annotations *Annotations `json:"annotations,omitempty"`
@@ -98,3 +100,17 @@ const (
 	ColorPinkBg   Color = "pink_background"
 	ColorRedBg    Color = "red_background"
 )
+
+// PlainText joins rich text, using the content of text that has no plain text
+// yet, as in properties built for a request.
+func PlainText(texts []RichText) string {
+	var sb strings.Builder
+	for _, text := range texts {
+		if len(text.PlainText) == 0 && text.Text != nil {
+			sb.WriteString(text.Text.Content)
+			continue
+		}
+		sb.WriteString(text.PlainText)
+	}
+	return sb.String()
+}
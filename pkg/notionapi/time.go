@@ -21,6 +21,13 @@ type DateTime struct {
 
 // ParseDateTime parses an RFC3339 formatted string with optional time.
 func ParseDateTime(value string) (DateTime, error) {
+	// DateTime marshals times with as many fractional digits as they need,
+	// from none to nanoseconds, which not every prefix of DateTimeFormat
+	// matches.
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return DateTime{Time: t, hasTime: true}, nil
+	}
+
 	if len(value) > len(DateTimeFormat) {
 		return DateTime{}, errors.New("invalid datetime string")
 	}
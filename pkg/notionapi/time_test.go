@@ -73,6 +73,20 @@ func TestTimeUnmarshalJSON(t *testing.T) {
 			expHasTime:  true,
 			expError:    nil,
 		},
+		{
+			name:        "time without fraction",
+			timeString:  "2021-05-23T09:11:50Z",
+			expDateTime: notion.NewDateTime(mustParseTime(time.RFC3339Nano, "2021-05-23T09:11:50Z"), true),
+			expHasTime:  true,
+			expError:    nil,
+		},
+		{
+			name:        "time in nanoseconds",
+			timeString:  "2021-05-23T09:11:50.123456789Z",
+			expDateTime: notion.NewDateTime(mustParseTime(time.RFC3339Nano, "2021-05-23T09:11:50.123456789Z"), true),
+			expHasTime:  true,
+			expError:    nil,
+		},
 		{
 			name:        "date without time",
 			timeString:  "2021-05-23",
@@ -0,0 +1,169 @@
+package notionapi
+
+const (
+	// DefaultAPIVersion is the Notion-Version sent by clients unless
+	// WithAPIVersion overrides it.
+	DefaultAPIVersion = "2022-06-28"
+	// APIVersion20210816 is the version the client used to pin. Its blocks and
+	// database query filters use the text key for rich text.
+	APIVersion20210816 = "2021-08-16"
+
+	// richTextVersion is the version that renamed the text key to rich_text.
+	richTextVersion = "2022-02-22"
+)
+
+// WithAPIVersion overrides the Notion-Version sent with requests, e.g.
+// APIVersion20210816. The rich text of blocks and the text conditions of
+// filters are sent with the key of the version.
+func WithAPIVersion(version string) ClientOption {
+	return func(c *Client) {
+		c.apiVersion = version
+	}
+}
+
+// legacyText reports whether the version of the client predates rich_text.
+// Versions are dates, so they compare as strings.
+func (c *Client) legacyText() bool {
+	return c.apiVersion < richTextVersion
+}
+
+// versionedBlocks returns copies of blocks, and of their children, with
+// their rich text in the field of the version: Text when legacy, RichText
+// otherwise. The blocks of the caller are left as they are.
+func versionedBlocks(blocks []Block, legacy bool) []Block {
+	if blocks == nil {
+		return nil
+	}
+	versioned := make([]Block, len(blocks))
+	for i, block := range blocks {
+		versioned[i] = versionedBlock(block, legacy)
+	}
+	return versioned
+}
+
+func versionedBlock(b Block, legacy bool) Block {
+	for _, body := range []**RichTextBlock{&b.Paragraph, &b.BulletedListItem, &b.NumberedListItem, &b.Toggle, &b.Quote, &b.Template} {
+		if *body != nil {
+			*body = (*body).versioned(legacy)
+		}
+	}
+	for _, heading := range []**Heading{&b.Heading1, &b.Heading2, &b.Heading3} {
+		if *heading != nil {
+			h := **heading
+			h.RichText, h.Text = versionedText(h.RichText, h.Text, legacy)
+			h.Children = versionedBlocks(h.Children, legacy)
+			*heading = &h
+		}
+	}
+	if b.ToDo != nil {
+		toDo := *b.ToDo
+		toDo.RichTextBlock = *toDo.RichTextBlock.versioned(legacy)
+		b.ToDo = &toDo
+	}
+	if b.Callout != nil {
+		callout := *b.Callout
+		callout.RichTextBlock = *callout.RichTextBlock.versioned(legacy)
+		b.Callout = &callout
+	}
+	if b.Code != nil {
+		code := *b.Code
+		code.RichTextBlock = *code.RichTextBlock.versioned(legacy)
+		b.Code = &code
+	}
+
+	if b.ColumnList != nil {
+		b.ColumnList = &ColumnList{Children: versionedBlocks(b.ColumnList.Children, legacy)}
+	}
+	if b.Column != nil {
+		b.Column = &Column{Children: versionedBlocks(b.Column.Children, legacy)}
+	}
+	if b.SyncedBlock != nil {
+		synced := *b.SyncedBlock
+		synced.Children = versionedBlocks(synced.Children, legacy)
+		b.SyncedBlock = &synced
+	}
+	if b.Table != nil {
+		table := *b.Table
+		table.Children = versionedBlocks(table.Children, legacy)
+		b.Table = &table
+	}
+	return b
+}
+
+func (b RichTextBlock) versioned(legacy bool) *RichTextBlock {
+	b.RichText, b.Text = versionedText(b.RichText, b.Text, legacy)
+	b.Children = versionedBlocks(b.Children, legacy)
+	return &b
+}
+
+// versionedText returns the rich text and the legacy text of a block with
+// the text in the one of the version, preferring the text already there.
+// The legacy key is required, so it is set even without text.
+func versionedText(richText, text []RichText, legacy bool) ([]RichText, []RichText) {
+	if legacy {
+		if text == nil {
+			text = richText
+		}
+		if text == nil {
+			text = []RichText{}
+		}
+		return nil, text
+	}
+	if richText == nil {
+		richText = text
+	}
+	return richText, nil
+}
+
+// versionedFilter returns a copy of filter, and of the filters it holds,
+// with its text conditions in the fields of the version: Text when legacy,
+// RichText and the String condition of formulas otherwise.
+func versionedFilter(filter *DatabaseQueryFilter, legacy bool) *DatabaseQueryFilter {
+	if filter == nil {
+		return nil
+	}
+
+	f := *filter
+	f.RichText, f.Text = versionedCondition(f.RichText, f.Text, legacy)
+	if f.Formula != nil {
+		formula := *f.Formula
+		formula.String, formula.Text = versionedCondition(formula.String, formula.Text, legacy)
+		f.Formula = &formula
+	}
+	if f.Rollup != nil {
+		rollup := *f.Rollup
+		rollup.Any = versionedFilter(rollup.Any, legacy)
+		rollup.Every = versionedFilter(rollup.Every, legacy)
+		rollup.None = versionedFilter(rollup.None, legacy)
+		f.Rollup = &rollup
+	}
+	f.Or = versionedFilters(f.Or, legacy)
+	f.And = versionedFilters(f.And, legacy)
+	return &f
+}
+
+func versionedFilters(filters []DatabaseQueryFilter, legacy bool) []DatabaseQueryFilter {
+	if filters == nil {
+		return nil
+	}
+	versioned := make([]DatabaseQueryFilter, len(filters))
+	for i := range filters {
+		versioned[i] = *versionedFilter(&filters[i], legacy)
+	}
+	return versioned
+}
+
+// versionedCondition returns a text condition and its legacy counterpart
+// with the condition in the one of the version.
+func versionedCondition(condition, text *TextDatabaseQueryFilter, legacy bool) (*TextDatabaseQueryFilter, *TextDatabaseQueryFilter) {
+	if legacy {
+		if text == nil {
+			text = condition
+		}
+		return nil, text
+	}
+	if condition == nil {
+		condition = text
+	}
+	return condition, nil
+}
@@ -0,0 +1,262 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+
+	"notionsync/pkg/notionapi"
+)
+
+// Limits of a single append request.
+const (
+	// maxChildren is the most blocks a children array may hold.
+	maxChildren = 100
+	// maxRequestBlocks is the most blocks a request may hold, counting
+	// nested children.
+	maxRequestBlocks = 1000
+	// maxNesting is the most levels of children the blocks of a request may
+	// carry.
+	maxNesting = 2
+)
+
+// BlockAppender appends blocks below a block, as notionapi.Client does.
+type BlockAppender interface {
+	AppendBlockChildren(ctx context.Context, blockID string, children []notionapi.Block) (notionapi.BlockChildrenResponse, error)
+}
+
+// AppendBlocks appends blocks below blockID, which may be a page ID, in
+// requests of at most 100 blocks, and of at most 1000 counting nested ones.
+// Children are sent along with their parent when they fit in the same
+// request and nest at most two levels deep; otherwise they are appended to
+// the created parent afterwards.
+func AppendBlocks(ctx context.Context, appender BlockAppender, blockID string, blocks []notionapi.Block) error {
+	for start := 0; start < len(blocks); {
+		var (
+			chunk    []notionapi.Block
+			deferred [][]notionapi.Block
+		)
+		budget := maxRequestBlocks
+		for _, block := range blocks[start:] {
+			if len(chunk) == maxChildren {
+				break
+			}
+			block, children := splitChildren(block, budget)
+			size := 1 + countBlocks(nestedChildren(block))
+			if size > budget {
+				break
+			}
+			budget -= size
+			chunk = append(chunk, block)
+			deferred = append(deferred, children)
+		}
+		if len(chunk) == 0 {
+			return fmt.Errorf("renderer: append children to block %v: block %v does not fit in a request", blockID, start)
+		}
+
+		resp, err := appender.AppendBlockChildren(ctx, blockID, chunk)
+		if err != nil {
+			return fmt.Errorf("renderer: append children to block %v: %w", blockID, err)
+		}
+
+		for i, children := range deferred {
+			if len(children) == 0 {
+				continue
+			}
+			if i >= len(resp.Results) {
+				return fmt.Errorf("renderer: append children to block %v: missing created block %v in response", blockID, start+i)
+			}
+			if err := AppendBlocks(ctx, appender, resp.Results[i].ID, children); err != nil {
+				return err
+			}
+		}
+		start += len(chunk)
+	}
+	return nil
+}
+
+// NestChildren returns the blocks of a tree with the children of each block
+// set in the field of its type, the form blocks are created in.
+func NestChildren(nodes []Node) []notionapi.Block {
+	blocks := make([]notionapi.Block, 0, len(nodes))
+	for _, node := range nodes {
+		block := node.Block
+		if children := blockChildren(&block); children != nil && len(node.Children) > 0 {
+			*children = NestChildren(node.Children)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// Creatable returns copies of blocks read from the API that can be appended
+// again: the fields the API sets are cleared, and child pages and databases,
+// unsupported blocks and files hosted by Notion, whose URLs expire, are left
+// out.
+func Creatable(blocks []notionapi.Block) []notionapi.Block {
+	creatable := make([]notionapi.Block, 0, len(blocks))
+	for _, block := range blocks {
+		switch block.Type {
+		case notionapi.BlockTypeChildPage, notionapi.BlockTypeChildDatabase, notionapi.BlockTypeUnsupported:
+			continue
+		case notionapi.BlockTypeImage, notionapi.BlockTypeVideo, notionapi.BlockTypeAudio, notionapi.BlockTypeFile, notionapi.BlockTypePDF:
+			file := block.Image
+			if block.Type != notionapi.BlockTypeImage {
+				file = fileBlock(block)
+			}
+			if file == nil || file.External == nil {
+				continue
+			}
+		}
+
+		block.ID = ""
+		block.Parent = nil
+		block.CreatedTime = nil
+		block.CreatedBy = nil
+		block.LastEditedTime = nil
+		block.LastEditedBy = nil
+		block.HasChildren = false
+		block.Archived = nil
+		if children := blockChildren(&block); children != nil {
+			*children = Creatable(*children)
+		}
+		creatable = append(creatable, block)
+	}
+	return creatable
+}
+
+// splitChildren returns a copy of block with the children that can be sent
+// along with it in a request with budget blocks left, and the children to
+// append once it exists.
+func splitChildren(block notionapi.Block, budget int) (notionapi.Block, []notionapi.Block) {
+	children := blockChildren(&block)
+	if children == nil || len(*children) == 0 {
+		return block, nil
+	}
+
+	// A table cannot be created without its rows, so the first rows are
+	// always sent along with it.
+	if block.Type == notionapi.BlockTypeTable {
+		if len(*children) <= maxChildren {
+			return block, nil
+		}
+		deferred := (*children)[maxChildren:]
+		*children = (*children)[:maxChildren]
+		return block, deferred
+	}
+
+	if fitsRequest(*children, 1) && 1+countBlocks(*children) <= budget {
+		return block, nil
+	}
+
+	deferred := *children
+	*children = nil
+	return block, deferred
+}
+
+// fitsRequest reports whether children at the given level of nesting can be
+// sent in a request: neither they nor their own children hold too many
+// blocks or nest too deep.
+func fitsRequest(children []notionapi.Block, level int) bool {
+	if len(children) == 0 {
+		return true
+	}
+	if len(children) > maxChildren || level > maxNesting {
+		return false
+	}
+	for _, child := range children {
+		if !fitsRequest(nestedChildren(child), level+1) {
+			return false
+		}
+	}
+	return true
+}
+
+// countBlocks returns the number of blocks, counting nested children.
+func countBlocks(blocks []notionapi.Block) int {
+	n := len(blocks)
+	for _, block := range blocks {
+		n += countBlocks(nestedChildren(block))
+	}
+	return n
+}
+
+// nestedChildren returns the children set in the field of the type of
+// block, if any.
+func nestedChildren(block notionapi.Block) []notionapi.Block {
+	if children := blockChildren(&block); children != nil {
+		return *children
+	}
+	return nil
+}
+
+// blockChildren returns the children field of the blocks that have one, in
+// a copy of the value of block so that it can be changed.
+func blockChildren(block *notionapi.Block) *[]notionapi.Block {
+	if !hasValue(*block) {
+		return nil
+	}
+
+	switch block.Type {
+	case notionapi.BlockTypeParagraph:
+		body := *block.Paragraph
+		block.Paragraph = &body
+		return &body.Children
+	case notionapi.BlockTypeHeading1:
+		body := *block.Heading1
+		block.Heading1 = &body
+		return &body.Children
+	case notionapi.BlockTypeHeading2:
+		body := *block.Heading2
+		block.Heading2 = &body
+		return &body.Children
+	case notionapi.BlockTypeHeading3:
+		body := *block.Heading3
+		block.Heading3 = &body
+		return &body.Children
+	case notionapi.BlockTypeBulletedListItem:
+		body := *block.BulletedListItem
+		block.BulletedListItem = &body
+		return &body.Children
+	case notionapi.BlockTypeNumberedListItem:
+		body := *block.NumberedListItem
+		block.NumberedListItem = &body
+		return &body.Children
+	case notionapi.BlockTypeToDo:
+		body := *block.ToDo
+		block.ToDo = &body
+		return &body.Children
+	case notionapi.BlockTypeToggle:
+		body := *block.Toggle
+		block.Toggle = &body
+		return &body.Children
+	case notionapi.BlockTypeQuote:
+		body := *block.Quote
+		block.Quote = &body
+		return &body.Children
+	case notionapi.BlockTypeCallout:
+		body := *block.Callout
+		block.Callout = &body
+		return &body.Children
+	case notionapi.BlockTypeTemplate:
+		body := *block.Template
+		block.Template = &body
+		return &body.Children
+	case notionapi.BlockTypeColumnList:
+		body := *block.ColumnList
+		block.ColumnList = &body
+		return &body.Children
+	case notionapi.BlockTypeColumn:
+		body := *block.Column
+		block.Column = &body
+		return &body.Children
+	case notionapi.BlockTypeSyncedBlock:
+		body := *block.SyncedBlock
+		block.SyncedBlock = &body
+		return &body.Children
+	case notionapi.BlockTypeTable:
+		body := *block.Table
+		block.Table = &body
+		return &body.Children
+	}
+	return nil
+}
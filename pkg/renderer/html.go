@@ -0,0 +1,233 @@
+package renderer
+
+import (
+	"html"
+	"strings"
+
+	"notionsync/pkg/notionapi"
+)
+
+// HTML renders a block tree as an HTML fragment. Colors and layout that HTML
+// has no element for are left to CSS through "notion-" classes.
+func HTML(nodes []Node) string {
+	return htmlBlocks(nodes)
+}
+
+// HTMLDocument renders a page as a standalone HTML document.
+func HTMLDocument(title string, nodes []Node) string {
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" + html.EscapeString(title) +
+		"</title>\n</head>\n<body>\n<h1>" + html.EscapeString(title) + "</h1>\n" + htmlBlocks(nodes) + "</body>\n</html>\n"
+}
+
+func htmlBlocks(nodes []Node) string {
+	var sb strings.Builder
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+		if !isListItem(node.Type) {
+			sb.WriteString(htmlBlock(node))
+			continue
+		}
+
+		// Group the items of a list into a single list element.
+		tag, class := "ul", ""
+		switch node.Type {
+		case notionapi.BlockTypeNumberedListItem:
+			tag = "ol"
+		case notionapi.BlockTypeToDo:
+			class = ` class="notion-to-do"`
+		}
+		sb.WriteString("<" + tag + class + ">\n")
+		for ; i < len(nodes) && nodes[i].Type == node.Type; i++ {
+			sb.WriteString(htmlBlock(nodes[i]))
+		}
+		sb.WriteString("</" + tag + ">\n")
+		i--
+	}
+	return sb.String()
+}
+
+func htmlBlock(node Node) string {
+	if !hasValue(node.Block) {
+		return ""
+	}
+
+	text := htmlRichText(blockText(node.Block))
+	children := htmlBlocks(node.Children)
+
+	switch node.Type {
+	case notionapi.BlockTypeParagraph, notionapi.BlockTypeTemplate:
+		return "<p>" + text + "</p>\n" + htmlIndented(children)
+	case notionapi.BlockTypeHeading1:
+		return "<h1>" + text + "</h1>\n" + htmlIndented(children)
+	case notionapi.BlockTypeHeading2:
+		return "<h2>" + text + "</h2>\n" + htmlIndented(children)
+	case notionapi.BlockTypeHeading3:
+		return "<h3>" + text + "</h3>\n" + htmlIndented(children)
+	case notionapi.BlockTypeBulletedListItem, notionapi.BlockTypeNumberedListItem:
+		return "<li>" + text + htmlNested(children) + "</li>\n"
+	case notionapi.BlockTypeToDo:
+		checked := ""
+		if node.ToDo.Checked != nil && *node.ToDo.Checked {
+			checked = " checked"
+		}
+		return `<li><input type="checkbox" disabled` + checked + "> " + text + htmlNested(children) + "</li>\n"
+	case notionapi.BlockTypeToggle:
+		return "<details>\n<summary>" + text + "</summary>\n" + children + "</details>\n"
+	case notionapi.BlockTypeQuote:
+		return "<blockquote>\n<p>" + text + "</p>\n" + children + "</blockquote>\n"
+	case notionapi.BlockTypeCallout:
+		var icon string
+		if node.Callout.Icon != nil && node.Callout.Icon.Emoji != nil {
+			icon = `<span class="notion-callout-icon">` + html.EscapeString(*node.Callout.Icon.Emoji) + "</span>\n"
+		}
+		return `<div class="notion-callout">` + "\n" + icon + "<p>" + text + "</p>\n" + children + "</div>\n"
+	case notionapi.BlockTypeCode:
+		class := ""
+		if node.Code.Language != nil && *node.Code.Language != "plain text" {
+			class = ` class="language-` + html.EscapeString(*node.Code.Language) + `"`
+		}
+		return "<pre><code" + class + ">" + html.EscapeString(plainText(node.Code.RichText)) + "</code></pre>\n"
+	case notionapi.BlockTypeEquation:
+		return `<div class="notion-equation">\[` + html.EscapeString(node.Equation.Expression) + `\]</div>` + "\n"
+	case notionapi.BlockTypeDivider:
+		return "<hr>\n"
+	case notionapi.BlockTypeImage:
+		url, caption := fileURL(node.Image)
+		figure := `<figure><img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(caption) + `">`
+		if len(caption) > 0 {
+			figure += "<figcaption>" + htmlRichText(node.Image.Caption) + "</figcaption>"
+		}
+		return figure + "</figure>\n"
+	case notionapi.BlockTypeVideo:
+		url, _ := fileURL(node.Video)
+		return `<video controls src="` + html.EscapeString(url) + `"></video>` + "\n"
+	case notionapi.BlockTypeAudio:
+		url, _ := fileURL(node.Audio)
+		return `<audio controls src="` + html.EscapeString(url) + `"></audio>` + "\n"
+	case notionapi.BlockTypeFile, notionapi.BlockTypePDF:
+		url, caption := fileURL(fileBlock(node.Block))
+		return "<p>" + htmlLink(caption, url) + "</p>\n"
+	case notionapi.BlockTypeBookmark:
+		return "<p>" + htmlLink(plainText(node.Bookmark.Caption), node.Bookmark.URL) + "</p>\n"
+	case notionapi.BlockTypeEmbed:
+		return "<p>" + htmlLink("", node.Embed.URL) + "</p>\n"
+	case notionapi.BlockTypeLinkPreview:
+		return "<p>" + htmlLink("", node.LinkPreview.URL) + "</p>\n"
+	case notionapi.BlockTypeChildPage:
+		return "<p>" + htmlLink(node.ChildPage.Title, pageURL(node.ID)) + "</p>\n"
+	case notionapi.BlockTypeChildDatabase:
+		return "<p>" + htmlLink(node.ChildDatabase.Title, pageURL(node.ID)) + "</p>\n"
+	case notionapi.BlockTypeLinkToPage:
+		id := node.LinkToPage.PageID
+		if len(id) == 0 {
+			id = node.LinkToPage.DatabaseID
+		}
+		return "<p>" + htmlLink("", pageURL(id)) + "</p>\n"
+	case notionapi.BlockTypeTable:
+		return htmlTable(node)
+	case notionapi.BlockTypeColumnList:
+		return `<div class="notion-column-list">` + "\n" + children + "</div>\n"
+	case notionapi.BlockTypeColumn:
+		return `<div class="notion-column">` + "\n" + children + "</div>\n"
+	case notionapi.BlockTypeSyncedBlock:
+		return children
+	case notionapi.BlockTypeTableOfContents, notionapi.BlockTypeBreadCrumb:
+		return ""
+	}
+
+	return "<!-- unsupported block: " + html.EscapeString(string(node.Type)) + " -->\n"
+}
+
+// htmlTable renders a table, with the column and row headers as th cells.
+func htmlTable(node Node) string {
+	var sb strings.Builder
+	sb.WriteString("<table>\n")
+	for i, child := range node.Children {
+		if child.Type != notionapi.BlockTypeTableRow || child.TableRow == nil {
+			continue
+		}
+		header := i == 0 && node.Table.HasColumnHeader
+		if header {
+			sb.WriteString("<thead>\n")
+		}
+		sb.WriteString("<tr>")
+		for j := 0; j < node.Table.TableWidth; j++ {
+			tag := "td"
+			if header || (j == 0 && node.Table.HasRowHeader) {
+				tag = "th"
+			}
+			var cell string
+			if j < len(child.TableRow.Cells) {
+				cell = htmlRichText(child.TableRow.Cells[j])
+			}
+			sb.WriteString("<" + tag + ">" + cell + "</" + tag + ">")
+		}
+		sb.WriteString("</tr>\n")
+		if header {
+			sb.WriteString("</thead>\n")
+		}
+	}
+	sb.WriteString("</table>\n")
+	return sb.String()
+}
+
+// htmlIndented nests the children of a block that is not a container.
+func htmlIndented(children string) string {
+	if len(children) == 0 {
+		return ""
+	}
+	return `<div class="notion-indent">` + "\n" + children + "</div>\n"
+}
+
+// htmlNested nests the children of a list item inside it.
+func htmlNested(children string) string {
+	if len(children) == 0 {
+		return ""
+	}
+	return "\n" + children
+}
+
+func htmlLink(text, url string) string {
+	if len(text) == 0 {
+		text = url
+	}
+	return `<a href="` + html.EscapeString(url) + `">` + html.EscapeString(text) + "</a>"
+}
+
+func htmlRichText(texts []notionapi.RichText) string {
+	var sb strings.Builder
+	for _, text := range texts {
+		if text.Type == notionapi.RichTextTypeEquation && text.Equation != nil {
+			sb.WriteString(`<span class="notion-equation">\(` + html.EscapeString(text.Equation.Expression) + `\)</span>`)
+			continue
+		}
+
+		content := strings.ReplaceAll(html.EscapeString(textContent(text)), "\n", "<br>")
+		if annotations := text.Annotations; annotations != nil {
+			if annotations.Code {
+				content = "<code>" + content + "</code>"
+			}
+			if annotations.Bold {
+				content = "<strong>" + content + "</strong>"
+			}
+			if annotations.Italic {
+				content = "<em>" + content + "</em>"
+			}
+			if annotations.Strikethrough {
+				content = "<s>" + content + "</s>"
+			}
+			if annotations.Underline {
+				content = "<u>" + content + "</u>"
+			}
+			if annotations.Color != "" && annotations.Color != notionapi.ColorDefault {
+				content = `<span class="notion-` + html.EscapeString(string(annotations.Color)) + `">` + content + "</span>"
+			}
+		}
+
+		if url := textLink(text); len(url) > 0 {
+			content = `<a href="` + html.EscapeString(url) + `">` + content + "</a>"
+		}
+		sb.WriteString(content)
+	}
+	return sb.String()
+}
@@ -0,0 +1,285 @@
+package renderer
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"notionsync/pkg/notionapi"
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`~`, `\~`,
+)
+
+// Markdown renders a block tree as CommonMark, with GitHub flavored task
+// lists, strikethrough and math. Toggles become <details> elements and
+// columns are laid out one after another.
+func Markdown(nodes []Node) string {
+	return markdownBlocks(nodes)
+}
+
+func markdownBlocks(nodes []Node) string {
+	var (
+		sb     strings.Builder
+		number int
+	)
+
+	for i, node := range nodes {
+		if node.Type == notionapi.BlockTypeNumberedListItem {
+			number++
+		} else {
+			number = 0
+		}
+
+		block := markdownBlock(node, number)
+		if len(block) == 0 {
+			continue
+		}
+		if sb.Len() > 0 {
+			// Items of the same list are kept together.
+			if isListItem(node.Type) && nodes[i-1].Type == node.Type {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(block)
+	}
+
+	return sb.String()
+}
+
+func isListItem(t notionapi.BlockType) bool {
+	return t == notionapi.BlockTypeBulletedListItem || t == notionapi.BlockTypeNumberedListItem || t == notionapi.BlockTypeToDo
+}
+
+func markdownBlock(node Node, number int) string {
+	if !hasValue(node.Block) {
+		return ""
+	}
+
+	text := markdownRichText(blockText(node.Block))
+	children := markdownBlocks(node.Children)
+
+	switch node.Type {
+	case notionapi.BlockTypeParagraph, notionapi.BlockTypeTemplate:
+		// Indenting would turn the children into a code block.
+		return withChildren(text, children, "")
+	case notionapi.BlockTypeHeading1:
+		return withChildren("# "+text, children, "")
+	case notionapi.BlockTypeHeading2:
+		return withChildren("## "+text, children, "")
+	case notionapi.BlockTypeHeading3:
+		return withChildren("### "+text, children, "")
+	case notionapi.BlockTypeBulletedListItem:
+		return withChildren("- "+text, children, "  ")
+	case notionapi.BlockTypeNumberedListItem:
+		marker := strconv.Itoa(number) + ". "
+		return withChildren(marker+text, children, strings.Repeat(" ", len(marker)))
+	case notionapi.BlockTypeToDo:
+		box := "- [ ] "
+		if node.ToDo.Checked != nil && *node.ToDo.Checked {
+			box = "- [x] "
+		}
+		return withChildren(box+text, children, "  ")
+	case notionapi.BlockTypeToggle:
+		return "<details>\n<summary>" + text + "</summary>\n\n" + children + "\n\n</details>"
+	case notionapi.BlockTypeQuote:
+		return prefixLines(withChildren(text, children, ""), "> ")
+	case notionapi.BlockTypeCallout:
+		if icon := node.Callout.Icon; icon != nil && icon.Emoji != nil {
+			text = *icon.Emoji + " " + text
+		}
+		return prefixLines(withChildren(text, children, ""), "> ")
+	case notionapi.BlockTypeCode:
+		var lang string
+		if node.Code.Language != nil && *node.Code.Language != "plain text" {
+			lang = *node.Code.Language
+		}
+		fence := codeFence(plainText(node.Code.RichText))
+		return fence + lang + "\n" + plainText(node.Code.RichText) + "\n" + fence
+	case notionapi.BlockTypeEquation:
+		return "$$\n" + node.Equation.Expression + "\n$$"
+	case notionapi.BlockTypeDivider:
+		return "---"
+	case notionapi.BlockTypeImage:
+		url, caption := fileURL(node.Image)
+		return "![" + markdownEscaper.Replace(caption) + "](" + url + ")"
+	case notionapi.BlockTypeVideo, notionapi.BlockTypeAudio, notionapi.BlockTypeFile, notionapi.BlockTypePDF:
+		url, caption := fileURL(fileBlock(node.Block))
+		return markdownLink(caption, url)
+	case notionapi.BlockTypeBookmark:
+		return markdownLink(plainText(node.Bookmark.Caption), node.Bookmark.URL)
+	case notionapi.BlockTypeEmbed:
+		return markdownLink("", node.Embed.URL)
+	case notionapi.BlockTypeLinkPreview:
+		return markdownLink("", node.LinkPreview.URL)
+	case notionapi.BlockTypeChildPage:
+		return markdownLink(node.ChildPage.Title, pageURL(node.ID))
+	case notionapi.BlockTypeChildDatabase:
+		return markdownLink(node.ChildDatabase.Title, pageURL(node.ID))
+	case notionapi.BlockTypeLinkToPage:
+		id := node.LinkToPage.PageID
+		if len(id) == 0 {
+			id = node.LinkToPage.DatabaseID
+		}
+		return markdownLink("", pageURL(id))
+	case notionapi.BlockTypeTable:
+		return markdownTable(node)
+	case notionapi.BlockTypeColumnList, notionapi.BlockTypeColumn, notionapi.BlockTypeSyncedBlock:
+		return children
+	case notionapi.BlockTypeTableOfContents, notionapi.BlockTypeBreadCrumb:
+		return ""
+	}
+
+	return "<!-- unsupported block: " + string(node.Type) + " -->"
+}
+
+func fileBlock(block notionapi.Block) *notionapi.FileBlock {
+	switch block.Type {
+	case notionapi.BlockTypeVideo:
+		return block.Video
+	case notionapi.BlockTypeAudio:
+		return block.Audio
+	case notionapi.BlockTypePDF:
+		return block.PDF
+	}
+	return block.File
+}
+
+var markdownCellEscaper = strings.NewReplacer("|", `\|`, "\\\n", "<br>")
+
+// markdownTable renders a table as a GitHub flavored table. Markdown tables
+// always have a header, so without a column header the first row is used.
+func markdownTable(node Node) string {
+	var rows []string
+	for _, child := range node.Children {
+		if child.Type != notionapi.BlockTypeTableRow || child.TableRow == nil {
+			continue
+		}
+		cells := make([]string, node.Table.TableWidth)
+		for i, cell := range child.TableRow.Cells {
+			if i < len(cells) {
+				cells[i] = markdownCellEscaper.Replace(markdownRichText(cell))
+			}
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+		if len(rows) == 1 {
+			rows = append(rows, "|"+strings.Repeat(" --- |", node.Table.TableWidth))
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+func markdownLink(text, url string) string {
+	if len(text) == 0 {
+		text = url
+	}
+	return "[" + markdownEscaper.Replace(text) + "](" + url + ")"
+}
+
+// codeFence returns a fence longer than any run of backticks in code.
+func codeFence(code string) string {
+	var longest, run int
+	for _, r := range code {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// withChildren appends the rendered children below text, indented so that
+// they nest inside it.
+func withChildren(text, children, indent string) string {
+	if len(children) == 0 {
+		return text
+	}
+	if len(text) == 0 {
+		return prefixLines(children, indent)
+	}
+	return text + "\n\n" + prefixLines(children, indent)
+}
+
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if len(line) == 0 {
+			lines[i] = strings.TrimRightFunc(prefix, unicode.IsSpace)
+			continue
+		}
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func markdownRichText(texts []notionapi.RichText) string {
+	var sb strings.Builder
+	for _, text := range texts {
+		if text.Type == notionapi.RichTextTypeEquation && text.Equation != nil {
+			sb.WriteString("$" + text.Equation.Expression + "$")
+			continue
+		}
+
+		content := textContent(text)
+		annotations := text.Annotations
+		if annotations == nil {
+			annotations = &notionapi.Annotations{}
+		}
+
+		if annotations.Code {
+			content = "`" + content + "`"
+		} else {
+			content = markdownEscaper.Replace(content)
+		}
+		content = wrapMarkdown(content, annotations)
+
+		if url := textLink(text); len(url) > 0 {
+			content = "[" + content + "](" + url + ")"
+		}
+		sb.WriteString(strings.ReplaceAll(content, "\n", "\\\n"))
+	}
+	return sb.String()
+}
+
+// wrapMarkdown applies the emphasis of annotations to content, keeping the
+// surrounding whitespace outside of the markers as CommonMark requires.
+func wrapMarkdown(content string, annotations *notionapi.Annotations) string {
+	trimmed := strings.TrimSpace(content)
+	if len(trimmed) == 0 {
+		return content
+	}
+	start := strings.Index(content, trimmed)
+	lead, trail := content[:start], content[start+len(trimmed):]
+
+	if annotations.Bold {
+		trimmed = "**" + trimmed + "**"
+	}
+	if annotations.Italic {
+		trimmed = "*" + trimmed + "*"
+	}
+	if annotations.Strikethrough {
+		trimmed = "~~" + trimmed + "~~"
+	}
+	if annotations.Underline {
+		trimmed = "<u>" + trimmed + "</u>"
+	}
+
+	return lead + trimmed + trail
+}
@@ -0,0 +1,356 @@
+package renderer
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"notionsync/pkg/notionapi"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// maxTextLength is the largest content a single rich text object may hold,
+// in UTF-16 code units as the API counts it.
+const maxTextLength = 2000
+
+// codeLanguages maps Markdown fence info to the languages Notion accepts.
+// Languages missing here become "plain text", which the API always accepts.
+var codeLanguages = map[string]string{
+	"bash": "bash", "sh": "shell", "shell": "shell", "zsh": "shell",
+	"c": "c", "cpp": "c++", "c++": "c++", "cs": "c#", "csharp": "c#",
+	"css": "css", "diff": "diff", "docker": "docker", "dockerfile": "docker",
+	"go": "go", "golang": "go", "graphql": "graphql", "html": "html",
+	"java": "java", "javascript": "javascript", "js": "javascript", "json": "json",
+	"kotlin": "kotlin", "latex": "latex", "tex": "latex", "makefile": "makefile",
+	"markdown": "markdown", "md": "markdown", "php": "php", "powershell": "powershell",
+	"protobuf": "protobuf", "proto": "protobuf", "python": "python", "py": "python",
+	"ruby": "ruby", "rb": "ruby", "rust": "rust", "rs": "rust", "scala": "scala",
+	"sql": "sql", "swift": "swift", "toml": "toml", "typescript": "typescript",
+	"ts": "typescript", "xml": "xml", "yaml": "yaml", "yml": "yaml",
+}
+
+// ParseMarkdown converts CommonMark with GitHub flavored tables, task lists
+// and strikethrough to blocks ready for notionapi.Client.AppendBlockChildren,
+// nesting list items and quotes through their children. Rich text is split
+// to respect the length limit of the API.
+func ParseMarkdown(src []byte) []notionapi.Block {
+	parser := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions))
+	return parseBlocks(parser.Parse(src))
+}
+
+func parseBlocks(parent *blackfriday.Node) []notionapi.Block {
+	var blocks []notionapi.Block
+	for node := parent.FirstChild; node != nil; node = node.Next {
+		blocks = append(blocks, parseBlock(node)...)
+	}
+	return blocks
+}
+
+func parseBlock(node *blackfriday.Node) []notionapi.Block {
+	switch node.Type {
+	case blackfriday.Paragraph:
+		if image := onlyImage(node); image != nil {
+			return []notionapi.Block{{
+				Type: notionapi.BlockTypeImage,
+				Image: &notionapi.FileBlock{
+					Type:     notionapi.FileTypeExternal,
+					External: &notionapi.FileExternal{URL: string(image.LinkData.Destination)},
+					Caption:  parseInline(image),
+				},
+			}}
+		}
+		return []notionapi.Block{{
+			Type:      notionapi.BlockTypeParagraph,
+			Paragraph: &notionapi.RichTextBlock{RichText: parseInline(node)},
+		}}
+	case blackfriday.Heading:
+		heading := &notionapi.Heading{RichText: parseInline(node)}
+		switch node.HeadingData.Level {
+		case 1:
+			return []notionapi.Block{{Type: notionapi.BlockTypeHeading1, Heading1: heading}}
+		case 2:
+			return []notionapi.Block{{Type: notionapi.BlockTypeHeading2, Heading2: heading}}
+		}
+		return []notionapi.Block{{Type: notionapi.BlockTypeHeading3, Heading3: heading}}
+	case blackfriday.List:
+		var items []notionapi.Block
+		for item := node.FirstChild; item != nil; item = item.Next {
+			items = append(items, parseListItem(item, node.ListData.ListFlags&blackfriday.ListTypeOrdered != 0))
+		}
+		return items
+	case blackfriday.BlockQuote:
+		text, children := splitFirstParagraph(node)
+		return []notionapi.Block{{
+			Type:  notionapi.BlockTypeQuote,
+			Quote: &notionapi.RichTextBlock{RichText: text, Children: children},
+		}}
+	case blackfriday.CodeBlock:
+		lang := strings.ToLower(strings.Fields(string(node.CodeBlockData.Info) + " ")[0])
+		return []notionapi.Block{codeBlock(strings.TrimSuffix(string(node.Literal), "\n"), lang)}
+	case blackfriday.HTMLBlock:
+		return []notionapi.Block{codeBlock(strings.TrimSuffix(string(node.Literal), "\n"), "html")}
+	case blackfriday.HorizontalRule:
+		return []notionapi.Block{{Type: notionapi.BlockTypeDivider, Divider: &notionapi.Divider{}}}
+	case blackfriday.Table:
+		return []notionapi.Block{parseTable(node)}
+	}
+
+	// Anything else is inline content or a container to flatten.
+	if node.FirstChild != nil {
+		return parseBlocks(node)
+	}
+	return nil
+}
+
+func parseListItem(item *blackfriday.Node, ordered bool) notionapi.Block {
+	text, children := splitFirstParagraph(item)
+	body := notionapi.RichTextBlock{RichText: text, Children: children}
+
+	if checked, ok := taskMarker(text); ok {
+		body.RichText = trimText(text, len("[ ] "))
+		return notionapi.Block{
+			Type: notionapi.BlockTypeToDo,
+			ToDo: &notionapi.ToDo{RichTextBlock: body, Checked: notionapi.BoolPtr(checked)},
+		}
+	}
+	if ordered {
+		return notionapi.Block{Type: notionapi.BlockTypeNumberedListItem, NumberedListItem: &body}
+	}
+	return notionapi.Block{Type: notionapi.BlockTypeBulletedListItem, BulletedListItem: &body}
+}
+
+// splitFirstParagraph returns the text of the leading paragraph of a
+// container, which becomes the text of its block, and the remaining content
+// as children.
+func splitFirstParagraph(node *blackfriday.Node) ([]notionapi.RichText, []notionapi.Block) {
+	first := node.FirstChild
+	if first == nil || first.Type != blackfriday.Paragraph {
+		return []notionapi.RichText{}, parseBlocks(node)
+	}
+
+	var children []notionapi.Block
+	for child := first.Next; child != nil; child = child.Next {
+		children = append(children, parseBlock(child)...)
+	}
+	return parseInline(first), children
+}
+
+// taskMarker reports whether text starts with a GitHub task list box.
+func taskMarker(text []notionapi.RichText) (checked, ok bool) {
+	if len(text) == 0 || text[0].Text == nil {
+		return false, false
+	}
+	content := text[0].Text.Content
+	switch {
+	case strings.HasPrefix(content, "[ ] "):
+		return false, true
+	case strings.HasPrefix(content, "[x] "), strings.HasPrefix(content, "[X] "):
+		return true, true
+	}
+	return false, false
+}
+
+// trimText drops the first n bytes of the first rich text object.
+func trimText(text []notionapi.RichText, n int) []notionapi.RichText {
+	first := text[0]
+	first.Text = &notionapi.Text{Content: first.Text.Content[n:], Link: first.Text.Link}
+	if len(first.Text.Content) == 0 {
+		return text[1:]
+	}
+	return append([]notionapi.RichText{first}, text[1:]...)
+}
+
+func codeBlock(code, lang string) notionapi.Block {
+	language, ok := codeLanguages[lang]
+	if !ok {
+		language = "plain text"
+	}
+	return notionapi.Block{
+		Type: notionapi.BlockTypeCode,
+		Code: &notionapi.Code{
+			RichTextBlock: notionapi.RichTextBlock{RichText: splitText(notionapi.RichText{
+				Type: notionapi.RichTextTypeText,
+				Text: &notionapi.Text{Content: code},
+			})},
+			Language: notionapi.StringPtr(language),
+		},
+	}
+}
+
+// parseTable converts a table to a table block with a row per table row.
+// The header of the table, if any, becomes the column header.
+func parseTable(table *blackfriday.Node) notionapi.Block {
+	body := &notionapi.Table{}
+	table.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if node.Type != blackfriday.TableRow || !entering {
+			return blackfriday.GoToNext
+		}
+		var cells [][]notionapi.RichText
+		for cell := node.FirstChild; cell != nil; cell = cell.Next {
+			cells = append(cells, parseInline(cell))
+		}
+		if node.Parent.Type == blackfriday.TableHead {
+			body.HasColumnHeader = true
+		}
+		if len(cells) > body.TableWidth {
+			body.TableWidth = len(cells)
+		}
+		body.Children = append(body.Children, notionapi.Block{
+			Type:     notionapi.BlockTypeTableRow,
+			TableRow: &notionapi.TableRow{Cells: cells},
+		})
+		return blackfriday.SkipChildren
+	})
+
+	// Every row must have a cell per column.
+	for _, row := range body.Children {
+		for len(row.TableRow.Cells) < body.TableWidth {
+			row.TableRow.Cells = append(row.TableRow.Cells, []notionapi.RichText{})
+		}
+	}
+	return notionapi.Block{Type: notionapi.BlockTypeTable, Table: body}
+}
+
+// onlyImage returns the image a paragraph consists of, if any.
+func onlyImage(paragraph *blackfriday.Node) *blackfriday.Node {
+	var image *blackfriday.Node
+	for child := paragraph.FirstChild; child != nil; child = child.Next {
+		switch {
+		// Blackfriday surrounds inline nodes with empty text.
+		case child.Type == blackfriday.Text && strings.TrimSpace(string(child.Literal)) == "":
+		case child.Type == blackfriday.Image && image == nil:
+			image = child
+		default:
+			return nil
+		}
+	}
+	return image
+}
+
+// parseInline converts the inline content of node to rich text, merging
+// neighbouring runs with the same formatting.
+func parseInline(node *blackfriday.Node) []notionapi.RichText {
+	texts := []notionapi.RichText{}
+	var walk func(node *blackfriday.Node, annotations notionapi.Annotations, link string)
+	add := func(content string, annotations notionapi.Annotations, link string) {
+		if len(content) == 0 {
+			return
+		}
+		if last := len(texts) - 1; last >= 0 && sameFormat(texts[last], annotations, link) {
+			texts[last].Text.Content += content
+			return
+		}
+		text := notionapi.RichText{
+			Type: notionapi.RichTextTypeText,
+			Text: &notionapi.Text{Content: content},
+		}
+		if annotations != (notionapi.Annotations{}) {
+			a := annotations
+			text.Annotations = &a
+		}
+		if len(link) > 0 {
+			text.Text.Link = &notionapi.Link{URL: link}
+		}
+		texts = append(texts, text)
+	}
+
+	walk = func(node *blackfriday.Node, annotations notionapi.Annotations, link string) {
+		for child := node.FirstChild; child != nil; child = child.Next {
+			switch child.Type {
+			case blackfriday.Text:
+				// Soft line breaks are kept in the text by the parser.
+				add(strings.ReplaceAll(string(child.Literal), "\n", " "), annotations, link)
+			case blackfriday.Code:
+				code := annotations
+				code.Code = true
+				add(string(child.Literal), code, link)
+			case blackfriday.HTMLSpan:
+				add(string(child.Literal), annotations, link)
+			case blackfriday.Softbreak:
+				add(" ", annotations, link)
+			case blackfriday.Hardbreak:
+				add("\n", annotations, link)
+			case blackfriday.Emph:
+				emph := annotations
+				emph.Italic = true
+				walk(child, emph, link)
+			case blackfriday.Strong:
+				strong := annotations
+				strong.Bold = true
+				walk(child, strong, link)
+			case blackfriday.Del:
+				del := annotations
+				del.Strikethrough = true
+				walk(child, del, link)
+			case blackfriday.Link:
+				walk(child, annotations, string(child.LinkData.Destination))
+			case blackfriday.Image:
+				// Images inside text are kept as links to them.
+				alt := len(texts)
+				walk(child, annotations, string(child.LinkData.Destination))
+				if len(texts) == alt {
+					add(string(child.LinkData.Destination), annotations, string(child.LinkData.Destination))
+				}
+			default:
+				walk(child, annotations, link)
+			}
+		}
+	}
+	walk(node, notionapi.Annotations{}, "")
+
+	split := make([]notionapi.RichText, 0, len(texts))
+	for _, text := range texts {
+		split = append(split, splitText(text)...)
+	}
+	return split
+}
+
+func sameFormat(text notionapi.RichText, annotations notionapi.Annotations, link string) bool {
+	var a notionapi.Annotations
+	if text.Annotations != nil {
+		a = *text.Annotations
+	}
+	var l string
+	if text.Text.Link != nil {
+		l = text.Text.Link.URL
+	}
+	return a == annotations && l == link
+}
+
+// splitText splits text into rich text objects within the length limit of
+// the API, keeping the formatting of text on every part.
+func splitText(text notionapi.RichText) []notionapi.RichText {
+	content := text.Text.Content
+	if textLength(content) <= maxTextLength {
+		return []notionapi.RichText{text}
+	}
+
+	var parts []notionapi.RichText
+	for len(content) > 0 {
+		end, count := 0, 0
+		for end < len(content) {
+			r, size := utf8.DecodeRuneInString(content[end:])
+			n := utf16.RuneLen(r)
+			if count+n > maxTextLength {
+				break
+			}
+			end += size
+			count += n
+		}
+		part := text
+		part.Text = &notionapi.Text{Content: content[:end], Link: text.Text.Link}
+		parts = append(parts, part)
+		content = content[end:]
+	}
+	return parts
+}
+
+// textLength returns the length of s in UTF-16 code units.
+func textLength(s string) int {
+	var n int
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,647 @@
+package renderer_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/renderer"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// fakeFetcher serves block children from a map, one block per page to
+// exercise pagination.
+type fakeFetcher map[string][]notionapi.Block
+
+func (f fakeFetcher) FindBlockChildrenByID(_ context.Context, blockID string, query *notionapi.PaginationQuery) (notionapi.BlockChildrenResponse, error) {
+	children, ok := f[blockID]
+	if !ok {
+		return notionapi.BlockChildrenResponse{}, fmt.Errorf("block %v not found", blockID)
+	}
+
+	var i int
+	if query != nil && query.StartCursor != "" {
+		fmt.Sscan(query.StartCursor, &i)
+	}
+	if i >= len(children) {
+		return notionapi.BlockChildrenResponse{}, nil
+	}
+
+	resp := notionapi.BlockChildrenResponse{Results: children[i : i+1]}
+	if i+1 < len(children) {
+		resp.HasMore = true
+		resp.NextCursor = notionapi.StringPtr(fmt.Sprint(i + 1))
+	}
+	return resp, nil
+}
+
+func text(content string) []notionapi.RichText {
+	return []notionapi.RichText{{Type: notionapi.RichTextTypeText, PlainText: content}}
+}
+
+func TestFetchTree(t *testing.T) {
+	t.Parallel()
+
+	fetcher := fakeFetcher{
+		"page": {
+			{ID: "a", Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: text("a")}},
+			{ID: "b", Type: notionapi.BlockTypeToggle, HasChildren: true, Toggle: &notionapi.RichTextBlock{RichText: text("b")}},
+			{ID: "c", Type: notionapi.BlockTypeChildPage, HasChildren: true, ChildPage: &notionapi.ChildPage{Title: "c"}},
+		},
+		"b": {
+			{ID: "b1", Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: text("b1")}},
+			{ID: "b2", Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: text("b2")}},
+		},
+	}
+
+	nodes, err := renderer.FetchTree(context.Background(), fetcher, "page")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	var walk func(nodes []renderer.Node, depth int)
+	walk = func(nodes []renderer.Node, depth int) {
+		for _, node := range nodes {
+			got = append(got, fmt.Sprintf("%v%v", depth, node.ID))
+			walk(node.Children, depth+1)
+		}
+	}
+	walk(nodes, 0)
+
+	if diff := cmp.Diff([]string{"0a", "0b", "1b1", "1b2", "0c"}, got); diff != "" {
+		t.Fatalf("tree not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	table := renderer.Node{
+		Block: notionapi.Block{Type: notionapi.BlockTypeTable, Table: &notionapi.Table{TableWidth: 2, HasColumnHeader: true, HasRowHeader: true}},
+		Children: []renderer.Node{
+			{Block: notionapi.Block{Type: notionapi.BlockTypeTableRow, TableRow: &notionapi.TableRow{Cells: [][]notionapi.RichText{text("Task"), text("a|b")}}}},
+			{Block: notionapi.Block{Type: notionapi.BlockTypeTableRow, TableRow: &notionapi.TableRow{Cells: [][]notionapi.RichText{text("one")}}}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		nodes []renderer.Node
+		exp   string
+	}{
+		{
+			name: "annotations",
+			nodes: []renderer.Node{
+				{Block: notionapi.Block{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: []notionapi.RichText{
+					{PlainText: "bold ", Annotations: &notionapi.Annotations{Bold: true}},
+					{PlainText: "code", Annotations: &notionapi.Annotations{Code: true}},
+					{PlainText: " and a_b "},
+					{PlainText: "link", HRef: notionapi.StringPtr("https://example.com")},
+					{Type: notionapi.RichTextTypeEquation, Equation: &notionapi.Equation{Expression: "x^2"}},
+				}}}},
+			},
+			exp: "**bold** `code` and a\\_b [link](https://example.com)$x^2$",
+		},
+		{
+			name: "lists",
+			nodes: []renderer.Node{
+				{Block: notionapi.Block{Type: notionapi.BlockTypeNumberedListItem, NumberedListItem: &notionapi.RichTextBlock{RichText: text("one")}}},
+				{
+					Block: notionapi.Block{Type: notionapi.BlockTypeNumberedListItem, NumberedListItem: &notionapi.RichTextBlock{RichText: text("two")}},
+					Children: []renderer.Node{
+						{Block: notionapi.Block{Type: notionapi.BlockTypeBulletedListItem, BulletedListItem: &notionapi.RichTextBlock{RichText: text("nested")}}},
+					},
+				},
+				{Block: notionapi.Block{Type: notionapi.BlockTypeToDo, ToDo: &notionapi.ToDo{RichTextBlock: notionapi.RichTextBlock{RichText: text("done")}, Checked: notionapi.BoolPtr(true)}}},
+				{Block: notionapi.Block{Type: notionapi.BlockTypeToDo, ToDo: &notionapi.ToDo{RichTextBlock: notionapi.RichTextBlock{RichText: text("open")}}}},
+			},
+			exp: "1. one\n2. two\n\n   - nested\n\n- [x] done\n- [ ] open",
+		},
+		{
+			name: "blocks",
+			nodes: []renderer.Node{
+				{Block: notionapi.Block{Type: notionapi.BlockTypeHeading2, Heading2: &notionapi.Heading{RichText: text("Title")}}},
+				{
+					Block: notionapi.Block{Type: notionapi.BlockTypeCallout, Callout: &notionapi.Callout{
+						RichTextBlock: notionapi.RichTextBlock{RichText: text("note")},
+						Icon:          &notionapi.Icon{Type: notionapi.IconTypeEmoji, Emoji: notionapi.StringPtr("💡")},
+					}},
+				},
+				{Block: notionapi.Block{Type: notionapi.BlockTypeCode, Code: &notionapi.Code{
+					RichTextBlock: notionapi.RichTextBlock{RichText: text("fmt.Println()")},
+					Language:      notionapi.StringPtr("go"),
+				}}},
+				{Block: notionapi.Block{Type: notionapi.BlockTypeEquation, Equation: &notionapi.Equation{Expression: "e=mc^2"}}},
+				{Block: notionapi.Block{Type: notionapi.BlockTypeDivider, Divider: &notionapi.Divider{}}},
+				{
+					Block: notionapi.Block{Type: notionapi.BlockTypeToggle, Toggle: &notionapi.RichTextBlock{RichText: text("more")}},
+					Children: []renderer.Node{
+						{Block: notionapi.Block{Type: notionapi.BlockTypeQuote, Quote: &notionapi.RichTextBlock{RichText: text("quoted")}}},
+					},
+				},
+			},
+			exp: "## Title\n\n> 💡 note\n\n```go\nfmt.Println()\n```\n\n$$\ne=mc^2\n$$\n\n---\n\n" +
+				"<details>\n<summary>more</summary>\n\n> quoted\n\n</details>",
+		},
+		{
+			name: "columns",
+			nodes: []renderer.Node{
+				{
+					Block: notionapi.Block{Type: notionapi.BlockTypeColumnList, ColumnList: &notionapi.ColumnList{}},
+					Children: []renderer.Node{
+						{
+							Block:    notionapi.Block{Type: notionapi.BlockTypeColumn, Column: &notionapi.Column{}},
+							Children: []renderer.Node{{Block: notionapi.Block{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: text("left")}}}},
+						},
+						{
+							Block:    notionapi.Block{Type: notionapi.BlockTypeColumn, Column: &notionapi.Column{}},
+							Children: []renderer.Node{{Block: notionapi.Block{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: text("right")}}}},
+						},
+					},
+				},
+			},
+			exp: "left\n\nright",
+		},
+		{
+			name:  "table",
+			nodes: []renderer.Node{table},
+			exp:   "| Task | a\\|b |\n| --- | --- |\n| one |  |",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.exp, renderer.Markdown(tt.nodes)); diff != "" {
+				t.Fatalf("markdown not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestHTML(t *testing.T) {
+	t.Parallel()
+
+	table := renderer.Node{
+		Block: notionapi.Block{Type: notionapi.BlockTypeTable, Table: &notionapi.Table{TableWidth: 2, HasColumnHeader: true, HasRowHeader: true}},
+		Children: []renderer.Node{
+			{Block: notionapi.Block{Type: notionapi.BlockTypeTableRow, TableRow: &notionapi.TableRow{Cells: [][]notionapi.RichText{text("Task"), text("a|b")}}}},
+			{Block: notionapi.Block{Type: notionapi.BlockTypeTableRow, TableRow: &notionapi.TableRow{Cells: [][]notionapi.RichText{text("one")}}}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		nodes []renderer.Node
+		exp   string
+	}{
+		{
+			name: "annotations",
+			nodes: []renderer.Node{
+				{Block: notionapi.Block{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: []notionapi.RichText{
+					{PlainText: "<b>", Annotations: &notionapi.Annotations{Bold: true, Color: notionapi.ColorRed}},
+					{PlainText: " link", HRef: notionapi.StringPtr("https://example.com/?a=1&b=2")},
+				}}}},
+			},
+			exp: `<p><span class="notion-red"><strong>&lt;b&gt;</strong></span><a href="https://example.com/?a=1&amp;b=2"> link</a></p>` + "\n",
+		},
+		{
+			name: "lists",
+			nodes: []renderer.Node{
+				{Block: notionapi.Block{Type: notionapi.BlockTypeBulletedListItem, BulletedListItem: &notionapi.RichTextBlock{RichText: text("a")}}},
+				{
+					Block: notionapi.Block{Type: notionapi.BlockTypeBulletedListItem, BulletedListItem: &notionapi.RichTextBlock{RichText: text("b")}},
+					Children: []renderer.Node{
+						{Block: notionapi.Block{Type: notionapi.BlockTypeNumberedListItem, NumberedListItem: &notionapi.RichTextBlock{RichText: text("c")}}},
+					},
+				},
+				{Block: notionapi.Block{Type: notionapi.BlockTypeToDo, ToDo: &notionapi.ToDo{RichTextBlock: notionapi.RichTextBlock{RichText: text("d")}, Checked: notionapi.BoolPtr(true)}}},
+			},
+			exp: "<ul>\n<li>a</li>\n<li>b\n<ol>\n<li>c</li>\n</ol>\n</li>\n</ul>\n" +
+				`<ul class="notion-to-do">` + "\n" + `<li><input type="checkbox" disabled checked> d</li>` + "\n</ul>\n",
+		},
+		{
+			name: "code",
+			nodes: []renderer.Node{
+				{Block: notionapi.Block{Type: notionapi.BlockTypeCode, Code: &notionapi.Code{
+					RichTextBlock: notionapi.RichTextBlock{RichText: text("a < b")},
+					Language:      notionapi.StringPtr("go"),
+				}}},
+			},
+			exp: `<pre><code class="language-go">a &lt; b</code></pre>` + "\n",
+		},
+		{
+			name:  "table",
+			nodes: []renderer.Node{table},
+			exp: "<table>\n<thead>\n<tr><th>Task</th><th>a|b</th></tr>\n</thead>\n" +
+				"<tr><th>one</th><td></td></tr>\n</table>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.exp, renderer.HTML(tt.nodes)); diff != "" {
+				t.Fatalf("html not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestParseMarkdown(t *testing.T) {
+	t.Parallel()
+
+	richText := func(content string, annotations *notionapi.Annotations) notionapi.RichText {
+		return notionapi.RichText{Type: notionapi.RichTextTypeText, Text: &notionapi.Text{Content: content}, Annotations: annotations}
+	}
+	long := strings.Repeat("é", 2500)
+	// Emoji outside the Basic Multilingual Plane count as two UTF-16 code
+	// units each.
+	emoji := strings.Repeat("😀", 1500)
+
+	tests := []struct {
+		name string
+		src  string
+		exp  []notionapi.Block
+	}{
+		{
+			name: "annotations",
+			src:  "# Title\n\nSome **bold _both_** and `code`,\nsoft [link](https://example.com).\n",
+			exp: []notionapi.Block{
+				{Type: notionapi.BlockTypeHeading1, Heading1: &notionapi.Heading{RichText: []notionapi.RichText{richText("Title", nil)}}},
+				{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: []notionapi.RichText{
+					richText("Some ", nil),
+					richText("bold ", &notionapi.Annotations{Bold: true}),
+					richText("both", &notionapi.Annotations{Bold: true, Italic: true}),
+					richText(" and ", nil),
+					richText("code", &notionapi.Annotations{Code: true}),
+					richText(", soft ", nil),
+					{Type: notionapi.RichTextTypeText, Text: &notionapi.Text{Content: "link", Link: &notionapi.Link{URL: "https://example.com"}}},
+					richText(".", nil),
+				}}},
+			},
+		},
+		{
+			name: "lists",
+			src:  "- [x] done\n- [ ] open\n  1. first\n  2. second\n- plain\n",
+			exp: []notionapi.Block{
+				{Type: notionapi.BlockTypeToDo, ToDo: &notionapi.ToDo{
+					RichTextBlock: notionapi.RichTextBlock{RichText: []notionapi.RichText{richText("done", nil)}},
+					Checked:       notionapi.BoolPtr(true),
+				}},
+				{Type: notionapi.BlockTypeToDo, ToDo: &notionapi.ToDo{
+					RichTextBlock: notionapi.RichTextBlock{
+						RichText: []notionapi.RichText{richText("open", nil)},
+						Children: []notionapi.Block{
+							{Type: notionapi.BlockTypeNumberedListItem, NumberedListItem: &notionapi.RichTextBlock{RichText: []notionapi.RichText{richText("first", nil)}}},
+							{Type: notionapi.BlockTypeNumberedListItem, NumberedListItem: &notionapi.RichTextBlock{RichText: []notionapi.RichText{richText("second", nil)}}},
+						},
+					},
+					Checked: notionapi.BoolPtr(false),
+				}},
+				{Type: notionapi.BlockTypeBulletedListItem, BulletedListItem: &notionapi.RichTextBlock{RichText: []notionapi.RichText{richText("plain", nil)}}},
+			},
+		},
+		{
+			name: "blocks",
+			src:  "> quoted\n\n```js\nlet a;\n```\n\n---\n\n| a | b |\n|---|---|\n| 1 |\n",
+			exp: []notionapi.Block{
+				{Type: notionapi.BlockTypeQuote, Quote: &notionapi.RichTextBlock{RichText: []notionapi.RichText{richText("quoted", nil)}}},
+				{Type: notionapi.BlockTypeCode, Code: &notionapi.Code{
+					RichTextBlock: notionapi.RichTextBlock{RichText: []notionapi.RichText{richText("let a;", nil)}},
+					Language:      notionapi.StringPtr("javascript"),
+				}},
+				{Type: notionapi.BlockTypeDivider, Divider: &notionapi.Divider{}},
+				{Type: notionapi.BlockTypeTable, Table: &notionapi.Table{
+					TableWidth:      2,
+					HasColumnHeader: true,
+					Children: []notionapi.Block{
+						{Type: notionapi.BlockTypeTableRow, TableRow: &notionapi.TableRow{Cells: [][]notionapi.RichText{
+							{richText("a", nil)}, {richText("b", nil)},
+						}}},
+						{Type: notionapi.BlockTypeTableRow, TableRow: &notionapi.TableRow{Cells: [][]notionapi.RichText{
+							{richText("1", nil)}, {},
+						}}},
+					},
+				}},
+			},
+		},
+		{
+			name: "images",
+			src:  "![Chart](chart.png)\n\nInline ![icon](icon.png) image.\n",
+			exp: []notionapi.Block{
+				{Type: notionapi.BlockTypeImage, Image: &notionapi.FileBlock{
+					Type:     notionapi.FileTypeExternal,
+					External: &notionapi.FileExternal{URL: "chart.png"},
+					Caption:  []notionapi.RichText{richText("Chart", nil)},
+				}},
+				{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: []notionapi.RichText{
+					richText("Inline ", nil),
+					{Type: notionapi.RichTextTypeText, Text: &notionapi.Text{Content: "icon", Link: &notionapi.Link{URL: "icon.png"}}},
+					richText(" image.", nil),
+				}}},
+			},
+		},
+		{
+			name: "long text is split",
+			src:  long + "\n",
+			exp: []notionapi.Block{
+				{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: []notionapi.RichText{
+					richText(long[:2*2000], nil),
+					richText(long[2*2000:], nil),
+				}}},
+			},
+		},
+		{
+			name: "long text is split in UTF-16 code units",
+			src:  emoji + "\n",
+			exp: []notionapi.Block{
+				{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: []notionapi.RichText{
+					richText(emoji[:4*1000], nil),
+					richText(emoji[4*1000:], nil),
+				}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.exp, renderer.ParseMarkdown([]byte(tt.src))); diff != "" {
+				t.Fatalf("blocks not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+// fakeAppender records append requests and creates blocks with sequential IDs.
+// It rejects requests over the limits of the API, as the API does.
+type fakeAppender struct {
+	requests []string
+	created  int
+}
+
+func (f *fakeAppender) AppendBlockChildren(_ context.Context, blockID string, children []notionapi.Block) (notionapi.BlockChildrenResponse, error) {
+	if total, err := checkLimits(children, 0); err != nil {
+		return notionapi.BlockChildrenResponse{}, err
+	} else if total > 1000 {
+		return notionapi.BlockChildrenResponse{}, fmt.Errorf("request holds %v blocks", total)
+	}
+
+	var resp notionapi.BlockChildrenResponse
+	for _, child := range children {
+		f.created++
+		child.ID = fmt.Sprint("block-", f.created)
+		resp.Results = append(resp.Results, child)
+	}
+	f.requests = append(f.requests, fmt.Sprintf("%v:%v", blockID, len(children)))
+	return resp, nil
+}
+
+// checkLimits returns the number of blocks, counting nested children, or an
+// error if a children array holds more than 100 blocks or children nest more
+// than two levels deep.
+func checkLimits(blocks []notionapi.Block, level int) (int, error) {
+	if len(blocks) > 100 {
+		return 0, fmt.Errorf("children array holds %v blocks", len(blocks))
+	}
+	if level > 2 && len(blocks) > 0 {
+		return 0, fmt.Errorf("children nest %v levels deep", level)
+	}
+	total := len(blocks)
+	for _, block := range blocks {
+		var children []notionapi.Block
+		switch {
+		case block.BulletedListItem != nil:
+			children = block.BulletedListItem.Children
+		case block.Paragraph != nil:
+			children = block.Paragraph.Children
+		}
+		n, err := checkLimits(children, level+1)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func TestAppendBlocks(t *testing.T) {
+	t.Parallel()
+
+	paragraph := notionapi.Block{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: []notionapi.RichText{}}}
+	paragraphs := func(n int) []notionapi.Block {
+		blocks := make([]notionapi.Block, n)
+		for i := range blocks {
+			blocks[i] = paragraph
+		}
+		return blocks
+	}
+	item := func(children []notionapi.Block) notionapi.Block {
+		return notionapi.Block{Type: notionapi.BlockTypeBulletedListItem, BulletedListItem: &notionapi.RichTextBlock{
+			Text:     []notionapi.RichText{},
+			Children: children,
+		}}
+	}
+
+	blocks := append(paragraphs(149),
+		// Sent along with its parent.
+		item(paragraphs(2)),
+		// Too many children, appended to the created block.
+		item(paragraphs(101)),
+		// Nested two levels deep, sent along with its parent.
+		item([]notionapi.Block{item(paragraphs(1))}),
+		// Nested three levels deep, children appended to the created block.
+		item([]notionapi.Block{item([]notionapi.Block{item(paragraphs(1))})}),
+	)
+
+	appender := &fakeAppender{}
+	if err := renderer.AppendBlocks(context.Background(), appender, "page", blocks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []string{"page:100", "page:53", "block-151:100", "block-151:1", "block-153:1"}
+	if diff := cmp.Diff(exp, appender.requests); diff != "" {
+		t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestAppendBlocksLimits(t *testing.T) {
+	t.Parallel()
+
+	paragraph := notionapi.Block{Type: notionapi.BlockTypeParagraph, Paragraph: &notionapi.RichTextBlock{RichText: []notionapi.RichText{}}}
+	item := func(children []notionapi.Block) notionapi.Block {
+		return notionapi.Block{Type: notionapi.BlockTypeBulletedListItem, BulletedListItem: &notionapi.RichTextBlock{
+			Text:     []notionapi.RichText{},
+			Children: children,
+		}}
+	}
+	repeat := func(block notionapi.Block, n int) []notionapi.Block {
+		blocks := make([]notionapi.Block, n)
+		for i := range blocks {
+			blocks[i] = block
+		}
+		return blocks
+	}
+
+	// Each item carries 100 children: 9 of them fit in a request of 1000
+	// blocks, the children of the others are appended to them afterwards.
+	wide := repeat(item(repeat(paragraph, 100)), 20)
+	// Items nested six levels deep: the children of an item are sent along
+	// with it once they nest at most two levels deep.
+	deep := paragraph
+	for i := 0; i < 6; i++ {
+		deep = item([]notionapi.Block{deep})
+	}
+
+	appender := &fakeAppender{}
+	if err := renderer.AppendBlocks(context.Background(), appender, "page", append(wide, deep)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []string{"page:21"}
+	for i := 10; i <= 20; i++ {
+		exp = append(exp, fmt.Sprintf("block-%v:100", i))
+	}
+	exp = append(exp, "block-21:1", "block-1122:1", "block-1123:1", "block-1124:1")
+	if diff := cmp.Diff(exp, appender.requests); diff != "" {
+		t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestCreatable(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	nodes := []renderer.Node{
+		{
+			Block: notionapi.Block{
+				ID: "a", CreatedTime: &now, HasChildren: true,
+				Type:   notionapi.BlockTypeToggle,
+				Toggle: &notionapi.RichTextBlock{RichText: text("a")},
+			},
+			Children: []renderer.Node{
+				{Block: notionapi.Block{ID: "b", Type: notionapi.BlockTypeChildPage, ChildPage: &notionapi.ChildPage{Title: "b"}}},
+				{Block: notionapi.Block{ID: "c", Type: notionapi.BlockTypeImage, Image: &notionapi.FileBlock{
+					Type: notionapi.FileTypeFile,
+					File: &notionapi.FileFile{URL: "https://example.com/hosted.png"},
+				}}},
+				{Block: notionapi.Block{ID: "d", LastEditedTime: &now, Type: notionapi.BlockTypeImage, Image: &notionapi.FileBlock{
+					Type:     notionapi.FileTypeExternal,
+					External: &notionapi.FileExternal{URL: "https://example.com/external.png"},
+				}}},
+			},
+		},
+	}
+
+	exp := []notionapi.Block{
+		{
+			Type: notionapi.BlockTypeToggle,
+			Toggle: &notionapi.RichTextBlock{
+				RichText: text("a"),
+				Children: []notionapi.Block{
+					{Type: notionapi.BlockTypeImage, Image: &notionapi.FileBlock{
+						Type:     notionapi.FileTypeExternal,
+						External: &notionapi.FileExternal{URL: "https://example.com/external.png"},
+					}},
+				},
+			},
+		},
+	}
+
+	nested := renderer.NestChildren(nodes)
+	if diff := cmp.Diff(exp, renderer.Creatable(nested)); diff != "" {
+		t.Fatalf("blocks not equal (-exp, +got):\n%v", diff)
+	}
+	if len(nested[0].Toggle.Children) != 3 || nested[0].ID != "a" {
+		t.Fatalf("nested blocks were changed: %+v", nested[0])
+	}
+}
+
+// fakeUploader records the contents of uploaded files and returns uploads
+// with sequential IDs.
+type fakeUploader struct {
+	uploads []string
+}
+
+func (f *fakeUploader) UploadFile(_ context.Context, filename, _ string, r io.Reader, size int64) (notionapi.FileUpload, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return notionapi.FileUpload{}, err
+	}
+	f.uploads = append(f.uploads, fmt.Sprintf("%v:%v:%s", filename, size, data))
+	return notionapi.FileUpload{ID: fmt.Sprint("upload-", len(f.uploads))}, nil
+}
+
+func TestUploadLocalFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.png"), []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "img"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "img", "b c.png"), []byte("bc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := renderer.ParseMarkdown([]byte("![a](a.png)\n\n> quote\n>\n> ![b](img/b%20c.png)\n\n![c](https://example.com/c.png)\n"))
+
+	uploader := &fakeUploader{}
+	if err := renderer.UploadLocalFiles(context.Background(), uploader, blocks, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"a.png:1:a", "b c.png:2:bc"}, uploader.uploads); diff != "" {
+		t.Fatalf("uploads not equal (-exp, +got):\n%v", diff)
+	}
+	caption := func(content string) []notionapi.RichText {
+		return []notionapi.RichText{{Type: notionapi.RichTextTypeText, Text: &notionapi.Text{Content: content}}}
+	}
+	exp := []*notionapi.FileBlock{
+		{Type: notionapi.FileTypeFileUpload, FileUpload: &notionapi.FileUploadReference{ID: "upload-1"}, Caption: caption("a")},
+		{Type: notionapi.FileTypeFileUpload, FileUpload: &notionapi.FileUploadReference{ID: "upload-2"}, Caption: caption("b")},
+		{Type: notionapi.FileTypeExternal, External: &notionapi.FileExternal{URL: "https://example.com/c.png"}, Caption: caption("c")},
+	}
+	got := []*notionapi.FileBlock{blocks[0].Image, blocks[1].Quote.Children[0].Image, blocks[2].Image}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("files not equal (-exp, +got):\n%v", diff)
+	}
+
+	missing := renderer.ParseMarkdown([]byte("![d](missing.png)\n"))
+	if err := renderer.UploadLocalFiles(context.Background(), uploader, missing, dir); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+
+	// Files outside of dir are only uploaded when allowed.
+	outside := filepath.Join(dir, "img", "b c.png")
+	for _, src := range []string{"![e](../b%20c.png)\n", "![f](" + filepath.ToSlash(outside) + ")\n"} {
+		blocks := renderer.ParseMarkdown([]byte(src))
+		imgDir := filepath.Join(dir, "img", "sub")
+		if err := renderer.UploadLocalFiles(context.Background(), uploader, blocks, imgDir); err == nil {
+			t.Fatalf("expected error for a file outside of the directory: %q", src)
+		}
+		if err := renderer.UploadLocalFiles(context.Background(), uploader, blocks, imgDir, renderer.AllowOutsideDir()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(uploader.uploads) != 4 {
+		t.Fatalf("expected 4 uploads, got %v", uploader.uploads)
+	}
+}
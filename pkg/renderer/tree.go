@@ -0,0 +1,222 @@
+// Package renderer converts Notion pages and block trees to Markdown and HTML,
+// and Markdown back to blocks.
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"notionsync/pkg/notionapi"
+)
+
+// maxPageSize is the largest page of block children the API returns.
+const maxPageSize = 100
+
+// BlockFetcher fetches the children of a block, as notionapi.Client does.
+type BlockFetcher interface {
+	FindBlockChildrenByID(ctx context.Context, blockID string, query *notionapi.PaginationQuery) (notionapi.BlockChildrenResponse, error)
+}
+
+// Node is a block together with its children.
+type Node struct {
+	notionapi.Block
+	Children []Node
+}
+
+// FetchTree returns the blocks below blockID, which may be a page ID, with
+// their children fetched recursively. Child pages and databases are not
+// descended into, as they are pages of their own.
+func FetchTree(ctx context.Context, fetcher BlockFetcher, blockID string) ([]Node, error) {
+	var (
+		nodes []Node
+		query = &notionapi.PaginationQuery{PageSize: maxPageSize}
+	)
+
+	for {
+		resp, err := fetcher.FindBlockChildrenByID(ctx, blockID, query)
+		if err != nil {
+			return nil, fmt.Errorf("renderer: fetch children of block %v: %w", blockID, err)
+		}
+
+		for _, block := range resp.Results {
+			node := Node{Block: block}
+			if block.HasChildren && block.Type != notionapi.BlockTypeChildPage && block.Type != notionapi.BlockTypeChildDatabase {
+				if node.Children, err = FetchTree(ctx, fetcher, block.ID); err != nil {
+					return nil, err
+				}
+			}
+			nodes = append(nodes, node)
+		}
+
+		if !resp.HasMore || resp.NextCursor == nil {
+			return nodes, nil
+		}
+		query.StartCursor = *resp.NextCursor
+	}
+}
+
+// PageTitle returns the plain text title of a page.
+func PageTitle(page notionapi.Page) string {
+	switch props := page.Properties.(type) {
+	case notionapi.PageProperties:
+		return plainText(props.Title.Title)
+	case notionapi.DatabasePageProperties:
+		for _, prop := range props {
+			if prop.Type == notionapi.DBPropTypeTitle {
+				return plainText(prop.Title)
+			}
+		}
+	}
+	return ""
+}
+
+// pageURL links to a page or database by its ID.
+func pageURL(id string) string {
+	return "https://www.notion.so/" + strings.ReplaceAll(id, "-", "")
+}
+
+func plainText(texts []notionapi.RichText) string {
+	var sb strings.Builder
+	for _, text := range texts {
+		sb.WriteString(textContent(text))
+	}
+	return sb.String()
+}
+
+// textContent returns the text of rich text read from the API, which has its
+// plain text set, or built locally, which may only have its content set.
+func textContent(text notionapi.RichText) string {
+	switch {
+	case len(text.PlainText) > 0:
+		return text.PlainText
+	case text.Text != nil:
+		return text.Text.Content
+	case text.Equation != nil:
+		return text.Equation.Expression
+	}
+	return ""
+}
+
+// textLink returns the URL rich text links to, if any.
+func textLink(text notionapi.RichText) string {
+	switch {
+	case text.HRef != nil:
+		return *text.HRef
+	case text.Text != nil && text.Text.Link != nil:
+		return text.Text.Link.URL
+	case text.Mention != nil && text.Mention.Page != nil:
+		return pageURL(text.Mention.Page.ID)
+	case text.Mention != nil && text.Mention.Database != nil:
+		return pageURL(text.Mention.Database.ID)
+	}
+	return ""
+}
+
+// fileURL returns the URL of a file block and the text to show for it.
+func fileURL(file *notionapi.FileBlock) (url, caption string) {
+	switch {
+	case file.File != nil:
+		url = file.File.URL
+	case file.External != nil:
+		url = file.External.URL
+	}
+	return url, plainText(file.Caption)
+}
+
+// blockText returns the rich text of the blocks that have one.
+func blockText(block notionapi.Block) []notionapi.RichText {
+	switch block.Type {
+	case notionapi.BlockTypeParagraph:
+		return block.Paragraph.RichText
+	case notionapi.BlockTypeHeading1:
+		return block.Heading1.RichText
+	case notionapi.BlockTypeHeading2:
+		return block.Heading2.RichText
+	case notionapi.BlockTypeHeading3:
+		return block.Heading3.RichText
+	case notionapi.BlockTypeBulletedListItem:
+		return block.BulletedListItem.RichText
+	case notionapi.BlockTypeNumberedListItem:
+		return block.NumberedListItem.RichText
+	case notionapi.BlockTypeToDo:
+		return block.ToDo.RichText
+	case notionapi.BlockTypeToggle:
+		return block.Toggle.RichText
+	case notionapi.BlockTypeCallout:
+		return block.Callout.RichText
+	case notionapi.BlockTypeQuote:
+		return block.Quote.RichText
+	case notionapi.BlockTypeCode:
+		return block.Code.RichText
+	case notionapi.BlockTypeTemplate:
+		return block.Template.RichText
+	}
+	return nil
+}
+
+// hasValue reports whether the field of block for its type is set, so that
+// renderers can index it safely.
+func hasValue(block notionapi.Block) bool {
+	switch block.Type {
+	case notionapi.BlockTypeParagraph:
+		return block.Paragraph != nil
+	case notionapi.BlockTypeHeading1:
+		return block.Heading1 != nil
+	case notionapi.BlockTypeHeading2:
+		return block.Heading2 != nil
+	case notionapi.BlockTypeHeading3:
+		return block.Heading3 != nil
+	case notionapi.BlockTypeBulletedListItem:
+		return block.BulletedListItem != nil
+	case notionapi.BlockTypeNumberedListItem:
+		return block.NumberedListItem != nil
+	case notionapi.BlockTypeToDo:
+		return block.ToDo != nil
+	case notionapi.BlockTypeToggle:
+		return block.Toggle != nil
+	case notionapi.BlockTypeChildPage:
+		return block.ChildPage != nil
+	case notionapi.BlockTypeChildDatabase:
+		return block.ChildDatabase != nil
+	case notionapi.BlockTypeCallout:
+		return block.Callout != nil
+	case notionapi.BlockTypeQuote:
+		return block.Quote != nil
+	case notionapi.BlockTypeCode:
+		return block.Code != nil
+	case notionapi.BlockTypeEmbed:
+		return block.Embed != nil
+	case notionapi.BlockTypeImage:
+		return block.Image != nil
+	case notionapi.BlockTypeVideo:
+		return block.Video != nil
+	case notionapi.BlockTypeAudio:
+		return block.Audio != nil
+	case notionapi.BlockTypeFile:
+		return block.File != nil
+	case notionapi.BlockTypePDF:
+		return block.PDF != nil
+	case notionapi.BlockTypeBookmark:
+		return block.Bookmark != nil
+	case notionapi.BlockTypeEquation:
+		return block.Equation != nil
+	case notionapi.BlockTypeLinkPreview:
+		return block.LinkPreview != nil
+	case notionapi.BlockTypeLinkToPage:
+		return block.LinkToPage != nil
+	case notionapi.BlockTypeTemplate:
+		return block.Template != nil
+	case notionapi.BlockTypeColumnList:
+		return block.ColumnList != nil
+	case notionapi.BlockTypeColumn:
+		return block.Column != nil
+	case notionapi.BlockTypeSyncedBlock:
+		return block.SyncedBlock != nil
+	case notionapi.BlockTypeTable:
+		return block.Table != nil
+	case notionapi.BlockTypeTableRow:
+		return block.TableRow != nil
+	}
+	return true
+}
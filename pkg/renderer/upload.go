@@ -0,0 +1,125 @@
+package renderer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"notionsync/pkg/notionapi"
+)
+
+// FileUploader uploads files to Notion, as notionapi.Client does.
+type FileUploader interface {
+	UploadFile(ctx context.Context, filename, contentType string, r io.Reader, size int64) (notionapi.FileUpload, error)
+}
+
+// UploadOption changes how UploadLocalFiles treats local paths.
+type UploadOption func(*uploadOptions)
+
+type uploadOptions struct {
+	allowOutsideDir bool
+}
+
+// AllowOutsideDir lets UploadLocalFiles upload files outside its directory,
+// by absolute paths or paths leading out of it with "..".
+func AllowOutsideDir() UploadOption {
+	return func(o *uploadOptions) {
+		o.allowOutsideDir = true
+	}
+}
+
+// UploadLocalFiles uploads the files of file blocks, such as the images
+// ParseMarkdown returns, whose external URL is a local path, and changes the
+// blocks to refer to the uploads instead. Relative paths are resolved against
+// dir, and paths outside of dir are an error unless AllowOutsideDir is given.
+// Blocks are changed in place, children included; other URLs are left as they
+// are.
+func UploadLocalFiles(ctx context.Context, uploader FileUploader, blocks []notionapi.Block, dir string, opts ...UploadOption) error {
+	var o uploadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return uploadLocalFiles(ctx, uploader, blocks, dir, o)
+}
+
+func uploadLocalFiles(ctx context.Context, uploader FileUploader, blocks []notionapi.Block, dir string, o uploadOptions) error {
+	for i := range blocks {
+		block := &blocks[i]
+		if children := blockChildren(block); children != nil {
+			if err := uploadLocalFiles(ctx, uploader, *children, dir, o); err != nil {
+				return err
+			}
+		}
+
+		file := fileBlock(*block)
+		if block.Type == notionapi.BlockTypeImage {
+			file = block.Image
+		}
+		if file == nil || file.External == nil {
+			continue
+		}
+		path, ok := localPath(file.External.URL, dir)
+		if !ok {
+			continue
+		}
+		if !o.allowOutsideDir && !withinDir(path, dir) {
+			return fmt.Errorf("renderer: upload %v: outside of %v", path, dir)
+		}
+
+		upload, err := uploadLocalFile(ctx, uploader, path)
+		if err != nil {
+			return fmt.Errorf("renderer: upload %v: %w", path, err)
+		}
+		file.Type = notionapi.FileTypeFileUpload
+		file.External = nil
+		file.FileUpload = &notionapi.FileUploadReference{ID: upload.ID}
+	}
+	return nil
+}
+
+func uploadLocalFile(ctx context.Context, uploader FileUploader, path string) (notionapi.FileUpload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return notionapi.FileUpload{}, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return notionapi.FileUpload{}, err
+	}
+	return uploader.UploadFile(ctx, filepath.Base(path), "", f, info.Size())
+}
+
+// localPath returns the file a URL without a host refers to, either a path
+// or a file URL.
+func localPath(rawURL, dir string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != "" || (u.Scheme != "" && u.Scheme != "file") || u.Path == "" {
+		return "", false
+	}
+
+	path := filepath.FromSlash(u.Path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, true
+}
+
+// withinDir reports whether path is dir or below it.
+func withinDir(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -5,27 +5,16 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
 
 	oauth "golang.org/x/oauth2"
 )
 
-func GetToken(clientID, clientSecret string) (*oauth.Token, error) {
-	const authURL = "https://login.microsoftonline.com/common/oauth2/v2.0/authorize"
-	const tokenURL = "https://login.microsoftonline.com/common/oauth2/v2.0/token"
-	const redirectURL = "https://login.microsoftonline.com/common/oauth2/nativeclient"
-
-	authConfig := oauth.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		Endpoint:     oauth.Endpoint{AuthURL: authURL, TokenURL: tokenURL},
-		RedirectURL:  redirectURL,
-		Scopes: []string{
-			"offline_access",
-			"Tasks.ReadWrite",
-		},
-	}
+// GetToken asks for an authorization code on the console and exchanges it for
+// a token, with the login endpoint and http.Client of opts.
+func GetToken(clientID, clientSecret string, opts ...ClientOption) (*oauth.Token, error) {
+	options := newClientOptions(opts)
+	authConfig := options.authConfig(clientID, clientSecret)
 	url := authConfig.AuthCodeURL("state", oauth.AccessTypeOffline)
 	log.Println("go to the next link : ")
 	log.Println(url)
@@ -40,7 +29,7 @@ func GetToken(clientID, clientSecret string) (*oauth.Token, error) {
 
 	log.Println(code)
 
-	ctx := context.WithValue(context.TODO(), oauth.HTTPClient, &http.Client{})
+	ctx := context.WithValue(context.TODO(), oauth.HTTPClient, options.httpClient)
 	token, err := authConfig.Exchange(ctx, code)
 	log.Printf("token: %v", token)
 
@@ -4,56 +4,224 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
 	oauth "golang.org/x/oauth2"
 )
 
+// listsPath is the path of the task lists below the Graph base URL.
+const listsPath = "/beta/me/tasks/lists"
+
+// Cloud is a deployment of Microsoft Graph together with the login endpoint
+// that issues its tokens.
+type Cloud struct {
+	LoginURL string
+	GraphURL string
+}
+
+var (
+	// CloudGlobal is the global service, the default.
+	CloudGlobal = Cloud{LoginURL: "https://login.microsoftonline.com", GraphURL: "https://graph.microsoft.com"}
+	// CloudChina is the service operated by 21Vianet.
+	CloudChina = Cloud{LoginURL: "https://login.chinacloudapi.cn", GraphURL: "https://microsoftgraph.chinacloudapi.cn"}
+	// CloudUSGov is the service for US government (L4).
+	CloudUSGov = Cloud{LoginURL: "https://login.microsoftonline.us", GraphURL: "https://graph.microsoft.us"}
+)
+
+// ParseCloud returns the cloud named global, china or usgov.
+func ParseCloud(name string) (Cloud, error) {
+	switch strings.ToLower(name) {
+	case "", "global":
+		return CloudGlobal, nil
+	case "china":
+		return CloudChina, nil
+	case "usgov":
+		return CloudUSGov, nil
+	}
+	return Cloud{}, fmt.Errorf("unknown cloud %q, expected global, china or usgov", name)
+}
+
 // Client is used for HTTP requests to the Notion API.
 type Client struct {
 	httpClient *http.Client
+	graphURL   string
+	cloudHost  string
+	listsURL   string
+	userAgent  string
+}
+
+type clientOptions struct {
+	httpClient   *http.Client
+	cloud        Cloud
+	baseURL      string
+	userAgent    string
+	middlewares  []Middleware
+	refreshToken string
+}
+
+// ClientOption is used to override default client behavior.
+type ClientOption func(*clientOptions)
+
+// Middleware wraps the transport requests are sent with, to observe or change
+// requests and responses. Middleware that changes a request should clone it
+// first, as http.RoundTripper requires.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper, for writing
+// middleware.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(r).
+func (f RoundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// WithHTTPClient overrides the http.Client that token refreshes and API
+// requests are sent with. The OAuth2 transport is layered on top of it.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithCloud sends requests to, and gets tokens from, a national cloud instead
+// of the global service.
+func WithCloud(cloud Cloud) ClientOption {
+	return func(o *clientOptions) {
+		o.cloud = cloud
+	}
+}
+
+// WithBaseURL overrides the Graph URL of the cloud, to send requests through
+// a proxy or to a fake server. The task lists are at /beta/me/tasks/lists
+// below it, and links Graph returns to the cloud are followed below it too.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(o *clientOptions) {
+		o.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithLoginURL overrides the login URL of the cloud, below which the OAuth2
+// endpoints of the common tenant are.
+func WithLoginURL(loginURL string) ClientOption {
+	return func(o *clientOptions) {
+		o.cloud.LoginURL = strings.TrimRight(loginURL, "/")
+	}
+}
+
+// WithUserAgent sets the User-Agent header of API requests.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(o *clientOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithMiddleware wraps the transport of the http.Client with middlewares,
+// the first one seeing requests first. Token requests go through them too,
+// and API requests carry their Authorization header. The http.Client itself
+// is not changed.
+func WithMiddleware(middlewares ...Middleware) ClientOption {
+	return func(o *clientOptions) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithRefreshToken authenticates with a refresh token instead of the one
+// saved in token.txt by GetToken.
+func WithRefreshToken(refreshToken string) ClientOption {
+	return func(o *clientOptions) {
+		o.refreshToken = refreshToken
+	}
 }
 
-func NewClient(clientID, clientSecret string) (*Client, error) {
-	authEndpoint := oauth.Endpoint{
-		AuthURL:  "https://login.microsoftonline.com/common/oauth2/v2.0/authorize",
-		TokenURL: "https://login.microsoftonline.com/common/oauth2/v2.0/token",
+func newClientOptions(opts []ClientOption) clientOptions {
+	options := clientOptions{
+		httpClient: &http.Client{},
+		cloud:      CloudGlobal,
+	}
+	for _, opt := range opts {
+		opt(&options)
 	}
 
-	authConfig := oauth.Config{
+	if len(options.middlewares) > 0 {
+		transport := options.httpClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		for i := len(options.middlewares) - 1; i >= 0; i-- {
+			transport = options.middlewares[i](transport)
+		}
+		httpClient := *options.httpClient
+		httpClient.Transport = transport
+		options.httpClient = &httpClient
+	}
+
+	return options
+}
+
+func (o clientOptions) authConfig(clientID, clientSecret string) oauth.Config {
+	return oauth.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
-		Endpoint:     authEndpoint,
-		RedirectURL:  "https://login.microsoftonline.com/common/oauth2/nativeclient",
+		Endpoint: oauth.Endpoint{
+			AuthURL:  o.cloud.LoginURL + "/common/oauth2/v2.0/authorize",
+			TokenURL: o.cloud.LoginURL + "/common/oauth2/v2.0/token",
+		},
+		RedirectURL: o.cloud.LoginURL + "/common/oauth2/nativeclient",
 		Scopes: []string{
 			"offline_access",
 			"Tasks.ReadWrite",
 		},
 	}
+}
 
-	ctx := context.WithValue(context.TODO(), oauth.HTTPClient, &http.Client{})
+func NewClient(clientID, clientSecret string, opts ...ClientOption) (*Client, error) {
+	options := newClientOptions(opts)
+	authConfig := options.authConfig(clientID, clientSecret)
 
-	token, err := GetSavedToken()
-	if err != nil {
-		return nil, err
+	ctx := context.WithValue(context.TODO(), oauth.HTTPClient, options.httpClient)
+
+	token := &oauth.Token{RefreshToken: options.refreshToken, TokenType: "Bearer"}
+	if len(options.refreshToken) == 0 {
+		var err error
+		if token, err = GetSavedToken(); err != nil {
+			return nil, err
+		}
 	}
 
-	return &Client{authConfig.Client(ctx, token)}, nil
+	graphURL := options.cloud.GraphURL
+	if len(options.baseURL) > 0 {
+		graphURL = options.baseURL
+	}
+	var cloudHost string
+	if u, err := url.Parse(options.cloud.GraphURL); err == nil {
+		cloudHost = u.Host
+	}
+
+	return &Client{
+		httpClient: authConfig.Client(ctx, token),
+		graphURL:   graphURL,
+		cloudHost:  cloudHost,
+		listsURL:   graphURL + listsPath,
+		userAgent:  options.userAgent,
+	}, nil
 }
 
-func NewRequest(method, url string, header http.Header, param url.Values, body []byte) (*http.Request, error) {
+func (c *Client) NewRequest(ctx context.Context, method, url string, header http.Header, param url.Values, body []byte) (*http.Request, error) {
 	reader := bytes.NewReader(body)
 	if method == http.MethodGet {
-		url = urlPrefix + url
+		url = c.listsURL + url
 		if param != nil {
 			url = url + "?" + param.Encode()
 		}
 	} else {
-		url = urlPrefix + url
+		url = c.listsURL + url
 	}
 
-	req, err := http.NewRequest(method, url, reader)
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
 	if err != nil {
 		return nil, err
 	}
@@ -61,17 +229,62 @@ func NewRequest(method, url string, header http.Header, param url.Values, body [
 	if header != nil {
 		req.Header = header
 	}
+	if len(c.userAgent) > 0 {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
 
 	return req, nil
 }
 
-func NewJSONRequest(method, url string, header http.Header, reqJo interface{}) (*http.Request, error) {
+// newLinkRequest returns a GET request of a next or delta link. Graph returns
+// absolute links to the Graph URL of the cloud, which are followed below the
+// base URL when it is overridden, e.g. through a proxy. Links to the host of
+// the base URL are followed as they are, and links to other hosts are an
+// error. A relative link is taken to be below the task lists.
+func (c *Client) newLinkRequest(ctx context.Context, link string) (*http.Request, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link %q: %w", link, err)
+	}
+	if !u.IsAbs() {
+		return c.NewRequest(ctx, http.MethodGet, strings.TrimPrefix(link, listsPath), nil, nil, nil)
+	}
+
+	graph, err := url.Parse(c.graphURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Graph URL %q: %w", c.graphURL, err)
+	}
+	switch u.Host {
+	case graph.Host:
+	case c.cloudHost:
+		rewritten := c.graphURL + u.EscapedPath()
+		if len(u.RawQuery) > 0 {
+			rewritten += "?" + u.RawQuery
+		}
+		if u, err = url.Parse(rewritten); err != nil {
+			return nil, fmt.Errorf("invalid link %q: %w", link, err)
+		}
+	default:
+		return nil, fmt.Errorf("link %q is not on the Graph host %v", link, graph.Host)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.userAgent) > 0 {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	return req, nil
+}
+
+func (c *Client) NewJSONRequest(ctx context.Context, method, url string, header http.Header, reqJo interface{}) (*http.Request, error) {
 	body, err := json.Marshal(reqJo)
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := NewRequest(method, url, header, nil, body)
+	req, err := c.NewRequest(ctx, method, url, header, nil, body)
 	if err != nil {
 		return nil, err
 	}
@@ -1,20 +1,23 @@
 package todoapi
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/url"
-	"strings"
 
 	"notionsync/pkg/logger"
 )
 
-const urlPrefix = "https://graph.microsoft.com/beta/me/tasks/lists"
+// ErrSyncStateNotFound is returned by GetTaskDelta when Graph no longer has
+// the sync state of a delta or next link. The delta has to be restarted
+// without a link.
+var ErrSyncStateNotFound = errors.New("sync state not found")
 
-func (c *Client) CreateTaskList(name string) error {
+func (c *Client) CreateTaskList(ctx context.Context, name string) error {
 	data := map[string]string{"displayName": name}
-	req, err := NewJSONRequest(http.MethodPost, "", nil, data)
+	req, err := c.NewJSONRequest(ctx, http.MethodPost, "", nil, data)
 	if err != nil {
 		return err
 	}
@@ -32,8 +35,8 @@ func (c *Client) CreateTaskList(name string) error {
 	return nil
 }
 
-func (c *Client) ListTaskLists() ([]TaskList, error) {
-	req, err := NewJSONRequest(http.MethodGet, "", nil, nil)
+func (c *Client) ListTaskLists(ctx context.Context) ([]TaskList, error) {
+	req, err := c.NewJSONRequest(ctx, http.MethodGet, "", nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -56,10 +59,10 @@ func (c *Client) ListTaskLists() ([]TaskList, error) {
 	return list.TaskLists, nil
 }
 
-func (c *Client) GetTaskListByListName(listName string) ([]Task, error) {
+func (c *Client) GetTaskListByListName(ctx context.Context, listName string) ([]Task, error) {
 	param := make(url.Values)
 	param.Add("$filter", "contains(displayName,'"+listName+"')")
-	req, err := NewRequest(http.MethodGet, "", nil, param, nil)
+	req, err := c.NewRequest(ctx, http.MethodGet, "", nil, param, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -82,8 +85,8 @@ func (c *Client) GetTaskListByListName(listName string) ([]Task, error) {
 	return listTasks.Tasks, nil
 }
 
-func (c *Client) ListTask(taskListID string) ([]Task, error) {
-	req, err := NewRequest(http.MethodGet, "/"+taskListID+"/tasks", nil, nil, nil)
+func (c *Client) ListTask(ctx context.Context, taskListID string) ([]Task, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, "/"+taskListID+"/tasks", nil, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -106,10 +109,31 @@ func (c *Client) ListTask(taskListID string) ([]Task, error) {
 	return listTasks.Tasks, nil
 }
 
-func (c *Client) GetTaskDeltaLatest(taskListID string) (string, error) {
+// UpdateTaskBody replaces the body of a task.
+func (c *Client) UpdateTaskBody(ctx context.Context, taskListID, taskID string, body TaskBody) error {
+	data := map[string]TaskBody{"body": body}
+	req, err := c.NewJSONRequest(ctx, http.MethodPatch, "/"+taskListID+"/tasks/"+taskID, nil, data)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("response status code error")
+	}
+
+	return nil
+}
+
+func (c *Client) GetTaskDeltaLatest(ctx context.Context, taskListID string) (string, error) {
 	param := make(url.Values)
 	param.Add("$deltaToken", "latest")
-	req, err := NewRequest(http.MethodGet, "/"+taskListID+"/tasks/delta", nil, param, nil)
+	req, err := c.NewRequest(ctx, http.MethodGet, "/"+taskListID+"/tasks/delta", nil, param, nil)
 	if err != nil {
 		return "", err
 	}
@@ -144,14 +168,16 @@ func (c *Client) GetTaskDeltaLatest(taskListID string) (string, error) {
 	return jsonStruct.DeltaLink, nil
 }
 
-func (c *Client) GetTaskDelta(taskListID string, inURL string) (*ListTasksResponse, error) {
-	var uri string
+func (c *Client) GetTaskDelta(ctx context.Context, taskListID string, inURL string) (*ListTasksResponse, error) {
+	var (
+		req *http.Request
+		err error
+	)
 	if inURL == "" {
-		uri = "/" + taskListID + "/tasks/delta"
+		req, err = c.NewRequest(ctx, http.MethodGet, "/"+taskListID+"/tasks/delta", nil, nil, nil)
 	} else {
-		uri = strings.Replace(inURL, "https://graph.microsoft.com/beta/me/tasks/lists", "", -1)
+		req, err = c.newLinkRequest(ctx, inURL)
 	}
-	req, err := NewRequest(http.MethodGet, uri, nil, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +188,9 @@ func (c *Client) GetTaskDelta(taskListID string, inURL string) (*ListTasksRespon
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusGone {
+		return nil, ErrSyncStateNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
 		logger.Warnf("response status code: %v", resp.StatusCode)
 		return nil, errors.New("response status code error")
@@ -0,0 +1,371 @@
+// Package todoapitest provides an in-memory fake of the Microsoft To Do API
+// of Microsoft Graph for tests, in the manner of net/http/httptest.
+//
+// The fake serves task lists and their tasks, with paging through
+// @odata.nextLink, and delta queries that return what changed since a
+// @odata.deltaLink, deleted tasks as @removed entries. Delta tokens can be
+// made to expire. It also serves the OAuth2 token endpoint, so that clients
+// authenticate against it without the network, and can make requests fail,
+// e.g. to throttle them.
+package todoapitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"notionsync/pkg/todoapi"
+)
+
+const (
+	listsPath = "/beta/me/tasks/lists"
+	tokenPath = "/common/oauth2/v2.0/token"
+
+	defaultPageSize = 100
+)
+
+// Server is a fake Microsoft Graph listening on a local address. Its state
+// can be seeded and inspected with its methods while a client sends
+// requests.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	clock         func() time.Time
+	pageSize      int
+	deltaLifetime time.Duration
+	ids           int
+	version       int
+	lists         []*taskList
+	accessTokens  map[string]bool
+	deltaTokens   map[string]deltaToken
+	skipTokens    map[string]skipToken
+	failures      []*Failure
+	requests      []string
+}
+
+// Option is used to override default server behavior.
+type Option func(*Server)
+
+// WithClock overrides the clock that created and modified times are read
+// from, and that delta tokens expire by.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.clock = now
+	}
+}
+
+// WithPageSize overrides the number of tasks per page, 100 by default. A
+// client can ask for fewer with the Prefer: odata.maxpagesize header.
+func WithPageSize(size int) Option {
+	return func(s *Server) {
+		s.pageSize = size
+	}
+}
+
+// WithDeltaTokenLifetime makes delta links expire once they are older than
+// lifetime, after which they are answered with 410 Gone. They do not expire
+// by default.
+func WithDeltaTokenLifetime(lifetime time.Duration) Option {
+	return func(s *Server) {
+		s.deltaLifetime = lifetime
+	}
+}
+
+// NewServer starts and returns a new Server, to be closed when done.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		clock:        time.Now,
+		pageSize:     defaultPageSize,
+		accessTokens: make(map[string]bool),
+		deltaTokens:  make(map[string]deltaToken),
+		skipTokens:   make(map[string]skipToken),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// ClientOptions returns the options of a client sending requests, and
+// getting tokens, from the server. Any refresh token is accepted, so that
+// todoapi.WithRefreshToken can be set to anything.
+func (s *Server) ClientOptions() []todoapi.ClientOption {
+	return []todoapi.ClientOption{
+		todoapi.WithBaseURL(s.URL),
+		todoapi.WithLoginURL(s.URL),
+		todoapi.WithHTTPClient(s.Server.Client()),
+	}
+}
+
+// Failure makes the requests it matches fail with an error response instead
+// of being served.
+type Failure struct {
+	// Method and Path select the failing requests, any when empty. Path is
+	// matched as a prefix of the URL path, e.g. "/beta/me/tasks/lists/" or
+	// "/common/oauth2/v2.0/token".
+	Method string
+	Path   string
+
+	Status int
+	// Code defaults to the error code Graph uses for Status.
+	Code    string
+	Message string
+	// RetryAfter sets the Retry-After header, in whole seconds.
+	RetryAfter time.Duration
+	// Times is the number of requests that fail: one when zero, all when
+	// negative.
+	Times int
+}
+
+// Fail makes requests fail as f describes. Failures are matched in the order
+// they were added, and dropped once used up.
+func (s *Server) Fail(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f.Times == 0 {
+		f.Times = 1
+	}
+	if f.Code == "" {
+		f.Code = errorCode(f.Status)
+	}
+	if f.Message == "" {
+		f.Message = http.StatusText(f.Status)
+	}
+	s.failures = append(s.failures, &f)
+}
+
+// Requests returns the requests served so far, failed ones included, as the
+// method and the URL path, e.g. "GET /beta/me/tasks/lists".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+// ExpireDeltaTokens makes every delta and next link handed out so far
+// expire, as when Graph drops its sync state.
+func (s *Server) ExpireDeltaTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deltaTokens = make(map[string]deltaToken)
+	s.skipTokens = make(map[string]skipToken)
+}
+
+func (s *Server) newID(prefix string) string {
+	s.ids++
+	return fmt.Sprintf("%v-%06d", prefix, s.ids)
+}
+
+func (s *Server) now() time.Time {
+	return s.clock().UTC().Truncate(time.Millisecond)
+}
+
+// graphError is an error answered with the status and code of Graph.
+type graphError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *graphError) Error() string {
+	return e.message
+}
+
+func errorf(status int, format string, args ...interface{}) *graphError {
+	return &graphError{status: status, code: errorCode(status), message: fmt.Sprintf(format, args...)}
+}
+
+func notFound(id string) *graphError {
+	return errorf(http.StatusNotFound, "The specified object was not found in the store: %v", id)
+}
+
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalidRequest"
+	case http.StatusUnauthorized:
+		return "InvalidAuthenticationToken"
+	case http.StatusForbidden:
+		return "accessDenied"
+	case http.StatusNotFound:
+		return "itemNotFound"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusGone:
+		return "syncStateNotFound"
+	case http.StatusTooManyRequests:
+		return "TooManyRequests"
+	case http.StatusServiceUnavailable:
+		return "serviceNotAvailable"
+	case http.StatusGatewayTimeout:
+		return "gatewayTimeout"
+	}
+	return "generalException"
+}
+
+func writeError(w http.ResponseWriter, err *graphError) {
+	type errorBody struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	writeJSON(w, err.status, struct {
+		Error errorBody `json:"error"`
+	}{errorBody{Code: err.code, Message: err.message}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	if f := s.failure(r.Method, r.URL.Path); f != nil {
+		if f.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Seconds())))
+		}
+		writeError(w, &graphError{status: f.Status, code: f.Code, message: f.Message})
+		return
+	}
+
+	if r.URL.Path == tokenPath {
+		s.token(w, r)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, listsPath) {
+		writeError(w, errorf(http.StatusBadRequest, "Invalid request URL: %v", r.URL.Path))
+		return
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") || !s.accessTokens[strings.TrimPrefix(auth, "Bearer ")] {
+		writeError(w, errorf(http.StatusUnauthorized, "Access token is empty or invalid."))
+		return
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, listsPath), "/"), "/")
+	status, result, err := s.route(r, segments)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	writeJSON(w, status, result)
+}
+
+// failure returns the failure matching a request, using it up.
+func (s *Server) failure(method, path string) *Failure {
+	for i, f := range s.failures {
+		if (f.Method != "" && f.Method != method) || !strings.HasPrefix(path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// token serves the OAuth2 token endpoint, issuing an access token for any
+// refresh token or authorization code.
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	oauthError := func(code, description string) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+	}
+	if r.Method != http.MethodPost {
+		oauthError("invalid_request", "The token endpoint only accepts POST.")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError("invalid_request", err.Error())
+		return
+	}
+
+	switch grant := r.PostForm.Get("grant_type"); grant {
+	case "refresh_token":
+		if r.PostForm.Get("refresh_token") == "" {
+			oauthError("invalid_grant", "The refresh token is missing.")
+			return
+		}
+	case "authorization_code":
+		if r.PostForm.Get("code") == "" {
+			oauthError("invalid_grant", "The authorization code is missing.")
+			return
+		}
+	default:
+		oauthError("unsupported_grant_type", fmt.Sprintf("The grant type %q is not supported.", grant))
+		return
+	}
+
+	accessToken := s.newID("access-token")
+	s.accessTokens[accessToken] = true
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token_type":    "Bearer",
+		"scope":         "Tasks.ReadWrite",
+		"expires_in":    3600,
+		"access_token":  accessToken,
+		"refresh_token": s.newID("refresh-token"),
+	})
+}
+
+func (s *Server) route(r *http.Request, segments []string) (int, interface{}, *graphError) {
+	if segments[0] == "" {
+		segments = nil
+	}
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		return s.listLists(r)
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		return s.createList(r)
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		return s.findList(segments[0])
+	case len(segments) == 1 && r.Method == http.MethodPatch:
+		return s.updateList(r, segments[0])
+	case len(segments) == 1 && r.Method == http.MethodDelete:
+		return s.deleteList(segments[0])
+	case len(segments) == 2 && segments[1] == "tasks" && r.Method == http.MethodGet:
+		return s.listTasks(r, segments[0])
+	case len(segments) == 2 && segments[1] == "tasks" && r.Method == http.MethodPost:
+		return s.createTask(r, segments[0])
+	case len(segments) == 3 && segments[1] == "tasks" && segments[2] == "delta" && r.Method == http.MethodGet:
+		return s.delta(r, segments[0])
+	case len(segments) == 3 && segments[1] == "tasks" && r.Method == http.MethodGet:
+		return s.findTask(segments[0], segments[2])
+	case len(segments) == 3 && segments[1] == "tasks" && r.Method == http.MethodPatch:
+		return s.updateTask(r, segments[0], segments[2])
+	case len(segments) == 3 && segments[1] == "tasks" && r.Method == http.MethodDelete:
+		return s.deleteTask(segments[0], segments[2])
+	}
+	return 0, nil, errorf(http.StatusBadRequest, "Unsupported request: %v %v", r.Method, r.URL.Path)
+}
+
+// decode decodes the JSON body of r into v.
+func decode(r *http.Request, v interface{}) *graphError {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, "Invalid JSON body: %v", err)
+	}
+	return nil
+}
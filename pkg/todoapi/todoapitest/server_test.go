@@ -0,0 +1,348 @@
+package todoapitest_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/todoapi"
+	"notionsync/pkg/todoapi/todoapitest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestMain keeps the log of the code under test on the console, rather than
+// in a log directory of the package.
+func TestMain(m *testing.M) {
+	config := logger.DefaultConfig()
+	config.EnableFile = false
+	logger.Init(&config)
+	os.Exit(m.Run())
+}
+
+// clock is a clock tests move forward while the server reads it.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newClient(t *testing.T, srv *todoapitest.Server) *todoapi.Client {
+	t.Helper()
+
+	client, err := todoapi.NewClient("client-id", "client-secret",
+		append(srv.ClientOptions(), todoapi.WithRefreshToken("refresh-token"))...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return client
+}
+
+func names(tasks []todoapi.Task) []string {
+	var got []string
+	for _, task := range tasks {
+		if task.Removed.Reason != "" {
+			got = append(got, task.Id+" "+task.Removed.Reason)
+			continue
+		}
+		got = append(got, task.DisplayName)
+	}
+	return got
+}
+
+// fetchDelta follows a delta round from url through all of its pages.
+func fetchDelta(t *testing.T, client *todoapi.Client, listID, url string) (tasks []todoapi.Task, pages int, deltaLink string) {
+	t.Helper()
+
+	for {
+		resp, err := client.GetTaskDelta(context.Background(), listID, url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tasks = append(tasks, resp.Tasks...)
+		pages++
+		if resp.OdataDeltaLink != "" {
+			return tasks, pages, resp.OdataDeltaLink
+		}
+		if resp.OdataNextLink == "" {
+			t.Fatal("expected a next or delta link")
+		}
+		url = resp.OdataNextLink
+	}
+}
+
+func TestTaskLists(t *testing.T) {
+	t.Parallel()
+
+	srv := todoapitest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	srv.AddList(todoapi.TaskList{DisplayName: "Tasks", WellKnownListName: "defaultList"})
+	if err := client.CreateTaskList(ctx, "Groceries"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lists, err := client.ListTaskLists(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(srv.Lists(), lists); diff != "" {
+		t.Fatalf("task lists not equal (-exp, +got):\n%v", diff)
+	}
+	if lists[0].WellKnownListName != "defaultList" || lists[1].WellKnownListName != "none" || lists[1].Id == "" {
+		t.Fatalf("unexpected task lists: %+v", lists)
+	}
+
+	srv.RenameList(lists[1].Id, "Shopping")
+	srv.DeleteList(lists[0].Id)
+	if lists, err = client.ListTaskLists(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lists) != 1 || lists[0].DisplayName != "Shopping" {
+		t.Fatalf("unexpected task lists: %+v", lists)
+	}
+}
+
+func TestTasks(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC)
+	srv := todoapitest.NewServer(todoapitest.WithClock(func() time.Time { return now }))
+	defer srv.Close()
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	list := srv.AddList(todoapi.TaskList{DisplayName: "Tasks"})
+	task := srv.AddTask(list.Id, todoapi.Task{DisplayName: "Buy milk", Importance: "high"})
+	srv.AddTask(list.Id, todoapi.Task{DisplayName: "Plan trip"})
+
+	tasks, err := client.ListTask(ctx, list.Id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"Buy milk", "Plan trip"}, names(tasks)); diff != "" {
+		t.Fatalf("tasks not equal (-exp, +got):\n%v", diff)
+	}
+	got := tasks[0]
+	if got.Id != task.Id || got.Importance != "high" || got.Status != "notStarted" ||
+		got.ParentList.Id != list.Id || !got.CreatedDateTime.Equal(now) {
+		t.Fatalf("unexpected task: %+v", got)
+	}
+
+	body := todoapi.TaskBody{Content: "Oat milk", ContentType: "text"}
+	if err := client.UpdateTaskBody(ctx, list.Id, task.Id, body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updated := srv.Tasks(list.Id)[0]
+	if updated.Body.Content != "Oat milk" || updated.DisplayName != "Buy milk" || updated.OdataEtag == task.OdataEtag {
+		t.Fatalf("unexpected task: %+v", updated)
+	}
+
+	if err := client.UpdateTaskBody(ctx, list.Id, "missing", body); err == nil {
+		t.Fatal("expected error updating a missing task")
+	}
+}
+
+func TestDelta(t *testing.T) {
+	t.Parallel()
+
+	srv := todoapitest.NewServer(todoapitest.WithPageSize(2))
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	list := srv.AddList(todoapi.TaskList{DisplayName: "Tasks"})
+	milk := srv.AddTask(list.Id, todoapi.Task{DisplayName: "Buy milk"})
+	trip := srv.AddTask(list.Id, todoapi.Task{DisplayName: "Plan trip"})
+	srv.AddTask(list.Id, todoapi.Task{DisplayName: "Call mom"})
+
+	tasks, pages, deltaLink := fetchDelta(t, client, list.Id, "")
+	if diff := cmp.Diff([]string{"Buy milk", "Plan trip", "Call mom"}, names(tasks)); diff != "" {
+		t.Fatalf("tasks not equal (-exp, +got):\n%v", diff)
+	}
+	if pages != 2 {
+		t.Fatalf("expected 2 pages, got %v", pages)
+	}
+
+	srv.DeleteTask(list.Id, trip.Id)
+	srv.UpdateTask(list.Id, milk.Id, func(task *todoapi.Task) {
+		task.Status = "completed"
+	})
+	srv.AddTask(list.Id, todoapi.Task{DisplayName: "Book hotel"})
+
+	tasks, _, deltaLink = fetchDelta(t, client, list.Id, deltaLink)
+	if diff := cmp.Diff([]string{"Buy milk", trip.Id + " deleted", "Book hotel"}, names(tasks)); diff != "" {
+		t.Fatalf("tasks not equal (-exp, +got):\n%v", diff)
+	}
+	if tasks[0].Status != "completed" || tasks[0].CompletedDateTime.IsZero() {
+		t.Fatalf("expected completed task, got %+v", tasks[0])
+	}
+
+	if tasks, _, _ = fetchDelta(t, client, list.Id, deltaLink); len(tasks) != 0 {
+		t.Fatalf("expected no changes, got %v", names(tasks))
+	}
+}
+
+func TestDeltaLatest(t *testing.T) {
+	t.Parallel()
+
+	srv := todoapitest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	list := srv.AddList(todoapi.TaskList{DisplayName: "Tasks"})
+	srv.AddTask(list.Id, todoapi.Task{DisplayName: "Buy milk"})
+
+	deltaLink, err := client.GetTaskDeltaLatest(context.Background(), list.Id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	srv.AddTask(list.Id, todoapi.Task{DisplayName: "Plan trip"})
+
+	tasks, _, _ := fetchDelta(t, client, list.Id, deltaLink)
+	if diff := cmp.Diff([]string{"Plan trip"}, names(tasks)); diff != "" {
+		t.Fatalf("tasks not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestDeltaExpiry(t *testing.T) {
+	t.Parallel()
+
+	c := &clock{now: time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC)}
+	srv := todoapitest.NewServer(
+		todoapitest.WithClock(c.Now),
+		todoapitest.WithDeltaTokenLifetime(time.Hour),
+	)
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	list := srv.AddList(todoapi.TaskList{DisplayName: "Tasks"})
+	_, _, deltaLink := fetchDelta(t, client, list.Id, "")
+
+	c.Advance(30 * time.Minute)
+	_, _, deltaLink = fetchDelta(t, client, list.Id, deltaLink)
+
+	c.Advance(2 * time.Hour)
+	if _, err := client.GetTaskDelta(context.Background(), list.Id, deltaLink); err == nil {
+		t.Fatal("expected error for an expired delta link")
+	}
+
+	_, _, deltaLink = fetchDelta(t, client, list.Id, "")
+	srv.ExpireDeltaTokens()
+	if _, err := client.GetTaskDelta(context.Background(), list.Id, deltaLink); err == nil {
+		t.Fatal("expected error for an expired delta link")
+	}
+}
+
+func TestFail(t *testing.T) {
+	t.Parallel()
+
+	srv := todoapitest.NewServer()
+	defer srv.Close()
+	client := newClient(t, srv)
+	list := srv.AddList(todoapi.TaskList{DisplayName: "Tasks"})
+
+	srv.Fail(todoapitest.Failure{
+		Method:     http.MethodGet,
+		Path:       "/beta/me/tasks/lists/",
+		Status:     http.StatusTooManyRequests,
+		RetryAfter: 2 * time.Second,
+	})
+	if _, err := client.GetTaskDelta(context.Background(), list.Id, ""); err == nil {
+		t.Fatal("expected error for a throttled request")
+	}
+	if _, err := client.GetTaskDelta(context.Background(), list.Id, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []string{
+		"POST /common/oauth2/v2.0/token",
+		"GET /beta/me/tasks/lists/" + list.Id + "/tasks/delta",
+		"GET /beta/me/tasks/lists/" + list.Id + "/tasks/delta",
+	}
+	if diff := cmp.Diff(exp, srv.Requests()); diff != "" {
+		t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+	}
+
+	resp, err := srv.Server.Client().Get(srv.URL + "/beta/me/tasks/lists")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected status 401 without a token, got %v", resp.StatusCode)
+	}
+}
+
+func TestDeltaLinkThroughProxy(t *testing.T) {
+	t.Parallel()
+
+	srv := todoapitest.NewServer(todoapitest.WithPageSize(1))
+	defer srv.Close()
+
+	// The client sends requests to a proxy, while the links of the server
+	// point at the server, which is the Graph URL of the cloud.
+	var mu sync.Mutex
+	var proxied []string
+	proxy := func(next http.RoundTripper) http.RoundTripper {
+		return todoapi.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "graph-proxy.invalid" {
+				mu.Lock()
+				proxied = append(proxied, r.URL.Path)
+				mu.Unlock()
+				r = r.Clone(r.Context())
+				r.URL.Host = strings.TrimPrefix(srv.URL, "http://")
+				r.URL.Path = strings.TrimPrefix(r.URL.Path, "/graph")
+				r.URL.RawPath = ""
+			}
+			return next.RoundTrip(r)
+		})
+	}
+	client, err := todoapi.NewClient("client-id", "client-secret", append(srv.ClientOptions(),
+		todoapi.WithCloud(todoapi.Cloud{LoginURL: srv.URL, GraphURL: srv.URL}),
+		todoapi.WithBaseURL("http://graph-proxy.invalid/graph"),
+		todoapi.WithRefreshToken("refresh-token"),
+		todoapi.WithMiddleware(proxy),
+	)...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list := srv.AddList(todoapi.TaskList{DisplayName: "Tasks"})
+	srv.AddTask(list.Id, todoapi.Task{DisplayName: "Buy milk"})
+	srv.AddTask(list.Id, todoapi.Task{DisplayName: "Plan trip"})
+
+	tasks, pages, _ := fetchDelta(t, client, list.Id, "")
+	if diff := cmp.Diff([]string{"Buy milk", "Plan trip"}, names(tasks)); diff != "" {
+		t.Fatalf("tasks not equal (-exp, +got):\n%v", diff)
+	}
+	if pages != 2 {
+		t.Fatalf("expected 2 pages, got %v", pages)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	exp := []string{"/graph/beta/me/tasks/lists/" + list.Id + "/tasks/delta", "/graph/beta/me/tasks/lists/" + list.Id + "/tasks/delta"}
+	if diff := cmp.Diff(exp, proxied); diff != "" {
+		t.Fatalf("proxied requests not equal (-exp, +got):\n%v", diff)
+	}
+
+	if _, err := client.GetTaskDelta(context.Background(), list.Id, "https://elsewhere.invalid/beta/me/tasks/lists/"+list.Id+"/tasks/delta"); err == nil {
+		t.Fatal("expected error for a link to another host")
+	}
+}
@@ -0,0 +1,525 @@
+package todoapitest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"notionsync/pkg/todoapi"
+)
+
+// taskList is a stored task list, with its tasks in creation order.
+type taskList struct {
+	todoapi.TaskList
+	tasks []*task
+}
+
+// task is a stored task. Deleted tasks are kept, so that delta queries can
+// report them as removed.
+type task struct {
+	todoapi.Task
+	// version is the version of the server the task last changed at.
+	version int
+	deleted bool
+}
+
+// deltaToken is the state of a delta link: the version of the server the
+// changes after which it returns.
+type deltaToken struct {
+	listID  string
+	version int
+	issued  time.Time
+}
+
+// skipToken is the state of a next link: the IDs of the tasks of a delta
+// round, fixed when it started, and the offset of the next page.
+type skipToken struct {
+	listID  string
+	ids     []string
+	offset  int
+	version int
+	issued  time.Time
+}
+
+// removedTask is the entry of a deleted task in a delta response.
+type removedTask struct {
+	OdataType string `json:"@odata.type"`
+	ID        string `json:"id"`
+	Removed   struct {
+		Reason string `json:"reason"`
+	} `json:"@removed"`
+}
+
+// AddList adds a task list, with a new ID when it has none, and returns it as
+// Graph would.
+func (s *Server) AddList(list todoapi.TaskList) todoapi.TaskList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addList(list).TaskList
+}
+
+// RenameList changes the display name of a task list. It panics when the
+// list does not exist.
+func (s *Server) RenameList(listID, displayName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mustList(listID).DisplayName = displayName
+}
+
+// DeleteList deletes a task list with its tasks.
+func (s *Server) DeleteList(listID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeList(listID)
+}
+
+// Lists returns the task lists, oldest first.
+func (s *Server) Lists() []todoapi.TaskList {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lists := make([]todoapi.TaskList, len(s.lists))
+	for i, list := range s.lists {
+		lists[i] = list.TaskList
+	}
+	return lists
+}
+
+// AddTask adds a task to a list and returns it as Graph would. It panics when
+// the list does not exist.
+func (s *Server) AddTask(listID string, t todoapi.Task) todoapi.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addTask(s.mustList(listID), t).Task
+}
+
+// UpdateTask changes a task with update, as a user editing it would, and
+// returns it. It panics when the list or task does not exist.
+func (s *Server) UpdateTask(listID, taskID string, update func(*todoapi.Task)) todoapi.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.mustTask(listID, taskID)
+	update(&t.Task)
+	s.touch(t)
+	return t.Task
+}
+
+// DeleteTask deletes a task. It panics when the list or task does not exist.
+func (s *Server) DeleteTask(listID, taskID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.mustTask(listID, taskID)
+	t.deleted = true
+	s.touch(t)
+}
+
+// Tasks returns the tasks of a list that are not deleted, oldest first.
+func (s *Server) Tasks(listID string) []todoapi.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tasks []todoapi.Task
+	for _, t := range s.mustList(listID).tasks {
+		if !t.deleted {
+			tasks = append(tasks, t.Task)
+		}
+	}
+	return tasks
+}
+
+func (s *Server) mustList(listID string) *taskList {
+	list := s.findListByID(listID)
+	if list == nil {
+		panic(fmt.Sprintf("todoapitest: no task list %v", listID))
+	}
+	return list
+}
+
+func (s *Server) mustTask(listID, taskID string) *task {
+	t := s.findTaskByID(s.mustList(listID), taskID)
+	if t == nil {
+		panic(fmt.Sprintf("todoapitest: no task %v in task list %v", taskID, listID))
+	}
+	return t
+}
+
+func (s *Server) findListByID(listID string) *taskList {
+	for _, list := range s.lists {
+		if list.Id == listID {
+			return list
+		}
+	}
+	return nil
+}
+
+func (s *Server) findTaskByID(list *taskList, taskID string) *task {
+	for _, t := range list.tasks {
+		if t.Id == taskID && !t.deleted {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Server) addList(list todoapi.TaskList) *taskList {
+	if list.Id == "" {
+		list.Id = s.newID("list")
+	}
+	if list.WellKnownListName == "" {
+		list.WellKnownListName = "none"
+	}
+	list.OdataType = "#microsoft.graph.todoTaskList"
+	list.OdataEtag = fmt.Sprintf("W/\"%v\"", s.ids)
+	stored := &taskList{TaskList: list}
+	s.lists = append(s.lists, stored)
+	return stored
+}
+
+func (s *Server) removeList(listID string) bool {
+	for i, list := range s.lists {
+		if list.Id == listID {
+			s.lists = append(s.lists[:i], s.lists[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) addTask(list *taskList, t todoapi.Task) *task {
+	if t.Id == "" {
+		t.Id = s.newID("task")
+	}
+	if t.Status == "" {
+		t.Status = "notStarted"
+	}
+	if t.Importance == "" {
+		t.Importance = "normal"
+	}
+	t.OdataType = "#microsoft.graph.todoTask"
+	t.CreatedDateTime = s.now()
+	t.ParentList.Id = list.Id
+	stored := &task{Task: t}
+	list.tasks = append(list.tasks, stored)
+	s.touch(stored)
+	return stored
+}
+
+// touch records a change of a task, which delta queries then return.
+func (s *Server) touch(t *task) {
+	s.version++
+	t.version = s.version
+	t.LastModifiedDateTime = s.now()
+	t.OdataEtag = fmt.Sprintf("W/\"%v\"", s.version)
+	if t.Status == "completed" && t.CompletedDateTime.IsZero() {
+		t.CompletedDateTime = t.LastModifiedDateTime
+	}
+}
+
+// containsFilter matches the $filter of lists by name, e.g.
+// contains(displayName,'Work').
+var containsFilter = regexp.MustCompile(`^contains\(displayName,\s*'(.*)'\)$`)
+
+func (s *Server) listLists(r *http.Request) (int, interface{}, *graphError) {
+	var name string
+	if filter := r.URL.Query().Get("$filter"); filter != "" {
+		m := containsFilter.FindStringSubmatch(filter)
+		if m == nil {
+			return 0, nil, errorf(http.StatusBadRequest, "Unsupported filter: %v", filter)
+		}
+		name = m[1]
+	}
+
+	lists := []todoapi.TaskList{}
+	for _, list := range s.lists {
+		if strings.Contains(list.DisplayName, name) {
+			lists = append(lists, list.TaskList)
+		}
+	}
+	return http.StatusOK, todoapi.ListTaskListsResponse{
+		DataContext: s.URL + "/beta/$metadata#users('me')/todo/lists",
+		TaskLists:   lists,
+	}, nil
+}
+
+func (s *Server) createList(r *http.Request) (int, interface{}, *graphError) {
+	var params struct {
+		DisplayName string `json:"displayName"`
+	}
+	if err := decode(r, &params); err != nil {
+		return 0, nil, err
+	}
+	if params.DisplayName == "" {
+		return 0, nil, errorf(http.StatusBadRequest, "displayName is required.")
+	}
+	return http.StatusCreated, s.addList(todoapi.TaskList{DisplayName: params.DisplayName}).TaskList, nil
+}
+
+func (s *Server) findList(listID string) (int, interface{}, *graphError) {
+	list := s.findListByID(listID)
+	if list == nil {
+		return 0, nil, notFound(listID)
+	}
+	return http.StatusOK, list.TaskList, nil
+}
+
+func (s *Server) updateList(r *http.Request, listID string) (int, interface{}, *graphError) {
+	list := s.findListByID(listID)
+	if list == nil {
+		return 0, nil, notFound(listID)
+	}
+	var params struct {
+		DisplayName string `json:"displayName"`
+	}
+	if err := decode(r, &params); err != nil {
+		return 0, nil, err
+	}
+	if params.DisplayName != "" {
+		list.DisplayName = params.DisplayName
+	}
+	return http.StatusOK, list.TaskList, nil
+}
+
+func (s *Server) deleteList(listID string) (int, interface{}, *graphError) {
+	if !s.removeList(listID) {
+		return 0, nil, notFound(listID)
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// listTasks returns the tasks of a list, paged with $top and $skip.
+func (s *Server) listTasks(r *http.Request, listID string) (int, interface{}, *graphError) {
+	list := s.findListByID(listID)
+	if list == nil {
+		return 0, nil, notFound(listID)
+	}
+
+	q := r.URL.Query()
+	top, err := queryInt(q, "$top", s.pageSize)
+	if err != nil {
+		return 0, nil, err
+	}
+	skip, err := queryInt(q, "$skip", 0)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var tasks []todoapi.Task
+	for _, t := range list.tasks {
+		if !t.deleted {
+			tasks = append(tasks, t.Task)
+		}
+	}
+	resp := todoapi.ListTasksResponse{Tasks: []todoapi.Task{}}
+	if skip < len(tasks) {
+		end := skip + top
+		if end < len(tasks) {
+			next := url.Values{"$top": {strconv.Itoa(top)}, "$skip": {strconv.Itoa(end)}}
+			resp.OdataNextLink = s.URL + listsPath + "/" + listID + "/tasks?" + next.Encode()
+		} else {
+			end = len(tasks)
+		}
+		resp.Tasks = tasks[skip:end]
+	}
+	return http.StatusOK, resp, nil
+}
+
+func queryInt(q url.Values, key string, def int) (int, *graphError) {
+	v := q.Get(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, errorf(http.StatusBadRequest, "Invalid %v: %v", key, v)
+	}
+	return n, nil
+}
+
+func (s *Server) createTask(r *http.Request, listID string) (int, interface{}, *graphError) {
+	list := s.findListByID(listID)
+	if list == nil {
+		return 0, nil, notFound(listID)
+	}
+	var t todoapi.Task
+	if err := decode(r, &t); err != nil {
+		return 0, nil, err
+	}
+	if t.DisplayName == "" {
+		return 0, nil, errorf(http.StatusBadRequest, "title is required.")
+	}
+	t.Id = ""
+	return http.StatusCreated, s.addTask(list, t).Task, nil
+}
+
+func (s *Server) findTask(listID, taskID string) (int, interface{}, *graphError) {
+	list := s.findListByID(listID)
+	if list == nil {
+		return 0, nil, notFound(listID)
+	}
+	t := s.findTaskByID(list, taskID)
+	if t == nil {
+		return 0, nil, notFound(taskID)
+	}
+	return http.StatusOK, t.Task, nil
+}
+
+// updateTask merges the fields sent into a task.
+func (s *Server) updateTask(r *http.Request, listID, taskID string) (int, interface{}, *graphError) {
+	list := s.findListByID(listID)
+	if list == nil {
+		return 0, nil, notFound(listID)
+	}
+	t := s.findTaskByID(list, taskID)
+	if t == nil {
+		return 0, nil, notFound(taskID)
+	}
+
+	updated := t.Task
+	if err := decode(r, &updated); err != nil {
+		return 0, nil, err
+	}
+	updated.Id, updated.CreatedDateTime, updated.ParentList = t.Id, t.CreatedDateTime, t.ParentList
+	t.Task = updated
+	s.touch(t)
+	return http.StatusOK, t.Task, nil
+}
+
+func (s *Server) deleteTask(listID, taskID string) (int, interface{}, *graphError) {
+	list := s.findListByID(listID)
+	if list == nil {
+		return 0, nil, notFound(listID)
+	}
+	t := s.findTaskByID(list, taskID)
+	if t == nil {
+		return 0, nil, notFound(taskID)
+	}
+	t.deleted = true
+	s.touch(t)
+	return http.StatusNoContent, nil, nil
+}
+
+// delta serves a round of delta queries. A round without token returns every
+// task, one with a $deltatoken the tasks changed since its delta link was
+// handed out, and one with the token latest nothing. Rounds are paged with
+// next links and end with a delta link for the next round.
+func (s *Server) delta(r *http.Request, listID string) (int, interface{}, *graphError) {
+	list := s.findListByID(listID)
+	if list == nil {
+		return 0, nil, notFound(listID)
+	}
+
+	q := r.URL.Query()
+	token := q.Get("$deltatoken")
+	if token == "" {
+		token = q.Get("$deltaToken")
+	}
+
+	var round skipToken
+	switch skip := q.Get("$skiptoken"); {
+	case skip != "":
+		state, ok := s.skipTokens[skip]
+		if !ok || state.listID != listID || s.expired(state.issued) {
+			return 0, nil, errorf(http.StatusGone, "The sync state of the next link was not found or has expired.")
+		}
+		round = state
+	case token == "latest":
+		round = skipToken{listID: listID, version: s.version}
+	case token != "":
+		state, ok := s.deltaTokens[token]
+		if !ok || state.listID != listID || s.expired(state.issued) {
+			return 0, nil, errorf(http.StatusGone, "The sync state of the delta link was not found or has expired.")
+		}
+		round = skipToken{listID: listID, version: s.version}
+		for _, t := range list.tasks {
+			if t.version > state.version {
+				round.ids = append(round.ids, t.Id)
+			}
+		}
+	default:
+		round = skipToken{listID: listID, version: s.version}
+		for _, t := range list.tasks {
+			if !t.deleted {
+				round.ids = append(round.ids, t.Id)
+			}
+		}
+	}
+
+	pageSize := s.pageSize
+	if size, ok := maxPageSize(r.Header.Get("Prefer")); ok && size < pageSize {
+		pageSize = size
+	}
+
+	end := round.offset + pageSize
+	if end > len(round.ids) {
+		end = len(round.ids)
+	}
+	values := []interface{}{}
+	for _, id := range round.ids[round.offset:end] {
+		values = append(values, s.deltaEntry(list, id))
+	}
+
+	link := s.URL + listsPath + "/" + listID + "/tasks/delta?"
+	resp := struct {
+		OdataContext   string        `json:"@odata.context"`
+		OdataNextLink  string        `json:"@odata.nextLink,omitempty"`
+		OdataDeltaLink string        `json:"@odata.deltaLink,omitempty"`
+		Value          []interface{} `json:"value"`
+	}{
+		OdataContext: s.URL + "/beta/$metadata#Collection(todoTask)",
+		Value:        values,
+	}
+	if end < len(round.ids) {
+		next := round
+		next.offset, next.issued = end, s.now()
+		skip := s.newID("skip")
+		s.skipTokens[skip] = next
+		resp.OdataNextLink = link + url.Values{"$skiptoken": {skip}}.Encode()
+	} else {
+		delta := s.newID("delta")
+		s.deltaTokens[delta] = deltaToken{listID: listID, version: round.version, issued: s.now()}
+		resp.OdataDeltaLink = link + url.Values{"$deltatoken": {delta}}.Encode()
+	}
+	return http.StatusOK, resp, nil
+}
+
+// deltaEntry returns the entry of a task in a delta response, as it is now.
+func (s *Server) deltaEntry(list *taskList, id string) interface{} {
+	var latest *task
+	for _, t := range list.tasks {
+		if t.Id == id {
+			latest = t
+		}
+	}
+	if latest.deleted {
+		removed := removedTask{OdataType: "#microsoft.graph.todoTask", ID: id}
+		removed.Removed.Reason = "deleted"
+		return removed
+	}
+	return latest.Task
+}
+
+func (s *Server) expired(issued time.Time) bool {
+	return s.deltaLifetime > 0 && s.now().Sub(issued) > s.deltaLifetime
+}
+
+// maxPageSize parses the odata.maxpagesize preference of a Prefer header.
+func maxPageSize(prefer string) (int, bool) {
+	for _, pref := range strings.Split(prefer, ",") {
+		pref = strings.TrimSpace(pref)
+		if strings.HasPrefix(pref, "odata.maxpagesize=") {
+			n, err := strconv.Atoi(strings.TrimPrefix(pref, "odata.maxpagesize="))
+			return n, err == nil && n > 0
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,136 @@
+// Package tracing sets up OpenTelemetry tracing for the sync and the API
+// calls it makes.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"notionsync/pkg/metrics"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "notionsync"
+
+// Exporters.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Config selects where spans are exported to.
+type Config struct {
+	// Exporter is one of the Exporter constants.
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector, e.g. "localhost:4318" or
+	// "http://localhost:4318". When empty the OTEL_EXPORTER_OTLP_* environment
+	// variables apply.
+	Endpoint string
+	// Writer receives the spans of the stdout exporter, os.Stderr when nil,
+	// so that they do not mix with the output of commands.
+	Writer      io.Writer
+	ServiceName string
+	Version     string
+}
+
+// Init installs the global tracer provider for config. The returned function
+// flushes the spans still buffered and must be called before exiting.
+func Init(ctx context.Context, config Config) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	switch config.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		w := config.Writer
+		if w == nil {
+			w = os.Stderr
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlpOptions(config.Endpoint)...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %v, %v or %v",
+			config.Exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %v trace exporter: %w", config.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(config.ServiceName),
+			semconv.ServiceVersionKey.String(config.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func otlpOptions(endpoint string) []otlptracehttp.Option {
+	if len(endpoint) == 0 {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(endpoint, "http://"):
+		return []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(strings.TrimPrefix(endpoint, "http://")),
+			otlptracehttp.WithInsecure(),
+		}
+	case strings.HasPrefix(endpoint, "https://"):
+		return []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(strings.TrimPrefix(endpoint, "https://")),
+		}
+	default:
+		return []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	}
+}
+
+// Start starts a span named name as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End records err on span, if not nil, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// RoundTripper starts a client span for every request sent through next,
+// named after the api and the endpoint, and propagates it to the server. A nil
+// next uses http.DefaultTransport.
+func RoundTripper(api string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(next, otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+		return api + " " + metrics.Endpoint(req.Method, req.URL.Path)
+	}))
+}
@@ -0,0 +1,430 @@
+// Package backup dumps a Notion database, with the content of its pages, to
+// a directory of JSON files and recreates it from them.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/renderer"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	databaseFile = "database.json"
+	pagesDir     = "pages"
+)
+
+// PageBackup is a page of the database with its block tree, children nested
+// in the field of the type of their parent.
+type PageBackup struct {
+	Page   notionapi.Page    `json:"page"`
+	Blocks []notionapi.Block `json:"blocks"`
+}
+
+// Backup writes the schema of a database to database.json and each of its
+// pages to pages/<pageID>.json under dir, and returns the number of pages.
+func Backup(ctx context.Context, client *notionapi.Client, databaseID, dir string) (int, error) {
+	database, err := client.FindDatabaseByID(ctx, databaseID)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "find database failed:%v", databaseID)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, pagesDir), 0o755); err != nil {
+		return 0, err
+	}
+	if err := writeJSON(filepath.Join(dir, databaseFile), database); err != nil {
+		return 0, err
+	}
+
+	var (
+		pages int
+		query = &notionapi.DatabaseQuery{PageSize: 100}
+	)
+	for {
+		resp, err := client.QueryDatabase(ctx, databaseID, query)
+		if err != nil {
+			return pages, errors.WithMessagef(err, "query database failed:%v", databaseID)
+		}
+
+		for _, page := range resp.Results {
+			if err := readFullValues(ctx, client, page); err != nil {
+				return pages, err
+			}
+			nodes, err := renderer.FetchTree(ctx, client, page.ID)
+			if err != nil {
+				return pages, errors.WithMessagef(err, "fetch blocks of page %v failed", page.ID)
+			}
+			backup := PageBackup{Page: page, Blocks: renderer.NestChildren(nodes)}
+			if err := writeJSON(filepath.Join(dir, pagesDir, page.ID+".json"), backup); err != nil {
+				return pages, err
+			}
+			logger.T(ctx).Debugf("backed up page: %v", page.ID)
+			pages++
+		}
+
+		if !resp.HasMore || resp.NextCursor == nil {
+			return pages, nil
+		}
+		query.StartCursor = *resp.NextCursor
+	}
+}
+
+// referenceLimit is the number of items the API returns in the title, rich
+// text, relation and people values of a page. Longer values are read item by
+// item with the page property endpoint.
+const referenceLimit = 25
+
+// readFullValues replaces the values of a page that may have been cut at
+// referenceLimit items with all of their items.
+func readFullValues(ctx context.Context, client *notionapi.Client, page notionapi.Page) error {
+	props, ok := page.Properties.(notionapi.DatabasePageProperties)
+	if !ok {
+		return nil
+	}
+	for name, prop := range props {
+		switch prop.Type {
+		case notionapi.DBPropTypeTitle, notionapi.DBPropTypeRichText, notionapi.DBPropTypeRelation, notionapi.DBPropTypePeople:
+		default:
+			continue
+		}
+		if len(prop.Title) < referenceLimit && len(prop.RichText) < referenceLimit &&
+			len(prop.Relation) < referenceLimit && len(prop.People) < referenceLimit {
+			continue
+		}
+
+		items, err := pagePropertyItems(ctx, client, page.ID, prop.ID)
+		if err != nil {
+			return errors.WithMessagef(err, "read property %v of page %v failed", name, page.ID)
+		}
+		prop.Title, prop.RichText, prop.Relation, prop.People = nil, nil, nil, nil
+		for _, item := range items {
+			switch prop.Type {
+			case notionapi.DBPropTypeTitle:
+				prop.Title = append(prop.Title, item.Title)
+			case notionapi.DBPropTypeRichText:
+				prop.RichText = append(prop.RichText, item.RichText)
+			case notionapi.DBPropTypeRelation:
+				prop.Relation = append(prop.Relation, item.Relation)
+			case notionapi.DBPropTypePeople:
+				prop.People = append(prop.People, item.People)
+			}
+		}
+		props[name] = prop
+	}
+	return nil
+}
+
+// pagePropertyItems returns all the items of a property of a page.
+func pagePropertyItems(ctx context.Context, client *notionapi.Client, pageID, propID string) ([]notionapi.PagePropItem, error) {
+	var (
+		items []notionapi.PagePropItem
+		query = &notionapi.PaginationQuery{PageSize: 100}
+	)
+	for {
+		resp, err := client.FindPagePropertyByID(ctx, pageID, propID, query)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, resp.Results...)
+
+		if !resp.HasMore || len(resp.NextCursor) == 0 {
+			return items, nil
+		}
+		query.StartCursor = resp.NextCursor
+	}
+}
+
+// Result is the outcome of a restore.
+type Result struct {
+	// DatabaseID is the ID of the new database, empty until it is created.
+	DatabaseID string
+	// Pages is the number of pages restored.
+	Pages int
+	// Skipped are the values of restored pages that could not be set.
+	Skipped []SkippedValue
+}
+
+// SkippedValue is a value of a backed up page that was left out of the
+// restored page.
+type SkippedValue struct {
+	PageID   string
+	Property string
+	Value    string
+	Reason   string
+}
+
+// Restore creates a database below parentPageID from a backup in dir, with
+// the pages and their content. Relations between pages of the database
+// point to the restored pages; relations to other databases are kept as they
+// are. Computed properties are recreated, not their values. Status
+// properties get the default options of the API, so status values other
+// than those are skipped.
+func Restore(ctx context.Context, client *notionapi.Client, dir, parentPageID string) (Result, error) {
+	var (
+		result   Result
+		database notionapi.Database
+	)
+	if err := readJSON(filepath.Join(dir, databaseFile), &database); err != nil {
+		return result, err
+	}
+	backups, err := readPages(dir)
+	if err != nil {
+		return result, err
+	}
+
+	// Relations to the database itself, and the rollups and formulas that may
+	// depend on them, can only be added once the database exists.
+	schema, deferred := splitSchema(database.Properties, database.ID)
+	created, err := client.CreateDatabase(ctx, notionapi.CreateDatabaseParams{
+		ParentPageID: parentPageID,
+		Title:        database.Title,
+		Properties:   schema,
+		Icon:         database.Icon,
+		Cover:        database.Cover,
+	})
+	if err != nil {
+		return result, errors.WithMessagef(err, "create database failed:%v", parentPageID)
+	}
+	result.DatabaseID = created.ID
+	if len(deferred) > 0 {
+		for _, prop := range deferred {
+			if prop.Relation != nil && prop.Relation.DatabaseID == database.ID {
+				prop.Relation.DatabaseID = created.ID
+			}
+		}
+		if _, err := client.UpdateDatabase(ctx, created.ID, notionapi.UpdateDatabaseParams{Properties: deferred}); err != nil {
+			return result, errors.WithMessagef(err, "add relations to database %v failed", created.ID)
+		}
+	}
+
+	pageIDs := make(map[string]string, len(backups))
+	for _, backup := range backups {
+		props, _ := backup.Page.Properties.(notionapi.DatabasePageProperties)
+		writable, skipped := writableProperties(props, database.Properties, database.ID, created.Properties)
+		page, err := client.CreatePage(ctx, notionapi.CreatePageParams{
+			ParentType:             notionapi.ParentTypeDatabase,
+			ParentID:               created.ID,
+			DatabasePageProperties: writable,
+			Icon:                   backup.Page.Icon,
+			Cover:                  backup.Page.Cover,
+		})
+		if err != nil {
+			return result, errors.WithMessagef(err, "restore page %v failed", backup.Page.ID)
+		}
+		pageIDs[backup.Page.ID] = page.ID
+		result.Pages++
+		for _, value := range skipped {
+			value.PageID = backup.Page.ID
+			logger.T(ctx).Warnf("page %v: %v %q not restored: %v", value.PageID, value.Property, value.Value, value.Reason)
+			result.Skipped = append(result.Skipped, value)
+		}
+
+		if err := renderer.AppendBlocks(ctx, client, page.ID, renderer.Creatable(backup.Blocks)); err != nil {
+			return result, errors.WithMessagef(err, "restore content of page %v failed", backup.Page.ID)
+		}
+		logger.T(ctx).Debugf("restored page %v as %v", backup.Page.ID, page.ID)
+	}
+
+	for _, backup := range backups {
+		props, _ := backup.Page.Properties.(notionapi.DatabasePageProperties)
+		relations := remapRelations(props, database.Properties, database.ID, pageIDs)
+		if len(relations) == 0 {
+			continue
+		}
+		if _, err := client.UpdatePage(ctx, pageIDs[backup.Page.ID], notionapi.UpdatePageParams{
+			DatabasePageProperties: &relations,
+		}); err != nil {
+			return result, errors.WithMessagef(err, "restore relations of page %v failed", backup.Page.ID)
+		}
+	}
+
+	return result, nil
+}
+
+// splitSchema returns the properties a database can be created with, and
+// the ones to add once it exists, stripped of the IDs of the backup.
+func splitSchema(props notionapi.DatabaseProperties, databaseID string) (notionapi.DatabaseProperties, map[string]*notionapi.DatabaseProperty) {
+	schema := make(notionapi.DatabaseProperties, len(props))
+	deferred := make(map[string]*notionapi.DatabaseProperty)
+	for name, prop := range props {
+		prop.ID = ""
+		prop.Name = ""
+		if prop.Select != nil {
+			prop.Select = &notionapi.SelectMetadata{Options: withoutOptionIDs(prop.Select.Options)}
+		}
+		if prop.MultiSelect != nil {
+			prop.MultiSelect = &notionapi.SelectMetadata{Options: withoutOptionIDs(prop.MultiSelect.Options)}
+		}
+		if prop.Status != nil {
+			// The API creates status properties with the default options only.
+			prop.Status = &notionapi.StatusMetadata{}
+		}
+
+		switch {
+		case prop.Type == notionapi.DBPropTypeRelation && prop.Relation != nil && prop.Relation.DatabaseID == databaseID,
+			prop.Type == notionapi.DBPropTypeRollup, prop.Type == notionapi.DBPropTypeFormula:
+			if prop.Relation != nil {
+				relation := notionapi.RelationMetadata{DatabaseID: prop.Relation.DatabaseID}
+				prop.Relation = &relation
+			}
+			if prop.Rollup != nil {
+				prop.Rollup = &notionapi.RollupMetadata{
+					RelationPropName: prop.Rollup.RelationPropName,
+					RollupPropName:   prop.Rollup.RollupPropName,
+					Function:         prop.Rollup.Function,
+				}
+			}
+			p := prop
+			deferred[name] = &p
+		default:
+			if prop.Relation != nil {
+				prop.Relation = &notionapi.RelationMetadata{DatabaseID: prop.Relation.DatabaseID}
+			}
+			schema[name] = prop
+		}
+	}
+	return schema, deferred
+}
+
+func withoutOptionIDs(options []notionapi.SelectOptions) []notionapi.SelectOptions {
+	stripped := make([]notionapi.SelectOptions, len(options))
+	for i, option := range options {
+		stripped[i] = notionapi.SelectOptions{Name: option.Name, Color: option.Color}
+	}
+	return stripped
+}
+
+// writableProperties returns the values of a page that can be set when it
+// is created in the database with the created schema, and the values that
+// cannot. Relations within the database are set once all pages exist.
+func writableProperties(props notionapi.DatabasePageProperties, schema notionapi.DatabaseProperties, databaseID string,
+	created notionapi.DatabaseProperties) (*notionapi.DatabasePageProperties, []SkippedValue) {
+	var skipped []SkippedValue
+	writable := make(notionapi.DatabasePageProperties, len(props))
+	for name, prop := range props {
+		switch prop.Type {
+		case notionapi.DBPropTypeFormula, notionapi.DBPropTypeRollup,
+			notionapi.DBPropTypeCreatedTime, notionapi.DBPropTypeCreatedBy,
+			notionapi.DBPropTypeLastEditedTime, notionapi.DBPropTypeLastEditedBy,
+			notionapi.DBPropTypeUniqueID, notionapi.DBPropTypeVerification:
+			continue
+		case notionapi.DBPropTypeRelation:
+			if isSelfRelation(schema[name], databaseID) {
+				continue
+			}
+		case notionapi.DBPropTypeFiles:
+			// Files hosted by Notion have expiring URLs and cannot be set.
+			var files []notionapi.File
+			for _, file := range prop.Files {
+				if file.External != nil {
+					files = append(files, file)
+				}
+			}
+			prop.Files = files
+		case notionapi.DBPropTypeSelect:
+			if prop.Select != nil {
+				prop.Select = &notionapi.SelectOptions{Name: prop.Select.Name}
+			}
+		case notionapi.DBPropTypeStatus:
+			if prop.Status != nil {
+				if !hasStatusOption(created[name], prop.Status.Name) {
+					skipped = append(skipped, SkippedValue{Property: name, Value: prop.Status.Name, Reason: "no such status option"})
+					continue
+				}
+				prop.Status = &notionapi.SelectOptions{Name: prop.Status.Name}
+			}
+		case notionapi.DBPropTypeMultiSelect:
+			options := make([]notionapi.SelectOptions, len(prop.MultiSelect))
+			for i, option := range prop.MultiSelect {
+				options[i] = notionapi.SelectOptions{Name: option.Name}
+			}
+			prop.MultiSelect = options
+		}
+		prop.ID = ""
+		writable[name] = prop
+	}
+	return &writable, skipped
+}
+
+func hasStatusOption(prop notionapi.DatabaseProperty, name string) bool {
+	if prop.Status == nil {
+		return false
+	}
+	for _, option := range prop.Status.Options {
+		if option.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// remapRelations returns the relations of a page within the database,
+// pointing to the restored pages.
+func remapRelations(props notionapi.DatabasePageProperties, schema notionapi.DatabaseProperties, databaseID string, pageIDs map[string]string) notionapi.DatabasePageProperties {
+	relations := make(notionapi.DatabasePageProperties)
+	for name, prop := range props {
+		if prop.Type != notionapi.DBPropTypeRelation || !isSelfRelation(schema[name], databaseID) || len(prop.Relation) == 0 {
+			continue
+		}
+		remapped := make([]notionapi.Relation, 0, len(prop.Relation))
+		for _, relation := range prop.Relation {
+			if id, ok := pageIDs[relation.ID]; ok {
+				remapped = append(remapped, notionapi.Relation{ID: id})
+			}
+		}
+		relations[name] = notionapi.DatabasePageProperty{Type: notionapi.DBPropTypeRelation, Relation: remapped}
+	}
+	return relations
+}
+
+func isSelfRelation(prop notionapi.DatabaseProperty, databaseID string) bool {
+	return prop.Relation != nil && prop.Relation.DatabaseID == databaseID
+}
+
+func readPages(dir string) ([]PageBackup, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, pagesDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	backups := make([]PageBackup, 0, len(paths))
+	for _, path := range paths {
+		var backup PageBackup
+		if err := readJSON(path, &backup); err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+
+	// Restore in the order the pages were created in.
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Page.CreatedTime.Before(backups[j].Page.CreatedTime)
+	})
+	return backups, nil
+}
+
+func writeJSON(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return errors.WithMessagef(err, "encode %v failed", path)
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return errors.WithMessagef(err, "decode %v failed", path)
+	}
+	return nil
+}
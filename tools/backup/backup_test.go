@@ -0,0 +1,318 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/notionapi/notionapitest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	backedUpID = "668d797c-76fa-4934-9b05-ad288df2d136"
+	otherID    = "b0668f48-8d66-4733-9bdb-2f82215707f7"
+)
+
+// TestMain keeps the log of the code under test on the console, rather than
+// in a log directory of the package.
+func TestMain(m *testing.M) {
+	config := logger.DefaultConfig()
+	config.EnableFile = false
+	logger.Init(&config)
+	os.Exit(m.Run())
+}
+
+func richText(s string) []notionapi.RichText {
+	return []notionapi.RichText{{Text: &notionapi.Text{Content: s}}}
+}
+
+func TestSplitSchema(t *testing.T) {
+	t.Parallel()
+
+	props := notionapi.DatabaseProperties{
+		"Name": {ID: "title", Name: "Name", Type: notionapi.DBPropTypeTitle, Title: &notionapi.EmptyMetadata{}},
+		"Tags": {ID: "p1", Name: "Tags", Type: notionapi.DBPropTypeMultiSelect, MultiSelect: &notionapi.SelectMetadata{
+			Options: []notionapi.SelectOptions{{ID: "o1", Name: "work", Color: notionapi.ColorBlue}},
+		}},
+		"Status": {ID: "p2", Name: "Status", Type: notionapi.DBPropTypeStatus, Status: &notionapi.StatusMetadata{
+			Options: []notionapi.SelectOptions{{ID: "o2", Name: "Blocked", Color: notionapi.ColorRed}},
+		}},
+		"Project": {ID: "p3", Name: "Project", Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{
+			DatabaseID: otherID, SyncedPropName: "Tasks", SyncedPropID: "p9",
+		}},
+		"Parent": {ID: "p4", Name: "Parent", Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{
+			DatabaseID: backedUpID, SyncedPropName: "Children", SyncedPropID: "p5",
+		}},
+		"Subtasks": {ID: "p6", Name: "Subtasks", Type: notionapi.DBPropTypeRollup, Rollup: &notionapi.RollupMetadata{
+			RelationPropName: "Parent", RelationPropID: "p4", RollupPropName: "Name", RollupPropID: "title",
+			Function: notionapi.RollupFunctionCountAll,
+		}},
+		"Score": {ID: "p7", Name: "Score", Type: notionapi.DBPropTypeFormula, Formula: &notionapi.FormulaMetadata{Expression: `prop("Subtasks")`}},
+	}
+
+	schema, deferred := splitSchema(props, backedUpID)
+
+	expSchema := notionapi.DatabaseProperties{
+		"Name": {Type: notionapi.DBPropTypeTitle, Title: &notionapi.EmptyMetadata{}},
+		"Tags": {Type: notionapi.DBPropTypeMultiSelect, MultiSelect: &notionapi.SelectMetadata{
+			Options: []notionapi.SelectOptions{{Name: "work", Color: notionapi.ColorBlue}},
+		}},
+		"Status":  {Type: notionapi.DBPropTypeStatus, Status: &notionapi.StatusMetadata{}},
+		"Project": {Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{DatabaseID: otherID}},
+	}
+	if diff := cmp.Diff(expSchema, schema); diff != "" {
+		t.Fatalf("schema not equal (-exp, +got):\n%v", diff)
+	}
+
+	expDeferred := map[string]*notionapi.DatabaseProperty{
+		"Parent": {Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{DatabaseID: backedUpID}},
+		"Subtasks": {Type: notionapi.DBPropTypeRollup, Rollup: &notionapi.RollupMetadata{
+			RelationPropName: "Parent", RollupPropName: "Name", Function: notionapi.RollupFunctionCountAll,
+		}},
+		"Score": {Type: notionapi.DBPropTypeFormula, Formula: &notionapi.FormulaMetadata{Expression: `prop("Subtasks")`}},
+	}
+	if diff := cmp.Diff(expDeferred, deferred); diff != "" {
+		t.Fatalf("deferred not equal (-exp, +got):\n%v", diff)
+	}
+
+	// The backup is left as it was read.
+	if props["Tags"].MultiSelect.Options[0].ID != "o1" || props["Parent"].Relation.SyncedPropID != "p5" {
+		t.Fatalf("expected the backed up schema to be unchanged, got %+v", props)
+	}
+}
+
+func TestWritableProperties(t *testing.T) {
+	t.Parallel()
+
+	schema := notionapi.DatabaseProperties{
+		"Parent":  {Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{DatabaseID: backedUpID}},
+		"Project": {Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{DatabaseID: otherID}},
+	}
+	created := notionapi.DatabaseProperties{
+		"Status": {Type: notionapi.DBPropTypeStatus, Status: &notionapi.StatusMetadata{
+			Options: []notionapi.SelectOptions{{ID: "n1", Name: "Not started"}, {ID: "n2", Name: "Done"}},
+		}},
+		"Stage": {Type: notionapi.DBPropTypeStatus, Status: &notionapi.StatusMetadata{
+			Options: []notionapi.SelectOptions{{ID: "n3", Name: "Not started"}},
+		}},
+	}
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	props := notionapi.DatabasePageProperties{
+		"Name":     {ID: "title", Type: notionapi.DBPropTypeTitle, Title: richText("Write report")},
+		"Status":   {ID: "p1", Type: notionapi.DBPropTypeStatus, Status: &notionapi.SelectOptions{ID: "o1", Name: "Done", Color: notionapi.ColorGreen}},
+		"Stage":    {ID: "p2", Type: notionapi.DBPropTypeStatus, Status: &notionapi.SelectOptions{ID: "o2", Name: "Blocked", Color: notionapi.ColorRed}},
+		"Priority": {ID: "p3", Type: notionapi.DBPropTypeSelect, Select: &notionapi.SelectOptions{ID: "o3", Name: "P1", Color: notionapi.ColorRed}},
+		"Tags":     {ID: "p4", Type: notionapi.DBPropTypeMultiSelect, MultiSelect: []notionapi.SelectOptions{{ID: "o4", Name: "work"}}},
+		"Files": {ID: "p5", Type: notionapi.DBPropTypeFiles, Files: []notionapi.File{
+			{Name: "hosted.png", Type: notionapi.FileTypeFile, File: &notionapi.FileFile{URL: "https://s3.example.com/hosted.png"}},
+			{Name: "linked.png", Type: notionapi.FileTypeExternal, External: &notionapi.FileExternal{URL: "https://example.com/linked.png"}},
+		}},
+		"Parent":  {ID: "p6", Type: notionapi.DBPropTypeRelation, Relation: []notionapi.Relation{{ID: "page-1"}}},
+		"Project": {ID: "p7", Type: notionapi.DBPropTypeRelation, Relation: []notionapi.Relation{{ID: "project-1"}}},
+		"Score":   {ID: "p8", Type: notionapi.DBPropTypeFormula, Formula: &notionapi.FormulaResult{Type: notionapi.FormulaResultTypeNumber}},
+		"Created": {ID: "p9", Type: notionapi.DBPropTypeCreatedTime, CreatedTime: &now},
+	}
+
+	writable, skipped := writableProperties(props, schema, backedUpID, created)
+
+	exp := &notionapi.DatabasePageProperties{
+		"Name":     {Type: notionapi.DBPropTypeTitle, Title: richText("Write report")},
+		"Status":   {Type: notionapi.DBPropTypeStatus, Status: &notionapi.SelectOptions{Name: "Done"}},
+		"Priority": {Type: notionapi.DBPropTypeSelect, Select: &notionapi.SelectOptions{Name: "P1"}},
+		"Tags":     {Type: notionapi.DBPropTypeMultiSelect, MultiSelect: []notionapi.SelectOptions{{Name: "work"}}},
+		"Files": {Type: notionapi.DBPropTypeFiles, Files: []notionapi.File{
+			{Name: "linked.png", Type: notionapi.FileTypeExternal, External: &notionapi.FileExternal{URL: "https://example.com/linked.png"}},
+		}},
+		"Project": {Type: notionapi.DBPropTypeRelation, Relation: []notionapi.Relation{{ID: "project-1"}}},
+	}
+	if diff := cmp.Diff(exp, writable); diff != "" {
+		t.Fatalf("properties not equal (-exp, +got):\n%v", diff)
+	}
+
+	expSkipped := []SkippedValue{{Property: "Stage", Value: "Blocked", Reason: "no such status option"}}
+	if diff := cmp.Diff(expSkipped, skipped); diff != "" {
+		t.Fatalf("skipped not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestRemapRelations(t *testing.T) {
+	t.Parallel()
+
+	schema := notionapi.DatabaseProperties{
+		"Parent":  {Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{DatabaseID: backedUpID}},
+		"Blocks":  {Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{DatabaseID: backedUpID}},
+		"Project": {Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{DatabaseID: otherID}},
+	}
+	props := notionapi.DatabasePageProperties{
+		"Name":    {Type: notionapi.DBPropTypeTitle, Title: richText("Write report")},
+		"Parent":  {Type: notionapi.DBPropTypeRelation, Relation: []notionapi.Relation{{ID: "old-1"}, {ID: "missing"}, {ID: "old-2"}}},
+		"Blocks":  {Type: notionapi.DBPropTypeRelation, Relation: []notionapi.Relation{}},
+		"Project": {Type: notionapi.DBPropTypeRelation, Relation: []notionapi.Relation{{ID: "project-1"}}},
+	}
+	pageIDs := map[string]string{"old-1": "new-1", "old-2": "new-2"}
+
+	exp := notionapi.DatabasePageProperties{
+		"Parent": {Type: notionapi.DBPropTypeRelation, Relation: []notionapi.Relation{{ID: "new-1"}, {ID: "new-2"}}},
+	}
+	if diff := cmp.Diff(exp, remapRelations(props, schema, backedUpID, pageIDs)); diff != "" {
+		t.Fatalf("relations not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestReadPages(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, pagesDir), 0o755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	created := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	for _, page := range []notionapi.Page{
+		{ID: "a", CreatedTime: created.Add(time.Hour)},
+		{ID: "b", CreatedTime: created},
+		{ID: "c", CreatedTime: created.Add(time.Hour)},
+		{ID: "d", CreatedTime: created.Add(-time.Hour)},
+	} {
+		page.Parent = notionapi.Parent{Type: notionapi.ParentTypeDatabase, DatabaseID: backedUpID}
+		if err := writeJSON(filepath.Join(dir, pagesDir, page.ID+".json"), PageBackup{Page: page}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	backups, err := readPages(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, backup := range backups {
+		got = append(got, backup.Page.ID)
+	}
+	// Oldest first, pages created at the same time in the order of their IDs.
+	if diff := cmp.Diff([]string{"d", "b", "a", "c"}, got); diff != "" {
+		t.Fatalf("pages not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	t.Parallel()
+
+	srv := notionapitest.NewServer()
+	defer srv.Close()
+	db := srv.AddDatabase(notionapi.Database{
+		ID:     backedUpID,
+		Parent: notionapi.Parent{Type: notionapi.ParentTypePage, PageID: otherID},
+		Title:  richText("Tasks"),
+		Properties: notionapi.DatabaseProperties{
+			"Name":  {Type: notionapi.DBPropTypeTitle},
+			"Notes": {Type: notionapi.DBPropTypeRichText},
+			"Status": {Type: notionapi.DBPropTypeStatus, Status: &notionapi.StatusMetadata{
+				Options: []notionapi.SelectOptions{{Name: "Blocked"}, {Name: "Done"}},
+			}},
+			"Parent": {Type: notionapi.DBPropTypeRelation, Relation: &notionapi.RelationMetadata{DatabaseID: backedUpID}},
+		},
+	})
+
+	root := srv.AddPage(db.ID, notionapi.DatabasePageProperties{
+		"Name":   {Title: richText("Plan trip")},
+		"Status": {Status: &notionapi.SelectOptions{Name: "Blocked"}},
+	})
+	srv.AddBlocks(root.ID, notionapi.Block{
+		Type:      notionapi.BlockTypeParagraph,
+		Paragraph: &notionapi.RichTextBlock{RichText: richText("Book flights first.")},
+	})
+	var (
+		notes    []notionapi.RichText
+		children []notionapi.Relation
+	)
+	for i := 0; i < 30; i++ {
+		notes = append(notes, richText(fmt.Sprintf("%v. ", i))...)
+	}
+	for i := 0; i < 30; i++ {
+		child := srv.AddPage(db.ID, notionapi.DatabasePageProperties{
+			"Name":   {Title: richText(fmt.Sprintf("Step %02d", i))},
+			"Status": {Status: &notionapi.SelectOptions{Name: "Done"}},
+			"Parent": {Relation: []notionapi.Relation{{ID: root.ID}}},
+		})
+		children = append(children, notionapi.Relation{ID: child.ID})
+	}
+	// The root relates to every step, more than a page holds.
+	srv.AddPage(db.ID, notionapi.DatabasePageProperties{
+		"Name":   {Title: richText("Checklist")},
+		"Notes":  {RichText: notes},
+		"Parent": {Relation: children},
+	})
+
+	client := srv.Client()
+	ctx := context.Background()
+	dir := t.TempDir()
+	pages, err := Backup(ctx, client, db.ID, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pages != 32 {
+		t.Fatalf("expected 32 pages backed up, got %v", pages)
+	}
+
+	result, err := Restore(ctx, client, dir, otherID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Pages != 32 {
+		t.Fatalf("expected 32 pages restored, got %v", result.Pages)
+	}
+	expSkipped := []SkippedValue{{PageID: root.ID, Property: "Status", Value: "Blocked", Reason: "no such status option"}}
+	if diff := cmp.Diff(expSkipped, result.Skipped); diff != "" {
+		t.Fatalf("skipped not equal (-exp, +got):\n%v", diff)
+	}
+
+	type row struct {
+		Name, Notes, Status string
+		Parent              []string
+	}
+	rows := func(databaseID string) []row {
+		pages := srv.Pages(databaseID)
+		names := make(map[string]string, len(pages))
+		for _, page := range pages {
+			names[page.ID] = notionapi.PlainText(page.Properties.(notionapi.DatabasePageProperties)["Name"].Title)
+		}
+		var got []row
+		for _, page := range pages {
+			props := page.Properties.(notionapi.DatabasePageProperties)
+			r := row{
+				Name:  notionapi.PlainText(props["Name"].Title),
+				Notes: notionapi.PlainText(props["Notes"].RichText),
+			}
+			if props["Status"].Status != nil {
+				r.Status = props["Status"].Status.Name
+			}
+			for _, relation := range props["Parent"].Relation {
+				r.Parent = append(r.Parent, names[relation.ID])
+			}
+			got = append(got, r)
+		}
+		return got
+	}
+
+	// Restored databases have the default status options only.
+	exp := rows(db.ID)
+	exp[0].Status = ""
+	if diff := cmp.Diff(exp, rows(result.DatabaseID)); diff != "" {
+		t.Fatalf("restored pages not equal (-exp, +got):\n%v", diff)
+	}
+	if got := exp[len(exp)-1]; len(got.Parent) != 30 || got.Notes != notionapi.PlainText(notes) {
+		t.Fatalf("expected all 30 relations and the full notes, got %+v", got)
+	}
+
+	restored := srv.Pages(result.DatabaseID)
+	blocks := srv.Blocks(restored[0].ID)
+	if len(blocks) != 1 || notionapi.PlainText(blocks[0].Paragraph.RichText) != "Book flights first." {
+		t.Fatalf("expected the content to be restored, got %+v", blocks)
+	}
+}
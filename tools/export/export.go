@@ -0,0 +1,334 @@
+// Package export writes the rows of a Notion database as CSV, JSON lines or
+// XLSX, one column per property.
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"notionsync/pkg/notionapi"
+
+	"github.com/pkg/errors"
+)
+
+// Format is the file format rows are written in.
+type Format string
+
+// Formats.
+const (
+	FormatCSV   Format = "csv"
+	FormatJSONL Format = "jsonl"
+	FormatXLSX  Format = "xlsx"
+)
+
+// ParseFormat returns the format named s.
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(s); format {
+	case FormatCSV, FormatJSONL, FormatXLSX:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected %v, %v or %v", s, FormatCSV, FormatJSONL, FormatXLSX)
+}
+
+// ParseSort parses a sort given as "property" or "property:direction",
+// where direction is asc, desc, ascending or descending. The created_time
+// and last_edited_time names sort by the timestamps of the page.
+func ParseSort(s string) (notionapi.DatabaseQuerySort, error) {
+	name, direction := s, notionapi.SortDirAsc
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		name = s[:i]
+		switch s[i+1:] {
+		case "asc", "ascending":
+		case "desc", "descending":
+			direction = notionapi.SortDirDesc
+		default:
+			return notionapi.DatabaseQuerySort{}, fmt.Errorf("unknown sort direction in %q, expected asc or desc", s)
+		}
+	}
+	if len(name) == 0 {
+		return notionapi.DatabaseQuerySort{}, fmt.Errorf("missing property in sort %q", s)
+	}
+
+	switch timestamp := notionapi.SortTimestamp(name); timestamp {
+	case notionapi.SortTimeStampCreatedTime, notionapi.SortTimeStampLastEditedTime:
+		return notionapi.DatabaseQuerySort{Timestamp: timestamp, Direction: direction}, nil
+	}
+	return notionapi.DatabaseQuerySort{Property: name, Direction: direction}, nil
+}
+
+// Options select the rows and columns to export.
+type Options struct {
+	Format Format
+	// Columns are the names of the properties to write, in order. All
+	// properties are written when empty, the title first.
+	Columns []string
+	// Filter is checked against the properties of the database before it is
+	// sent.
+	Filter *notionapi.DatabaseQueryFilter
+	Sorts  []notionapi.DatabaseQuerySort
+	// Location dates and times are written in, UTC when nil.
+	Location *time.Location
+}
+
+// rowWriter writes rows of flattened values.
+type rowWriter interface {
+	Write(row []interface{}) error
+	Close() error
+}
+
+// Export queries a database and writes its rows to w, returning the number
+// of rows.
+func Export(ctx context.Context, client *notionapi.Client, databaseID string, w io.Writer, opts Options) (int, error) {
+	database, err := client.FindDatabaseByID(ctx, databaseID)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "find database failed:%v", databaseID)
+	}
+	columns, err := selectColumns(database.Properties, opts.Columns)
+	if err != nil {
+		return 0, err
+	}
+	if opts.Filter != nil {
+		if err := opts.Filter.Validate(database.Properties); err != nil {
+			return 0, errors.WithMessage(err, "invalid filter")
+		}
+	}
+	location := opts.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	var rw rowWriter
+	switch opts.Format {
+	case FormatJSONL:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		rw = &jsonlWriter{enc: enc, columns: columns}
+	case FormatXLSX:
+		rw = newXLSXWriter(w)
+	default:
+		rw = &csvWriter{w: csv.NewWriter(w)}
+	}
+	if opts.Format != FormatJSONL {
+		header := make([]interface{}, len(columns))
+		for i, column := range columns {
+			header[i] = column
+		}
+		if err := rw.Write(header); err != nil {
+			return 0, err
+		}
+	}
+
+	var (
+		rows  int
+		query = &notionapi.DatabaseQuery{Filter: opts.Filter, Sorts: opts.Sorts, PageSize: 100}
+	)
+	for {
+		resp, err := client.QueryDatabase(ctx, databaseID, query)
+		if err != nil {
+			return rows, errors.WithMessagef(err, "query database failed:%v", databaseID)
+		}
+
+		for _, page := range resp.Results {
+			props, _ := page.Properties.(notionapi.DatabasePageProperties)
+			row := make([]interface{}, len(columns))
+			for i, column := range columns {
+				if prop, ok := props[column]; ok {
+					row[i] = Flatten(prop.Value(), location)
+				}
+			}
+			if err := rw.Write(row); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+
+		if !resp.HasMore || resp.NextCursor == nil {
+			return rows, rw.Close()
+		}
+		query.StartCursor = *resp.NextCursor
+	}
+}
+
+// selectColumns checks the configured columns against the properties of the
+// database, or lists them all when none are configured.
+func selectColumns(props notionapi.DatabaseProperties, columns []string) ([]string, error) {
+	if len(columns) > 0 {
+		for _, column := range columns {
+			if _, ok := props[column]; !ok {
+				return nil, fmt.Errorf("unknown column %q, not a property of the database", column)
+			}
+		}
+		return columns, nil
+	}
+
+	for name := range props {
+		columns = append(columns, name)
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		ti, tj := props[columns[i]].Type == notionapi.DBPropTypeTitle, props[columns[j]].Type == notionapi.DBPropTypeTitle
+		if ti != tj {
+			return ti
+		}
+		return columns[i] < columns[j]
+	})
+	return columns, nil
+}
+
+// Flatten converts the value of a property, as returned by
+// notionapi.DatabasePageProperty.Value, to a string, float64, bool, a list
+// of strings, or nil when empty. Rich text becomes plain text, options and
+// people their names, and dates are formatted in location.
+func Flatten(value interface{}, location *time.Location) interface{} {
+	switch v := value.(type) {
+	case []notionapi.RichText:
+		var sb strings.Builder
+		for _, text := range v {
+			if len(text.PlainText) == 0 && text.Text != nil {
+				sb.WriteString(text.Text.Content)
+				continue
+			}
+			sb.WriteString(text.PlainText)
+		}
+		return sb.String()
+	case *float64:
+		if v != nil {
+			return *v
+		}
+	case *bool:
+		if v != nil {
+			return *v
+		}
+	case *string:
+		if v != nil {
+			return *v
+		}
+	case *notionapi.SelectOptions:
+		if v != nil {
+			return v.Name
+		}
+	case []notionapi.SelectOptions:
+		names := make([]string, len(v))
+		for i, option := range v {
+			names[i] = option.Name
+		}
+		return names
+	case *notionapi.Date:
+		if v != nil {
+			return formatDate(*v, location)
+		}
+	case *time.Time:
+		if v != nil {
+			return v.In(location).Format("2006-01-02 15:04")
+		}
+	case *notionapi.User:
+		if v != nil {
+			return v.Name
+		}
+	case []notionapi.User:
+		names := make([]string, len(v))
+		for i, user := range v {
+			names[i] = user.Name
+		}
+		return names
+	case []notionapi.File:
+		names := make([]string, len(v))
+		for i, file := range v {
+			names[i] = file.Name
+		}
+		return names
+	case []notionapi.Relation:
+		ids := make([]string, len(v))
+		for i, relation := range v {
+			ids[i] = relation.ID
+		}
+		return ids
+	case *notionapi.FormulaResult:
+		if v != nil {
+			return Flatten(v.Value(), location)
+		}
+	case *notionapi.RollupResult:
+		if v != nil {
+			return Flatten(v.Value(), location)
+		}
+	case []notionapi.DatabasePageProperty:
+		var values []string
+		for _, prop := range v {
+			values = append(values, formatCell(Flatten(prop.Value(), location)))
+		}
+		return values
+	}
+	return nil
+}
+
+func formatDate(date notionapi.Date, location *time.Location) string {
+	format := func(dt notionapi.DateTime) string {
+		if dt.HasTime() {
+			return dt.In(location).Format("2006-01-02 15:04")
+		}
+		return dt.Format("2006-01-02")
+	}
+
+	s := format(date.Start)
+	if date.End != nil {
+		s += " → " + format(*date.End)
+	}
+	return s
+}
+
+// formatCell writes a flattened value as text.
+func formatCell(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []string:
+		return strings.Join(v, ", ")
+	}
+	return ""
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = formatCell(value)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlWriter writes a row as an object keyed by column, keeping numbers,
+// booleans and lists as JSON values.
+type jsonlWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+func (j *jsonlWriter) Write(row []interface{}) error {
+	object := make(map[string]interface{}, len(row))
+	for i, value := range row {
+		object[j.columns[i]] = value
+	}
+	return j.enc.Encode(object)
+}
+
+func (j *jsonlWriter) Close() error {
+	return nil
+}
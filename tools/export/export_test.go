@@ -0,0 +1,200 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/notionapi/notionapitest"
+	"notionsync/tools/export"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// exportRows writes the rows of a database with an escaped title, a checkbox,
+// a number and empty cells in format.
+func exportRows(t *testing.T, format export.Format) []byte {
+	t.Helper()
+
+	srv := notionapitest.NewServer()
+	t.Cleanup(srv.Close)
+
+	db := srv.AddDatabase(notionapi.Database{
+		Properties: notionapi.DatabaseProperties{
+			"Task":   {Type: notionapi.DBPropTypeTitle},
+			"Done":   {Type: notionapi.DBPropTypeCheckbox},
+			"Points": {Type: notionapi.DBPropTypeNumber},
+		},
+	})
+	done, points := true, 3.5
+	srv.AddPage(db.ID, notionapi.DatabasePageProperties{
+		"Task":   {Title: []notionapi.RichText{{Text: &notionapi.Text{Content: `Buy <milk> & "eggs", bread`}}}},
+		"Done":   {Checkbox: &done},
+		"Points": {Number: &points},
+	})
+	srv.AddPage(db.ID, notionapi.DatabasePageProperties{
+		"Task": {Title: []notionapi.RichText{{Text: &notionapi.Text{Content: "Walk the dog"}}}},
+	})
+
+	client := notionapi.NewClient("secret-api-key", notionapi.WithHTTPClient(srv.Server.Client()), notionapi.WithBaseURL(srv.BaseURL()))
+	var buf bytes.Buffer
+	rows, err := export.Export(context.Background(), client, db.ID, &buf, export.Options{
+		Format:  format,
+		Columns: []string{"Task", "Done", "Points"},
+		Sorts:   []notionapi.DatabaseQuerySort{{Property: "Task", Direction: notionapi.SortDirAsc}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rows != 2 {
+		t.Fatalf("expected 2 rows, got %v", rows)
+	}
+	return buf.Bytes()
+}
+
+func TestExportCSV(t *testing.T) {
+	t.Parallel()
+
+	records, err := csv.NewReader(bytes.NewReader(exportRows(t, export.FormatCSV))).ReadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := [][]string{
+		{"Task", "Done", "Points"},
+		{`Buy <milk> & "eggs", bread`, "true", "3.5"},
+		{"Walk the dog", "", ""},
+	}
+	if diff := cmp.Diff(exp, records); diff != "" {
+		t.Fatalf("records not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	t.Parallel()
+
+	out := exportRows(t, export.FormatJSONL)
+	if !bytes.Contains(out, []byte(`"Buy <milk> & \"eggs\", bread"`)) {
+		t.Errorf("expected HTML characters to be written unescaped, got %s", out)
+	}
+
+	var objects []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		var object map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &object); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		objects = append(objects, object)
+	}
+
+	exp := []map[string]interface{}{
+		{"Task": `Buy <milk> & "eggs", bread`, "Done": true, "Points": 3.5},
+		{"Task": "Walk the dog", "Done": nil, "Points": nil},
+	}
+	if diff := cmp.Diff(exp, objects); diff != "" {
+		t.Fatalf("objects not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestExportXLSX(t *testing.T) {
+	t.Parallel()
+
+	out := exportRows(t, export.FormatXLSX)
+	zr, err := zip.NewReader(bytes.NewReader(out), int64(len(out)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	parts := make(map[string][]byte)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parts[f.Name], err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expNames := []string{
+		"[Content_Types].xml",
+		"_rels/.rels",
+		"xl/workbook.xml",
+		"xl/_rels/workbook.xml.rels",
+		"xl/styles.xml",
+		"xl/worksheets/sheet1.xml",
+	}
+	if diff := cmp.Diff(expNames, names); diff != "" {
+		t.Fatalf("parts not equal (-exp, +got):\n%v", diff)
+	}
+	for _, name := range names {
+		if err := xml.Unmarshal(parts[name], new(struct{})); err != nil {
+			t.Errorf("part %v is not well-formed XML: %v", name, err)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	if !bytes.Contains(sheet, []byte(`<t xml:space="preserve">Buy &lt;milk&gt; &amp; &#34;eggs&#34;, bread</t>`)) {
+		t.Errorf("expected the title to be escaped, got %s", sheet)
+	}
+
+	var worksheet struct {
+		Rows []struct {
+			R     string `xml:"r,attr"`
+			Cells []struct {
+				R     string `xml:"r,attr"`
+				S     string `xml:"s,attr"`
+				T     string `xml:"t,attr"`
+				V     string `xml:"v"`
+				IsStr string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &worksheet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type cell struct{ Ref, Style, Type, Value string }
+	var got [][]cell
+	for _, row := range worksheet.Rows {
+		var cells []cell
+		for _, c := range row.Cells {
+			value := c.V
+			if c.T == "inlineStr" {
+				value = c.IsStr
+			}
+			cells = append(cells, cell{Ref: c.R, Style: c.S, Type: c.T, Value: value})
+		}
+		got = append(got, cells)
+	}
+
+	exp := [][]cell{
+		{
+			{Ref: "A1", Style: "1", Type: "inlineStr", Value: "Task"},
+			{Ref: "B1", Style: "1", Type: "inlineStr", Value: "Done"},
+			{Ref: "C1", Style: "1", Type: "inlineStr", Value: "Points"},
+		},
+		{
+			{Ref: "A2", Type: "inlineStr", Value: `Buy <milk> & "eggs", bread`},
+			{Ref: "B2", Type: "b", Value: "1"},
+			{Ref: "C2", Value: "3.5"},
+		},
+		{
+			{Ref: "A3", Type: "inlineStr", Value: "Walk the dog"},
+		},
+	}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("cells not equal (-exp, +got):\n%v", diff)
+	}
+}
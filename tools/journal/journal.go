@@ -0,0 +1,235 @@
+// Package journal keeps an append-only JSONL record of every change the sync
+// makes to a Notion page, with the property values before and after it.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"notionsync/pkg/notionapi"
+
+	"github.com/pkg/errors"
+)
+
+// Operations.
+const (
+	OperationCreated  = "created"
+	OperationUpdated  = "updated"
+	OperationDeleted  = "deleted"
+	OperationRenamed  = "renamed"
+	OperationRestored = "restored"
+	OperationArchived = "archived"
+)
+
+// Outcomes.
+const (
+	OutcomeOK     = "ok"
+	OutcomeFailed = "failed"
+)
+
+// Entry is a single change to a Notion page.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	TaskID     string    `json:"taskId"`
+	PageID     string    `json:"pageId,omitempty"`
+	DatabaseID string    `json:"databaseId"`
+	Operation  string    `json:"operation"`
+	// Before holds the values the changed properties had, and is empty for
+	// created pages.
+	Before notionapi.DatabasePageProperties `json:"before,omitempty"`
+	After  notionapi.DatabasePageProperties `json:"after,omitempty"`
+	// Archived is set when the change archived the page, or restored it.
+	Archived *bool `json:"archived,omitempty"`
+	// Outcome is one of the Outcome constants, with the error in Error when
+	// the change failed.
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Journal appends entries to a JSONL file. It is safe for concurrent use.
+type Journal struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens the journal at path for appending, creating the file and its
+// directory if needed.
+func Open(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, errors.WithMessagef(err, "create journal directory for %v failed", path)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "open journal %v failed", path)
+	}
+
+	return &Journal{file: file}, nil
+}
+
+// Append writes entry as one line, setting its time if unset. Appending to a
+// nil Journal does nothing, so callers need not check whether it is enabled.
+func (j *Journal) Append(entry Entry) error {
+	if j == nil {
+		return nil
+	}
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return errors.WithMessagef(err, "marshal journal entry of task %v failed", entry.TaskID)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(line); err != nil {
+		return errors.WithMessagef(err, "write journal %v failed", j.file.Name())
+	}
+	return nil
+}
+
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// Scan calls fn with every entry of the journal at path, oldest first, until
+// fn returns an error.
+func Scan(path string, fn func(Entry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.WithMessagef(err, "open journal %v failed", path)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return errors.WithMessagef(err, "journal %v line %v", path, line)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return errors.WithMessagef(scanner.Err(), "read journal %v failed", path)
+}
+
+// History returns the entries of a To Do task, oldest first.
+func History(path, taskID string) ([]Entry, error) {
+	var entries []Entry
+	err := Scan(path, func(entry Entry) error {
+		if entry.TaskID == taskID {
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Value renders a journaled property value for display.
+func Value(prop notionapi.DatabasePageProperty) string {
+	switch {
+	case prop.Title != nil:
+		return notionapi.PlainText(prop.Title)
+	case prop.RichText != nil:
+		return notionapi.PlainText(prop.RichText)
+	case prop.Checkbox != nil:
+		return strconv.FormatBool(*prop.Checkbox)
+	case prop.Select != nil:
+		return prop.Select.Name
+	case prop.Date != nil:
+		start, _ := prop.Date.Start.MarshalJSON()
+		return strings.Trim(string(start), `"`)
+	case prop.Relation != nil:
+		ids := make([]string, 0, len(prop.Relation))
+		for _, relation := range prop.Relation {
+			ids = append(ids, relation.ID)
+		}
+		return strings.Join(ids, ",")
+	case prop.Number != nil:
+		return strconv.FormatFloat(*prop.Number, 'f', -1, 64)
+	}
+	return ""
+}
+
+// Restore holds the property values a page had before the changes journaled
+// since a point in time.
+type Restore struct {
+	TaskID     string
+	PageID     string
+	DatabaseID string
+	Properties notionapi.DatabasePageProperties
+	// Created is set when the page was created after that point, and has no
+	// earlier values to go back to.
+	Created bool
+	// Unarchive is set when the page was archived after that point.
+	Unarchive bool
+}
+
+// Restores returns, for every page changed successfully since the given time,
+// the values its changed properties had before the first of those changes.
+// Pages are in the order they were first changed.
+func Restores(path string, since time.Time) ([]Restore, error) {
+	var (
+		restores []Restore
+		index    = make(map[string]int)
+	)
+
+	err := Scan(path, func(entry Entry) error {
+		if entry.Time.Before(since) || entry.Outcome != OutcomeOK || len(entry.PageID) == 0 {
+			return nil
+		}
+
+		i, ok := index[entry.PageID]
+		if !ok {
+			i = len(restores)
+			index[entry.PageID] = i
+			restores = append(restores, Restore{
+				TaskID:     entry.TaskID,
+				PageID:     entry.PageID,
+				DatabaseID: entry.DatabaseID,
+				Properties: make(notionapi.DatabasePageProperties),
+				Created:    entry.Operation == OperationCreated,
+			})
+		}
+
+		restore := &restores[i]
+		if restore.Created {
+			return nil
+		}
+		if entry.Archived != nil && *entry.Archived {
+			restore.Unarchive = true
+		}
+		for name := range entry.After {
+			if _, seen := restore.Properties[name]; seen {
+				continue
+			}
+			if before, ok := entry.Before[name]; ok {
+				restore.Properties[name] = before
+			}
+		}
+		return nil
+	})
+
+	return restores, err
+}
@@ -0,0 +1,319 @@
+package journal_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/tools/journal"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	_true  = true
+	_false = false
+)
+
+func title(s string) notionapi.DatabasePageProperty {
+	return notionapi.DatabasePageProperty{Title: []notionapi.RichText{{Text: &notionapi.Text{Content: s}}}}
+}
+
+func checkbox(b *bool) notionapi.DatabasePageProperty {
+	return notionapi.DatabasePageProperty{Checkbox: b}
+}
+
+func writeJournal(t *testing.T, entries ...journal.Entry) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "nested", "journal.jsonl")
+	j, err := journal.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, entry := range entries {
+		if err := j.Append(entry); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestAppendScan(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	entries := []journal.Entry{
+		{
+			Time:       start,
+			TaskID:     "task-1",
+			PageID:     "page-1",
+			DatabaseID: "db",
+			Operation:  journal.OperationCreated,
+			After:      notionapi.DatabasePageProperties{"Task": title("Buy milk")},
+			Outcome:    journal.OutcomeOK,
+		},
+		{
+			Time:       start.Add(time.Minute),
+			TaskID:     "task-2",
+			PageID:     "page-2",
+			DatabaseID: "db",
+			Operation:  journal.OperationUpdated,
+			Before:     notionapi.DatabasePageProperties{"Done": checkbox(&_false)},
+			After:      notionapi.DatabasePageProperties{"Done": checkbox(&_true)},
+			Outcome:    journal.OutcomeFailed,
+			Error:      "conflict",
+		},
+		{
+			Time:       start.Add(2 * time.Minute),
+			TaskID:     "task-1",
+			PageID:     "page-1",
+			DatabaseID: "db",
+			Operation:  journal.OperationDeleted,
+			Archived:   &_true,
+			Outcome:    journal.OutcomeOK,
+		},
+	}
+	path := writeJournal(t, entries...)
+
+	// Reopening appends rather than truncates.
+	j, err := journal.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.Append(journal.Entry{TaskID: "task-3", Operation: journal.OperationCreated, Outcome: journal.OutcomeOK}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []journal.Entry
+	if err := journal.Scan(path, func(entry journal.Entry) error {
+		got = append(got, entry)
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("expected 4 entries, got %v", len(got))
+	}
+	if got[3].Time.IsZero() {
+		t.Errorf("expected the time of an appended entry to be set")
+	}
+	if diff := cmp.Diff(entries, got[:3]); diff != "" {
+		t.Errorf("entries not equal (-exp, +got):\n%v", diff)
+	}
+
+	history, err := journal.History(path, "task-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]journal.Entry{entries[0], entries[2]}, history); diff != "" {
+		t.Errorf("history not equal (-exp, +got):\n%v", diff)
+	}
+
+	stop := errors.New("stop")
+	var scanned int
+	err = journal.Scan(path, func(journal.Entry) error {
+		scanned++
+		return stop
+	})
+	if !errors.Is(err, stop) || scanned != 1 {
+		t.Errorf("expected the scan to stop at the first entry, got %v after %v entries", err, scanned)
+	}
+}
+
+func TestAppendNil(t *testing.T) {
+	t.Parallel()
+
+	var j *journal.Journal
+	if err := j.Append(journal.Entry{TaskID: "task-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestScanMissing(t *testing.T) {
+	t.Parallel()
+
+	err := journal.Scan(filepath.Join(t.TempDir(), "missing.jsonl"), func(journal.Entry) error { return nil })
+	if err == nil {
+		t.Fatalf("expected an error for a missing journal")
+	}
+}
+
+func TestRestores(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	path := writeJournal(t,
+		// Before the rollback point, so not undone.
+		journal.Entry{
+			Time: at(-10), TaskID: "task-1", PageID: "page-1", DatabaseID: "db",
+			Operation: journal.OperationUpdated,
+			Before:    notionapi.DatabasePageProperties{"Task": title("Milk")},
+			After:     notionapi.DatabasePageProperties{"Task": title("Buy milk")},
+			Outcome:   journal.OutcomeOK,
+		},
+		journal.Entry{
+			Time: at(0), TaskID: "task-2", PageID: "page-2", DatabaseID: "db",
+			Operation: journal.OperationCreated,
+			After:     notionapi.DatabasePageProperties{"Task": title("Walk the dog")},
+			Outcome:   journal.OutcomeOK,
+		},
+		journal.Entry{
+			Time: at(1), TaskID: "task-1", PageID: "page-1", DatabaseID: "db",
+			Operation: journal.OperationUpdated,
+			Before:    notionapi.DatabasePageProperties{"Task": title("Buy milk"), "Done": checkbox(&_false)},
+			After:     notionapi.DatabasePageProperties{"Task": title("Buy oat milk"), "Done": checkbox(&_true)},
+			Outcome:   journal.OutcomeOK,
+		},
+		// Later changes of the same property keep the earliest value.
+		journal.Entry{
+			Time: at(2), TaskID: "task-1", PageID: "page-1", DatabaseID: "db",
+			Operation: journal.OperationUpdated,
+			Before:    notionapi.DatabasePageProperties{"Task": title("Buy oat milk")},
+			After:     notionapi.DatabasePageProperties{"Task": title("Buy soy milk")},
+			Outcome:   journal.OutcomeOK,
+		},
+		// Changes of a created page are not restored.
+		journal.Entry{
+			Time: at(3), TaskID: "task-2", PageID: "page-2", DatabaseID: "db",
+			Operation: journal.OperationUpdated,
+			Before:    notionapi.DatabasePageProperties{"Task": title("Walk the dog")},
+			After:     notionapi.DatabasePageProperties{"Task": title("Walk the cat")},
+			Outcome:   journal.OutcomeOK,
+		},
+		journal.Entry{
+			Time: at(4), TaskID: "list-1", PageID: "page-3", DatabaseID: "lists",
+			Operation: journal.OperationRenamed,
+			Before:    notionapi.DatabasePageProperties{"List": title("Groceries")},
+			After:     notionapi.DatabasePageProperties{"List": title("Shopping")},
+			Outcome:   journal.OutcomeOK,
+		},
+		// Failed changes did not happen.
+		journal.Entry{
+			Time: at(5), TaskID: "task-4", PageID: "page-4", DatabaseID: "db",
+			Operation: journal.OperationUpdated,
+			Before:    notionapi.DatabasePageProperties{"Done": checkbox(&_false)},
+			After:     notionapi.DatabasePageProperties{"Done": checkbox(&_true)},
+			Outcome:   journal.OutcomeFailed,
+			Error:     "conflict",
+		},
+		journal.Entry{
+			Time: at(6), TaskID: "task-5", PageID: "page-5", DatabaseID: "db",
+			Operation: journal.OperationDeleted,
+			Archived:  &_true,
+			Outcome:   journal.OutcomeOK,
+		},
+	)
+
+	restores, err := journal.Restores(path, start)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exp := []journal.Restore{
+		{
+			TaskID: "task-2", PageID: "page-2", DatabaseID: "db",
+			Properties: notionapi.DatabasePageProperties{},
+			Created:    true,
+		},
+		{
+			TaskID: "task-1", PageID: "page-1", DatabaseID: "db",
+			Properties: notionapi.DatabasePageProperties{"Task": title("Buy milk"), "Done": checkbox(&_false)},
+		},
+		{
+			TaskID: "list-1", PageID: "page-3", DatabaseID: "lists",
+			Properties: notionapi.DatabasePageProperties{"List": title("Groceries")},
+		},
+		{
+			TaskID: "task-5", PageID: "page-5", DatabaseID: "db",
+			Properties: notionapi.DatabasePageProperties{},
+			Unarchive:  true,
+		},
+	}
+	if diff := cmp.Diff(exp, restores); diff != "" {
+		t.Fatalf("restores not equal (-exp, +got):\n%v", diff)
+	}
+
+	restores, err = journal.Restores(path, at(10))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(restores) > 0 {
+		t.Fatalf("expected no restores after the last change, got %+v", restores)
+	}
+}
+
+func TestValue(t *testing.T) {
+	t.Parallel()
+
+	number := 2.5
+	date, _ := notionapi.ParseDateTime("2022-07-01")
+	tests := []struct {
+		name     string
+		prop     notionapi.DatabasePageProperty
+		expected string
+	}{
+		{
+			name:     "title written by the sync",
+			prop:     title("Buy milk"),
+			expected: "Buy milk",
+		},
+		{
+			name:     "rich text read from Notion",
+			prop:     notionapi.DatabasePageProperty{RichText: []notionapi.RichText{{PlainText: "a"}, {PlainText: "b"}}},
+			expected: "ab",
+		},
+		{
+			name:     "checkbox",
+			prop:     checkbox(&_true),
+			expected: "true",
+		},
+		{
+			name:     "select",
+			prop:     notionapi.DatabasePageProperty{Select: &notionapi.SelectOptions{Name: "High"}},
+			expected: "High",
+		},
+		{
+			name:     "date",
+			prop:     notionapi.DatabasePageProperty{Date: &notionapi.Date{Start: date}},
+			expected: "2022-07-01",
+		},
+		{
+			name:     "relation",
+			prop:     notionapi.DatabasePageProperty{Relation: []notionapi.Relation{{ID: "a"}, {ID: "b"}}},
+			expected: "a,b",
+		},
+		{
+			name:     "number",
+			prop:     notionapi.DatabasePageProperty{Number: &number},
+			expected: "2.5",
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := journal.Value(tt.prop); got != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/notionapi"
+
+	"github.com/pkg/errors"
+)
+
+// WithConflictComments posts a comment on the page of a task when an update
+// from To Do overwrites values that were last edited in Notion by someone
+// other than the integration, listing the values that were replaced.
+func WithConflictComments() Option {
+	return func(o *options) {
+		o.conflictComments = true
+	}
+}
+
+// botUser is the user of the integration, looked up once and shared by the
+// APIs of every database. A failed lookup is retried on the next call.
+type botUser struct {
+	mu sync.Mutex
+	id string
+}
+
+func (n *notion) botUserID(ctx context.Context) (string, error) {
+	n.bot.mu.Lock()
+	defer n.bot.mu.Unlock()
+
+	if len(n.bot.id) > 0 {
+		return n.bot.id, nil
+	}
+	user, err := n.client.FindCurrentUser(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(user.ID) == 0 {
+		return "", errors.New("current user has no ID")
+	}
+	n.bot.id = user.ID
+	return n.bot.id, nil
+}
+
+// commentOnConflict posts a comment on page, as it was before an update to
+// after, when the update replaced values last edited in Notion by a person.
+// Only the last editor of the whole page is known, so any replaced value of
+// such a page is reported.
+func (n *notion) commentOnConflict(ctx context.Context, page notionapi.Page, after notionapi.DatabasePageProperties) {
+	if page.LastEditedBy == nil {
+		return
+	}
+	botID, err := n.botUserID(ctx)
+	if err != nil {
+		logger.T(ctx).Warnf("find integration user failed: %v", err)
+		return
+	}
+	if page.LastEditedBy.ID == botID {
+		return
+	}
+
+	before := changedProperties(&page, after)
+	var changes []string
+	for name, prop := range after {
+		old, ok := before[name]
+		if !ok {
+			continue
+		}
+		if oldValue, newValue := propertyText(old), propertyText(prop); oldValue != newValue {
+			changes = append(changes, fmt.Sprintf("%v: %q → %q", name, oldValue, newValue))
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+	sort.Strings(changes)
+
+	text := "To Do overwrote values edited in Notion:\n" + strings.Join(changes, "\n")
+	_, err = n.client.CreateComment(ctx, notionapi.CreateCommentParams{
+		ParentPageID: page.ID,
+		RichText:     []notionapi.RichText{{Text: &notionapi.Text{Content: text}}},
+	})
+	if err != nil {
+		logger.T(ctx).Warnf("comment on conflict of page %v failed: %v", page.ID, err)
+		return
+	}
+	logger.T(ctx).Infof("commented on conflict of page %v: %v", page.ID, strings.Join(changes, "; "))
+}
+
+// propertyText returns the value of a property the sync writes as text, for
+// comparing and reporting it.
+func propertyText(prop notionapi.DatabasePageProperty) string {
+	switch {
+	case prop.Title != nil:
+		return notionapi.PlainText(prop.Title)
+	case prop.RichText != nil:
+		return notionapi.PlainText(prop.RichText)
+	case prop.Checkbox != nil:
+		return strconv.FormatBool(*prop.Checkbox)
+	case prop.Select != nil:
+		return prop.Select.Name
+	case prop.Status != nil:
+		return prop.Status.Name
+	case prop.Date != nil:
+		return prop.Date.Start.Format("2006-01-02")
+	case prop.Relation != nil:
+		ids := make([]string, len(prop.Relation))
+		for i, relation := range prop.Relation {
+			ids[i] = relation.ID
+		}
+		return strings.Join(ids, ", ")
+	}
+	return ""
+}
+
+// TaskComments returns the unresolved comments on the page of a task, oldest
+// first.
+func (n *notion) TaskComments(ctx context.Context, todoID string) ([]notionapi.Comment, error) {
+	queryDatabase, err := n.queryDatabase(ctx, n.option.databaseID, &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop("TodoID").RichText().Equals(todoID).Query(),
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "task comments query failed:%v:%v", n.option.databaseID, todoID)
+	}
+	if len(queryDatabase.Results) == 0 {
+		return nil, nil
+	}
+
+	var (
+		comments []notionapi.Comment
+		pageID   = queryDatabase.Results[0].ID
+		query    = &notionapi.PaginationQuery{PageSize: 100}
+	)
+	for {
+		resp, err := n.client.ListComments(ctx, pageID, query)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "list comments of page %v failed", pageID)
+		}
+		comments = append(comments, resp.Results...)
+
+		if !resp.HasMore || resp.NextCursor == nil {
+			return comments, nil
+		}
+		query.StartCursor = *resp.NextCursor
+	}
+}
@@ -0,0 +1,45 @@
+package notion
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/notionapi/notionapitest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBotUserID(t *testing.T) {
+	t.Parallel()
+
+	srv := notionapitest.NewServer()
+	defer srv.Close()
+	srv.Fail(notionapitest.Failure{Path: "/users/me", Status: http.StatusBadGateway})
+
+	n := New("secret-api-key", "",
+		WithHTTPClient(srv.Server.Client()),
+		WithClientOptions(notionapi.WithBaseURL(srv.BaseURL())),
+	).(*notion)
+	ctx := context.Background()
+
+	if _, err := n.botUserID(ctx); err == nil {
+		t.Fatal("expected the first lookup to fail")
+	}
+	id, err := n.botUserID(ctx)
+	if err != nil || len(id) == 0 {
+		t.Fatalf("expected the lookup to be retried, got %q, %v", id, err)
+	}
+
+	// Databases share the user of the integration.
+	clone := n.WithDatabaseID("b0668f48-8d66-4733-9bdb-2f82215707f7").(*notion)
+	if cloneID, err := clone.botUserID(ctx); err != nil || cloneID != id {
+		t.Fatalf("expected cached user %q, got %q, %v", id, cloneID, err)
+	}
+
+	exp := []string{"GET /users/me", "GET /users/me"}
+	if diff := cmp.Diff(exp, srv.Requests()); diff != "" {
+		t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+	}
+}
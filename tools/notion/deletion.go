@@ -0,0 +1,130 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/notionapi"
+	"notionsync/tools/journal"
+
+	"github.com/pkg/errors"
+)
+
+// DeletionPolicy decides what happens to the row of a task deleted in To Do.
+type DeletionPolicy string
+
+// Deletion policies.
+const (
+	// DeletionCheckbox only ticks the "Deleted" checkbox.
+	DeletionCheckbox DeletionPolicy = "checkbox"
+	// DeletionArchive ticks the checkbox and archives the page right away.
+	DeletionArchive DeletionPolicy = "archive"
+	// DeletionArchiveAfter ticks the checkbox and records the time of the
+	// deletion, and ArchiveDeleted archives the page once the retention period
+	// has passed since.
+	DeletionArchiveAfter DeletionPolicy = "archive-after"
+)
+
+// deletedAtProp is the date property the time of the deletion is recorded in
+// under DeletionArchiveAfter.
+const deletedAtProp = "Deleted At"
+
+// ParseDeletionPolicy returns the policy named s.
+func ParseDeletionPolicy(s string) (DeletionPolicy, error) {
+	switch policy := DeletionPolicy(s); policy {
+	case DeletionCheckbox, DeletionArchive, DeletionArchiveAfter:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown deletion policy %q, expected %v, %v or %v",
+		s, DeletionCheckbox, DeletionArchive, DeletionArchiveAfter)
+}
+
+// WithDeletionPolicy overrides what happens to the rows of deleted tasks. The
+// retention only applies to DeletionArchiveAfter.
+func WithDeletionPolicy(policy DeletionPolicy, retention time.Duration) Option {
+	return func(o *options) {
+		o.deletionPolicy = policy
+		o.deletionRetention = retention
+	}
+}
+
+func (n *notion) ArchiveDeleted(ctx context.Context) (int, error) {
+	if n.option.deletionPolicy != DeletionArchiveAfter {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-n.option.deletionRetention)
+	query := &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop("Deleted").Checkbox().Equals(true).Query(),
+	}
+
+	var archived int
+	for {
+		queryDatabase, err := n.queryDatabase(ctx, n.option.databaseID, query)
+		if err != nil {
+			return archived, errors.WithMessagef(err, "archive deleted query failed:%v", n.option.databaseID)
+		}
+
+		// Archived pages drop out of the filter while the cursor moves on, so
+		// some may be skipped until the next call.
+		for _, page := range queryDatabase.Results {
+			props, _ := page.Properties.(notionapi.DatabasePageProperties)
+			if deletedAt(page, props).After(cutoff) {
+				continue
+			}
+			todoID := notionapi.PlainText(props["TodoID"].RichText)
+
+			_, err := n.client.UpdatePage(ctx, page.ID, notionapi.UpdatePageParams{
+				Archived: &_true,
+			})
+			n.record(ctx, journal.Entry{
+				TaskID:    todoID,
+				PageID:    page.ID,
+				Operation: journal.OperationArchived,
+				Archived:  &_true,
+			}, nil, err)
+			if err != nil {
+				return archived, errors.WithMessagef(err, "archive database %v, page %v failed", n.option.databaseID, page.ID)
+			}
+			logger.T(ctx).Debugf("archived deleted task: %v", todoID)
+			archived++
+		}
+
+		if !queryDatabase.HasMore || queryDatabase.NextCursor == nil {
+			return archived, nil
+		}
+		query.StartCursor = *queryDatabase.NextCursor
+	}
+}
+
+// setDeletedAt records the time a row is marked deleted under
+// DeletionArchiveAfter, keeping the time of a row that was deleted already,
+// and clears it when the row is no longer deleted.
+func (n *notion) setDeletedAt(props notionapi.DatabasePageProperties, page notionapi.Page, deleted bool) {
+	if n.option.deletionPolicy != DeletionArchiveAfter {
+		return
+	}
+
+	current, _ := page.Properties.(notionapi.DatabasePageProperties)
+	wasDeleted := current["Deleted"].Checkbox != nil && *current["Deleted"].Checkbox
+	recorded := current[deletedAtProp].Date != nil
+	switch {
+	case deleted && !(wasDeleted && recorded):
+		props[deletedAtProp] = notionapi.DatabasePageProperty{
+			Date: &notionapi.Date{Start: notionapi.NewDateTime(time.Now(), true)},
+		}
+	case !deleted && recorded:
+		props[deletedAtProp] = notionapi.DatabasePageProperty{Type: notionapi.DBPropTypeDate}
+	}
+}
+
+// deletedAt returns the time a row was marked deleted. Rows deleted before the
+// time was recorded fall back to their last edit.
+func deletedAt(page notionapi.Page, props notionapi.DatabasePageProperties) time.Time {
+	if date := props[deletedAtProp].Date; date != nil {
+		return date.Start.Time
+	}
+	return page.LastEditedTime
+}
@@ -0,0 +1,79 @@
+package notion
+
+import (
+	"context"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/notionapi"
+	"notionsync/tools/journal"
+
+	"github.com/pkg/errors"
+)
+
+// record journals a change to the page of a task. before is the page as it
+// was read before the change, or nil for created pages. A failed journal
+// write is logged rather than failing the change, which already happened.
+func (n *notion) record(ctx context.Context, entry journal.Entry, before *notionapi.Page, err error) {
+	entry.DatabaseID = n.option.databaseID
+	entry.Before = changedProperties(before, entry.After)
+	entry.Outcome = journal.OutcomeOK
+	if err != nil {
+		entry.Outcome = journal.OutcomeFailed
+		entry.Error = err.Error()
+	}
+
+	if err := n.option.journal.Append(entry); err != nil {
+		logger.T(ctx).Warnf("journal task %v failed: %v", entry.TaskID, err)
+	}
+}
+
+func (n *notion) RestorePage(ctx context.Context, todoID, pageID string, props notionapi.DatabasePageProperties, unarchive bool) error {
+	page, err := n.client.FindPageByID(ctx, pageID)
+	if err != nil {
+		return errors.WithMessagef(err, "find page %v failed", pageID)
+	}
+
+	params := notionapi.UpdatePageParams{}
+	entry := journal.Entry{
+		TaskID:    todoID,
+		PageID:    pageID,
+		Operation: journal.OperationRestored,
+	}
+	if len(props) > 0 {
+		params.DatabasePageProperties = &props
+		entry.After = props
+	}
+	if unarchive {
+		params.Archived = &_false
+		entry.Archived = &_false
+	}
+
+	_, err = n.client.UpdatePage(ctx, pageID, params)
+	n.record(ctx, entry, &page, err)
+	if err != nil {
+		return errors.WithMessagef(err, "restore page %v failed", pageID)
+	}
+	return nil
+}
+
+// changedProperties returns the values page had for the properties in after.
+func changedProperties(page *notionapi.Page, after notionapi.DatabasePageProperties) notionapi.DatabasePageProperties {
+	if page == nil {
+		return nil
+	}
+	props, ok := page.Properties.(notionapi.DatabasePageProperties)
+	if !ok {
+		return nil
+	}
+
+	before := make(notionapi.DatabasePageProperties, len(after))
+	for name := range after {
+		prop, ok := props[name]
+		if !ok {
+			continue
+		}
+		prop.ID = ""
+		before[name] = prop
+	}
+	return before
+}
@@ -2,9 +2,11 @@ package notion
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"notionsync/pkg/notionapi"
+	"notionsync/tools/journal"
 
 	"github.com/pkg/errors"
 )
@@ -15,53 +17,147 @@ var (
 )
 
 type API interface {
-	AddTask(title, todoID, importance, displayName string) error
-	AddTaskWithScheduleTime(title, todoID, importance, displayName, scheduleTimeStr string) error
-	CompleteTask(title string) error
-	ExistTaskFromTodoID(todoID string) (bool, error)
-	UpdateTaskInfo(todoID, title, status, importance, dueDateTime, taskListName string, completedDateTime time.Time, deleted bool) error
+	AddTask(ctx context.Context, title, todoID, status, importance, displayName string) error
+	AddTaskWithScheduleTime(ctx context.Context, title, todoID, status, importance, displayName, scheduleTimeStr string) error
+	CompleteTask(ctx context.Context, title string) error
+	ExistTaskFromTodoID(ctx context.Context, todoID string) (bool, error)
+	UpdateTaskInfo(ctx context.Context, todoID, title, status, importance, dueDateTime, taskListName string, completedDateTime time.Time, deleted bool) error
+	RenameTaskList(ctx context.Context, oldName, newName string) error
+	// SyncTaskList creates or updates the page of a task list when task lists
+	// are kept in their own database, and is a no-op otherwise.
+	SyncTaskList(ctx context.Context, taskListID, displayName, wellKnownListName string) error
+	// ListTaskIDs returns the To Do IDs of the rows of a task list that are
+	// not marked deleted.
+	ListTaskIDs(ctx context.Context, displayName string) ([]string, error)
+	// RestorePage writes property values recorded in the journal back to the
+	// page of a task, restoring the page first if unarchive is set.
+	RestorePage(ctx context.Context, todoID, pageID string, props notionapi.DatabasePageProperties, unarchive bool) error
+	// ArchiveDeleted archives the rows marked deleted that have not been edited
+	// for the retention of the DeletionArchiveAfter policy, and returns how
+	// many were archived. It is a no-op under other policies.
+	ArchiveDeleted(ctx context.Context) (int, error)
+	// TaskComments returns the unresolved comments on the page of a task,
+	// oldest first, or none when the task has no page.
+	TaskComments(ctx context.Context, todoID string) ([]notionapi.Comment, error)
+	// Ping checks that the database can be read.
+	Ping(ctx context.Context) error
+	CheckSchema(ctx context.Context) ([]SchemaProblem, error)
+	// ApplySchema adds the properties the sync needs that are missing from the
+	// database. Properties of the wrong type are reported, not changed.
+	ApplySchema(ctx context.Context) error
+	// WithDatabaseID returns an API sharing the same client that works on
+	// another database. An empty database ID returns the API itself.
+	WithDatabaseID(databaseID string) API
 }
 
 type options struct {
-	apiSecret  string
-	databaseID string
+	apiSecret          string
+	databaseID         string
+	taskListDatabaseID string
+	httpClient         *http.Client
+	clientOpts         []notionapi.ClientOption
+	journal            *journal.Journal
+	deletionPolicy     DeletionPolicy
+	deletionRetention  time.Duration
+	statusProperty     string
+	statusMapping      StatusMapping
+	conflictComments   bool
+}
+
+// Option is used to override default notion behavior.
+type Option func(*options)
+
+// WithTaskListDatabase keeps one page per task list in the given database and
+// links every task row to it through a "Task List" relation, instead of
+// writing the list name as text.
+func WithTaskListDatabase(databaseID string) Option {
+	return func(o *options) {
+		o.taskListDatabaseID = databaseID
+	}
+}
+
+// WithHTTPClient overrides the http.Client used for Notion API requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithClientOptions passes options to the Notion API client, after the
+// http.Client of WithHTTPClient.
+func WithClientOptions(opts ...notionapi.ClientOption) Option {
+	return func(o *options) {
+		o.clientOpts = append(o.clientOpts, opts...)
+	}
+}
+
+// WithJournal records every change made to a task page in j.
+func WithJournal(j *journal.Journal) Option {
+	return func(o *options) {
+		o.journal = j
+	}
 }
 
 type notion struct {
-	ctx    context.Context
-	cancel context.CancelFunc
-	client *notionapi.Client
-	option options
-	pageID string
+	client    *notionapi.Client
+	option    options
+	pageID    string
+	taskLists *taskLists
+	bot       *botUser
+	schemas   *schemaCache
 }
 
-func New(apiSecret, databaseID string) API {
+func New(apiSecret, databaseID string, opts ...Option) API {
 	option := options{
-		apiSecret:  apiSecret,
-		databaseID: databaseID,
+		apiSecret:      apiSecret,
+		databaseID:     databaseID,
+		deletionPolicy: DeletionCheckbox,
+	}
+
+	for _, opt := range opts {
+		opt(&option)
 	}
 
-	ctx, cancel := context.WithCancel(context.TODO())
+	var lists *taskLists
+	if len(option.taskListDatabaseID) > 0 {
+		lists = newTaskLists(option.taskListDatabaseID)
+	}
+
+	var clientOpts []notionapi.ClientOption
+	if option.httpClient != nil {
+		clientOpts = append(clientOpts, notionapi.WithHTTPClient(option.httpClient))
+	}
+	clientOpts = append(clientOpts, option.clientOpts...)
+
 	return &notion{
-		ctx:    ctx,
-		cancel: cancel,
-		client: notionapi.NewClient(apiSecret),
-		option: option,
+		client:    notionapi.NewClient(apiSecret, clientOpts...),
+		option:    option,
+		taskLists: lists,
+		bot:       &botUser{},
+		schemas:   &schemaCache{databases: make(map[string]notionapi.DatabaseProperties)},
 	}
 }
 
-func (n *notion) UpdateTaskInfo(todoID string, title string, status string, importance string, dueDateTime string, taskListName string, completedDateTime time.Time, deleted bool) error {
-	queryDatabase, err := n.client.QueryDatabase(n.ctx, n.option.databaseID, &notionapi.DatabaseQuery{
-		Filter: &notionapi.DatabaseQueryFilter{
-			And: []notionapi.DatabaseQueryFilter{
-				{
-					Property: "TodoID",
-					Text: &notionapi.TextDatabaseQueryFilter{
-						Equals: todoID,
-					},
-				},
-			},
-		},
+func (n *notion) WithDatabaseID(databaseID string) API {
+	if len(databaseID) == 0 || databaseID == n.option.databaseID {
+		return n
+	}
+
+	clone := *n
+	clone.option.databaseID = databaseID
+	return &clone
+}
+
+func (n *notion) Ping(ctx context.Context) error {
+	if _, err := n.client.FindDatabaseByID(ctx, n.option.databaseID); err != nil {
+		return errors.WithMessagef(err, "find database id: %v failed", n.option.databaseID)
+	}
+	return nil
+}
+
+func (n *notion) UpdateTaskInfo(ctx context.Context, todoID string, title string, status string, importance string, dueDateTime string, taskListName string, completedDateTime time.Time, deleted bool) error {
+	queryDatabase, err := n.queryDatabase(ctx, n.option.databaseID, &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop("TodoID").RichText().Equals(todoID).Query(),
 	})
 	if err != nil {
 		return errors.WithMessagef(err, "database query failed:%v:%v", n.option.databaseID, todoID)
@@ -80,16 +176,18 @@ func (n *notion) UpdateTaskInfo(todoID string, title string, status string, impo
 
 	if len(status) > 0 {
 		done := false
-		if status == "completed" {
+		if status == TodoStatusCompleted {
 			done = true
 		}
 		databasePageProperties["Done"] = notionapi.DatabasePageProperty{
 			Checkbox: &done,
 		}
+		n.setStatus(databasePageProperties, status)
 	}
 	databasePageProperties["Deleted"] = notionapi.DatabasePageProperty{
 		Checkbox: &deleted,
 	}
+	n.setDeletedAt(databasePageProperties, page, deleted)
 
 	if len(dueDateTime) > 0 {
 		const timeLayout = "2006-01-02T15:04:05.0000000"
@@ -142,38 +240,94 @@ func (n *notion) UpdateTaskInfo(todoID string, title string, status string, impo
 	}
 
 	if len(taskListName) > 0 {
-		databasePageProperties["Task List Name"] = notionapi.DatabasePageProperty{
-			RichText: []notionapi.RichText{
-				{
-					Text: &notionapi.Text{
-						Content: taskListName,
-					},
-				},
-			},
+		if err := n.setTaskList(ctx, databasePageProperties, taskListName); err != nil {
+			return errors.WithMessagef(err, "set task list %v failed", taskListName)
 		}
 	}
 
-	_, err = n.client.UpdatePage(n.ctx, page.ID, notionapi.UpdatePageParams{
+	params := notionapi.UpdatePageParams{
 		DatabasePageProperties: &databasePageProperties,
-	})
+	}
+	entry := journal.Entry{
+		TaskID:    todoID,
+		PageID:    page.ID,
+		Operation: journal.OperationUpdated,
+		After:     databasePageProperties,
+	}
+	if deleted {
+		entry.Operation = journal.OperationDeleted
+		if n.option.deletionPolicy == DeletionArchive {
+			params.Archived = &_true
+			entry.Archived = &_true
+		}
+	}
+
+	_, err = n.client.UpdatePage(ctx, page.ID, params)
+	n.record(ctx, entry, &page, err)
 	if err != nil {
 		return errors.WithMessagef(err, "update database %v, page %v failed", n.option.databaseID, page.ID)
 	}
+	if n.option.conflictComments && !deleted {
+		n.commentOnConflict(ctx, page, databasePageProperties)
+	}
 	return nil
 }
 
-func (n *notion) ExistTaskFromTodoID(todoID string) (bool, error) {
-	database, err := n.client.QueryDatabase(n.ctx, n.option.databaseID, &notionapi.DatabaseQuery{
-		Filter: &notionapi.DatabaseQueryFilter{
-			And: []notionapi.DatabaseQueryFilter{
-				{
-					Property: "TodoID",
-					Text: &notionapi.TextDatabaseQueryFilter{
-						Equals: todoID,
-					},
-				},
-			},
-		},
+func (n *notion) RenameTaskList(ctx context.Context, oldName, newName string) error {
+	if oldName == newName {
+		return nil
+	}
+	if n.taskLists != nil {
+		return n.renameTaskListPage(ctx, oldName, newName)
+	}
+
+	// Collect the pages before renaming them, as renamed pages drop out of
+	// the filter and would shift the cursor.
+	var pages []notionapi.Page
+	query := &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop("Task List Name").RichText().Equals(oldName).Query(),
+	}
+	for {
+		queryDatabase, err := n.queryDatabase(ctx, n.option.databaseID, query)
+		if err != nil {
+			return errors.WithMessagef(err, "rename task list query failed:%v:%v", n.option.databaseID, oldName)
+		}
+		pages = append(pages, queryDatabase.Results...)
+
+		if !queryDatabase.HasMore || queryDatabase.NextCursor == nil {
+			break
+		}
+		query.StartCursor = *queryDatabase.NextCursor
+	}
+
+	for _, page := range pages {
+		page := page
+		props := notionapi.DatabasePageProperties{
+			"Task List Name": richTextProperty(newName),
+		}
+		_, err := n.client.UpdatePage(ctx, page.ID, notionapi.UpdatePageParams{
+			DatabasePageProperties: &props,
+		})
+		var todoID string
+		if pageProps, ok := page.Properties.(notionapi.DatabasePageProperties); ok {
+			todoID = notionapi.PlainText(pageProps["TodoID"].RichText)
+		}
+		n.record(ctx, journal.Entry{
+			TaskID:    todoID,
+			PageID:    page.ID,
+			Operation: journal.OperationRenamed,
+			After:     props,
+		}, &page, err)
+		if err != nil {
+			return errors.WithMessagef(err, "update database %v, page %v failed", n.option.databaseID, page.ID)
+		}
+	}
+	return nil
+}
+
+func (n *notion) ExistTaskFromTodoID(ctx context.Context, todoID string) (bool, error) {
+	database, err := n.queryDatabase(ctx, n.option.databaseID, &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop("TodoID").RichText().Equals(todoID).Query(),
 	})
 	if err != nil {
 		return false, errors.WithMessagef(err, "exist database query failed:%v:%v", n.option.databaseID, todoID)
@@ -186,87 +340,69 @@ func (n *notion) ExistTaskFromTodoID(todoID string) (bool, error) {
 	return true, nil
 }
 
-func (n *notion) AddTask(title, todoID, importance, displayName string) error {
-	return n.addTask(title, todoID, importance, displayName, "")
+func (n *notion) AddTask(ctx context.Context, title, todoID, status, importance, displayName string) error {
+	return n.addTask(ctx, title, todoID, status, importance, displayName, "")
 }
 
-func (n *notion) AddTaskWithScheduleTime(title, todoID, importance, displayName, scheduleTimeStr string) error {
-	return n.addTask(title, todoID, importance, displayName, scheduleTimeStr)
+func (n *notion) AddTaskWithScheduleTime(ctx context.Context, title, todoID, status, importance, displayName, scheduleTimeStr string) error {
+	return n.addTask(ctx, title, todoID, status, importance, displayName, scheduleTimeStr)
 }
 
-func (n *notion) addTask(title, todoID, importance, displayName, scheduleTime string) error {
-	database, err := n.client.FindDatabaseByID(n.ctx, n.option.databaseID)
+// newTaskProperties are the properties a task page is created with, besides
+// its task list.
+type newTaskProperties struct {
+	Task          string             `notion:"Task,title"`
+	TodoID        string             `notion:"TodoID,rich_text"`
+	Priority      string             `notion:"Priority,select,omitempty"`
+	ScheduledTime notionapi.DateTime `notion:"Scheduled Time,date,omitempty"`
+}
+
+func (n *notion) addTask(ctx context.Context, title, todoID, status, importance, displayName, scheduleTime string) error {
+	database, err := n.client.FindDatabaseByID(ctx, n.option.databaseID)
 	if err != nil {
 		return errors.WithMessagef(err, "add task database id: %v failed", n.option.databaseID)
 	}
 
-	var databasePageProperties = make(notionapi.DatabasePageProperties)
-
+	task := newTaskProperties{Task: title, TodoID: todoID}
 	if len(importance) > 0 {
-		var s notionapi.SelectOptions
-		if importance == "normal" {
-			s.Name = "P2"
-		} else if importance == "high" {
-			s.Name = "P0 🔥"
+		if importance == "high" {
+			task.Priority = "P0 🔥"
 		} else {
-			s.Name = "P2"
-		}
-		databasePageProperties["Priority"] = notionapi.DatabasePageProperty{
-			Select: &s,
+			task.Priority = "P2"
 		}
 	}
-
-	databasePageProperties["Task"] = notionapi.DatabasePageProperty{
-		Title: []notionapi.RichText{
-			{
-				Text: &notionapi.Text{
-					Content: title,
-				},
-			},
-		},
-	}
-	databasePageProperties["TodoID"] = notionapi.DatabasePageProperty{
-		RichText: []notionapi.RichText{
-			{
-				Text: &notionapi.Text{
-					Content: todoID,
-				},
-			},
-		},
-	}
-	databasePageProperties["Task List Name"] = notionapi.DatabasePageProperty{
-		RichText: []notionapi.RichText{
-			{
-				Text: &notionapi.Text{
-					Content: displayName,
-				},
-			},
-		},
-	}
-
 	if len(scheduleTime) > 0 {
 		const timeLayout = "2006-01-02T15:04:05.0000000"
 		parse, err := time.Parse(timeLayout, scheduleTime)
 		if err != nil {
 			panic(err)
 		}
-		scheduleTime := parse.Add(time.Hour * 24)
-		databasePageProperties["Scheduled Time"] = notionapi.DatabasePageProperty{
-			Date: &notionapi.Date{
-				Start:    notionapi.NewDateTime(scheduleTime, false),
-				End:      nil,
-				TimeZone: nil,
-			}}
+		task.ScheduledTime = notionapi.NewDateTime(parse.Add(time.Hour*24), false)
+	}
+
+	databasePageProperties, err := notionapi.MarshalProperties(task)
+	if err != nil {
+		return errors.WithMessagef(err, "marshal task %v failed", todoID)
+	}
+	n.setStatus(databasePageProperties, status)
+	if err := n.setTaskList(ctx, databasePageProperties, displayName); err != nil {
+		return errors.WithMessagef(err, "set task list %v failed", displayName)
 	}
 
-	_, err = n.client.CreatePage(
-		n.ctx,
+	page, err := n.client.CreatePage(
+		ctx,
 		notionapi.CreatePageParams{
 			ParentType:             notionapi.ParentTypeDatabase,
 			ParentID:               database.ID,
 			DatabasePageProperties: &databasePageProperties,
 		},
 	)
+	n.record(ctx, journal.Entry{
+		TaskID:    todoID,
+		PageID:    page.ID,
+		Operation: journal.OperationCreated,
+		After:     databasePageProperties,
+	}, nil, err)
 	if err != nil {
 		return errors.WithMessagef(err, "database id: %v, create page failed", n.option.databaseID)
 	}
@@ -274,24 +410,11 @@ func (n *notion) addTask(title, todoID, importance, displayName, scheduleTime st
 	return nil
 }
 
-func (n *notion) CompleteTask(title string) error {
-	queryDatabase, err := n.client.QueryDatabase(n.ctx, n.option.databaseID, &notionapi.DatabaseQuery{
-		Filter: &notionapi.DatabaseQueryFilter{
-			And: []notionapi.DatabaseQueryFilter{
-				{
-					Property: "Done",
-					Checkbox: &notionapi.CheckboxDatabaseQueryFilter{
-						Equals: &_false,
-					},
-				},
-				{
-					Property: "Task",
-					Text: &notionapi.TextDatabaseQueryFilter{
-						Equals: title,
-					},
-				},
-			},
-		},
+func (n *notion) CompleteTask(ctx context.Context, title string) error {
+	queryDatabase, err := n.queryDatabase(ctx, n.option.databaseID, &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop("Done").Checkbox().Equals(false).
+			And(notionapi.Prop("Task").Title().Equals(title)).
+			Query(),
 	})
 	if err != nil {
 		return errors.WithMessagef(err, "query database id: %v failed", n.option.databaseID)
@@ -306,19 +429,21 @@ func (n *notion) CompleteTask(title string) error {
 	}
 
 	page := queryDatabase.Results[0]
-	_, err = n.client.UpdatePage(n.ctx, page.ID, notionapi.UpdatePageParams{
-		DatabasePageProperties: &notionapi.DatabasePageProperties{
-			"Done": notionapi.DatabasePageProperty{
-				Checkbox: &_true,
-			},
-			"Completion time": notionapi.DatabasePageProperty{
-				Date: &notionapi.Date{
-					Start:    notionapi.NewDateTime(time.Now(), true),
-					End:      nil,
-					TimeZone: nil,
-				},
+	props := notionapi.DatabasePageProperties{
+		"Done": notionapi.DatabasePageProperty{
+			Checkbox: &_true,
+		},
+		"Completion time": notionapi.DatabasePageProperty{
+			Date: &notionapi.Date{
+				Start:    notionapi.NewDateTime(time.Now(), true),
+				End:      nil,
+				TimeZone: nil,
 			},
 		},
+	}
+	n.setStatus(props, TodoStatusCompleted)
+	_, err = n.client.UpdatePage(ctx, page.ID, notionapi.UpdatePageParams{
+		DatabasePageProperties: &props,
 	})
 	if err != nil {
 		return errors.WithMessagef(err, "update database %v, page %v failed", n.option.databaseID, page.ID)
@@ -0,0 +1,204 @@
+package notion_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/notionapi/notionapitest"
+	"notionsync/tools/notion"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+
+	srv := notionapitest.NewServer()
+	defer srv.Close()
+
+	db := srv.AddDatabase(notionapi.Database{
+		Parent: notionapi.Parent{Type: notionapi.ParentTypePage, PageID: "b0668f48-8d66-4733-9bdb-2f82215707f7"},
+		Properties: notionapi.DatabaseProperties{
+			"Task":   {Type: notionapi.DBPropTypeTitle},
+			"Status": {Type: notionapi.DBPropTypeStatus},
+		},
+	})
+	api := notion.New("secret-api-key", db.ID,
+		notion.WithHTTPClient(srv.Server.Client()),
+		notion.WithClientOptions(notionapi.WithBaseURL(srv.BaseURL())),
+		notion.WithStatusProperty("Status", notion.DefaultStatusMapping()),
+	)
+	ctx := context.Background()
+
+	problems, err := api.CheckSchema(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(problems) == 0 {
+		t.Fatal("expected schema problems")
+	}
+	if err := api.ApplySchema(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if problems, err = api.CheckSchema(ctx); err != nil || len(problems) > 0 {
+		t.Fatalf("expected no schema problems, got %v, %v", problems, err)
+	}
+
+	if err := api.AddTask(ctx, "Buy milk", "todo-1", notion.TodoStatusNotStarted, "high", "Groceries"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := api.AddTask(ctx, "Plan trip", "todo-2", notion.TodoStatusWaitingOnOthers, "normal", "Travel"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exists, err := api.ExistTaskFromTodoID(ctx, "todo-1")
+	if err != nil || !exists {
+		t.Fatalf("expected task to exist, got %v, %v", exists, err)
+	}
+
+	completed := time.Date(2022, 6, 15, 0, 0, 0, 0, time.UTC)
+	if err := api.UpdateTaskInfo(ctx, "todo-1", "Buy oat milk", notion.TodoStatusCompleted, "", "", "", completed, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := api.RenameTaskList(ctx, "Groceries", "Groceries"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := api.RenameTaskList(ctx, "Travel", "Holidays"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids, err := api.ListTaskIDs(ctx, "Holidays")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"todo-2"}, ids); diff != "" {
+		t.Fatalf("task IDs not equal (-exp, +got):\n%v", diff)
+	}
+
+	type row struct {
+		Task, Priority, List, Status string
+		Done                         bool
+	}
+	var got []row
+	for _, page := range srv.Pages(db.ID) {
+		props := page.Properties.(notionapi.DatabasePageProperties)
+		r := row{
+			Task: props["Task"].Title[0].PlainText,
+			List: props["Task List Name"].RichText[0].PlainText,
+			Done: props["Done"].Checkbox != nil && *props["Done"].Checkbox,
+		}
+		if props["Priority"].Select != nil {
+			r.Priority = props["Priority"].Select.Name
+		}
+		if props["Status"].Status != nil {
+			r.Status = props["Status"].Status.Name
+		}
+		got = append(got, r)
+	}
+	exp := []row{
+		{Task: "Buy oat milk", Priority: "P0 🔥", List: "Groceries", Status: "Done", Done: true},
+		{Task: "Plan trip", Priority: "P2", List: "Holidays", Status: "In progress"},
+	}
+	if diff := cmp.Diff(exp, got); diff != "" {
+		t.Fatalf("pages not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestArchiveDeleted(t *testing.T) {
+	t.Parallel()
+
+	// Pages are last edited two days ago, by the clock of the server.
+	srv := notionapitest.NewServer(notionapitest.WithClock(func() time.Time {
+		return time.Now().Add(-48 * time.Hour)
+	}))
+	defer srv.Close()
+
+	db := srv.AddDatabase(notionapi.Database{
+		Parent: notionapi.Parent{Type: notionapi.ParentTypePage, PageID: "b0668f48-8d66-4733-9bdb-2f82215707f7"},
+		Properties: notionapi.DatabaseProperties{
+			"Task": {Type: notionapi.DBPropTypeTitle},
+		},
+	})
+	api := notion.New("secret-api-key", db.ID,
+		notion.WithHTTPClient(srv.Server.Client()),
+		notion.WithClientOptions(notionapi.WithBaseURL(srv.BaseURL())),
+		notion.WithDeletionPolicy(notion.DeletionArchiveAfter, 24*time.Hour),
+	)
+	ctx := context.Background()
+	if err := api.ApplySchema(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, todoID := range []string{"todo-1", "todo-2"} {
+		if err := api.AddTask(ctx, todoID, todoID, notion.TodoStatusNotStarted, "normal", "Tasks"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	deletedAt := func(todoID string) *notionapi.Date {
+		for _, page := range srv.Pages(db.ID) {
+			props := page.Properties.(notionapi.DatabasePageProperties)
+			if props["TodoID"].RichText[0].PlainText == todoID {
+				return props["Deleted At"].Date
+			}
+		}
+		t.Fatalf("page of %v not found", todoID)
+		return nil
+	}
+
+	// A row deleted just now is kept, however long ago it was edited, and
+	// deleting it again does not move the time of the deletion.
+	if err := api.UpdateTaskInfo(ctx, "todo-1", "", "", "", "", "", time.Time{}, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := deletedAt("todo-1")
+	if first == nil {
+		t.Fatal("expected the time of the deletion to be recorded")
+	}
+	if err := api.UpdateTaskInfo(ctx, "todo-1", "", "", "", "", "", time.Time{}, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(first, deletedAt("todo-1"), cmp.AllowUnexported(notionapi.DateTime{})); diff != "" {
+		t.Fatalf("deletion time not equal (-exp, +got):\n%v", diff)
+	}
+
+	// A restored row loses the time of its deletion.
+	if err := api.UpdateTaskInfo(ctx, "todo-2", "", "", "", "", "", time.Time{}, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := api.UpdateTaskInfo(ctx, "todo-2", "", "", "", "", "", time.Time{}, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if date := deletedAt("todo-2"); date != nil {
+		t.Fatalf("expected no deletion time, got %v", date.Start)
+	}
+
+	// Rows deleted past the retention are archived, measured from their
+	// deletion time when recorded, or else from their last edit.
+	old := srv.AddPage(db.ID, notionapi.DatabasePageProperties{
+		"Task":       {Title: []notionapi.RichText{{Text: &notionapi.Text{Content: "Old"}}}},
+		"TodoID":     {RichText: []notionapi.RichText{{Text: &notionapi.Text{Content: "todo-3"}}}},
+		"Deleted":    {Checkbox: notionapi.BoolPtr(true)},
+		"Deleted At": {Date: &notionapi.Date{Start: notionapi.NewDateTime(time.Now().Add(-25*time.Hour), true)}},
+	})
+	legacy := srv.AddPage(db.ID, notionapi.DatabasePageProperties{
+		"Task":    {Title: []notionapi.RichText{{Text: &notionapi.Text{Content: "Legacy"}}}},
+		"TodoID":  {RichText: []notionapi.RichText{{Text: &notionapi.Text{Content: "todo-4"}}}},
+		"Deleted": {Checkbox: notionapi.BoolPtr(true)},
+	})
+
+	archived, err := api.ArchiveDeleted(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if archived != 2 {
+		t.Fatalf("expected 2 archived rows, got %v", archived)
+	}
+	for _, page := range []notionapi.Page{old, legacy} {
+		if page, _ := srv.Page(page.ID); !page.Archived {
+			t.Fatalf("expected page %v to be archived", page.ID)
+		}
+	}
+	if pages := srv.Pages(db.ID); len(pages) != 2 {
+		t.Fatalf("expected 2 pages left, got %v", len(pages))
+	}
+}
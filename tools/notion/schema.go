@@ -0,0 +1,269 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"notionsync/pkg/notionapi"
+
+	"github.com/pkg/errors"
+)
+
+// schemaCache holds the properties of the databases the sync queries, shared
+// by the APIs of every database, so that query filters can be checked before
+// they are sent.
+type schemaCache struct {
+	mu        sync.Mutex
+	databases map[string]notionapi.DatabaseProperties
+}
+
+func (n *notion) cacheSchema(databaseID string, props notionapi.DatabaseProperties) {
+	n.schemas.mu.Lock()
+	defer n.schemas.mu.Unlock()
+	n.schemas.databases[databaseID] = props
+}
+
+// databaseSchema returns the properties of a database, read once unless
+// reload is set.
+func (n *notion) databaseSchema(ctx context.Context, databaseID string, reload bool) (notionapi.DatabaseProperties, error) {
+	n.schemas.mu.Lock()
+	props, ok := n.schemas.databases[databaseID]
+	n.schemas.mu.Unlock()
+	if ok && !reload {
+		return props, nil
+	}
+
+	database, err := n.client.FindDatabaseByID(ctx, databaseID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "find database id: %v failed", databaseID)
+	}
+	n.cacheSchema(databaseID, database.Properties)
+	return database.Properties, nil
+}
+
+// queryDatabase queries a database once its filter is valid for the schema
+// of the database. The schema is read again before a filter is rejected, in
+// case it changed since it was cached.
+func (n *notion) queryDatabase(ctx context.Context, databaseID string, query *notionapi.DatabaseQuery) (notionapi.DatabaseQueryResponse, error) {
+	if query != nil && query.Filter != nil {
+		props, err := n.databaseSchema(ctx, databaseID, false)
+		if err != nil {
+			return notionapi.DatabaseQueryResponse{}, err
+		}
+		if query.Filter.Validate(props) != nil {
+			if props, err = n.databaseSchema(ctx, databaseID, true); err != nil {
+				return notionapi.DatabaseQueryResponse{}, err
+			}
+			if err := query.Filter.Validate(props); err != nil {
+				return notionapi.DatabaseQueryResponse{}, errors.WithMessagef(err, "invalid filter on database id: %v", databaseID)
+			}
+		}
+	}
+	return n.client.QueryDatabase(ctx, databaseID, query)
+}
+
+// schemaProperty is a property the sync reads or writes on the task database.
+type schemaProperty struct {
+	name string
+	prop notionapi.DatabaseProperty
+}
+
+func (n *notion) schema() []schemaProperty {
+	props := []schemaProperty{
+		{"Task", notionapi.DatabaseProperty{Type: notionapi.DBPropTypeTitle, Title: &notionapi.EmptyMetadata{}}},
+		{"TodoID", notionapi.DatabaseProperty{Type: notionapi.DBPropTypeRichText, RichText: &notionapi.EmptyMetadata{}}},
+		{"Done", notionapi.DatabaseProperty{Type: notionapi.DBPropTypeCheckbox, Checkbox: &notionapi.EmptyMetadata{}}},
+		{"Deleted", notionapi.DatabaseProperty{Type: notionapi.DBPropTypeCheckbox, Checkbox: &notionapi.EmptyMetadata{}}},
+		{"Priority", notionapi.DatabaseProperty{Type: notionapi.DBPropTypeSelect, Select: &notionapi.SelectMetadata{
+			Options: []notionapi.SelectOptions{
+				{Name: "P0 🔥", Color: notionapi.ColorRed},
+				{Name: "P2", Color: notionapi.ColorBlue},
+			},
+		}}},
+		{"Scheduled Time", notionapi.DatabaseProperty{Type: notionapi.DBPropTypeDate, Date: &notionapi.EmptyMetadata{}}},
+		{"Completion time", notionapi.DatabaseProperty{Type: notionapi.DBPropTypeDate, Date: &notionapi.EmptyMetadata{}}},
+	}
+
+	if n.option.deletionPolicy == DeletionArchiveAfter {
+		props = append(props, schemaProperty{deletedAtProp, notionapi.DatabaseProperty{
+			Type: notionapi.DBPropTypeDate, Date: &notionapi.EmptyMetadata{},
+		}})
+	}
+
+	if len(n.option.statusProperty) > 0 {
+		props = append(props, schemaProperty{n.option.statusProperty, notionapi.DatabaseProperty{
+			Type: notionapi.DBPropTypeStatus, Status: &notionapi.StatusMetadata{},
+		}})
+	}
+
+	if n.taskLists != nil {
+		props = append(props, schemaProperty{taskListRelationProp, notionapi.DatabaseProperty{
+			Type:     notionapi.DBPropTypeRelation,
+			Relation: &notionapi.RelationMetadata{DatabaseID: n.taskLists.databaseID},
+		}})
+	} else {
+		props = append(props, schemaProperty{"Task List Name", notionapi.DatabaseProperty{
+			Type: notionapi.DBPropTypeRichText, RichText: &notionapi.EmptyMetadata{},
+		}})
+	}
+
+	return props
+}
+
+// SchemaProblem describes a property of the task database that does not
+// match what the sync expects.
+type SchemaProblem struct {
+	Property string
+	Expected notionapi.DatabasePropertyType
+	// Actual is empty when the property is missing.
+	Actual notionapi.DatabasePropertyType
+	// Option is set when a status option the sync writes is missing from a
+	// property of the right type.
+	Option string
+}
+
+func (p SchemaProblem) String() string {
+	if len(p.Option) > 0 {
+		return fmt.Sprintf("property %q has no option %q", p.Property, p.Option)
+	}
+	if len(p.Actual) == 0 {
+		return fmt.Sprintf("property %q is missing, expected type %v", p.Property, p.Expected)
+	}
+	return fmt.Sprintf("property %q has type %v, expected type %v", p.Property, p.Actual, p.Expected)
+}
+
+func (n *notion) CheckSchema(ctx context.Context) ([]SchemaProblem, error) {
+	database, err := n.client.FindDatabaseByID(ctx, n.option.databaseID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "check schema database id: %v failed", n.option.databaseID)
+	}
+	n.cacheSchema(n.option.databaseID, database.Properties)
+
+	var problems []SchemaProblem
+	for _, expected := range n.schema() {
+		actual, ok := database.Properties[expected.name]
+		if ok && actual.Type == expected.prop.Type {
+			if actual.Type == notionapi.DBPropTypeStatus {
+				problems = append(problems, n.missingStatusOptions(expected.name, actual)...)
+			}
+			continue
+		}
+		problems = append(problems, SchemaProblem{
+			Property: expected.name,
+			Expected: expected.prop.Type,
+			Actual:   actual.Type,
+		})
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Property < problems[j].Property
+	})
+
+	return problems, nil
+}
+
+// missingStatusOptions reports the options of the status mapping that prop
+// does not have.
+func (n *notion) missingStatusOptions(name string, prop notionapi.DatabaseProperty) []SchemaProblem {
+	existing := make(map[string]bool)
+	if prop.Status != nil {
+		for _, option := range prop.Status.Options {
+			existing[option.Name] = true
+		}
+	}
+
+	var problems []SchemaProblem
+	for _, option := range n.option.statusMapping.options() {
+		if !existing[option] {
+			problems = append(problems, SchemaProblem{
+				Property: name,
+				Expected: notionapi.DBPropTypeStatus,
+				Actual:   prop.Type,
+				Option:   option,
+			})
+		}
+	}
+	return problems
+}
+
+func (n *notion) ApplySchema(ctx context.Context) error {
+	problems, err := n.CheckSchema(ctx)
+	if err != nil {
+		return err
+	}
+
+	expected := make(map[string]notionapi.DatabaseProperty)
+	for _, p := range n.schema() {
+		expected[p.name] = p.prop
+	}
+
+	missing := make(map[string]*notionapi.DatabaseProperty)
+	for _, problem := range problems {
+		// The API can neither create status properties nor add options to them.
+		if problem.Expected == notionapi.DBPropTypeStatus && (len(problem.Actual) == 0 || len(problem.Option) > 0) {
+			return errors.Errorf("database id: %v, %v, add it in notion first", n.option.databaseID, problem)
+		}
+		if len(problem.Actual) > 0 {
+			return errors.Errorf("database id: %v, %v, change it in notion first", n.option.databaseID, problem)
+		}
+		if problem.Expected == notionapi.DBPropTypeTitle {
+			return errors.Errorf("database id: %v, %v, rename the title property in notion first", n.option.databaseID, problem)
+		}
+		prop := expected[problem.Property]
+		missing[problem.Property] = &prop
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	database, err := n.client.UpdateDatabase(ctx, n.option.databaseID, notionapi.UpdateDatabaseParams{
+		Properties: missing,
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "apply schema database id: %v failed", n.option.databaseID)
+	}
+	n.cacheSchema(n.option.databaseID, database.Properties)
+
+	return nil
+}
+
+func (n *notion) ListTaskIDs(ctx context.Context, displayName string) ([]string, error) {
+	listFilter := notionapi.Prop("Task List Name").RichText().Equals(displayName)
+	if n.taskLists != nil {
+		pageID, err := n.findTaskListPage(ctx, displayName)
+		if err != nil {
+			return nil, err
+		}
+		listFilter = notionapi.Prop(taskListRelationProp).Relation().Contains(pageID)
+	}
+
+	query := &notionapi.DatabaseQuery{
+		Filter: listFilter.And(notionapi.Prop("Deleted").Checkbox().Equals(false)).Query(),
+	}
+
+	var todoIDs []string
+	for {
+		queryDatabase, err := n.queryDatabase(ctx, n.option.databaseID, query)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "list task ids query failed:%v:%v", n.option.databaseID, displayName)
+		}
+
+		for _, page := range queryDatabase.Results {
+			props, ok := page.Properties.(notionapi.DatabasePageProperties)
+			if !ok {
+				continue
+			}
+			if todoID := notionapi.PlainText(props["TodoID"].RichText); len(todoID) > 0 {
+				todoIDs = append(todoIDs, todoID)
+			}
+		}
+
+		if !queryDatabase.HasMore || queryDatabase.NextCursor == nil {
+			return todoIDs, nil
+		}
+		query.StartCursor = *queryDatabase.NextCursor
+	}
+}
@@ -0,0 +1,58 @@
+package notion
+
+import (
+	"context"
+	"testing"
+
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/notionapi/notionapitest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQueryDatabase(t *testing.T) {
+	t.Parallel()
+
+	srv := notionapitest.NewServer()
+	defer srv.Close()
+	db := srv.AddDatabase(notionapi.Database{
+		Parent: notionapi.Parent{Type: notionapi.ParentTypePage, PageID: "b0668f48-8d66-4733-9bdb-2f82215707f7"},
+		Properties: notionapi.DatabaseProperties{
+			"Task": {Type: notionapi.DBPropTypeTitle},
+		},
+	})
+
+	n := New("secret-api-key", db.ID,
+		WithHTTPClient(srv.Server.Client()),
+		WithClientOptions(notionapi.WithBaseURL(srv.BaseURL())),
+	).(*notion)
+	ctx := context.Background()
+
+	// The schema is read again before the filter is rejected, and the query
+	// is not sent.
+	query := &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop("TodoID").RichText().Equals("todo-1").Query(),
+	}
+	if _, err := n.queryDatabase(ctx, db.ID, query); err == nil {
+		t.Fatal("expected a filter on a missing property to be rejected")
+	}
+
+	if err := n.ApplySchema(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := n.queryDatabase(ctx, db.ID, query); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	database := "/databases/" + db.ID
+	exp := []string{
+		"GET " + database,
+		"GET " + database,
+		"GET " + database,
+		"PATCH " + database,
+		"POST " + database + "/query",
+	}
+	if diff := cmp.Diff(exp, srv.Requests()); diff != "" {
+		t.Fatalf("requests not equal (-exp, +got):\n%v", diff)
+	}
+}
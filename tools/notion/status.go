@@ -0,0 +1,93 @@
+package notion
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"notionsync/pkg/notionapi"
+)
+
+// To Do task statuses.
+const (
+	TodoStatusNotStarted      = "notStarted"
+	TodoStatusInProgress      = "inProgress"
+	TodoStatusCompleted       = "completed"
+	TodoStatusWaitingOnOthers = "waitingOnOthers"
+	TodoStatusDeferred        = "deferred"
+)
+
+// StatusMapping maps the status of a To Do task to the name of an option of
+// the Notion status property.
+type StatusMapping map[string]string
+
+// DefaultStatusMapping maps To Do statuses to the options a new Notion status
+// property starts with.
+func DefaultStatusMapping() StatusMapping {
+	return StatusMapping{
+		TodoStatusNotStarted:      "Not started",
+		TodoStatusInProgress:      "In progress",
+		TodoStatusCompleted:       "Done",
+		TodoStatusWaitingOnOthers: "In progress",
+		TodoStatusDeferred:        "Not started",
+	}
+}
+
+// ParseStatusMapping parses "todoStatus=option" pairs, overriding the
+// options of DefaultStatusMapping.
+func ParseStatusMapping(pairs []string) (StatusMapping, error) {
+	mapping := DefaultStatusMapping()
+	for _, pair := range pairs {
+		i := strings.Index(pair, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid status mapping %q, expected todoStatus=option", pair)
+		}
+		status, option := strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		if _, ok := mapping[status]; !ok {
+			return nil, fmt.Errorf("unknown To Do status %q in %q, expected %v, %v, %v, %v or %v", status, pair,
+				TodoStatusNotStarted, TodoStatusInProgress, TodoStatusCompleted, TodoStatusWaitingOnOthers, TodoStatusDeferred)
+		}
+		if len(option) == 0 {
+			return nil, fmt.Errorf("missing option in status mapping %q", pair)
+		}
+		mapping[status] = option
+	}
+	return mapping, nil
+}
+
+// options returns the distinct options of the mapping, sorted.
+func (m StatusMapping) options() []string {
+	seen := make(map[string]bool)
+	var options []string
+	for _, option := range m {
+		if !seen[option] {
+			seen[option] = true
+			options = append(options, option)
+		}
+	}
+	sort.Strings(options)
+	return options
+}
+
+// WithStatusProperty also writes the status of tasks to the status property
+// name, mapping To Do statuses through mapping. The "Done" checkbox is still
+// ticked for completed tasks.
+func WithStatusProperty(name string, mapping StatusMapping) Option {
+	return func(o *options) {
+		o.statusProperty = name
+		o.statusMapping = mapping
+	}
+}
+
+// setStatus sets the status property of a task to the option of status, if a
+// status property is configured and status is mapped.
+func (n *notion) setStatus(props notionapi.DatabasePageProperties, status string) {
+	if len(n.option.statusProperty) == 0 {
+		return
+	}
+	if option, ok := n.option.statusMapping[status]; ok {
+		props[n.option.statusProperty] = notionapi.DatabasePageProperty{
+			Status: &notionapi.SelectOptions{Name: option},
+		}
+	}
+}
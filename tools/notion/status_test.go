@@ -0,0 +1,157 @@
+package notion
+
+import (
+	"strings"
+	"testing"
+
+	"notionsync/pkg/notionapi"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDefaultStatusMapping(t *testing.T) {
+	t.Parallel()
+
+	mapping := DefaultStatusMapping()
+	for _, status := range []string{
+		TodoStatusNotStarted, TodoStatusInProgress, TodoStatusCompleted, TodoStatusWaitingOnOthers, TodoStatusDeferred,
+	} {
+		if len(mapping[status]) == 0 {
+			t.Errorf("expected an option for status %v", status)
+		}
+	}
+	if diff := cmp.Diff([]string{"Done", "In progress", "Not started"}, mapping.options()); diff != "" {
+		t.Errorf("options not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestParseStatusMapping(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		pairs    []string
+		expected map[string]string
+		expError string
+	}{
+		{
+			name:     "defaults",
+			expected: DefaultStatusMapping(),
+		},
+		{
+			name:  "overrides",
+			pairs: []string{"deferred=Someday", " waitingOnOthers = Blocked "},
+			expected: map[string]string{
+				TodoStatusNotStarted:      "Not started",
+				TodoStatusInProgress:      "In progress",
+				TodoStatusCompleted:       "Done",
+				TodoStatusWaitingOnOthers: "Blocked",
+				TodoStatusDeferred:        "Someday",
+			},
+		},
+		{
+			name:  "option containing equals sign",
+			pairs: []string{"completed=Done = shipped"},
+			expected: map[string]string{
+				TodoStatusNotStarted:      "Not started",
+				TodoStatusInProgress:      "In progress",
+				TodoStatusCompleted:       "Done = shipped",
+				TodoStatusWaitingOnOthers: "In progress",
+				TodoStatusDeferred:        "Not started",
+			},
+		},
+		{
+			name:     "missing equals sign",
+			pairs:    []string{"completed"},
+			expError: `invalid status mapping "completed"`,
+		},
+		{
+			name:     "unknown status",
+			pairs:    []string{"cancelled=Won't do"},
+			expError: `unknown To Do status "cancelled"`,
+		},
+		{
+			name:     "empty status",
+			pairs:    []string{"=Done"},
+			expError: `unknown To Do status ""`,
+		},
+		{
+			name:     "missing option",
+			pairs:    []string{"completed= "},
+			expError: `missing option in status mapping "completed= "`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mapping, err := ParseStatusMapping(tt.pairs)
+			if len(tt.expError) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.expError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, map[string]string(mapping)); diff != "" {
+				t.Fatalf("mapping not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		property string
+		status   string
+		expected *notionapi.SelectOptions
+	}{
+		{
+			name:     "mapped status",
+			property: "Status",
+			status:   TodoStatusCompleted,
+			expected: &notionapi.SelectOptions{Name: "Done"},
+		},
+		{
+			name:     "unknown status",
+			property: "Status",
+			status:   "cancelled",
+		},
+		{
+			name:     "empty status",
+			property: "Status",
+		},
+		{
+			name:   "no status property",
+			status: TodoStatusCompleted,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			n := &notion{option: options{statusProperty: tt.property, statusMapping: DefaultStatusMapping()}}
+			props := notionapi.DatabasePageProperties{}
+			n.setStatus(props, tt.status)
+
+			if tt.expected == nil {
+				if len(props) > 0 {
+					t.Fatalf("expected no properties, got %+v", props)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.expected, props[tt.property].Status); diff != "" {
+				t.Fatalf("status not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package notion
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"notionsync/pkg/notionapi"
+
+	"github.com/pkg/errors"
+)
+
+// Property names used when task lists are kept in their own database.
+const (
+	taskListNameProp      = "Name"
+	taskListIDProp        = "TaskListID"
+	taskListWellKnownProp = "Well Known List Name"
+	taskListCountProp     = "Task Count"
+	taskListRelationProp  = "Task List"
+)
+
+// taskLists is the state shared by every API cloned from the same New call
+// when task lists are kept in a related database.
+type taskLists struct {
+	databaseID string
+
+	mu      sync.Mutex
+	names   map[string]string // display name -> page ID
+	schemas map[string]bool   // task database IDs whose relation is in place
+}
+
+func newTaskLists(databaseID string) *taskLists {
+	return &taskLists{
+		databaseID: databaseID,
+		names:      make(map[string]string),
+		schemas:    make(map[string]bool),
+	}
+}
+
+func richTextProperty(content string) notionapi.DatabasePageProperty {
+	return notionapi.DatabasePageProperty{
+		RichText: []notionapi.RichText{
+			{
+				Text: &notionapi.Text{
+					Content: content,
+				},
+			},
+		},
+	}
+}
+
+func titleProperty(content string) notionapi.DatabasePageProperty {
+	return notionapi.DatabasePageProperty{
+		Title: []notionapi.RichText{
+			{
+				Text: &notionapi.Text{
+					Content: content,
+				},
+			},
+		},
+	}
+}
+
+// setTaskList writes the task list of a task row, either as plain text or,
+// when task lists live in their own database, as a relation to its page.
+func (n *notion) setTaskList(ctx context.Context, props notionapi.DatabasePageProperties, displayName string) error {
+	if n.taskLists == nil {
+		props["Task List Name"] = richTextProperty(displayName)
+		return nil
+	}
+
+	if err := n.ensureTaskListSchema(ctx); err != nil {
+		return err
+	}
+
+	pageID, err := n.findTaskListPage(ctx, displayName)
+	if err != nil {
+		return err
+	}
+
+	props[taskListRelationProp] = notionapi.DatabasePageProperty{
+		Relation: []notionapi.Relation{{ID: pageID}},
+	}
+	return nil
+}
+
+func (n *notion) SyncTaskList(ctx context.Context, taskListID, displayName, wellKnownListName string) error {
+	if n.taskLists == nil {
+		return nil
+	}
+
+	if err := n.ensureTaskListSchema(ctx); err != nil {
+		return err
+	}
+
+	tl := n.taskLists
+	props := notionapi.DatabasePageProperties{
+		taskListNameProp:      titleProperty(displayName),
+		taskListIDProp:        richTextProperty(taskListID),
+		taskListWellKnownProp: richTextProperty(wellKnownListName),
+	}
+
+	queryDatabase, err := n.queryDatabase(ctx, tl.databaseID, &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop(taskListIDProp).RichText().Equals(taskListID).Query(),
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "task list database query failed:%v:%v", tl.databaseID, taskListID)
+	}
+
+	var pageID string
+	if len(queryDatabase.Results) > 0 {
+		pageID = queryDatabase.Results[0].ID
+		_, err = n.client.UpdatePage(ctx, pageID, notionapi.UpdatePageParams{
+			DatabasePageProperties: &props,
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "update task list database %v, page %v failed", tl.databaseID, pageID)
+		}
+	} else {
+		page, err := n.client.CreatePage(ctx, notionapi.CreatePageParams{
+			ParentType:             notionapi.ParentTypeDatabase,
+			ParentID:               tl.databaseID,
+			DatabasePageProperties: &props,
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "task list database id: %v, create page failed", tl.databaseID)
+		}
+		pageID = page.ID
+	}
+
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	for name, id := range tl.names {
+		if id == pageID {
+			delete(tl.names, name)
+		}
+	}
+	tl.names[displayName] = pageID
+
+	return nil
+}
+
+func (n *notion) renameTaskListPage(ctx context.Context, oldName, newName string) error {
+	pageID, err := n.findTaskListPage(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.client.UpdatePage(ctx, pageID, notionapi.UpdatePageParams{
+		DatabasePageProperties: &notionapi.DatabasePageProperties{
+			taskListNameProp: titleProperty(newName),
+		},
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "update task list database %v, page %v failed", n.taskLists.databaseID, pageID)
+	}
+
+	tl := n.taskLists
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	delete(tl.names, oldName)
+	tl.names[newName] = pageID
+
+	return nil
+}
+
+func (n *notion) findTaskListPage(ctx context.Context, displayName string) (string, error) {
+	tl := n.taskLists
+
+	tl.mu.Lock()
+	pageID, ok := tl.names[displayName]
+	tl.mu.Unlock()
+	if ok {
+		return pageID, nil
+	}
+
+	queryDatabase, err := n.queryDatabase(ctx, tl.databaseID, &notionapi.DatabaseQuery{
+		Filter: notionapi.Prop(taskListNameProp).Title().Equals(displayName).Query(),
+	})
+	if err != nil {
+		return "", errors.WithMessagef(err, "task list database query failed:%v:%v", tl.databaseID, displayName)
+	}
+
+	if len(queryDatabase.Results) == 0 {
+		return "", errors.Errorf("query task list database id: %v, filter name: %v not found", tl.databaseID, displayName)
+	}
+
+	pageID = queryDatabase.Results[0].ID
+
+	tl.mu.Lock()
+	tl.names[displayName] = pageID
+	tl.mu.Unlock()
+
+	return pageID, nil
+}
+
+// ensureTaskListSchema adds the relation from the task database to the task
+// list database, and a rollup counting the related tasks on the other side.
+func (n *notion) ensureTaskListSchema(ctx context.Context) error {
+	tl := n.taskLists
+
+	tl.mu.Lock()
+	done := tl.schemas[n.option.databaseID]
+	tl.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	taskDatabase, err := n.client.FindDatabaseByID(ctx, n.option.databaseID)
+	if err != nil {
+		return errors.WithMessagef(err, "find task database id: %v failed", n.option.databaseID)
+	}
+
+	relation, ok := taskDatabase.Properties[taskListRelationProp]
+	if !ok || relation.Relation == nil {
+		taskDatabase, err = n.client.UpdateDatabase(ctx, n.option.databaseID, notionapi.UpdateDatabaseParams{
+			Properties: map[string]*notionapi.DatabaseProperty{
+				taskListRelationProp: {
+					Type: notionapi.DBPropTypeRelation,
+					Relation: &notionapi.RelationMetadata{
+						DatabaseID: tl.databaseID,
+					},
+				},
+			},
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "add task list relation to database id: %v failed", n.option.databaseID)
+		}
+		relation = taskDatabase.Properties[taskListRelationProp]
+	}
+
+	if relation.Relation == nil || !sameID(relation.Relation.DatabaseID, tl.databaseID) {
+		return errors.Errorf("database id: %v, property %q is not a relation to %v", n.option.databaseID, taskListRelationProp, tl.databaseID)
+	}
+
+	listDatabase, err := n.client.FindDatabaseByID(ctx, tl.databaseID)
+	if err != nil {
+		return errors.WithMessagef(err, "find task list database id: %v failed", tl.databaseID)
+	}
+
+	missing := make(map[string]*notionapi.DatabaseProperty)
+	for _, name := range []string{taskListIDProp, taskListWellKnownProp} {
+		if _, ok := listDatabase.Properties[name]; !ok {
+			missing[name] = &notionapi.DatabaseProperty{
+				Type:     notionapi.DBPropTypeRichText,
+				RichText: &notionapi.EmptyMetadata{},
+			}
+		}
+	}
+	if _, ok := listDatabase.Properties[taskListCountProp]; !ok && len(relation.Relation.SyncedPropName) > 0 {
+		missing[taskListCountProp] = &notionapi.DatabaseProperty{
+			Type: notionapi.DBPropTypeRollup,
+			Rollup: &notionapi.RollupMetadata{
+				RelationPropName: relation.Relation.SyncedPropName,
+				RollupPropName:   titlePropName(taskDatabase.Properties),
+				Function:         notionapi.RollupFunctionCountAll,
+			},
+		}
+	}
+
+	if len(missing) > 0 {
+		_, err = n.client.UpdateDatabase(ctx, tl.databaseID, notionapi.UpdateDatabaseParams{
+			Properties: missing,
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "update task list database id: %v failed", tl.databaseID)
+		}
+	}
+
+	tl.mu.Lock()
+	tl.schemas[n.option.databaseID] = true
+	tl.mu.Unlock()
+
+	return nil
+}
+
+// titlePropName returns the name of the title property of a database, which
+// is not necessarily the "Task" property the schema expects.
+func titlePropName(props notionapi.DatabaseProperties) string {
+	for name, prop := range props {
+		if prop.Type == notionapi.DBPropTypeTitle {
+			return name
+		}
+	}
+	return "Task"
+}
+
+// sameID compares Notion IDs, which are accepted with or without dashes.
+func sameID(a, b string) bool {
+	return strings.ReplaceAll(a, "-", "") == strings.ReplaceAll(b, "-", "")
+}
@@ -0,0 +1,79 @@
+package todo
+
+import (
+	"context"
+	"strings"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/todoapi"
+	"notionsync/tools/notion"
+)
+
+// notionCommentsMarker starts the part of a task body that mirrors the
+// comments on its Notion page. Everything after it is replaced on each sync.
+const notionCommentsMarker = "——— Notion comments ———"
+
+// WithCommentMirror copies the comments on the Notion page of a task to the
+// end of its body in To Do whenever the task is synced. Tasks with an HTML
+// body are left alone.
+func WithCommentMirror() Option {
+	return func(t *todo) {
+		t.mirrorComments = true
+	}
+}
+
+// mirrorNotionComments updates the body of task with the comments on its
+// Notion page, if they changed. Failures are logged: the task itself has
+// been synced.
+func (t *todo) mirrorNotionComments(ctx context.Context, notionAPI notion.API, task todoapi.Task, taskListID string) {
+	if task.Body.ContentType == "html" {
+		return
+	}
+
+	comments, err := notionAPI.TaskComments(ctx, task.Id)
+	if err != nil {
+		logger.T(ctx).Warnf("notion task comments: %v failed, task: %v", err, task.Id)
+		return
+	}
+
+	// To Do may return the body with CRLF line endings, which would otherwise
+	// never match and update the task on every sync.
+	current := strings.ReplaceAll(task.Body.Content, "\r\n", "\n")
+	body := mirroredBody(current, comments)
+	if body == current {
+		return
+	}
+	if err := t.client.UpdateTaskBody(ctx, taskListID, task.Id, todoapi.TaskBody{Content: body, ContentType: "text"}); err != nil {
+		logger.T(ctx).Warnf("update task body: %v failed, task: %v", err, task.Id)
+		return
+	}
+	logger.T(ctx).Debugf("mirrored %v notion comments into task %v", len(comments), task.Id)
+}
+
+// mirroredBody returns body with its mirrored comments replaced by comments,
+// or removed when there are none.
+func mirroredBody(body string, comments []notionapi.Comment) string {
+	if i := strings.Index(body, notionCommentsMarker); i >= 0 {
+		body = strings.TrimRight(body[:i], "\r\n")
+	}
+	if len(comments) == 0 {
+		return body
+	}
+
+	var sb strings.Builder
+	sb.WriteString(body)
+	if len(body) > 0 {
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString(notionCommentsMarker)
+	for _, comment := range comments {
+		sb.WriteString("\n")
+		sb.WriteString(comment.CreatedTime.Local().Format("2006-01-02 15:04"))
+		sb.WriteString(" ")
+		for _, text := range comment.RichText {
+			sb.WriteString(text.PlainText)
+		}
+	}
+	return sb.String()
+}
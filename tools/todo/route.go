@@ -0,0 +1,85 @@
+package todo
+
+import (
+	"path"
+	"strings"
+
+	"notionsync/pkg/todoapi"
+)
+
+// ListRule matches a task list by its display name or its well-known list
+// name (e.g. "defaultList", "flaggedEmails"). Match may be a glob pattern as
+// understood by path.Match. DatabaseID routes the matched list to a specific
+// Notion database; when empty the default database is used.
+type ListRule struct {
+	Match      string
+	DatabaseID string
+}
+
+// ParseListRule parses a rule written as "match" or "match=databaseID".
+func ParseListRule(s string) ListRule {
+	idx := strings.LastIndex(s, "=")
+	if idx < 0 {
+		return ListRule{Match: strings.TrimSpace(s)}
+	}
+
+	return ListRule{
+		Match:      strings.TrimSpace(s[:idx]),
+		DatabaseID: strings.TrimSpace(s[idx+1:]),
+	}
+}
+
+// ParseListRules parses every rule with ParseListRule, skipping empty ones.
+func ParseListRules(ss []string) []ListRule {
+	var rules []ListRule
+	for _, s := range ss {
+		rule := ParseListRule(s)
+		if len(rule.Match) == 0 {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (r ListRule) matches(list todoapi.TaskList) bool {
+	for _, name := range []string{list.DisplayName, list.WellKnownListName} {
+		if len(name) == 0 {
+			continue
+		}
+		if r.Match == name {
+			return true
+		}
+		if ok, err := path.Match(r.Match, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+type router struct {
+	include []ListRule
+	exclude []ListRule
+}
+
+// route reports whether the list should be synced and, if so, the Notion
+// database it belongs to. An empty database ID means the default database.
+func (r router) route(list todoapi.TaskList) (databaseID string, ok bool) {
+	for _, rule := range r.exclude {
+		if rule.matches(list) {
+			return "", false
+		}
+	}
+
+	if len(r.include) == 0 {
+		return "", true
+	}
+
+	for _, rule := range r.include {
+		if rule.matches(list) {
+			return rule.DatabaseID, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,134 @@
+package todo
+
+import (
+	"testing"
+
+	"notionsync/pkg/todoapi"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseListRules(t *testing.T) {
+	t.Parallel()
+
+	rules := ParseListRules([]string{
+		"Groceries",
+		" Work* = db-work ",
+		"a=b=db-ab",
+		"",
+		" = db-empty",
+	})
+
+	exp := []ListRule{
+		{Match: "Groceries"},
+		{Match: "Work*", DatabaseID: "db-work"},
+		{Match: "a=b", DatabaseID: "db-ab"},
+	}
+	if diff := cmp.Diff(exp, rules); diff != "" {
+		t.Fatalf("rules not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		router    router
+		list      todoapi.TaskList
+		expected  string
+		expRouted bool
+	}{
+		{
+			name:      "no rules",
+			list:      todoapi.TaskList{DisplayName: "Groceries"},
+			expRouted: true,
+		},
+		{
+			name:      "exact display name",
+			router:    router{include: []ListRule{{Match: "Groceries", DatabaseID: "db-groceries"}}},
+			list:      todoapi.TaskList{DisplayName: "Groceries"},
+			expected:  "db-groceries",
+			expRouted: true,
+		},
+		{
+			name:      "glob",
+			router:    router{include: []ListRule{{Match: "Work*", DatabaseID: "db-work"}}},
+			list:      todoapi.TaskList{DisplayName: "Work projects"},
+			expected:  "db-work",
+			expRouted: true,
+		},
+		{
+			name:      "well-known list name",
+			router:    router{include: []ListRule{{Match: "flaggedEmails", DatabaseID: "db-mail"}}},
+			list:      todoapi.TaskList{DisplayName: "Flagged email", WellKnownListName: "flaggedEmails"},
+			expected:  "db-mail",
+			expRouted: true,
+		},
+		{
+			name:      "default database",
+			router:    router{include: []ListRule{{Match: "Groceries"}}},
+			list:      todoapi.TaskList{DisplayName: "Groceries"},
+			expRouted: true,
+		},
+		{
+			name: "first matching include wins",
+			router: router{include: []ListRule{
+				{Match: "Home", DatabaseID: "db-home"},
+				{Match: "Work*", DatabaseID: "db-work"},
+				{Match: "*", DatabaseID: "db-all"},
+			}},
+			list:      todoapi.TaskList{DisplayName: "Work projects"},
+			expected:  "db-work",
+			expRouted: true,
+		},
+		{
+			name: "exclude wins over include",
+			router: router{
+				include: []ListRule{{Match: "*", DatabaseID: "db-all"}},
+				exclude: []ListRule{{Match: "Work*"}},
+			},
+			list: todoapi.TaskList{DisplayName: "Work projects"},
+		},
+		{
+			name:   "exclude without include",
+			router: router{exclude: []ListRule{{Match: "defaultList"}}},
+			list:   todoapi.TaskList{DisplayName: "Tasks", WellKnownListName: "defaultList"},
+		},
+		{
+			name:      "not excluded without include",
+			router:    router{exclude: []ListRule{{Match: "defaultList"}}},
+			list:      todoapi.TaskList{DisplayName: "Groceries", WellKnownListName: "none"},
+			expRouted: true,
+		},
+		{
+			name:   "no include matches",
+			router: router{include: []ListRule{{Match: "Work*", DatabaseID: "db-work"}}},
+			list:   todoapi.TaskList{DisplayName: "Groceries"},
+		},
+		{
+			name:   "invalid pattern matches only itself",
+			router: router{include: []ListRule{{Match: "[Work", DatabaseID: "db-work"}}},
+			list:   todoapi.TaskList{DisplayName: "Work"},
+		},
+		{
+			name:      "invalid pattern as exact name",
+			router:    router{include: []ListRule{{Match: "[Work", DatabaseID: "db-work"}}},
+			list:      todoapi.TaskList{DisplayName: "[Work"},
+			expected:  "db-work",
+			expRouted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			databaseID, ok := tt.router.route(tt.list)
+			if databaseID != tt.expected || ok != tt.expRouted {
+				t.Fatalf("expected %q, %v, got %q, %v", tt.expected, tt.expRouted, databaseID, ok)
+			}
+		})
+	}
+}
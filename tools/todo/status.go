@@ -0,0 +1,100 @@
+package todo
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// List worker states.
+const (
+	StateStarting = "starting"
+	StateSyncing  = "syncing"
+	StateWaiting  = "waiting"
+	StateFailing  = "failing"
+)
+
+// MaxPollInterval is the longest a list worker waits between two delta polls.
+const MaxPollInterval = 60 * time.Second
+
+// ListStatus is a snapshot of a list worker.
+type ListStatus struct {
+	TaskListID  string    `json:"taskListId"`
+	DisplayName string    `json:"displayName"`
+	DatabaseID  string    `json:"databaseId,omitempty"`
+	State       string    `json:"state"`
+	StartedAt   time.Time `json:"startedAt"`
+	LastDelta   time.Time `json:"lastDelta"`
+	LastError   string    `json:"lastError,omitempty"`
+	Backlog     int       `json:"backlog"`
+}
+
+// Status is a snapshot of the running sync.
+type Status struct {
+	LastDiscovery      time.Time    `json:"lastDiscovery"`
+	LastDiscoveryError string       `json:"lastDiscoveryError,omitempty"`
+	Lists              []ListStatus `json:"lists"`
+}
+
+func (t *todo) Status() Status {
+	t.mu.Lock()
+	status := Status{
+		LastDiscovery:      t.lastDiscovery,
+		LastDiscoveryError: t.lastDiscoveryErr,
+	}
+	running := t.running
+	t.mu.Unlock()
+
+	status.Lists = make([]ListStatus, 0, len(running))
+	for _, w := range running {
+		status.Lists = append(status.Lists, w.status())
+	}
+
+	sort.Slice(status.Lists, func(i, j int) bool {
+		return status.Lists[i].DisplayName < status.Lists[j].DisplayName
+	})
+
+	return status
+}
+
+func (t *todo) setDiscovery(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.lastDiscoveryErr = err.Error()
+		return
+	}
+	t.lastDiscovery = time.Now()
+	t.lastDiscoveryErr = ""
+}
+
+// Problems lists why the sync is not ready: the To Do token stopped working,
+// or a list worker has not fetched a delta within intervals poll intervals.
+func (s Status) Problems(intervals int, now time.Time) []string {
+	var problems []string
+
+	if len(s.LastDiscoveryError) > 0 {
+		problems = append(problems, fmt.Sprintf("list task lists: %v", s.LastDiscoveryError))
+	} else if s.LastDiscovery.IsZero() {
+		problems = append(problems, "task lists not listed yet")
+	}
+
+	maxStale := time.Duration(intervals) * MaxPollInterval
+	for _, list := range s.Lists {
+		last := list.LastDelta
+		if last.IsZero() {
+			last = list.StartedAt
+		}
+		if now.Sub(last) <= maxStale {
+			continue
+		}
+
+		problem := fmt.Sprintf("task list %v: no delta since %v", list.DisplayName, last.Format(time.RFC3339))
+		if len(list.LastError) > 0 {
+			problem += ": " + list.LastError
+		}
+		problems = append(problems, problem)
+	}
+
+	return problems
+}
@@ -0,0 +1,96 @@
+package todo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStatusProblems(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) time.Time { return now.Add(-d) }
+
+	tests := []struct {
+		name      string
+		status    Status
+		intervals int
+		expected  []string
+	}{
+		{
+			name:      "healthy",
+			status:    Status{LastDiscovery: ago(time.Minute), Lists: []ListStatus{{DisplayName: "Groceries", LastDelta: ago(time.Minute)}}},
+			intervals: 3,
+		},
+		{
+			name:      "not listed yet",
+			intervals: 3,
+			expected:  []string{"task lists not listed yet"},
+		},
+		{
+			name:      "discovery error",
+			status:    Status{LastDiscovery: ago(time.Hour), LastDiscoveryError: "token expired"},
+			intervals: 3,
+			expected:  []string{"list task lists: token expired"},
+		},
+		{
+			name: "delta exactly at the threshold",
+			status: Status{LastDiscovery: now, Lists: []ListStatus{
+				{DisplayName: "Groceries", LastDelta: ago(3 * MaxPollInterval)},
+			}},
+			intervals: 3,
+		},
+		{
+			name: "stale delta",
+			status: Status{LastDiscovery: now, Lists: []ListStatus{
+				{DisplayName: "Groceries", LastDelta: ago(3*MaxPollInterval + time.Second)},
+				{DisplayName: "Work", LastDelta: ago(time.Second)},
+			}},
+			intervals: 3,
+			expected:  []string{"task list Groceries: no delta since 2022-07-01T11:56:59Z"},
+		},
+		{
+			name: "stale delta with error",
+			status: Status{LastDiscovery: now, Lists: []ListStatus{
+				{DisplayName: "Groceries", LastDelta: ago(2 * MaxPollInterval), LastError: "delta failed"},
+			}},
+			intervals: 1,
+			expected:  []string{"task list Groceries: no delta since 2022-07-01T11:58:00Z: delta failed"},
+		},
+		{
+			name: "recent error within threshold",
+			status: Status{LastDiscovery: now, Lists: []ListStatus{
+				{DisplayName: "Groceries", LastDelta: ago(time.Second), LastError: "delta failed"},
+			}},
+			intervals: 1,
+		},
+		{
+			name: "no delta yet, started recently",
+			status: Status{LastDiscovery: now, Lists: []ListStatus{
+				{DisplayName: "Groceries", StartedAt: ago(time.Second)},
+			}},
+			intervals: 1,
+		},
+		{
+			name: "no delta since start",
+			status: Status{LastDiscovery: now, Lists: []ListStatus{
+				{DisplayName: "Groceries", StartedAt: ago(time.Hour)},
+			}},
+			intervals: 1,
+			expected:  []string{"task list Groceries: no delta since 2022-07-01T11:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.expected, tt.status.Problems(tt.intervals, now)); diff != "" {
+				t.Fatalf("problems not equal (-exp, +got):\n%v", diff)
+			}
+		})
+	}
+}
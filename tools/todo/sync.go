@@ -0,0 +1,245 @@
+package todo
+
+import (
+	"context"
+	"time"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/metrics"
+	"notionsync/pkg/todoapi"
+	"notionsync/pkg/tracing"
+	"notionsync/tools/notion"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// applyTasks writes a batch of To Do tasks, as returned by a delta request,
+// into Notion. A task that fails does not stop the others; the error counts
+// them.
+func (t *todo) applyTasks(ctx context.Context, notionAPI notion.API, tasks []todoapi.Task, taskListID, displayName string) error {
+	var failed int
+	for _, task := range tasks {
+		if err := t.applyTask(ctx, notionAPI, task, taskListID, displayName); err != nil {
+			failed++
+		}
+	}
+	return tasksFailed(failed, len(tasks))
+}
+
+// tasksFailed returns an error when some of total tasks failed to sync.
+func tasksFailed(failed, total int) error {
+	if failed > 0 {
+		return errors.Errorf("%v of %v tasks failed to sync", failed, total)
+	}
+	return nil
+}
+
+// applyTask writes a single task under its own span, so that the Notion
+// requests it makes, and its log lines, share a trace.
+func (t *todo) applyTask(ctx context.Context, notionAPI notion.API, task todoapi.Task, taskListID, displayName string) (err error) {
+	ctx, span := tracing.Start(ctx, "todo.task", trace.WithAttributes(
+		attribute.String("todo.task.id", task.Id),
+		attribute.String("todo.task_list.name", displayName),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if task.Removed.Reason == "deleted" {
+		span.SetAttributes(attribute.String("todo.operation", metrics.OperationDeleted))
+		return t.notionDeleteTask(ctx, notionAPI, task.Id, displayName)
+	}
+
+	if len(task.DisplayName) == 0 {
+		logger.T(ctx).Warnf("task displayName is empty")
+		return nil
+	}
+
+	exist, err := notionAPI.ExistTaskFromTodoID(ctx, task.Id)
+	if err != nil {
+		logger.T(ctx).Warnf("notion exist task from todo id failed: %v", err)
+		return err
+	}
+
+	if !exist {
+		span.SetAttributes(attribute.String("todo.operation", metrics.OperationCreated))
+		return t.notinAddTaskInfo(ctx, notionAPI, task, displayName)
+	}
+
+	span.SetAttributes(attribute.String("todo.operation", metrics.OperationUpdated))
+	if err := t.notionUpdateTaskInfo(ctx, notionAPI, task, displayName); err != nil {
+		return err
+	}
+	if t.mirrorComments {
+		t.mirrorNotionComments(ctx, notionAPI, task, taskListID)
+	}
+	return nil
+}
+
+// fetchAllTasks follows a fresh delta query of a task list through all of
+// its pages. It returns the tasks and the delta link of the last page.
+func (t *todo) fetchAllTasks(ctx context.Context, taskListID string) ([]todoapi.Task, string, error) {
+	var (
+		all []todoapi.Task
+		url string
+	)
+
+	for {
+		resp, err := t.client.GetTaskDelta(ctx, taskListID, url)
+		if err != nil {
+			return nil, "", err
+		}
+		all = append(all, resp.Tasks...)
+
+		if len(resp.OdataNextLink) == 0 {
+			return all, resp.OdataDeltaLink, nil
+		}
+		url = resp.OdataNextLink
+	}
+}
+
+// routedList is a task list together with the API of the database it is
+// routed to.
+type routedList struct {
+	todoapi.TaskList
+	databaseID string
+	notion     notion.API
+}
+
+func (t *todo) routedLists(ctx context.Context) ([]routedList, error) {
+	listTaskLists, err := t.client.ListTaskLists(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "list task lists failed")
+	}
+
+	var lists []routedList
+	for _, taskList := range listTaskLists {
+		databaseID, ok := t.router.route(taskList)
+		if !ok {
+			continue
+		}
+		lists = append(lists, routedList{
+			TaskList:   taskList,
+			databaseID: databaseID,
+			notion:     t.notion.WithDatabaseID(databaseID),
+		})
+	}
+
+	return lists, nil
+}
+
+func (t *todo) SyncOnce(ctx context.Context) error {
+	return t.syncAll(ctx, false)
+}
+
+func (t *todo) Reconcile(ctx context.Context) error {
+	return t.syncAll(ctx, true)
+}
+
+func (t *todo) syncAll(ctx context.Context, markDeleted bool) (err error) {
+	ctx, span := tracing.Start(ctx, "todo.sync", trace.WithAttributes(
+		attribute.Bool("todo.mark_deleted", markDeleted),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	lists, err := t.routedLists(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	databaseIDs := make([]string, 0, len(lists))
+	for _, list := range lists {
+		if err := t.syncList(ctx, list, markDeleted); err != nil {
+			logger.T(ctx).Warnf("sync task list: %v failed: %v", list.DisplayName, err)
+			failed++
+		}
+		databaseIDs = append(databaseIDs, list.databaseID)
+	}
+	t.archiveDeleted(ctx, databaseIDs)
+
+	if failed > 0 {
+		return errors.Errorf("%v of %v task lists failed to sync", failed, len(lists))
+	}
+	return nil
+}
+
+func (t *todo) syncList(ctx context.Context, list routedList, markDeleted bool) (err error) {
+	ctx, span := tracing.Start(ctx, "todo.sync_list", trace.WithAttributes(
+		attribute.String("todo.task_list.id", list.Id),
+		attribute.String("todo.task_list.name", list.DisplayName),
+		attribute.Bool("todo.mark_deleted", markDeleted),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if err := list.notion.SyncTaskList(ctx, list.Id, list.DisplayName, list.WellKnownListName); err != nil {
+		return err
+	}
+
+	tasks, _, err := t.fetchAllTasks(ctx, list.Id)
+	if err != nil {
+		return errors.WithMessage(err, "get task delta failed")
+	}
+
+	logger.T(ctx).Infof("sync task list: %v, tasks: %v", list.DisplayName, len(tasks))
+	applyErr := t.applyTasks(ctx, list.notion, tasks, list.Id, list.DisplayName)
+	if !markDeleted {
+		if applyErr == nil {
+			metrics.SetLastSync(list.DisplayName, time.Now())
+		}
+		return applyErr
+	}
+
+	exists := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		if task.Removed.Reason != "deleted" {
+			exists[task.Id] = true
+		}
+	}
+
+	todoIDs, err := list.notion.ListTaskIDs(ctx, list.DisplayName)
+	if err != nil {
+		return err
+	}
+
+	var removed, failed int
+	for _, todoID := range todoIDs {
+		if exists[todoID] {
+			continue
+		}
+		removed++
+		logger.T(ctx).Infof("task no longer in todo, mark deleted: %v", todoID)
+		if err := t.notionDeleteTask(ctx, list.notion, todoID, list.DisplayName); err != nil {
+			failed++
+		}
+	}
+
+	if applyErr != nil {
+		return applyErr
+	}
+	if err := tasksFailed(failed, removed); err != nil {
+		return errors.WithMessage(err, "mark deleted")
+	}
+	metrics.SetLastSync(list.DisplayName, time.Now())
+	return nil
+}
+
+// archiveDeleted applies the deletion retention to each of the databases
+// tasks are routed to, an empty ID being the default database.
+func (t *todo) archiveDeleted(ctx context.Context, databaseIDs []string) {
+	seen := make(map[string]bool, len(databaseIDs))
+	for _, databaseID := range databaseIDs {
+		if seen[databaseID] {
+			continue
+		}
+		seen[databaseID] = true
+
+		archived, err := t.notion.WithDatabaseID(databaseID).ArchiveDeleted(ctx)
+		if err != nil {
+			logger.T(ctx).Warnf("archive deleted tasks failed: %v", err)
+		}
+		if archived > 0 {
+			logger.T(ctx).Infof("archived %v deleted tasks", archived)
+		}
+	}
+}
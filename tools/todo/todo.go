@@ -1,49 +1,112 @@
 package todo
 
 import (
+	"context"
 	"log"
 	"math/rand"
+	"sync"
 	"time"
 
 	"notionsync/pkg/logger"
+	"notionsync/pkg/metrics"
 	"notionsync/pkg/todoapi"
+	"notionsync/pkg/tracing"
 	"notionsync/tools/notion"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type API interface {
-	UpdateNotionAllToDo() error
+	// UpdateNotionAllToDo keeps syncing task list changes until the process exits.
+	UpdateNotionAllToDo(ctx context.Context) error
+	// SyncOnce syncs every task of every routed task list once.
+	SyncOnce(ctx context.Context) error
+	// Reconcile syncs every task like SyncOnce, and marks Notion rows whose
+	// task no longer exists in To Do as deleted.
+	Reconcile(ctx context.Context) error
+	// Status returns a snapshot of the list workers started by
+	// UpdateNotionAllToDo.
+	Status() Status
 }
 
+const defaultDiscoveryInterval = 5 * time.Minute
+
 type todo struct {
-	client *todoapi.Client
-	notion notion.API
+	client            *todoapi.Client
+	clientOpts        []todoapi.ClientOption
+	notion            notion.API
+	router            router
+	discoveryInterval time.Duration
+	mirrorComments    bool
+
+	mu               sync.Mutex
+	running          []*listWorker
+	lastDiscovery    time.Time
+	lastDiscoveryErr string
+}
+
+// Option is used to override default sync behavior.
+type Option func(*todo)
+
+// WithListRules limits the synced task lists. A list matching any exclude
+// rule is skipped. When include rules are given, only lists matching one of
+// them are synced, into the rule's database if it names one.
+func WithListRules(include, exclude []ListRule) Option {
+	return func(t *todo) {
+		t.router = router{include: include, exclude: exclude}
+	}
+}
+
+// WithDiscoveryInterval overrides how often task lists are listed to pick up
+// newly created ones.
+func WithDiscoveryInterval(interval time.Duration) Option {
+	return func(t *todo) {
+		if interval > 0 {
+			t.discoveryInterval = interval
+		}
+	}
+}
+
+// WithClientOptions passes options to the To Do API client.
+func WithClientOptions(opts ...todoapi.ClientOption) Option {
+	return func(t *todo) {
+		t.clientOpts = append(t.clientOpts, opts...)
+	}
 }
 
-func New(clientID, clientSecret string, notionAPI notion.API) (API, error) {
-	client, err := todoapi.NewClient(clientID, clientSecret)
+func New(clientID, clientSecret string, notionAPI notion.API, opts ...Option) (API, error) {
+	t := &todo{
+		notion:            notionAPI,
+		discoveryInterval: defaultDiscoveryInterval,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	client, err := todoapi.NewClient(clientID, clientSecret, t.clientOpts...)
 	if err != nil {
 		return nil, err
 	}
+	t.client = client
 
-	return &todo{
-		client: client,
-		notion: notionAPI,
-	}, nil
+	return t, nil
 }
 
-func GetToken(clientID, clientSecret string) {
-	token, err := todoapi.GetToken(clientID, clientSecret)
+func GetToken(clientID, clientSecret string, opts ...todoapi.ClientOption) error {
+	token, err := todoapi.GetToken(clientID, clientSecret, opts...)
 	if err != nil {
-		log.Fatal("Can't get token. Check your network and try again")
-		return
+		return errors.WithMessage(err, "can't get token, check your network and try again")
 	}
 
 	log.Printf("%v", token.Expiry.String())
-	saveTokenErr := todoapi.SaveToken([]byte(token.RefreshToken))
-	if saveTokenErr != nil {
-		log.Fatal("Can't save authenticating information. Try again")
-		return
+	if err := todoapi.SaveToken([]byte(token.RefreshToken)); err != nil {
+		return errors.WithMessage(err, "can't save authenticating information, try again")
 	}
+
+	return nil
 }
 
 func getTaskDeltaUrl(tasks *todoapi.ListTasksResponse) (isDeltaLink bool, url string) {
@@ -53,105 +116,189 @@ func getTaskDeltaUrl(tasks *todoapi.ListTasksResponse) (isDeltaLink bool, url st
 	return false, tasks.OdataNextLink
 }
 
-func (t *todo) notionDeleteTask(tasksID string) {
-	if err := t.notion.UpdateTaskInfo(tasksID, "", "", "", "", "", time.Time{}, true); err != nil {
-		logger.Warnf("deleted task id failed: %v", err)
+func (t *todo) notionDeleteTask(ctx context.Context, notionAPI notion.API, tasksID, displayName string) error {
+	if err := notionAPI.UpdateTaskInfo(ctx, tasksID, "", "", "", "", "", time.Time{}, true); err != nil {
+		logger.T(ctx).Warnf("deleted task id failed: %v", err)
+		return err
 	}
+	metrics.ObserveTask(displayName, metrics.OperationDeleted)
+	return nil
 }
 
-func (t *todo) notionUpdateTaskInfo(task todoapi.Task, displayName string) {
-	logger.Debugf("task update >>>> : [%v]", task.DisplayName)
-	err := t.notion.UpdateTaskInfo(task.Id, task.DisplayName, task.Status, task.Importance, task.DueDateTime.DateTime, displayName, task.CompletedDateTime, false)
+func (t *todo) notionUpdateTaskInfo(ctx context.Context, notionAPI notion.API, task todoapi.Task, displayName string) error {
+	logger.T(ctx).Debugf("task update >>>> : [%v]", task.DisplayName)
+	err := notionAPI.UpdateTaskInfo(ctx, task.Id, task.DisplayName, task.Status, task.Importance, task.DueDateTime.DateTime, displayName, task.CompletedDateTime, false)
 	if err != nil {
-		logger.Warnf("notion update task info: %v failed, displayName: %v", err, displayName)
+		logger.T(ctx).Warnf("notion update task info: %v failed, displayName: %v", err, displayName)
+		return err
 	}
+	metrics.ObserveTask(displayName, metrics.OperationUpdated)
+	return nil
 }
 
-func (t *todo) notinAddTaskInfo(task todoapi.Task, displayName string) {
-	logger.Debugf("task create >>>> : [%v]", task.DisplayName)
+func (t *todo) notinAddTaskInfo(ctx context.Context, notionAPI notion.API, task todoapi.Task, displayName string) error {
+	logger.T(ctx).Debugf("task create >>>> : [%v]", task.DisplayName)
 	if len(task.DueDateTime.DateTime) == 0 {
-		err := t.notion.AddTask(task.DisplayName, task.Id, task.Importance, displayName)
+		err := notionAPI.AddTask(ctx, task.DisplayName, task.Id, task.Status, task.Importance, displayName)
 		if err != nil {
-			logger.Warnf("notion add task: %v failed, displayName: %v", err, displayName)
+			logger.T(ctx).Warnf("notion add task: %v failed, displayName: %v", err, displayName)
+			return err
 		}
-		return
+		metrics.ObserveTask(displayName, metrics.OperationCreated)
+		return nil
 	}
 
-	if err := t.notion.AddTaskWithScheduleTime(task.DisplayName, task.Id,
-		task.Importance, displayName, task.DueDateTime.DateTime); err != nil {
-		logger.Warnf("notion add task: %v failed", err)
+	if err := notionAPI.AddTaskWithScheduleTime(ctx, task.DisplayName, task.Id,
+		task.Status, task.Importance, displayName, task.DueDateTime.DateTime); err != nil {
+		logger.T(ctx).Warnf("notion add task: %v failed", err)
+		return err
 	}
+	metrics.ObserveTask(displayName, metrics.OperationCreated)
+	return nil
 }
 
-func (t *todo) deltaLoop(taskListID, displayName string) {
+func (t *todo) deltaLoop(ctx context.Context, w *listWorker) {
 	var tasks = &todoapi.ListTasksResponse{}
 
-	time.Sleep(time.Duration(rand.Intn(30)) * time.Second)
+	if !sleepContext(ctx, time.Duration(rand.Intn(30))*time.Second) {
+		return
+	}
 
-	logger.Debugf(taskListID + "::::" + displayName + "loop will start")
+	logger.Debugf(w.taskListID + "::::" + w.name() + "loop will start")
 
+	listSynced := false
 	for {
-		deltaLink, url := getTaskDeltaUrl(tasks)
-		respTask, err := t.client.GetTaskDelta(taskListID, url)
+		var (
+			sleep time.Duration
+			err   error
+		)
+		tasks, listSynced, sleep, err = t.pollOnce(ctx, w, tasks, listSynced)
 		if err != nil {
-			logger.Warnf("get task delta: %v failed, displayName: %v", err, displayName)
-			time.Sleep(time.Duration(rand.Intn(3)) * time.Second)
-			continue
+			w.setError(err)
 		}
-		tasks = respTask
+		if !sleepContext(ctx, sleep) {
+			break
+		}
+	}
 
-		if !deltaLink {
-			logger.Debugf("not delta link: %v, will next", displayName)
-			time.Sleep(time.Duration(rand.Intn(3)) * time.Second)
-			continue
+	logger.Debugf(w.taskListID + "::::" + w.name() + "loop stopped")
+}
+
+// pollOnce runs one poll cycle of a list worker under its own span: it
+// fetches the next delta page and writes its tasks when it is a delta. It
+// returns the response to continue from and how long to wait before the next
+// cycle.
+func (t *todo) pollOnce(ctx context.Context, w *listWorker, tasks *todoapi.ListTasksResponse, listSynced bool) (
+	next *todoapi.ListTasksResponse, synced bool, sleep time.Duration, err error) {
+	displayName := w.name()
+	ctx, span := tracing.Start(ctx, "todo.poll", trace.WithAttributes(
+		attribute.String("todo.task_list.id", w.taskListID),
+		attribute.String("todo.task_list.name", displayName),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if !listSynced {
+		if err := w.notion.SyncTaskList(ctx, w.taskListID, displayName, w.wellKnownListName); err != nil {
+			logger.T(ctx).Warnf("notion sync task list: %v failed, displayName: %v", err, displayName)
+		} else {
+			listSynced = true
+		}
+	}
+
+	deltaLink, url := getTaskDeltaUrl(tasks)
+	respTask, err := t.client.GetTaskDelta(ctx, w.taskListID, url)
+	if errors.Is(err, todoapi.ErrSyncStateNotFound) {
+		// Graph dropped the sync state of the link. Changes since the last
+		// delta are lost with it, so restart with a full delta and write
+		// every task of the list.
+		logger.T(ctx).Warnf("delta link expired, restart with a full delta, displayName: %v", displayName)
+		var all []todoapi.Task
+		var link string
+		all, link, err = t.fetchAllTasks(ctx, w.taskListID)
+		if err == nil {
+			respTask = &todoapi.ListTasksResponse{Tasks: all, OdataDeltaLink: link}
+			deltaLink = true
 		}
+	}
+	if err != nil {
+		logger.T(ctx).Warnf("get task delta: %v failed, displayName: %v", err, displayName)
+		return tasks, listSynced, time.Duration(rand.Intn(3)) * time.Second, err
+	}
+	tasks = respTask
+	metrics.ObservePoll(displayName)
+	w.setBacklog(len(tasks.Tasks), true)
+	span.SetAttributes(
+		attribute.Bool("todo.delta", deltaLink),
+		attribute.Int("todo.tasks", len(tasks.Tasks)),
+	)
+
+	if !deltaLink {
+		logger.T(ctx).Debugf("not delta link: %v, will next", displayName)
+		return tasks, listSynced, time.Duration(rand.Intn(3)) * time.Second, nil
+	}
 
-		for _, task := range tasks.Tasks {
-			if task.Removed.Reason == "deleted" {
-				t.notionDeleteTask(task.Id)
-				continue
-			}
-
-			if len(task.DisplayName) == 0 {
-				logger.Warnf("task displayName is empty")
-				continue
-			}
-
-			exist, err := t.notion.ExistTaskFromTodoID(task.Id)
-			if err != nil {
-				logger.Warnf("notion exist task from todo id failed: %v", err)
-				continue
-			}
-
-			if exist {
-				t.notionUpdateTaskInfo(task, displayName)
-			} else {
-				t.notinAddTaskInfo(task, displayName)
-			}
+	w.setState(StateSyncing)
+	var failed int
+	for i, task := range tasks.Tasks {
+		if err := t.applyTask(ctx, w.notion, task, w.taskListID, displayName); err != nil {
+			failed++
 		}
+		w.setBacklog(len(tasks.Tasks)-i-1, false)
+	}
+	if failed == 0 {
+		metrics.SetLastSync(displayName, time.Now())
+	}
+	w.setState(StateWaiting)
+	// The delta link moves on either way; failed tasks are written again
+	// when they next change, or by once or reconcile.
+	err = tasksFailed(failed, len(tasks.Tasks))
 
-		var randSec int
-		for {
-			randSec = rand.Intn(60)
-			if randSec > 30 {
-				break
-			}
+	var randSec int
+	for {
+		randSec = rand.Intn(60)
+		if randSec > 30 {
+			break
 		}
-		logger.Debugf("time update now: %v, random second: %vs", displayName, randSec)
-		time.Sleep(time.Duration(randSec) * time.Second)
 	}
+	logger.T(ctx).Debugf("time update now: %v, random second: %vs", displayName, randSec)
+	return tasks, listSynced, time.Duration(randSec) * time.Second, err
 }
 
-func (t *todo) UpdateNotionAllToDo() error {
-	listTaskLists, err := t.client.ListTaskLists()
+func (t *todo) UpdateNotionAllToDo(ctx context.Context) error {
+	listTaskLists, err := t.discoverTaskLists(ctx)
 	if err != nil {
 		return err
 	}
 
-	logger.Debugf("list len: %v", len(listTaskLists))
-	for _, taskLists := range listTaskLists {
-		go t.deltaLoop(taskLists.Id, taskLists.DisplayName)
+	workers := make(map[string]*listWorker)
+	t.syncListWorkers(ctx, listTaskLists, workers)
+
+	for {
+		if !sleepContext(ctx, t.discoveryInterval) {
+			return ctx.Err()
+		}
+
+		listTaskLists, err := t.discoverTaskLists(ctx)
+		if err != nil {
+			logger.Warnf("list task lists failed: %v", err)
+			continue
+		}
+		t.syncListWorkers(ctx, listTaskLists, workers)
+
+		databaseIDs := make([]string, 0, len(workers))
+		for _, w := range workers {
+			databaseIDs = append(databaseIDs, w.databaseID)
+		}
+		t.archiveDeleted(ctx, databaseIDs)
 	}
+}
+
+func (t *todo) discoverTaskLists(ctx context.Context) (lists []todoapi.TaskList, err error) {
+	ctx, span := tracing.Start(ctx, "todo.discover")
+	defer func() { tracing.End(span, err) }()
 
-	select {}
+	lists, err = t.client.ListTaskLists(ctx)
+	t.setDiscovery(err)
+	span.SetAttributes(attribute.Int("todo.task_lists", len(lists)))
+	return lists, err
 }
@@ -0,0 +1,197 @@
+package todo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/metrics"
+	"notionsync/pkg/notionapi"
+	"notionsync/pkg/notionapi/notionapitest"
+	"notionsync/pkg/todoapi"
+	"notionsync/pkg/todoapi/todoapitest"
+	"notionsync/tools/notion"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestMain keeps the log of the code under test on the console, rather than
+// in a log directory of the package.
+func TestMain(m *testing.M) {
+	config := logger.DefaultConfig()
+	config.EnableFile = false
+	logger.Init(&config)
+	os.Exit(m.Run())
+}
+
+func TestPollOnce(t *testing.T) {
+	t.Parallel()
+
+	notionSrv := notionapitest.NewServer()
+	defer notionSrv.Close()
+	db := notionSrv.AddDatabase(notionapi.Database{
+		Parent: notionapi.Parent{Type: notionapi.ParentTypePage, PageID: "b0668f48-8d66-4733-9bdb-2f82215707f7"},
+		Properties: notionapi.DatabaseProperties{
+			"Task": {Type: notionapi.DBPropTypeTitle},
+		},
+	})
+	notionAPI := notion.New("secret-api-key", db.ID,
+		notion.WithHTTPClient(notionSrv.Server.Client()),
+		notion.WithClientOptions(notionapi.WithBaseURL(notionSrv.BaseURL())),
+	)
+	ctx := context.Background()
+	if err := notionAPI.ApplySchema(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	todoSrv := todoapitest.NewServer()
+	defer todoSrv.Close()
+	list := todoSrv.AddList(todoapi.TaskList{DisplayName: "Tasks", WellKnownListName: "defaultList"})
+	milk := todoSrv.AddTask(list.Id, todoapi.Task{DisplayName: "Buy milk", Importance: "high"})
+	trip := todoSrv.AddTask(list.Id, todoapi.Task{DisplayName: "Plan trip"})
+
+	api, err := New("client-id", "client-secret", notionAPI,
+		WithClientOptions(append(todoSrv.ClientOptions(), todoapi.WithRefreshToken("refresh-token"))...))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := api.SyncOnce(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type row struct {
+		Task, Priority string
+		Done, Deleted  bool
+	}
+	rows := func() []row {
+		var got []row
+		for _, page := range notionSrv.Pages(db.ID) {
+			props := page.Properties.(notionapi.DatabasePageProperties)
+			r := row{
+				Task:    props["Task"].Title[0].PlainText,
+				Done:    props["Done"].Checkbox != nil && *props["Done"].Checkbox,
+				Deleted: props["Deleted"].Checkbox != nil && *props["Deleted"].Checkbox,
+			}
+			if props["Priority"].Select != nil {
+				r.Priority = props["Priority"].Select.Name
+			}
+			got = append(got, r)
+		}
+		return got
+	}
+	exp := []row{
+		{Task: "Buy milk", Priority: "P0 🔥"},
+		{Task: "Plan trip", Priority: "P2"},
+	}
+	if diff := cmp.Diff(exp, rows()); diff != "" {
+		t.Fatalf("pages not equal (-exp, +got):\n%v", diff)
+	}
+
+	// The first poll starts a delta round, whose tasks were synced already.
+	td := api.(*todo)
+	w := &listWorker{taskListID: list.Id, notion: notionAPI, displayName: list.DisplayName}
+	tasks, synced, _, err := td.pollOnce(ctx, w, &todoapi.ListTasksResponse{}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tasks.OdataDeltaLink == "" {
+		t.Fatalf("expected a delta link, got %+v", tasks)
+	}
+
+	todoSrv.UpdateTask(list.Id, milk.Id, func(task *todoapi.Task) {
+		task.DisplayName = "Buy oat milk"
+		task.Status = "completed"
+	})
+	todoSrv.DeleteTask(list.Id, trip.Id)
+	todoSrv.AddTask(list.Id, todoapi.Task{DisplayName: "Call mom"})
+
+	if tasks, _, _, err = td.pollOnce(ctx, w, tasks, synced); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp = []row{
+		{Task: "Buy oat milk", Priority: "P0 🔥", Done: true},
+		{Task: "Plan trip", Priority: "P2", Deleted: true},
+		{Task: "Call mom", Priority: "P2"},
+	}
+	if diff := cmp.Diff(exp, rows()); diff != "" {
+		t.Fatalf("pages not equal (-exp, +got):\n%v", diff)
+	}
+
+	// An expired delta link restarts with a full delta, which writes the
+	// changes made in the meantime.
+	todoSrv.ExpireDeltaTokens()
+	todoSrv.UpdateTask(list.Id, milk.Id, func(task *todoapi.Task) {
+		task.DisplayName = "Buy soy milk"
+	})
+	expired := tasks.OdataDeltaLink
+	if tasks, _, _, err = td.pollOnce(ctx, w, tasks, synced); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tasks.OdataDeltaLink == "" || tasks.OdataDeltaLink == expired {
+		t.Fatalf("expected a new delta link, got %+v", tasks)
+	}
+	exp[0].Task = "Buy soy milk"
+	if diff := cmp.Diff(exp, rows()); diff != "" {
+		t.Fatalf("pages not equal (-exp, +got):\n%v", diff)
+	}
+
+	todoSrv.AddTask(list.Id, todoapi.Task{DisplayName: "Book flights"})
+	if _, _, _, err = td.pollOnce(ctx, w, tasks, synced); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp = append(exp, row{Task: "Book flights", Priority: "P2"})
+	if diff := cmp.Diff(exp, rows()); diff != "" {
+		t.Fatalf("pages not equal (-exp, +got):\n%v", diff)
+	}
+}
+
+func TestSyncOnceFailedWrites(t *testing.T) {
+	t.Parallel()
+
+	notionSrv := notionapitest.NewServer()
+	defer notionSrv.Close()
+	db := notionSrv.AddDatabase(notionapi.Database{
+		Parent: notionapi.Parent{Type: notionapi.ParentTypePage, PageID: "b0668f48-8d66-4733-9bdb-2f82215707f7"},
+		Properties: notionapi.DatabaseProperties{
+			"Task": {Type: notionapi.DBPropTypeTitle},
+		},
+	})
+	notionAPI := notion.New("secret-api-key", db.ID,
+		notion.WithHTTPClient(notionSrv.Server.Client()),
+		notion.WithClientOptions(notionapi.WithBaseURL(notionSrv.BaseURL())),
+	)
+	ctx := context.Background()
+	if err := notionAPI.ApplySchema(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	todoSrv := todoapitest.NewServer()
+	defer todoSrv.Close()
+	list := todoSrv.AddList(todoapi.TaskList{DisplayName: "Failing writes"})
+	todoSrv.AddTask(list.Id, todoapi.Task{DisplayName: "Buy milk"})
+	todoSrv.AddTask(list.Id, todoapi.Task{DisplayName: "Plan trip"})
+
+	api, err := New("client-id", "client-secret", notionAPI,
+		WithClientOptions(append(todoSrv.ClientOptions(), todoapi.WithRefreshToken("refresh-token"))...))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	notionSrv.Fail(notionapitest.Failure{Method: "POST", Path: "/pages", Status: http.StatusBadRequest, Times: -1})
+	if err := api.SyncOnce(ctx); err == nil {
+		t.Fatal("expected an error when every task write fails")
+	}
+	if pages := notionSrv.Pages(db.ID); len(pages) != 0 {
+		t.Fatalf("expected no pages, got %v", len(pages))
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if body := rec.Body.String(); strings.Contains(body, `notionsync_last_sync_timestamp_seconds{list="Failing writes"}`) {
+		t.Fatalf("expected no last sync time for a failed sync, got %v", body)
+	}
+}
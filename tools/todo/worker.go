@@ -0,0 +1,176 @@
+package todo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"notionsync/pkg/logger"
+	"notionsync/pkg/metrics"
+	"notionsync/pkg/todoapi"
+	"notionsync/tools/notion"
+)
+
+// listWorker syncs a single task list into its Notion database until
+// cancelled.
+type listWorker struct {
+	taskListID        string
+	wellKnownListName string
+	databaseID        string
+	notion            notion.API
+	cancel            context.CancelFunc
+
+	mu          sync.Mutex
+	displayName string
+	state       string
+	startedAt   time.Time
+	lastDelta   time.Time
+	lastError   string
+	backlog     int
+}
+
+func (w *listWorker) name() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.displayName
+}
+
+func (w *listWorker) rename(displayName string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.displayName = displayName
+}
+
+func (w *listWorker) setState(state string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = state
+}
+
+func (w *listWorker) setError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = StateFailing
+	w.lastError = err.Error()
+}
+
+// setBacklog records how many tasks of the current delta are left to write,
+// and when a delta was last fetched.
+func (w *listWorker) setBacklog(backlog int, fetched bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.backlog = backlog
+	if fetched {
+		w.lastDelta = time.Now()
+		w.lastError = ""
+		if w.state == StateFailing {
+			w.state = StateWaiting
+		}
+	}
+}
+
+func (w *listWorker) status() ListStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return ListStatus{
+		TaskListID:  w.taskListID,
+		DisplayName: w.displayName,
+		DatabaseID:  w.databaseID,
+		State:       w.state,
+		StartedAt:   w.startedAt,
+		LastDelta:   w.lastDelta,
+		LastError:   w.lastError,
+		Backlog:     w.backlog,
+	}
+}
+
+// syncListWorkers reconciles the running workers with the current task lists:
+// new lists get a worker, removed or no longer routed lists have theirs
+// stopped, and renamed lists are renamed on the Notion side too.
+func (t *todo) syncListWorkers(ctx context.Context, listTaskLists []todoapi.TaskList, workers map[string]*listWorker) {
+	logger.Debugf("list len: %v", len(listTaskLists))
+
+	seen := make(map[string]bool, len(listTaskLists))
+	for _, taskList := range listTaskLists {
+		seen[taskList.Id] = true
+
+		databaseID, ok := t.router.route(taskList)
+		w, running := workers[taskList.Id]
+
+		if running && (!ok || w.databaseID != databaseID) {
+			logger.Infof("task list route changed, stop: %v", w.name())
+			w.cancel()
+			delete(workers, taskList.Id)
+			running = false
+		}
+
+		if !ok {
+			logger.Debugf("task list skipped: %v, wellKnownListName: %v", taskList.DisplayName, taskList.WellKnownListName)
+			continue
+		}
+
+		if !running {
+			workers[taskList.Id] = t.startListWorker(ctx, taskList, databaseID)
+			continue
+		}
+
+		if oldName := w.name(); oldName != taskList.DisplayName {
+			logger.Infof("task list renamed: %v -> %v", oldName, taskList.DisplayName)
+			if err := w.notion.RenameTaskList(ctx, oldName, taskList.DisplayName); err != nil {
+				logger.Warnf("notion rename task list failed: %v", err)
+				continue
+			}
+			w.rename(taskList.DisplayName)
+			metrics.DeleteLastSync(oldName)
+		}
+	}
+
+	for id, w := range workers {
+		if seen[id] {
+			continue
+		}
+		logger.Infof("task list deleted, stop: %v", w.name())
+		w.cancel()
+		delete(workers, id)
+		metrics.DeleteLastSync(w.name())
+	}
+
+	running := make([]*listWorker, 0, len(workers))
+	for _, w := range workers {
+		running = append(running, w)
+	}
+
+	t.mu.Lock()
+	t.running = running
+	t.mu.Unlock()
+}
+
+func (t *todo) startListWorker(ctx context.Context, taskList todoapi.TaskList, databaseID string) *listWorker {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &listWorker{
+		taskListID:        taskList.Id,
+		wellKnownListName: taskList.WellKnownListName,
+		databaseID:        databaseID,
+		notion:            t.notion.WithDatabaseID(databaseID),
+		cancel:            cancel,
+		displayName:       taskList.DisplayName,
+		state:             StateStarting,
+		startedAt:         time.Now(),
+	}
+
+	go t.deltaLoop(ctx, w)
+	return w
+}
+
+// sleepContext pauses for d, returning false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}